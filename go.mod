@@ -5,10 +5,15 @@ go 1.13
 require github.com/sirupsen/logrus v1.4.2
 
 require (
+	github.com/fsnotify/fsnotify v1.4.7
 	github.com/golang/mock v1.4.3
 	github.com/gorilla/mux v1.7.4
+	github.com/lib/pq v1.10.9
+	github.com/mattn/go-sqlite3 v1.14.6
 	github.com/pin/tftp v2.1.0+incompatible
 	github.com/spf13/pflag v1.0.3
 	github.com/spf13/viper v1.6.2
+	go.etcd.io/bbolt v1.3.5
 	go.uber.org/atomic v1.4.0
+	gopkg.in/yaml.v2 v2.2.4
 )
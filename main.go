@@ -4,13 +4,18 @@ import (
 	"errors"
 	"fmt"
 	"github.com/pxecore/pxecore/pkg/controller"
+	"github.com/pxecore/pxecore/pkg/dhcp"
+	"github.com/pxecore/pxecore/pkg/entity"
 	"github.com/pxecore/pxecore/pkg/http"
 	repo "github.com/pxecore/pxecore/pkg/repository"
+	"github.com/pxecore/pxecore/pkg/secret"
+	"github.com/pxecore/pxecore/pkg/template"
 	"github.com/pxecore/pxecore/pkg/tftp"
 	"github.com/pxecore/pxecore/pkg/tftp/locator"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
+	"net"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -19,6 +24,7 @@ import (
 )
 
 var tftpServer *tftp.Server
+var dhcpServer *dhcp.Server
 var repository repo.Repository
 
 func main() {
@@ -34,12 +40,32 @@ func main() {
 	if err != nil {
 		log.WithError(err).Fatal("Error loading repository.")
 	}
-	repository = r
+	var k *secret.Keyring
+	if viper.GetString("secrets.key") != "" {
+		if k, err = secret.NewKeyring(viper.GetStringMap("secrets")); err != nil {
+			log.WithError(err).Fatal("Error loading secrets key.")
+		}
+		if n, err := repo.RotateSecrets(r, k); err != nil {
+			log.WithError(err).Warn("Error rotating secrets.")
+		} else if n > 0 {
+			log.WithField("entities", n).Info("Secrets rotated.")
+		}
+	}
+	repository = repo.NewSecretRepository(r, k)
+
+	aa := net.ParseIP(viper.GetString("advertise-address"))
+	bu := viper.GetString("dhcp.boot-url")
+	if _, port, err := net.SplitHostPort(viper.GetString("http.address")); err == nil && bu == "" && aa != nil {
+		bu = fmt.Sprintf("http://%s", net.JoinHostPort(aa.String(), port))
+	}
 
 	tftpServer = new(tftp.Server)
+	sl := locator.NewRepositoryIPXEScript(repository,
+		template.Context{ServerAddr: viper.GetString("advertise-address"), BaseURL: bu})
+	sl.Discovery = viper.GetBool("discovery.enabled")
 	fl := []tftp.FileLocator{
 		locator.NewIPXEFirmware(),
-		locator.NewRepositoryIPXEScript(repository),
+		sl,
 	}
 	if basedir != "" {
 		fl = append(fl, locator.NewStaticFile(basedir, "/"))
@@ -49,14 +75,60 @@ func main() {
 		Timeout:      viper.GetDuration("tftp.timeout"),
 		LogRequests:  viper.GetBool("verbose"),
 		FileLocators: fl,
+		Events: func(e entity.BootEvent, n int64, err error) error {
+			return template.RecordEvent(repository, e, n, err)
+		},
 	}); err != nil {
 		log.Fatal(err)
 	}
 
+	if viper.GetBool("dhcp.enabled") {
+		if aa == nil || aa.To4() == nil {
+			log.WithField("advertise-address", viper.GetString("advertise-address")).
+				Fatal("DHCP server requires an IPv4 advertise-address.")
+		}
+		cu := viper.GetString("dhcp.chain-url")
+		if cu == "" && bu != "" {
+			cu = fmt.Sprintf("%s/boot/uuid/${uuid}.ipxe?mac=${net0/mac:hexhyp}&platform=${platform}&buildarch=${buildarch}", bu)
+		} else if cu == "" {
+			cu = fmt.Sprintf("tftp://%s/mac-${net0/mac:hexhyp}.ipxe?uuid=${uuid}&platform=${platform}&buildarch=${buildarch}",
+				aa)
+		}
+		pools, err := dhcp.NewPools(viper.Get("dhcp.pools"))
+		if err != nil {
+			log.WithError(err).Fatal("Error loading DHCP pools.")
+		}
+		dhcpServer = new(dhcp.Server)
+		if err := dhcpServer.StartInBackground(dhcp.ServerConfig{
+			Address:          viper.GetString("dhcp.address"),
+			ProxyAddress:     viper.GetString("dhcp.proxy-address"),
+			AdvertiseAddress: aa,
+			ChainURL:         cu,
+			BootURL:          bu,
+			LogRequests:      viper.GetBool("verbose"),
+			Mode:             viper.GetString("dhcp.mode"),
+			Pools:            pools,
+			Repository:       repository,
+		}); err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	cs := []http.Controller{
 		controller.Template{Repository: repository},
 		controller.Host{Repository: repository},
 		controller.Group{Repository: repository},
+		controller.Rule{Repository: repository},
+		controller.Facts{Repository: repository},
+		controller.Event{Repository: repository},
+		controller.Lifecycle{Repository: repository},
+		controller.Boot{Repository: repository, ServerAddr: viper.GetString("advertise-address"),
+			Discovery: viper.GetBool("discovery.enabled")},
+		controller.Lease{Repository: repository},
+		controller.Reservation{Repository: repository},
+	}
+	if viper.GetBool("discovery.enabled") {
+		cs = append(cs, controller.Discovery{Repository: repository})
 	}
 	if basedir != "" {
 		cs = append(cs, controller.Static{BaseDir: basedir})
@@ -76,6 +148,14 @@ func loadDefaultConfig() {
 		"address": ":69",
 		"timeout": 5 * time.Second,
 	})
+	viper.SetDefault("dhcp", map[string]interface{}{
+		"enabled":       false,
+		"mode":          dhcp.ModeProxy,
+		"address":       ":67",
+		"proxy-address": ":4011",
+		"chain-url":     "",
+		"boot-url":      "",
+	})
 	viper.SetDefault("http", map[string]interface{}{
 		"address":       ":80",
 		"read-timeout":  10,
@@ -84,6 +164,9 @@ func loadDefaultConfig() {
 	viper.SetDefault("db", map[string]interface{}{
 		"driver": "memory",
 	})
+	viper.SetDefault("discovery", map[string]interface{}{
+		"enabled": false,
+	})
 }
 
 // loadCoreConfig defines the flags and environment used by the server.
@@ -101,6 +184,8 @@ func loadCoreConfig() {
 	viper.BindEnv("logfile")
 	pflag.StringP("basedir", "b", "", "Static file directory.")
 	viper.BindEnv("basedir")
+	pflag.StringP("advertise-address", "a", "", "IPv4 address announced to the PXE clients.")
+	viper.BindEnv("advertise-address")
 	pflag.Parse()
 	if err := viper.BindPFlags(pflag.CommandLine); err != nil {
 		log.Warn("Error reading flags: ", err)
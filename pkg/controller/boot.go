@@ -0,0 +1,140 @@
+package controller
+
+import (
+	"bytes"
+	"github.com/gorilla/mux"
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+	server "github.com/pxecore/pxecore/pkg/http"
+	"github.com/pxecore/pxecore/pkg/repository"
+	"github.com/pxecore/pxecore/pkg/template"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+var (
+	hardwareAddrRegex, _ = regexp.Compile("^([0-9a-fA-F]{2}[-:]){5}[0-9a-fA-F]{2}$")
+)
+
+//~ STRUCT - Server -----------------------------------------------------------
+
+// Boot controller for the "/boot" base path operations.
+// Serves the same iPXE scripts as the TFTP server so the whole boot can go over HTTP.
+type Boot struct {
+	Repository repository.Repository // Repository dependency injection.
+	ServerAddr string                // Advertised address exposed to the templates, defaults to the request host.
+	Discovery  bool                  // Serves the discovery template to the unknown hardware addresses.
+}
+
+// Register implements http.Controller interface.
+func (t Boot) Register(r *mux.Router, config server.Config) {
+	r.HandleFunc("/boot/mac/{mac:(?:[0-9a-fA-F]{2}[-:]){5}[0-9a-fA-F]{2}}.ipxe", t.Get).Methods(http.MethodGet)
+	r.HandleFunc("/boot", t.Get).Queries("mac", "{mac}").Methods(http.MethodGet)
+	r.HandleFunc("/boot/{kind:uuid|serial|asset}/{value:[^/]*}.ipxe", t.GetByIdentifier).Methods(http.MethodGet)
+}
+
+// Get compiles the template of the host owning the hardware address.
+// Triggers the host trap like a TFTP boot would. Unknown hardware addresses
+// are served the discovery template when Discovery is enabled.
+func (t Boot) Get(w http.ResponseWriter, r *http.Request) {
+	v := mux.Vars(r)
+	mac, _ := v["mac"]
+	if !hardwareAddrRegex.MatchString(mac) {
+		server.WriteText(w, "[controller.Boot] mac should be a hardware address", http.StatusBadRequest)
+		return
+	}
+	mac = strings.Replace(strings.ToLower(mac), ":", "-", -1)
+	ctx := t.context(r)
+	ctx.HardwareAddr = mac
+	e := ctx.NewEvent()
+
+	buf := new(bytes.Buffer)
+	t.write(w, buf, t.compileHardwareAddr(buf, mac, ctx.WithEvent(&e)), e)
+}
+
+// GetByIdentifier compiles the template of the host owning the SMBIOS uuid, serial or asset tag.
+// Unset or unknown identifiers fall back to the optional "mac" query param like Get,
+// so the boot script can send ${uuid} and ${net0/mac} in a single request.
+func (t Boot) GetByIdentifier(w http.ResponseWriter, r *http.Request) {
+	v := mux.Vars(r)
+	kind, value := v["kind"], v["value"]
+	mac := r.URL.Query().Get("mac")
+	if mac != "" && !hardwareAddrRegex.MatchString(mac) {
+		server.WriteText(w, "[controller.Boot] mac should be a hardware address", http.StatusBadRequest)
+		return
+	}
+	mac = strings.Replace(strings.ToLower(mac), ":", "-", -1)
+	ctx := t.context(r)
+	ctx.HardwareAddr = mac
+	e := ctx.NewEvent()
+	ctx = ctx.WithEvent(&e)
+
+	buf := new(bytes.Buffer)
+	var err error = &errors.Error{Code: errors.ERepositoryKeyNotFound,
+		Msg: "[controller.Boot] " + kind + " is not set"}
+	if value != "" && (kind != entity.IdentifierUUID || !entity.PlaceholderUUID(value)) {
+		err = template.CompileWithIdentifier(buf, t.Repository, kind, value, "", ctx)
+	}
+	if mac != "" && errors.Is(err, errors.ERepositoryKeyNotFound) {
+		buf.Reset()
+		err = t.compileHardwareAddr(buf, mac, ctx)
+	}
+	t.write(w, buf, err, e)
+}
+
+// compileHardwareAddr compiles the template of the host owning the hardware address,
+// or the discovery template if the address is unknown and Discovery is enabled.
+func (t Boot) compileHardwareAddr(buf *bytes.Buffer, mac string, ctx template.Context) error {
+	err := template.CompileWithHardwareAddr(buf, t.Repository, mac, "", ctx)
+	if t.Discovery && errors.Is(err, errors.ERepositoryKeyNotFound) {
+		buf.Reset()
+		err = template.Discover(buf, t.Repository, mac, ctx)
+	}
+	return err
+}
+
+// write answers the compiled script or the error of the boot request and records its boot event.
+// Failing to record the event doesn't fail the boot.
+func (t Boot) write(w http.ResponseWriter, buf *bytes.Buffer, err error, e entity.BootEvent) {
+	_ = template.RecordEvent(t.Repository, e, int64(buf.Len()), err)
+	if err != nil {
+		if errors.Is(err, errors.ERepositoryKeyNotFound) {
+			server.WriteText(w, err.Error(), http.StatusNotFound)
+		} else {
+			server.WriteText(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	server.WriteText(w, buf.String(), http.StatusOK)
+}
+
+// context returns the template context of the boot request.
+func (t Boot) context(r *http.Request) template.Context {
+	ctx := requestContext(r)
+	if t.ServerAddr != "" {
+		ctx.ServerAddr = t.ServerAddr
+	}
+	return ctx
+}
+
+// requestContext returns the template context of an HTTP request.
+func requestContext(r *http.Request) template.Context {
+	ctx := template.Context{Transport: entity.BootTransportHTTP, Path: r.URL.RequestURI()}
+	if ip, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		ctx.ClientIP = ip
+	}
+	if r.TLS != nil {
+		ctx.BaseURL = "https://" + r.Host
+	} else {
+		ctx.BaseURL = "http://" + r.Host
+	}
+	if h, _, err := net.SplitHostPort(r.Host); err == nil {
+		ctx.ServerAddr = h
+	} else {
+		ctx.ServerAddr = r.Host
+	}
+	ctx.LoadQuery(r.URL.Query())
+	return ctx
+}
@@ -0,0 +1,147 @@
+package controller
+
+import (
+	"bytes"
+	"github.com/gorilla/mux"
+	"github.com/pxecore/pxecore/pkg/entity"
+	server "github.com/pxecore/pxecore/pkg/http"
+	"github.com/pxecore/pxecore/pkg/repository"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBoot(t *testing.T) {
+	r, _ := repository.NewRepository(map[string]interface{}{"driver": "memory"})
+	_ = r.Write(func(session repository.Session) error {
+		_ = session.Template().Create(entity.Template{ID: "template1", Template: "#!ipxe\necho {{ .HostID }} " +
+			"{{ .Context.Transport }} {{ .Context.Firmware }} {{ .Context.ServerAddr }}"})
+		_ = session.Host().Create(entity.Host{ID: "host1", HardwareAddr: []string{"00-14-22-04-25-ab"},
+			TemplateID: "template1"})
+		return nil
+	})
+	ro := mux.NewRouter()
+	ss := Boot{Repository: r, ServerAddr: "10.0.0.1"}
+	ss.Register(ro, server.Config{})
+	tests := []struct {
+		name           string
+		path           string
+		wantStatusCode int
+		wantResponse   string
+	}{
+		{"OK_PATH", "/boot/mac/00-14-22-04-25-ab.ipxe",
+			http.StatusOK, "#!ipxe\necho host1 http  10.0.0.1"},
+		{"OK_PATH_UPPERCASE", "/boot/mac/00-14-22-04-25-AB.ipxe",
+			http.StatusOK, "#!ipxe\necho host1 http  10.0.0.1"},
+		{"OK_QUERY", "/boot?mac=00:14:22:04:25:ab",
+			http.StatusOK, "#!ipxe\necho host1 http  10.0.0.1"},
+		{"OK_UEFI", "/boot/mac/00-14-22-04-25-ab.ipxe?platform=efi&buildarch=x86_64",
+			http.StatusOK, "#!ipxe\necho host1 http uefi 10.0.0.1"},
+		{"KO_QUERY_INVALID", "/boot?mac=00:14:22",
+			http.StatusBadRequest, ""},
+		{"KO_NOT_FOUND", "/boot/mac/00-14-22-04-25-38.ipxe",
+			http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, tt.path, bytes.NewBuffer([]byte{}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			rr := httptest.NewRecorder()
+			ro.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tt.wantStatusCode {
+				t.Errorf("handler returned wrong status code: got %v want %v",
+					status, tt.wantStatusCode)
+			}
+
+			if body := rr.Body.String(); tt.wantResponse != "" && body != tt.wantResponse {
+				t.Errorf("handler returned wrong body: got %v want %v",
+					body, tt.wantResponse)
+			}
+		})
+	}
+}
+
+func TestBoot_Identifier(t *testing.T) {
+	r, _ := repository.NewRepository(map[string]interface{}{"driver": "memory"})
+	_ = r.Write(func(session repository.Session) error {
+		_ = session.Template().Create(entity.Template{ID: "template1",
+			Template: "#!ipxe\necho {{ .HostID }} {{ .Context.HardwareAddr }}"})
+		_ = session.Template().Create(entity.Template{ID: "discovery", Template: "#!ipxe\necho discovery"})
+		_ = session.Host().Create(entity.Host{ID: "host1", HardwareAddr: []string{"00-14-22-04-25-ab"},
+			TemplateID: "template1", UUID: "4c4c4544-0042-3010-8052-b4c04f4e4e32", Serial: "CN1234", AssetTag: "A-1"})
+		return session.Host().Create(entity.Host{ID: "host2", HardwareAddr: []string{"00-14-22-04-25-ac"},
+			TemplateID: "template1"})
+	})
+	ro := mux.NewRouter()
+	Boot{Repository: r}.Register(ro, server.Config{})
+	rd := mux.NewRouter()
+	Boot{Repository: r, Discovery: true}.Register(rd, server.Config{})
+	tests := []struct {
+		name           string
+		router         *mux.Router
+		path           string
+		wantStatusCode int
+		wantResponse   string
+	}{
+		{"OK_UUID", ro, "/boot/uuid/4C4C4544-0042-3010-8052-B4C04F4E4E32.ipxe",
+			http.StatusOK, "#!ipxe\necho host1 "},
+		{"OK_UUID_SWAPPED_NIC", ro, "/boot/uuid/4c4c4544-0042-3010-8052-b4c04f4e4e32.ipxe?mac=00-14-22-04-25-ff",
+			http.StatusOK, "#!ipxe\necho host1 00-14-22-04-25-ff"},
+		{"OK_SERIAL", ro, "/boot/serial/CN1234.ipxe", http.StatusOK, "#!ipxe\necho host1 "},
+		{"OK_ASSET_TAG", ro, "/boot/asset/A-1.ipxe", http.StatusOK, "#!ipxe\necho host1 "},
+		{"OK_FALLBACK_MAC", ro, "/boot/uuid/4c4c4544-0042-3010-8052-000000000000.ipxe?mac=00:14:22:04:25:AC",
+			http.StatusOK, "#!ipxe\necho host2 00-14-22-04-25-ac"},
+		{"OK_FALLBACK_EMPTY_UUID", ro, "/boot/uuid/.ipxe?mac=00-14-22-04-25-ac",
+			http.StatusOK, "#!ipxe\necho host2 00-14-22-04-25-ac"},
+		{"OK_FALLBACK_PLACEHOLDER_UUID", ro,
+			"/boot/uuid/FFFFFFFF-FFFF-FFFF-FFFF-FFFFFFFFFFFF.ipxe?mac=00-14-22-04-25-ac",
+			http.StatusOK, "#!ipxe\necho host2 00-14-22-04-25-ac"},
+		{"OK_FALLBACK_DISCOVERY", rd, "/boot/uuid/.ipxe?mac=00-14-22-04-25-ff",
+			http.StatusOK, "#!ipxe\necho discovery"},
+		{"KO_NOT_FOUND", ro, "/boot/serial/CN0000.ipxe", http.StatusNotFound, ""},
+		{"KO_FALLBACK_NOT_FOUND", ro, "/boot/uuid/.ipxe?mac=00-14-22-04-25-ff", http.StatusNotFound, ""},
+		{"KO_MAC_INVALID", ro, "/boot/serial/CN1234.ipxe?mac=00-14", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, tt.path, bytes.NewBuffer([]byte{}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			rr := httptest.NewRecorder()
+			tt.router.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tt.wantStatusCode {
+				t.Errorf("handler returned wrong status code: got %v want %v",
+					status, tt.wantStatusCode)
+			}
+
+			if body := rr.Body.String(); tt.wantResponse != "" && body != tt.wantResponse {
+				t.Errorf("handler returned wrong body: got %v want %v",
+					body, tt.wantResponse)
+			}
+		})
+	}
+}
+
+func TestBoot_Discovery(t *testing.T) {
+	r, _ := repository.NewRepository(map[string]interface{}{"driver": "memory"})
+	_ = r.Write(func(session repository.Session) error {
+		return session.Template().Create(entity.Template{ID: "discovery", Template: "#!ipxe\necho {{ .Context.HardwareAddr }}"})
+	})
+	ro := mux.NewRouter()
+	Boot{Repository: r, Discovery: true}.Register(ro, server.Config{})
+	req, err := http.NewRequest(http.MethodGet, "/boot/mac/00-14-22-04-25-AC.ipxe", bytes.NewBuffer([]byte{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	ro.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK || rr.Body.String() != "#!ipxe\necho 00-14-22-04-25-ac" {
+		t.Errorf("handler returned %v %q want %v %q", rr.Code, rr.Body.String(), http.StatusOK,
+			"#!ipxe\necho 00-14-22-04-25-ac")
+	}
+}
@@ -0,0 +1,135 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+	server "github.com/pxecore/pxecore/pkg/http"
+	"github.com/pxecore/pxecore/pkg/repository"
+	"net/http"
+	"strings"
+)
+
+const (
+	// discoveryPendingScript is the iPXE script answered to the discovered machines,
+	// they keep rebooting into the discovery template until an operator approves them.
+	discoveryPendingScript = "#!ipxe\necho Host %v is pending approval, rebooting in 60 seconds.\nsleep 60\nreboot\n"
+)
+
+//~ STRUCT - Server -----------------------------------------------------------
+
+// Discovery controller for the "/discovery" base path operations.
+// Registers the unknown machines booting the discovery template as pending hosts.
+type Discovery struct {
+	Repository repository.Repository // Repository dependency injection.
+}
+
+// Register implements http.Controller interface.
+func (t Discovery) Register(r *mux.Router, config server.Config) {
+	r.HandleFunc("/discovery", t.Post).Methods(http.MethodPost)
+}
+
+// Post registers the facts of a machine, sent as JSON or as the form posted by the discovery template.
+// Unknown hardware addresses create a pending host and pending hosts get their facts updated.
+func (t Discovery) Post(w http.ResponseWriter, r *http.Request) {
+	body := DiscoveryBody{}
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			server.WriteJSON(w, errors.MarshalJSON(err), http.StatusBadRequest)
+			return
+		}
+	} else {
+		if err := r.ParseForm(); err != nil {
+			server.WriteJSON(w, errors.MarshalJSON(err), http.StatusBadRequest)
+			return
+		}
+		body.LoadForm(r)
+	}
+	if err := body.Validate(); err != nil {
+		server.WriteJSON(w, errors.MarshalJSON(err), http.StatusBadRequest)
+		return
+	}
+	mac := strings.Replace(strings.ToLower(body.HardwareAddr), ":", "-", -1)
+
+	var ID string
+	if err := t.Repository.Write(func(session repository.Session) error {
+		h, err := session.Host().FindByHardwareAddr(mac)
+		if errors.Is(err, errors.ERepositoryKeyNotFound) {
+			ID = "mac" + strings.Replace(mac, "-", "", -1)
+			err = session.Host().Create(entity.Host{ID: ID, HardwareAddr: []string{mac}, Pending: true})
+		} else if err != nil {
+			return err
+		} else if !h.Pending {
+			return &errors.Error{Code: errors.ERepositoryKeyExist,
+				Msg: fmt.Sprintf("[controller.Discovery] hardware address %v belongs to host %v.", mac, h.ID)}
+		} else {
+			ID = h.ID
+		}
+		if err != nil {
+			return err
+		}
+		return session.Facts().Create(body.ToFacts(ID))
+	}); err != nil {
+		if errors.Is(err, errors.ERepositoryKeyExist) {
+			server.WriteJSON(w, errors.MarshalJSON(err), http.StatusConflict)
+		} else {
+			server.WriteJSON(w, errors.MarshalJSON(err), http.StatusInternalServerError)
+		}
+		return
+	}
+	server.WriteText(w, fmt.Sprintf(discoveryPendingScript, ID), http.StatusCreated)
+}
+
+//~ STRUCT - JSON -----------------------------------------------------------
+
+// DiscoveryBody stores the hardware facts reported by a discovered machine.
+type DiscoveryBody struct {
+	HardwareAddr string `json:"mac"`
+	Manufacturer string `json:"manufacturer,omitempty"`
+	Product      string `json:"product,omitempty"`
+	Serial       string `json:"serial,omitempty"`
+	UUID         string `json:"uuid,omitempty"`
+	Asset        string `json:"asset,omitempty"`
+	Platform     string `json:"platform,omitempty"` // iPXE platform: "pcbios" or "efi".
+	Arch         string `json:"buildarch,omitempty"`
+}
+
+// LoadForm fills the body with the form values, named as the JSON fields.
+func (t *DiscoveryBody) LoadForm(r *http.Request) {
+	t.HardwareAddr = r.FormValue("mac")
+	t.Manufacturer = r.FormValue("manufacturer")
+	t.Product = r.FormValue("product")
+	t.Serial = r.FormValue("serial")
+	t.UUID = r.FormValue("uuid")
+	t.Asset = r.FormValue("asset")
+	t.Platform = r.FormValue("platform")
+	t.Arch = r.FormValue("buildarch")
+}
+
+// Validate checks if the data hold in the instance follows the desired schema.
+func (t DiscoveryBody) Validate() error {
+	if !hardwareAddrRegex.MatchString(t.HardwareAddr) {
+		return &errors.Error{
+			Code: errors.EInvalidType,
+			Msg:  "[controller.Discovery] mac should be a hardware address.",
+		}
+	}
+	return nil
+}
+
+// ToFacts returns the reported facts as a facts revision of the host.
+func (t DiscoveryBody) ToFacts(hostID string) entity.Facts {
+	return entity.Facts{
+		HostID:       hostID,
+		Source:       factsSourceDiscovery,
+		Manufacturer: strings.TrimSpace(t.Manufacturer),
+		Product:      strings.TrimSpace(t.Product),
+		Serial:       strings.TrimSpace(t.Serial),
+		UUID:         strings.TrimSpace(t.UUID),
+		AssetTag:     strings.TrimSpace(t.Asset),
+		Firmware:     ipxeFirmware(t.Platform),
+		Arch:         strings.TrimSpace(t.Arch),
+	}
+}
@@ -0,0 +1,114 @@
+package controller
+
+import (
+	"bytes"
+	"github.com/gorilla/mux"
+	"github.com/pxecore/pxecore/pkg/entity"
+	server "github.com/pxecore/pxecore/pkg/http"
+	"github.com/pxecore/pxecore/pkg/repository"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestDiscovery(t *testing.T) {
+	r, _ := repository.NewRepository(map[string]interface{}{"driver": "memory"})
+	_ = r.Write(func(session repository.Session) error {
+		_ = session.Group().Create(entity.Group{ID: "group1"})
+		_ = session.Host().Create(entity.Host{ID: "node-1", HardwareAddr: []string{"00-14-22-04-25-38"}, Pending: true})
+		return session.Host().Create(entity.Host{ID: "host1", HardwareAddr: []string{"00-14-22-04-25-37"}})
+	})
+	ro := mux.NewRouter()
+	Discovery{Repository: r}.Register(ro, server.Config{})
+	Host{Repository: r}.Register(ro, server.Config{})
+	Facts{Repository: r}.Register(ro, server.Config{})
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		contentType    string
+		body           string
+		wantStatusCode int
+		wantResponse   string
+	}{
+		{"OK_DISCOVER_FORM", http.MethodPost, "/discovery",
+			"application/x-www-form-urlencoded", "mac=00:14:22:04:25:AA&manufacturer=Dell&serial=S1&uuid=",
+			http.StatusCreated,
+			"#!ipxe\necho Host mac0014220425aa is pending approval, rebooting in 60 seconds.\nsleep 60\nreboot\n"},
+		{"OK_FOUND_PENDING", http.MethodGet, "/host/mac0014220425aa",
+			"application/json", "",
+			http.StatusOK, "{\"id\":\"mac0014220425aa\",\"hardware-addr\":[\"00-14-22-04-25-aa\"],\"trap-mode\":false," +
+				"\"vars\":null,\"group-id\":\"\",\"template-id\":\"\",\"pending\":true}"},
+		{"OK_REDISCOVER_JSON", http.MethodPost, "/discovery",
+			"application/json", "{\"mac\":\"00-14-22-04-25-aa\",\"serial\":\"S2\"}",
+			http.StatusCreated, ""},
+		{"OK_FACTS", http.MethodGet, "/host/mac0014220425aa/facts/revision",
+			"application/json", "",
+			http.StatusOK, ""},
+		{"KO_DISCOVER_KNOWN", http.MethodPost, "/discovery",
+			"application/json", "{\"mac\":\"00-14-22-04-25-37\"}",
+			http.StatusConflict, ""},
+		{"KO_DISCOVER_INVALID_MAC", http.MethodPost, "/discovery",
+			"application/json", "{\"mac\":\"00-14-22\"}",
+			http.StatusBadRequest, ""},
+		{"KO_APPROVE_MISSING_GROUP", http.MethodPost, "/host/mac0014220425aa/approve",
+			"application/json", "{\"group-id\":\"group2\"}",
+			http.StatusFailedDependency, ""},
+		{"KO_APPROVE_NOT_FOUND", http.MethodPost, "/host/host2/approve",
+			"application/json", "",
+			http.StatusNotFound, ""},
+		{"OK_APPROVE", http.MethodPost, "/host/mac0014220425aa/approve",
+			"application/json", "{\"group-id\":\"group1\"}",
+			http.StatusNoContent, ""},
+		{"OK_FOUND_APPROVED", http.MethodGet, "/host/mac0014220425aa",
+			"application/json", "",
+			http.StatusOK, "{\"id\":\"mac0014220425aa\",\"hardware-addr\":[\"00-14-22-04-25-aa\"],\"trap-mode\":false," +
+				"\"vars\":null,\"group-id\":\"group1\",\"template-id\":\"\",\"state\":\"ready\"}"},
+		{"KO_DISCOVER_APPROVED", http.MethodPost, "/discovery",
+			"application/json", "{\"mac\":\"00-14-22-04-25-aa\"}",
+			http.StatusConflict, ""},
+		{"OK_APPROVE_HYPHEN_ID", http.MethodPost, "/host/node-1/approve",
+			"application/json", "{\"group-id\":\"group1\"}",
+			http.StatusNoContent, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, tt.path, bytes.NewBuffer([]byte(tt.body)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Add("Content-Type", tt.contentType)
+			rr := httptest.NewRecorder()
+			ro.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tt.wantStatusCode {
+				t.Errorf("handler returned wrong status code: got %v want %v",
+					status, tt.wantStatusCode)
+			}
+
+			if body := rr.Body.String(); tt.wantResponse != "" && body != tt.wantResponse {
+				t.Errorf("handler returned wrong body: got %v want %v",
+					body, tt.wantResponse)
+			}
+		})
+	}
+
+	_ = r.Read(func(session repository.Session) error {
+		l, err := session.Facts().ListRevisions("mac0014220425aa")
+		if err != nil || len(l) != 2 || l[0].Manufacturer != "Dell" || l[1].Serial != "S2" {
+			t.Errorf("wrong discovered facts: got %+v, %v", l, err)
+		}
+		return err
+	})
+}
+
+func TestDiscoveryBody_ToFacts(t *testing.T) {
+	got := DiscoveryBody{HardwareAddr: "00-14-22-04-25-aa", Manufacturer: " Dell ", Product: "", Asset: "A-1",
+		Platform: "efi", Arch: "x86_64"}.ToFacts("host1")
+	want := entity.Facts{HostID: "host1", Source: factsSourceDiscovery, Manufacturer: "Dell", AssetTag: "A-1",
+		Firmware: entity.FirmwareUEFI, Arch: "x86_64"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ToFacts() = %+v, want %+v", got, want)
+	}
+}
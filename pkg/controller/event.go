@@ -0,0 +1,120 @@
+package controller
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+	server "github.com/pxecore/pxecore/pkg/http"
+	"github.com/pxecore/pxecore/pkg/repository"
+	"net/http"
+	"time"
+)
+
+//~ STRUCT - Server -----------------------------------------------------------
+
+// Event controller for the boot events recorded by the TFTP and HTTP boot paths.
+type Event struct {
+	Repository repository.Repository // Repository dependency injection.
+}
+
+// Register implements http.Controller interface.
+func (t Event) Register(r *mux.Router, config server.Config) {
+	r.HandleFunc("/host/{id:[a-zA-Z0-9_-]+}/events", t.List).Methods(http.MethodGet)
+	r.HandleFunc("/event", t.Recent).Methods(http.MethodGet)
+}
+
+// List returns the kept boot events of a host, newest first.
+func (t Event) List(w http.ResponseWriter, r *http.Request) {
+	s := mux.Vars(r)["id"]
+	var l []EventBody
+	if err := t.Repository.Read(func(session repository.Session) error {
+		if _, err := session.Host().Get(s); err != nil {
+			return err
+		}
+		events, err := session.Event().List(s)
+		if err != nil {
+			return err
+		}
+		l = newEventBodies(events)
+		return nil
+	}); err != nil {
+		if errors.Is(err, errors.ERepositoryKeyNotFound) {
+			server.WriteJSON(w, errors.MarshalJSON(err), http.StatusNotFound)
+		} else {
+			server.WriteJSON(w, errors.MarshalJSON(err), http.StatusInternalServerError)
+		}
+		return
+	}
+	j, _ := json.Marshal(l)
+	server.WriteJSON(w, j, http.StatusOK)
+}
+
+// Recent returns the latest boot events of all the hosts, newest first.
+// The events of the requests matching no host are included with an empty host-id.
+func (t Event) Recent(w http.ResponseWriter, r *http.Request) {
+	_, limit, err := listParams(r)
+	if err != nil {
+		server.WriteJSON(w, errors.MarshalJSON(err), http.StatusBadRequest)
+		return
+	}
+	var l []EventBody
+	if err := t.Repository.Read(func(session repository.Session) error {
+		events, err := session.Event().Recent(limit)
+		if err != nil {
+			return err
+		}
+		l = newEventBodies(events)
+		return nil
+	}); err != nil {
+		server.WriteJSON(w, errors.MarshalJSON(err), http.StatusInternalServerError)
+		return
+	}
+	j, _ := json.Marshal(l)
+	server.WriteJSON(w, j, http.StatusOK)
+}
+
+//~ STRUCT - JSON -----------------------------------------------------------
+
+// EventBody stores the boot event response data.
+type EventBody struct {
+	Sequence         int       `json:"sequence"`
+	Time             time.Time `json:"time"`
+	HostID           string    `json:"host-id"`
+	HardwareAddr     string    `json:"hardware-addr,omitempty"`
+	ClientIP         string    `json:"client-ip,omitempty"`
+	Transport        string    `json:"transport"`
+	Path             string    `json:"path"`
+	TemplateID       string    `json:"template-id,omitempty"`
+	TemplateRevision int       `json:"template-revision,omitempty"` // 0 is the latest revision.
+	Outcome          string    `json:"outcome"`                     // "served", "not-found" or "failed".
+	Error            string    `json:"error,omitempty"`
+	Bytes            int64     `json:"bytes"`
+}
+
+// LoadEntity fills the body with the entity values.
+func (t *EventBody) LoadEntity(e entity.BootEvent) {
+	t.Sequence = e.Sequence
+	t.Time = e.Time
+	t.HostID = e.HostID
+	t.HardwareAddr = e.HardwareAddr
+	t.ClientIP = e.ClientIP
+	t.Transport = e.Transport
+	t.Path = e.Path
+	t.TemplateID = e.TemplateID
+	t.TemplateRevision = e.TemplateRevision
+	t.Outcome = e.Outcome
+	t.Error = e.Error
+	t.Bytes = e.Bytes
+}
+
+// newEventBodies returns the bodies of the events, keeping their order.
+func newEventBodies(events []entity.BootEvent) []EventBody {
+	l := make([]EventBody, 0, len(events))
+	for _, e := range events {
+		eb := EventBody{}
+		eb.LoadEntity(e)
+		l = append(l, eb)
+	}
+	return l
+}
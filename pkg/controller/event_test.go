@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/pxecore/pxecore/pkg/entity"
+	server "github.com/pxecore/pxecore/pkg/http"
+	"github.com/pxecore/pxecore/pkg/repository"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEvent(t *testing.T) {
+	r, _ := repository.NewRepository(map[string]interface{}{"driver": "memory"})
+	_ = r.Write(func(session repository.Session) error {
+		_ = session.Template().Create(entity.Template{ID: "template1", Template: "#!ipxe\necho {{ .HostID }}"})
+		_ = session.Template().Create(entity.Template{ID: "template2", Template: "#!ipxe\necho {{ .Missing.Field }}"})
+		_ = session.Host().Create(entity.Host{ID: "host1", HardwareAddr: []string{"00-14-22-04-25-ab"},
+			TemplateID: "template1"})
+		_ = session.Host().Create(entity.Host{ID: "node-1", HardwareAddr: []string{"00-14-22-04-25-ad"},
+			TemplateID: "template1"})
+		return session.Host().Create(entity.Host{ID: "host2", HardwareAddr: []string{"00-14-22-04-25-ac"},
+			TemplateID: "template2"})
+	})
+	ro := mux.NewRouter()
+	Boot{Repository: r}.Register(ro, server.Config{})
+	Event{Repository: r}.Register(ro, server.Config{})
+	for _, p := range []string{"/boot/mac/00-14-22-04-25-ab.ipxe", "/boot/mac/00-14-22-04-25-ac.ipxe",
+		"/boot/mac/00-14-22-04-25-ff.ipxe", "/boot/mac/00-14-22-04-25-ab.ipxe?platform=efi"} {
+		req, _ := http.NewRequest(http.MethodGet, p, bytes.NewBuffer([]byte{}))
+		ro.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	tests := []struct {
+		name           string
+		path           string
+		wantStatusCode int
+		wantOutcomes   []string
+		wantHosts      []string
+	}{
+		{"OK_HOST", "/host/host1/events", http.StatusOK,
+			[]string{entity.BootOutcomeServed, entity.BootOutcomeServed}, []string{"host1", "host1"}},
+		{"OK_HOST_FAILED", "/host/host2/events", http.StatusOK,
+			[]string{entity.BootOutcomeFailed}, []string{"host2"}},
+		{"KO_HOST_NOT_FOUND", "/host/host3/events", http.StatusNotFound, nil, nil},
+		{"OK_HYPHEN_ID", "/host/node-1/events", http.StatusOK, []string{}, []string{}},
+		{"OK_RECENT", "/event", http.StatusOK,
+			[]string{entity.BootOutcomeServed, entity.BootOutcomeNotFound, entity.BootOutcomeFailed,
+				entity.BootOutcomeServed}, []string{"host1", "", "host2", "host1"}},
+		{"OK_RECENT_LIMIT", "/event?limit=1", http.StatusOK,
+			[]string{entity.BootOutcomeServed}, []string{"host1"}},
+		{"KO_RECENT_LIMIT", "/event?limit=a", http.StatusBadRequest, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, tt.path, bytes.NewBuffer([]byte{}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			rr := httptest.NewRecorder()
+			ro.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tt.wantStatusCode {
+				t.Errorf("handler returned wrong status code: got %v want %v",
+					status, tt.wantStatusCode)
+			}
+			if tt.wantStatusCode != http.StatusOK {
+				return
+			}
+			var l []EventBody
+			if err := json.Unmarshal(rr.Body.Bytes(), &l); err != nil {
+				t.Fatal(err)
+			}
+			if len(l) != len(tt.wantOutcomes) {
+				t.Fatalf("handler returned wrong events: got %+v want outcomes %v", l, tt.wantOutcomes)
+			}
+			for i, e := range l {
+				if e.Outcome != tt.wantOutcomes[i] || e.HostID != tt.wantHosts[i] ||
+					e.Transport != "http" || e.Time.IsZero() {
+					t.Errorf("handler returned wrong event %v: got %+v want %v of %v",
+						i, e, tt.wantOutcomes[i], tt.wantHosts[i])
+				}
+				if e.Outcome == entity.BootOutcomeServed && (e.TemplateID != "template1" || e.Bytes == 0) {
+					t.Errorf("handler returned wrong served event: got %+v", e)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,309 @@
+package controller
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+	server "github.com/pxecore/pxecore/pkg/http"
+	"github.com/pxecore/pxecore/pkg/repository"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// factsSourceAgent is the source of the facts posted as JSON without source.
+	factsSourceAgent = "agent"
+	// factsSourceIPXE is the source of the facts posted as a form by an iPXE script.
+	factsSourceIPXE = "ipxe"
+	// factsSourceDiscovery is the source of the facts reported by the discovery template.
+	factsSourceDiscovery = "discovery"
+)
+
+//~ STRUCT - Server -----------------------------------------------------------
+
+// Facts controller for the "/host/{id}/facts" base path operations.
+// Every post records a new revision of the host hardware facts.
+type Facts struct {
+	Repository repository.Repository // Repository dependency injection.
+}
+
+// Register implements http.Controller interface.
+func (t Facts) Register(r *mux.Router, config server.Config) {
+	r.HandleFunc("/host/{id:[a-zA-Z0-9_-]+}/facts", t.Get).Methods(http.MethodGet)
+	r.HandleFunc("/host/{id:[a-zA-Z0-9_-]+}/facts", t.Post).Methods(http.MethodPost)
+	r.HandleFunc("/host/{id:[a-zA-Z0-9_-]+}/facts/revision", t.ListRevisions).Methods(http.MethodGet)
+	r.HandleFunc("/host/{id:[a-zA-Z0-9_-]+}/facts/revision/{revision:[0-9]+}", t.GetRevision).Methods(http.MethodGet)
+}
+
+// Get returns the latest facts of a host.
+func (t Facts) Get(w http.ResponseWriter, r *http.Request) {
+	s := mux.Vars(r)["id"]
+	fb := FactsBody{}
+	if err := t.Repository.Read(func(session repository.Session) error {
+		e, err := session.Facts().Get(s)
+		if err != nil {
+			return err
+		}
+		fb.LoadEntity(e)
+		return nil
+	}); err != nil {
+		writeFactsError(w, err)
+	} else {
+		server.WriteJSON(w, fb.JSON(), http.StatusOK)
+	}
+}
+
+// Post records the facts of a host, sent as JSON by an inventory agent
+// or as the form posted by an iPXE script.
+func (t Facts) Post(w http.ResponseWriter, r *http.Request) {
+	s := mux.Vars(r)["id"]
+	body := FactsBody{}
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			server.WriteJSON(w, errors.MarshalJSON(err), http.StatusBadRequest)
+			return
+		}
+		if body.Source == "" {
+			body.Source = factsSourceAgent
+		}
+	} else {
+		if err := r.ParseForm(); err != nil {
+			server.WriteJSON(w, errors.MarshalJSON(err), http.StatusBadRequest)
+			return
+		}
+		body.LoadForm(r)
+	}
+	if err := body.Validate(); err != nil {
+		server.WriteJSON(w, errors.MarshalJSON(err), http.StatusBadRequest)
+		return
+	}
+
+	e := body.ToEntity()
+	e.HostID = s
+	if err := t.Repository.Write(func(session repository.Session) error {
+		if err := session.Facts().Create(e); err != nil {
+			return err
+		}
+		var err error
+		e, err = session.Facts().Get(s)
+		return err
+	}); err != nil {
+		writeFactsError(w, err)
+		return
+	}
+	fb := FactsBody{}
+	fb.LoadEntity(e)
+	server.WriteJSON(w, fb.JSON(), http.StatusCreated)
+}
+
+// ListRevisions returns the kept facts revisions of a host, oldest first.
+func (t Facts) ListRevisions(w http.ResponseWriter, r *http.Request) {
+	s := mux.Vars(r)["id"]
+	var l []FactsBody
+	if err := t.Repository.Read(func(session repository.Session) error {
+		revisions, err := session.Facts().ListRevisions(s)
+		if err != nil {
+			return err
+		}
+		l = make([]FactsBody, 0, len(revisions))
+		for _, e := range revisions {
+			fb := FactsBody{}
+			fb.LoadEntity(e)
+			l = append(l, fb)
+		}
+		return nil
+	}); err != nil {
+		writeFactsError(w, err)
+	} else {
+		j, _ := json.Marshal(l)
+		server.WriteJSON(w, j, http.StatusOK)
+	}
+}
+
+// GetRevision returns a facts revision of a host.
+func (t Facts) GetRevision(w http.ResponseWriter, r *http.Request) {
+	v := mux.Vars(r)
+	rev, _ := strconv.Atoi(v["revision"])
+	fb := FactsBody{}
+	if err := t.Repository.Read(func(session repository.Session) error {
+		e, err := session.Facts().GetRevision(v["id"], rev)
+		if err != nil {
+			return err
+		}
+		fb.LoadEntity(e)
+		return nil
+	}); err != nil {
+		writeFactsError(w, err)
+	} else {
+		server.WriteJSON(w, fb.JSON(), http.StatusOK)
+	}
+}
+
+// writeFactsError maps the repository errors to the response status.
+func writeFactsError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errors.ERepositoryKeyNotFound) {
+		server.WriteJSON(w, errors.MarshalJSON(err), http.StatusNotFound)
+	} else {
+		server.WriteJSON(w, errors.MarshalJSON(err), http.StatusInternalServerError)
+	}
+}
+
+// ipxeFirmware returns the firmware of the iPXE ${platform} setting, "pcbios" or "efi".
+func ipxeFirmware(platform string) string {
+	switch strings.ToLower(platform) {
+	case "pcbios":
+		return entity.FirmwareBIOS
+	case "efi":
+		return entity.FirmwareUEFI
+	}
+	return ""
+}
+
+//~ STRUCT - JSON -----------------------------------------------------------
+
+// FactsBody stores facts request and response data as well
+// hold transformations and validations.
+type FactsBody struct {
+	HostID       string          `json:"host-id"`  // read-only.
+	Revision     int             `json:"revision"` // read-only.
+	Created      time.Time       `json:"created"`  // read-only.
+	Source       string          `json:"source"`
+	Manufacturer string          `json:"manufacturer,omitempty"`
+	Product      string          `json:"product,omitempty"`
+	Serial       string          `json:"serial,omitempty"`
+	UUID         string          `json:"uuid,omitempty"`
+	AssetTag     string          `json:"asset-tag,omitempty"`
+	Firmware     string          `json:"firmware,omitempty"` // "bios" or "uefi".
+	Arch         string          `json:"arch,omitempty"`
+	CPU          FactsCPUBody    `json:"cpu"`
+	Memory       uint64          `json:"memory,omitempty"` // bytes.
+	Disks        []FactsDiskBody `json:"disks,omitempty"`
+	NICs         []FactsNICBody  `json:"nics,omitempty"`
+}
+
+// FactsCPUBody stores the processors of FactsBody.
+type FactsCPUBody struct {
+	Model   string `json:"model,omitempty"`
+	Cores   int    `json:"cores,omitempty"`
+	Threads int    `json:"threads,omitempty"`
+}
+
+// FactsDiskBody stores a disk of FactsBody.
+type FactsDiskBody struct {
+	Name       string `json:"name"`
+	Model      string `json:"model,omitempty"`
+	Serial     string `json:"serial,omitempty"`
+	Size       uint64 `json:"size"` // bytes.
+	Rotational bool   `json:"rotational,omitempty"`
+	Removable  bool   `json:"removable,omitempty"`
+}
+
+// FactsNICBody stores a network interface of FactsBody.
+type FactsNICBody struct {
+	Name         string `json:"name"`
+	HardwareAddr string `json:"hardware-addr,omitempty"`
+	Speed        int    `json:"speed,omitempty"` // Mbit/s.
+}
+
+// LoadForm fills the body with the iPXE settings posted as form values:
+// manufacturer, product, serial, uuid, asset, platform, buildarch and memsize in MiB.
+func (t *FactsBody) LoadForm(r *http.Request) {
+	t.Source = factsSourceIPXE
+	t.Manufacturer = strings.TrimSpace(r.FormValue("manufacturer"))
+	t.Product = strings.TrimSpace(r.FormValue("product"))
+	t.Serial = strings.TrimSpace(r.FormValue("serial"))
+	t.UUID = strings.TrimSpace(r.FormValue("uuid"))
+	t.AssetTag = strings.TrimSpace(r.FormValue("asset"))
+	t.Firmware = ipxeFirmware(r.FormValue("platform"))
+	t.Arch = strings.TrimSpace(r.FormValue("buildarch"))
+	if m, err := strconv.ParseUint(r.FormValue("memsize"), 10, 64); err == nil {
+		t.Memory = m << 20
+	}
+}
+
+// Validate checks if the data hold in the instance follows the desired schema.
+func (t FactsBody) Validate() error {
+	if t.Firmware != "" && t.Firmware != entity.FirmwareBIOS && t.Firmware != entity.FirmwareUEFI {
+		return &errors.Error{
+			Code: errors.EInvalidType,
+			Msg:  "[controller.Facts] firmware should be bios or uefi.",
+		}
+	}
+	for _, d := range t.Disks {
+		if d.Name == "" {
+			return &errors.Error{
+				Code: errors.EInvalidType,
+				Msg:  "[controller.Facts] disks should have a name.",
+			}
+		}
+	}
+	for _, n := range t.NICs {
+		if n.HardwareAddr != "" && !hardwareAddrRegex.MatchString(n.HardwareAddr) {
+			return &errors.Error{
+				Code: errors.EInvalidType,
+				Msg:  "[controller.Facts] nics hardware-addr should be a hardware address.",
+			}
+		}
+	}
+	return nil
+}
+
+// ToEntity returns an entity from the provided request.
+func (t FactsBody) ToEntity() entity.Facts {
+	e := entity.Facts{
+		Source:       t.Source,
+		Manufacturer: t.Manufacturer,
+		Product:      t.Product,
+		Serial:       t.Serial,
+		UUID:         t.UUID,
+		AssetTag:     t.AssetTag,
+		Firmware:     t.Firmware,
+		Arch:         t.Arch,
+		CPU:          entity.FactsCPU{Model: t.CPU.Model, Cores: t.CPU.Cores, Threads: t.CPU.Threads},
+		Memory:       t.Memory,
+	}
+	for _, d := range t.Disks {
+		e.Disks = append(e.Disks, entity.FactsDisk{Name: d.Name, Model: d.Model, Serial: d.Serial, Size: d.Size,
+			Rotational: d.Rotational, Removable: d.Removable})
+	}
+	for _, n := range t.NICs {
+		e.NICs = append(e.NICs, entity.FactsNIC{Name: n.Name,
+			HardwareAddr: strings.Replace(strings.ToLower(n.HardwareAddr), ":", "-", -1), Speed: n.Speed})
+	}
+	return e
+}
+
+// LoadEntity fills the body with the entity values.
+func (t *FactsBody) LoadEntity(e entity.Facts) {
+	t.HostID = e.HostID
+	t.Revision = e.Revision
+	t.Created = e.Created
+	t.Source = e.Source
+	t.Manufacturer = e.Manufacturer
+	t.Product = e.Product
+	t.Serial = e.Serial
+	t.UUID = e.UUID
+	t.AssetTag = e.AssetTag
+	t.Firmware = e.Firmware
+	t.Arch = e.Arch
+	t.CPU = FactsCPUBody{Model: e.CPU.Model, Cores: e.CPU.Cores, Threads: e.CPU.Threads}
+	t.Memory = e.Memory
+	t.Disks = nil
+	for _, d := range e.Disks {
+		t.Disks = append(t.Disks, FactsDiskBody{Name: d.Name, Model: d.Model, Serial: d.Serial, Size: d.Size,
+			Rotational: d.Rotational, Removable: d.Removable})
+	}
+	t.NICs = nil
+	for _, n := range e.NICs {
+		t.NICs = append(t.NICs, FactsNICBody{Name: n.Name, HardwareAddr: n.HardwareAddr, Speed: n.Speed})
+	}
+}
+
+// JSON returns a json representation of the structure.
+func (t FactsBody) JSON() []byte {
+	j, _ := json.Marshal(t)
+	return j
+}
@@ -0,0 +1,98 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/pxecore/pxecore/pkg/entity"
+	server "github.com/pxecore/pxecore/pkg/http"
+	"github.com/pxecore/pxecore/pkg/repository"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFacts(t *testing.T) {
+	r, _ := repository.NewRepository(map[string]interface{}{"driver": "memory"})
+	_ = r.Write(func(session repository.Session) error {
+		_ = session.Host().Create(entity.Host{ID: "node-1", HardwareAddr: []string{"00-14-22-04-25-38"}})
+		return session.Host().Create(entity.Host{ID: "host1", HardwareAddr: []string{"00-14-22-04-25-37"}})
+	})
+	ro := mux.NewRouter()
+	Facts{Repository: r}.Register(ro, server.Config{})
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		contentType    string
+		body           string
+		wantStatusCode int
+		wantRevision   int
+		wantSource     string
+	}{
+		{"KO_GET_NOT_FOUND", http.MethodGet, "/host/host1/facts", "application/json", "",
+			http.StatusNotFound, 0, ""},
+		{"OK_POST_FORM", http.MethodPost, "/host/host1/facts", "application/x-www-form-urlencoded",
+			"manufacturer=Dell&serial=S1&platform=efi&memsize=2048",
+			http.StatusCreated, 1, factsSourceIPXE},
+		{"OK_POST_JSON", http.MethodPost, "/host/host1/facts", "application/json",
+			"{\"serial\":\"S1\",\"firmware\":\"uefi\",\"disks\":[{\"name\":\"sda\",\"size\":1000}]," +
+				"\"nics\":[{\"name\":\"eth0\",\"hardware-addr\":\"00:14:22:04:25:37\"}]}",
+			http.StatusCreated, 2, factsSourceAgent},
+		{"KO_POST_FIRMWARE", http.MethodPost, "/host/host1/facts", "application/json",
+			"{\"firmware\":\"efi\"}", http.StatusBadRequest, 0, ""},
+		{"KO_POST_DISK_NAME", http.MethodPost, "/host/host1/facts", "application/json",
+			"{\"disks\":[{\"size\":1000}]}", http.StatusBadRequest, 0, ""},
+		{"KO_POST_HOST_NOT_FOUND", http.MethodPost, "/host/host2/facts", "application/json",
+			"{\"serial\":\"S2\"}", http.StatusNotFound, 0, ""},
+		{"OK_GET_LATEST", http.MethodGet, "/host/host1/facts", "application/json", "",
+			http.StatusOK, 2, factsSourceAgent},
+		{"OK_GET_REVISION", http.MethodGet, "/host/host1/facts/revision/1", "application/json", "",
+			http.StatusOK, 1, factsSourceIPXE},
+		{"KO_GET_REVISION_NOT_FOUND", http.MethodGet, "/host/host1/facts/revision/3", "application/json", "",
+			http.StatusNotFound, 0, ""},
+		{"OK_POST_HYPHEN_ID", http.MethodPost, "/host/node-1/facts", "application/json",
+			"{\"serial\":\"S3\"}", http.StatusCreated, 1, factsSourceAgent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, tt.path, bytes.NewBuffer([]byte(tt.body)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Add("Content-Type", tt.contentType)
+			rr := httptest.NewRecorder()
+			ro.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tt.wantStatusCode {
+				t.Errorf("handler returned wrong status code: got %v want %v",
+					status, tt.wantStatusCode)
+			}
+			if tt.wantRevision == 0 {
+				return
+			}
+			fb := FactsBody{}
+			if err := json.Unmarshal(rr.Body.Bytes(), &fb); err != nil {
+				t.Fatal(err)
+			}
+			if fb.HostID != strings.Split(tt.path, "/")[2] || fb.Revision != tt.wantRevision || fb.Source != tt.wantSource ||
+				fb.Created.IsZero() {
+				t.Errorf("handler returned wrong body: got %+v want revision %v from %v",
+					fb, tt.wantRevision, tt.wantSource)
+			}
+		})
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "/host/host1/facts/revision", nil)
+	rr := httptest.NewRecorder()
+	ro.ServeHTTP(rr, req)
+	var l []FactsBody
+	if err := json.Unmarshal(rr.Body.Bytes(), &l); err != nil {
+		t.Fatal(err)
+	}
+	if len(l) != 2 || l[0].Memory != 2048<<20 || l[0].Firmware != entity.FirmwareUEFI ||
+		l[1].NICs[0].HardwareAddr != "00-14-22-04-25-37" {
+		t.Errorf("ListRevisions() = %+v", l)
+	}
+}
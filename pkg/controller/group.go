@@ -24,7 +24,9 @@ type Group struct {
 
 // Register implements http.Controller interface.
 func (t Group) Register(r *mux.Router, config server.Config) {
-	r.HandleFunc("/group/{id:[a-zA-Z0-9]+}", t.Get).Methods(http.MethodGet)
+	r.HandleFunc("/group/{id:[a-zA-Z0-9_-]+}", t.Get).Methods(http.MethodGet)
+	r.HandleFunc("/group/{id:[a-zA-Z0-9_-]+}", t.Delete).Methods(http.MethodDelete)
+	r.HandleFunc("/group", t.List).Methods(http.MethodGet)
 	r.HandleFunc("/group", t.Put).Methods(http.MethodPut)
 }
 
@@ -68,7 +70,11 @@ func (t Group) Put(w http.ResponseWriter, r *http.Request) {
 		var err error
 		if err = session.Group().Create(body.ToEntity()); err != nil {
 			if errors.Is(err, errors.ERepositoryKeyExist) {
-				err = session.Group().Update(body.ToEntity())
+				e := body.ToEntity()
+				if oe, gerr := session.Group().Get(e.ID); gerr == nil {
+					e.Secrets = keepRedactedSecrets(body.Secrets, oe.Secrets)
+				}
+				err = session.Group().Update(e)
 			}
 		}
 		return err
@@ -82,24 +88,73 @@ func (t Group) Put(w http.ResponseWriter, r *http.Request) {
 	server.WriteJSON(w, []byte{}, http.StatusCreated)
 }
 
+// List returns a page of groups sorted by ID.
+func (t Group) List(w http.ResponseWriter, r *http.Request) {
+	cursor, limit, err := listParams(r)
+	if err != nil {
+		server.WriteJSON(w, errors.MarshalJSON(err), http.StatusBadRequest)
+		return
+	}
+	lb := ListBody{}
+	if err := t.Repository.Read(func(session repository.Session) error {
+		l, err := session.Group().List(cursor, limit+1)
+		if err != nil {
+			return err
+		}
+		if len(l) > limit {
+			l = l[:limit]
+			lb.NextCursor = l[limit-1].ID
+		}
+		items := make([]GroupBody, 0, len(l))
+		for _, e := range l {
+			gb := NewGroupBody()
+			gb.LoadEntity(e)
+			items = append(items, gb)
+		}
+		lb.Items = items
+		return nil
+	}); err != nil {
+		server.WriteJSON(w, errors.MarshalJSON(err), http.StatusInternalServerError)
+	} else {
+		server.WriteJSON(w, lb.JSON(), http.StatusOK)
+	}
+}
+
+// Delete removes a group by ID.
+func (t Group) Delete(w http.ResponseWriter, r *http.Request) {
+	v := mux.Vars(r)
+	s, _ := v["id"]
+
+	if err := t.Repository.Write(func(session repository.Session) error {
+		return session.Group().Delete(entity.Group{ID: s})
+	}); err != nil {
+		writeDeleteError(w, err)
+	} else {
+		server.WriteJSON(w, []byte{}, http.StatusNoContent)
+	}
+}
+
 //~ STRUCT - JSON -----------------------------------------------------------
 
 // GroupBody stores group request and response data as well
 // hold transformations and validations.
 type GroupBody struct {
-	ID         string            `json:"id"`
-	Vars       map[string]string `json:"vars"`
-	ParentID   string            `json:"parent-id"`
-	TemplateID string            `json:"template-id"`
-	HostsIDs   []string          `json:"hosts"`
-	GroupIDs   []string          `json:"groups"`
+	ID               string                 `json:"id"`
+	Vars             map[string]interface{} `json:"vars"`
+	ParentID         string                 `json:"parent-id"`
+	TemplateID       string                 `json:"template-id"`
+	HostsIDs         []string               `json:"hosts"`                       // read-only, hosts having the group.
+	GroupIDs         []string               `json:"groups"`                      // read-only, children groups.
+	TemplateRevision int                    `json:"template-revision,omitempty"` // 0 follows the latest revision.
+	Artifacts        map[string]string      `json:"artifacts,omitempty"`         // artifact name to template ID.
+	Secrets          map[string]string      `json:"secrets,omitempty"`           // write-only, values are redacted.
 }
 
 // NewGroupBody constructs a new GroupBody
 func NewGroupBody() GroupBody {
 	return GroupBody{
 		ID:         "",
-		Vars:       make(map[string]string),
+		Vars:       make(map[string]interface{}),
 		ParentID:   "",
 		TemplateID: "",
 		HostsIDs:   make([]string, 0),
@@ -112,24 +167,35 @@ func (t *GroupBody) LoadEntity(e entity.Group) {
 	t.ID = e.ID
 	t.Vars = e.Vars
 	t.TemplateID = e.TemplateID
+	t.TemplateRevision = e.TemplateRevision
 	t.ParentID = e.ParentID
 	t.GroupIDs = e.GroupIDs
 	t.HostsIDs = e.HostsIDs
-
+	t.Artifacts = e.Artifacts
+	t.Secrets = redactSecrets(e.Secrets)
 }
 
 // Validate checks if the data hold in the instance follows the desired schema.
 func (t GroupBody) Validate() error {
-	return nil
+	if t.TemplateRevision < 0 || (t.TemplateRevision > 0 && t.TemplateID == "") {
+		return &errors.Error{
+			Code: errors.EInvalidType,
+			Msg:  "[controller.Group] TemplateRevision should be a positive revision of TemplateID.",
+		}
+	}
+	return validateArtifacts(t.Artifacts)
 }
 
 // ToEntity returns an entity from the provided request.
 func (t GroupBody) ToEntity() entity.Group {
 	return entity.Group{
-		ID:         t.ID,
-		Vars:       t.Vars,
-		ParentID:   t.ParentID,
-		TemplateID: t.TemplateID,
+		ID:               t.ID,
+		Vars:             t.Vars,
+		ParentID:         t.ParentID,
+		TemplateID:       t.TemplateID,
+		TemplateRevision: t.TemplateRevision,
+		Artifacts:        t.Artifacts,
+		Secrets:          keepRedactedSecrets(t.Secrets, nil),
 	}
 }
 
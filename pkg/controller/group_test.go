@@ -3,6 +3,7 @@ package controller
 import (
 	"bytes"
 	"github.com/gorilla/mux"
+	"github.com/pxecore/pxecore/pkg/entity"
 	server "github.com/pxecore/pxecore/pkg/http"
 	"github.com/pxecore/pxecore/pkg/repository"
 	"net/http"
@@ -12,6 +13,11 @@ import (
 
 func TestGroup(t *testing.T) {
 	r, _ := repository.NewRepository(map[string]interface{}{"driver": "memory"})
+	_ = r.Write(func(session repository.Session) error {
+		_ = session.Group().Create(entity.Group{ID: "group1"})
+		_ = session.Host().Create(entity.Host{ID: "host1", HardwareAddr: []string{"00-14-22-04-25-37"}, GroupID: "group1"})
+		return nil
+	})
 	ro := mux.NewRouter()
 	ss := Group{Repository: r}
 	ss.Register(ro, server.Config{})
@@ -24,6 +30,39 @@ func TestGroup(t *testing.T) {
 		wantStatusCode int
 		wantResponse   string
 	}{
+		{"OK_CREATE", http.MethodPut, "/group",
+			"application/json", "{\"id\":\"group2\",\"vars\":{\"foo\":\"bar\"}}",
+			http.StatusCreated, ""},
+		{"OK_LIST", http.MethodGet, "/group",
+			"application/json", "",
+			http.StatusOK, "{\"items\":[{\"id\":\"group1\",\"vars\":null,\"parent-id\":\"\",\"template-id\":\"\"," +
+				"\"hosts\":[\"host1\"],\"groups\":[]},{\"id\":\"group2\",\"vars\":{\"foo\":\"bar\"}," +
+				"\"parent-id\":\"\",\"template-id\":\"\",\"hosts\":[],\"groups\":[]}],\"next-cursor\":\"\"}"},
+		{"KO_DELETE_DEPENDENCY", http.MethodDelete, "/group/group1",
+			"application/json", "",
+			http.StatusConflict, ""},
+		{"OK_DELETE", http.MethodDelete, "/group/group2",
+			"application/json", "",
+			http.StatusNoContent, ""},
+		{"KO_DELETE_NOT_FOUND", http.MethodDelete, "/group/group2",
+			"application/json", "",
+			http.StatusNotFound, ""},
+		{"OK_CREATE_CHILD", http.MethodPut, "/group",
+			"application/json", "{\"id\":\"group3\",\"parent-id\":\"group1\"}",
+			http.StatusCreated, ""},
+		{"OK_UPDATE", http.MethodPut, "/group",
+			"application/json", "{\"id\":\"group1\",\"vars\":{\"foo\":\"bar\"},\"hosts\":[],\"groups\":[]}",
+			http.StatusCreated, ""},
+		{"OK_FOUND_MEMBERS", http.MethodGet, "/group/group1",
+			"application/json", "",
+			http.StatusOK, "{\"id\":\"group1\",\"vars\":{\"foo\":\"bar\"},\"parent-id\":\"\",\"template-id\":\"\"," +
+				"\"hosts\":[\"host1\"],\"groups\":[\"group3\"]}"},
+		{"OK_CREATE_HYPHEN_ID", http.MethodPut, "/group",
+			"application/json", "{\"id\":\"rack-a\"}",
+			http.StatusCreated, ""},
+		{"OK_DELETE_HYPHEN_ID", http.MethodDelete, "/group/rack-a",
+			"application/json", "",
+			http.StatusNoContent, ""},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"github.com/gorilla/mux"
@@ -9,12 +10,25 @@ import (
 	server "github.com/pxecore/pxecore/pkg/http"
 	"github.com/pxecore/pxecore/pkg/repository"
 	"github.com/pxecore/pxecore/pkg/template"
+	"io"
+	"net"
 	"net/http"
 	"regexp"
+	"time"
+)
+
+const (
+	// redactedSecret replaces the secret values in the responses.
+	// Requests sending it back keep the stored value.
+	redactedSecret = "********"
 )
 
 var (
-	hostIDRegex, _ = regexp.Compile("^[a-zA-Z0-9]+(?:[-_][a-zA-Z0-9]+)*$")
+	hostIDRegex, _       = regexp.Compile("^[a-zA-Z0-9]+(?:[-_][a-zA-Z0-9]+)*$")
+	artifactNameRegex, _ = regexp.Compile("^[a-zA-Z0-9]+(?:[-_][a-zA-Z0-9]+)*$")
+	labelNameRegex, _    = regexp.Compile("^[a-zA-Z0-9]+(?:[-_./][a-zA-Z0-9]+)*$")
+	uuidRegex, _         = regexp.Compile("^[0-9a-fA-F]{8}-(?:[0-9a-fA-F]{4}-){3}[0-9a-fA-F]{12}$")
+	identifierRegex, _   = regexp.Compile("^[a-zA-Z0-9][a-zA-Z0-9._:-]*$")
 )
 
 //~ STRUCT - Server -----------------------------------------------------------
@@ -26,10 +40,16 @@ type Host struct {
 
 // Register implements http.Controller interface.
 func (t Host) Register(r *mux.Router, config server.Config) {
-	r.HandleFunc("/host/{id:[a-zA-Z0-9]+}", t.Get).Methods(http.MethodGet)
+	r.HandleFunc("/host/{id:[a-zA-Z0-9_-]+}", t.Get).Methods(http.MethodGet)
+	r.HandleFunc("/host/{id:[a-zA-Z0-9_-]+}", t.Delete).Methods(http.MethodDelete)
+	r.HandleFunc("/host", t.List).Methods(http.MethodGet)
 	r.HandleFunc("/host", t.Put).Methods(http.MethodPut)
-	r.HandleFunc("/host/{id:[a-zA-Z0-9]+}/template", t.GetTemplate).Methods(http.MethodGet)
-	r.HandleFunc("/host/{id:[a-zA-Z0-9]+}/template/{template-id:[a-zA-Z0-9]+}", t.GetTemplate).Methods(http.MethodGet)
+	r.HandleFunc("/host/{id:[a-zA-Z0-9_-]+}/trap", t.Trap).Methods(http.MethodPost)
+	r.HandleFunc("/host/{id:[a-zA-Z0-9_-]+}/approve", t.Approve).Methods(http.MethodPost)
+	r.HandleFunc("/host/{id:[a-zA-Z0-9_-]+}/template", t.GetTemplate).Methods(http.MethodGet)
+	r.HandleFunc("/host/{id:[a-zA-Z0-9_-]+}/template/{template-id:[a-zA-Z0-9_-]+}", t.GetTemplate).Methods(http.MethodGet)
+	r.HandleFunc("/host/{id:[a-zA-Z0-9_-]+}/artifact/{name:[a-zA-Z0-9_-]+}", t.GetArtifact).Methods(http.MethodGet)
+	r.HandleFunc("/host/{id:[a-zA-Z0-9_-]+}/effective", t.GetEffective).Methods(http.MethodGet)
 }
 
 // Get returns a template by ID.
@@ -72,13 +92,24 @@ func (t Host) Put(w http.ResponseWriter, r *http.Request) {
 		var err error
 		if err = session.Host().Create(tp.ToEntity()); err != nil {
 			if errors.Is(err, errors.ERepositoryKeyExist) {
-				err = session.Host().Update(tp.ToEntity())
+				e := tp.ToEntity()
+				if oe, gerr := session.Host().Get(e.ID); gerr == nil {
+					e.TrapTriggered = oe.TrapTriggered
+					e.Secrets = keepRedactedSecrets(tp.Secrets, oe.Secrets)
+					e.Pending = oe.Pending
+					e.State, e.StateHistory = oe.State, oe.StateHistory
+					e.CallbackToken, e.CallbackTokenExpires, e.LastCallback = oe.CallbackToken, oe.CallbackTokenExpires,
+						oe.LastCallback
+					err = session.Host().Update(e)
+				}
 			}
 		}
 		return err
 	}); err != nil {
 		if errors.Is(err, errors.ERepositoryKeyNotFound) {
 			server.WriteJSON(w, errors.MarshalJSON(err), http.StatusFailedDependency)
+		} else if errors.Is(err, errors.ERepositoryKeyExist) {
+			server.WriteJSON(w, errors.MarshalJSON(err), http.StatusConflict)
 		} else {
 			server.WriteJSON(w, errors.MarshalJSON(err), http.StatusInternalServerError)
 		}
@@ -86,6 +117,73 @@ func (t Host) Put(w http.ResponseWriter, r *http.Request) {
 	server.WriteJSON(w, []byte{}, http.StatusCreated)
 }
 
+// Trap re-arms the host trap mode, so the next boot is served the host template again.
+// Installed and decommissioned hosts are moved back to entity.HostStateReady to be reinstalled.
+func (t Host) Trap(w http.ResponseWriter, r *http.Request) {
+	v := mux.Vars(r)
+	s, _ := v["id"]
+
+	if err := t.Repository.Write(func(session repository.Session) error {
+		h, err := session.Host().Get(s)
+		if err != nil {
+			return err
+		}
+		if l := h.Lifecycle(); l == entity.HostStateInstalled || l == entity.HostStateDecommissioned {
+			h.Transition(entity.HostStateReady, entity.TransitionTriggerAPI, "trap re-armed", time.Now())
+		}
+		h.TrapMode = true
+		h.TrapTriggered = false
+		return session.Host().Update(h)
+	}); err != nil {
+		if errors.Is(err, errors.ERepositoryKeyNotFound) {
+			server.WriteJSON(w, errors.MarshalJSON(err), http.StatusNotFound)
+		} else {
+			server.WriteJSON(w, errors.MarshalJSON(err), http.StatusInternalServerError)
+		}
+	} else {
+		server.WriteJSON(w, []byte{}, http.StatusNoContent)
+	}
+}
+
+// Approve accepts a host registered by discovery, optionally assigning it a group.
+// The host is served its template from the next boot on.
+func (t Host) Approve(w http.ResponseWriter, r *http.Request) {
+	v := mux.Vars(r)
+	s, _ := v["id"]
+
+	body := ApproveBody{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && err != io.EOF {
+		server.WriteJSON(w, errors.MarshalJSON(err), http.StatusBadRequest)
+		return
+	}
+	found := false
+	if err := t.Repository.Write(func(session repository.Session) error {
+		h, err := session.Host().Get(s)
+		if err != nil {
+			return err
+		}
+		found = true
+		if h.Lifecycle() == entity.HostStateDiscovered {
+			h.Transition(entity.HostStateReady, entity.TransitionTriggerAPI, "approved", time.Now())
+		}
+		h.Pending = false
+		if body.GroupID != "" {
+			h.GroupID = body.GroupID
+		}
+		return session.Host().Update(h)
+	}); err != nil {
+		if errors.Is(err, errors.ERepositoryKeyNotFound) && !found {
+			server.WriteJSON(w, errors.MarshalJSON(err), http.StatusNotFound)
+		} else if errors.Is(err, errors.ERepositoryKeyNotFound) {
+			server.WriteJSON(w, errors.MarshalJSON(err), http.StatusFailedDependency)
+		} else {
+			server.WriteJSON(w, errors.MarshalJSON(err), http.StatusInternalServerError)
+		}
+	} else {
+		server.WriteJSON(w, []byte{}, http.StatusNoContent)
+	}
+}
+
 // GetTemplate compiles the default or desired template.
 func (t Host) GetTemplate(w http.ResponseWriter, r *http.Request) {
 	v := mux.Vars(r)
@@ -93,22 +191,121 @@ func (t Host) GetTemplate(w http.ResponseWriter, r *http.Request) {
 	templateID, _ := v["template-id"]
 	w.Header().Set("Content-Type", "application/text; charset=utf-8")
 	w.Header().Set("X-Content-Type-Options", "nosniff")
-	if err := template.Compile(w, t.Repository, id, templateID); err != nil {
+	if err := template.Compile(w, t.Repository, id, templateID, requestContext(r)); err != nil {
 		server.WriteText(w, err.Error(), http.StatusCreated)
 	}
 }
 
+// GetArtifact compiles the template mapped to the artifact name of the host,
+// served with the Content-Type of the template kind.
+func (t Host) GetArtifact(w http.ResponseWriter, r *http.Request) {
+	v := mux.Vars(r)
+	buf := new(bytes.Buffer)
+	contentType, err := template.Artifact(buf, t.Repository, v["id"], v["name"], requestContext(r))
+	if err != nil {
+		if errors.Is(err, errors.ERepositoryKeyNotFound) {
+			server.WriteJSON(w, errors.MarshalJSON(err), http.StatusNotFound)
+		} else if errors.Is(err, errors.ETemplateError) {
+			server.WriteJSON(w, errors.MarshalJSON(err), http.StatusUnprocessableEntity)
+		} else {
+			server.WriteJSON(w, errors.MarshalJSON(err), http.StatusInternalServerError)
+		}
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	_, _ = buf.WriteTo(w)
+}
+
+// GetEffective returns the template and vars resolved for the host with the group or host that provided them.
+func (t Host) GetEffective(w http.ResponseWriter, r *http.Request) {
+	v := mux.Vars(r)
+	e, err := template.Effective(t.Repository, v["id"], requestContext(r))
+	if err != nil {
+		if errors.Is(err, errors.ERepositoryKeyNotFound) {
+			server.WriteJSON(w, errors.MarshalJSON(err), http.StatusNotFound)
+		} else if errors.Is(err, errors.ETemplateError) {
+			server.WriteJSON(w, errors.MarshalJSON(err), http.StatusUnprocessableEntity)
+		} else {
+			server.WriteJSON(w, errors.MarshalJSON(err), http.StatusInternalServerError)
+		}
+		return
+	}
+	j, _ := json.Marshal(e)
+	server.WriteJSON(w, j, http.StatusOK)
+}
+
+// List returns a page of hosts sorted by ID.
+func (t Host) List(w http.ResponseWriter, r *http.Request) {
+	cursor, limit, err := listParams(r)
+	if err != nil {
+		server.WriteJSON(w, errors.MarshalJSON(err), http.StatusBadRequest)
+		return
+	}
+	lb := ListBody{}
+	if err := t.Repository.Read(func(session repository.Session) error {
+		l, err := session.Host().List(cursor, limit+1)
+		if err != nil {
+			return err
+		}
+		if len(l) > limit {
+			l = l[:limit]
+			lb.NextCursor = l[limit-1].ID
+		}
+		items := make([]HostBody, 0, len(l))
+		for _, e := range l {
+			hb := NewHostBody()
+			hb.LoadEntity(e)
+			items = append(items, hb)
+		}
+		lb.Items = items
+		return nil
+	}); err != nil {
+		server.WriteJSON(w, errors.MarshalJSON(err), http.StatusInternalServerError)
+	} else {
+		server.WriteJSON(w, lb.JSON(), http.StatusOK)
+	}
+}
+
+// Delete removes a host by ID.
+func (t Host) Delete(w http.ResponseWriter, r *http.Request) {
+	v := mux.Vars(r)
+	s, _ := v["id"]
+
+	if err := t.Repository.Write(func(session repository.Session) error {
+		return session.Host().Delete(entity.Host{ID: s})
+	}); err != nil {
+		writeDeleteError(w, err)
+	} else {
+		server.WriteJSON(w, []byte{}, http.StatusNoContent)
+	}
+}
+
 //~ STRUCT - JSON -----------------------------------------------------------
 
 // HostBody stores host request and response data as well
 // hold transformations and validations.
 type HostBody struct {
-	ID           string            `json:"id"`
-	HardwareAddr []string          `json:"hardware-addr"`
-	TrapMode     bool              `json:"trap-mode"`
-	Vars         map[string]string `json:"vars"`
-	GroupID      string            `json:"group-id"`
-	TemplateID   string            `json:"template-id"`
+	ID               string                 `json:"id"`
+	HardwareAddr     []string               `json:"hardware-addr"`
+	TrapMode         bool                   `json:"trap-mode"`
+	TrapTriggered    bool                   `json:"trap-triggered,omitempty"` // read-only, set on boot.
+	Vars             map[string]interface{} `json:"vars"`
+	GroupID          string                 `json:"group-id"`
+	TemplateID       string                 `json:"template-id"`
+	IP               string                 `json:"ip,omitempty"`                // DHCP reservation.
+	Hostname         string                 `json:"hostname,omitempty"`          // sent with the DHCP reservation.
+	TemplateRevision int                    `json:"template-revision,omitempty"` // 0 follows the latest revision.
+	Artifacts        map[string]string      `json:"artifacts,omitempty"`         // artifact name to template ID.
+	Secrets          map[string]string      `json:"secrets,omitempty"`           // write-only, values are redacted.
+	GroupIDs         []string               `json:"group-ids,omitempty"`         // merged after GroupID, in order.
+	Labels           map[string]string      `json:"labels,omitempty"`            // matched by the rule selectors.
+	Pending          bool                   `json:"pending,omitempty"`           // read-only, discovered and not approved.
+	UUID             string                 `json:"uuid,omitempty"`              // SMBIOS UUID, matched at boot.
+	Serial           string                 `json:"serial,omitempty"`            // SMBIOS serial number, matched at boot.
+	AssetTag         string                 `json:"asset-tag,omitempty"`         // SMBIOS asset tag, matched at boot.
+	State            string                 `json:"state,omitempty"`             // read-only, set by the transitions.
 }
 
 // NewHostBody construct a new HostBody with default vars.
@@ -117,7 +314,7 @@ func NewHostBody() HostBody {
 		ID:           "",
 		HardwareAddr: make([]string, 0),
 		TrapMode:     false,
-		Vars:         make(map[string]string),
+		Vars:         make(map[string]interface{}),
 		GroupID:      "",
 		TemplateID:   "",
 	}
@@ -140,19 +337,76 @@ func (t HostBody) Validate() error {
 		}
 	}
 
+	if t.TemplateRevision < 0 || (t.TemplateRevision > 0 && t.TemplateID == "") {
+		return &errors.Error{
+			Code: errors.EInvalidType,
+			Msg:  "[controller.Host] TemplateRevision should be a positive revision of TemplateID. ",
+		}
+	}
+
+	if t.IP != "" && net.ParseIP(t.IP).To4() == nil {
+		return &errors.Error{
+			Code: errors.EInvalidType,
+			Msg:  "[controller.Host] IP should be an IPv4 address. ",
+		}
+	}
+
+	for _, g := range t.GroupIDs {
+		if g == "" {
+			return &errors.Error{
+				Code: errors.EInvalidType,
+				Msg:  "[controller.Host] GroupIDs should not contain empty group ids. ",
+			}
+		}
+	}
+
+	if err := validateArtifacts(t.Artifacts); err != nil {
+		return err
+	}
+
+	if err := validateLabels(t.Labels); err != nil {
+		return err
+	}
+
+	if t.UUID != "" && !uuidRegex.MatchString(t.UUID) {
+		return &errors.Error{
+			Code: errors.EInvalidType,
+			Msg:  "[controller.Host] UUID should be a SMBIOS UUID. ",
+		}
+	}
+
+	for _, v := range []string{t.Serial, t.AssetTag} {
+		if v != "" && !identifierRegex.MatchString(v) {
+			return &errors.Error{
+				Code: errors.EInvalidType,
+				Msg:  fmt.Sprint("[controller.Host] Serial and AssetTag should follow pattern: ", identifierRegex.String()),
+			}
+		}
+	}
+
 	return nil
 }
 
 // ToEntity returns an entity from the provided request.
 func (t HostBody) ToEntity() entity.Host {
 	return entity.Host{
-		ID:            t.ID,
-		HardwareAddr:  t.HardwareAddr,
-		TrapMode:      t.TrapMode,
-		TrapTriggered: false,
-		Vars:          t.Vars,
-		GroupID:       t.GroupID,
-		TemplateID:    t.TemplateID,
+		ID:               t.ID,
+		HardwareAddr:     t.HardwareAddr,
+		TrapMode:         t.TrapMode,
+		TrapTriggered:    false,
+		Vars:             t.Vars,
+		GroupID:          t.GroupID,
+		TemplateID:       t.TemplateID,
+		TemplateRevision: t.TemplateRevision,
+		IP:               t.IP,
+		Hostname:         t.Hostname,
+		Artifacts:        t.Artifacts,
+		Secrets:          keepRedactedSecrets(t.Secrets, nil),
+		GroupIDs:         t.GroupIDs,
+		Labels:           t.Labels,
+		UUID:             entity.NormalizeIdentifier(entity.IdentifierUUID, t.UUID),
+		Serial:           t.Serial,
+		AssetTag:         t.AssetTag,
 	}
 }
 
@@ -167,7 +421,86 @@ func (t *HostBody) LoadEntity(h entity.Host) {
 	t.ID = h.ID
 	t.GroupID = h.GroupID
 	t.TemplateID = h.TemplateID
+	t.TemplateRevision = h.TemplateRevision
 	t.Vars = h.Vars
 	t.TrapMode = h.TrapMode
+	t.TrapTriggered = h.TrapTriggered
 	t.HardwareAddr = h.HardwareAddr
+	t.IP = h.IP
+	t.Hostname = h.Hostname
+	t.Artifacts = h.Artifacts
+	t.Secrets = redactSecrets(h.Secrets)
+	t.GroupIDs = h.GroupIDs
+	t.Labels = h.Labels
+	t.Pending = h.Pending
+	t.UUID = h.UUID
+	t.Serial = h.Serial
+	t.AssetTag = h.AssetTag
+	t.State = h.State
+}
+
+// ApproveBody stores the optional group assigned to an approved host.
+type ApproveBody struct {
+	GroupID string `json:"group-id"`
+}
+
+// redactSecrets returns the secret names with their values redacted.
+func redactSecrets(secrets map[string]string) map[string]string {
+	if len(secrets) == 0 {
+		return nil
+	}
+	r := make(map[string]string, len(secrets))
+	for k := range secrets {
+		r[k] = redactedSecret
+	}
+	return r
+}
+
+// keepRedactedSecrets replaces the redacted secret values by the stored ones.
+// Redacted secrets without stored value are removed.
+func keepRedactedSecrets(secrets map[string]string, stored map[string]string) map[string]string {
+	if len(secrets) == 0 {
+		return secrets
+	}
+	r := make(map[string]string, len(secrets))
+	for k, v := range secrets {
+		if v != redactedSecret {
+			r[k] = v
+		} else if s, ok := stored[k]; ok {
+			r[k] = s
+		}
+	}
+	return r
+}
+
+// validateArtifacts checks the artifact names and their template IDs.
+func validateArtifacts(artifacts map[string]string) error {
+	for name, ID := range artifacts {
+		if !artifactNameRegex.MatchString(name) {
+			return &errors.Error{
+				Code: errors.EInvalidType,
+				Msg:  fmt.Sprint("[controller.Artifact] name should follow pattern: ", artifactNameRegex.String()),
+			}
+		}
+		if !templateIDRegex.MatchString(ID) {
+			return &errors.Error{
+				Code: errors.EInvalidType,
+				Msg:  fmt.Sprint("[controller.Artifact] template id should follow pattern: ", templateIDRegex.String()),
+			}
+		}
+	}
+	return nil
+}
+
+// validateLabels checks the label names, used by the host labels and the rule selectors.
+func validateLabels(labels map[string]string) error {
+	for name := range labels {
+		if !labelNameRegex.MatchString(name) {
+			return &errors.Error{
+				Code: errors.EInvalidType,
+				Msg:  fmt.Sprint("[controller.Label] name should follow pattern: ", labelNameRegex.String()),
+			}
+		}
+	}
+	return nil
 }
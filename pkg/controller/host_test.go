@@ -6,8 +6,10 @@ import (
 	"github.com/pxecore/pxecore/pkg/entity"
 	server "github.com/pxecore/pxecore/pkg/http"
 	"github.com/pxecore/pxecore/pkg/repository"
+	"github.com/pxecore/pxecore/pkg/secret"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"testing"
 )
 
@@ -61,6 +63,113 @@ func TestHost(t *testing.T) {
 			"{\"id\": \"host1\",\"hardware-addr\":[\"00-14-22-04-25-37\",\"00-14-22-04-25-38\"]," +
 				"\"trap-mode\":true,\"vars\":{\"foo\":\"bar1\"},\"group-id\":\"group1\",\"template-id\":\"template2\"}",
 			http.StatusFailedDependency, ""},
+		{"KO_TRAP_NOT_FOUND", http.MethodPost, "/host/host2/trap",
+			"application/json", "",
+			http.StatusNotFound, ""},
+		{"OK_TRAP", http.MethodPost, "/host/host1/trap",
+			"application/json", "",
+			http.StatusNoContent, ""},
+		{"OK_LIST", http.MethodGet, "/host?limit=10",
+			"application/json", "",
+			http.StatusOK, "{\"items\":[{\"id\":\"host1\",\"hardware-addr\":[\"00-14-22-04-25-37\",\"00-14-22-04-25-38\"]," +
+				"\"trap-mode\":true,\"vars\":{\"foo\":\"bar1\"},\"group-id\":\"group1\",\"template-id\":\"template1\"}]," +
+				"\"next-cursor\":\"\"}"},
+		{"OK_DELETE", http.MethodDelete, "/host/host1",
+			"application/json", "",
+			http.StatusNoContent, ""},
+		{"KO_DELETE_NOT_FOUND", http.MethodDelete, "/host/host1",
+			"application/json", "",
+			http.StatusNotFound, ""},
+		{"OK_LIST_EMPTY", http.MethodGet, "/host",
+			"application/json", "",
+			http.StatusOK, "{\"items\":[],\"next-cursor\":\"\"}"},
+		{"OK_CREATE_RESERVATION", http.MethodPut, "/host",
+			"application/json",
+			"{\"id\": \"host2\",\"hardware-addr\":[\"00-14-22-04-25-39\"],\"ip\":\"10.0.0.5\",\"hostname\":\"node2\"}",
+			http.StatusCreated, ""},
+		{"OK_FOUND_RESERVATION", http.MethodGet, "/host/host2",
+			"application/json", "",
+			http.StatusOK, "{\"id\":\"host2\",\"hardware-addr\":[\"00-14-22-04-25-39\"],\"trap-mode\":false," +
+				"\"vars\":{},\"group-id\":\"\",\"template-id\":\"\",\"ip\":\"10.0.0.5\",\"hostname\":\"node2\"}"},
+		{"KO_INVALID_IP", http.MethodPut, "/host",
+			"application/json",
+			"{\"id\": \"host3\",\"hardware-addr\":[\"00-14-22-04-25-40\"],\"ip\":\"10.0.0\"}",
+			http.StatusBadRequest, ""},
+		{"KO_DUPLICATED_IP", http.MethodPut, "/host",
+			"application/json",
+			"{\"id\": \"host3\",\"hardware-addr\":[\"00-14-22-04-25-40\"],\"ip\":\"10.0.0.5\"}",
+			http.StatusConflict, ""},
+		{"KO_INVALID_ARTIFACT_NAME", http.MethodPut, "/host",
+			"application/json",
+			"{\"id\": \"host3\",\"hardware-addr\":[\"00-14-22-04-25-40\"],\"artifacts\":{\"user data\":\"template1\"}}",
+			http.StatusBadRequest, ""},
+		{"OK_CREATE_STRUCTURED_VARS", http.MethodPut, "/host",
+			"application/json",
+			"{\"id\": \"host4\",\"hardware-addr\":[\"00-14-22-04-25-41\"]," +
+				"\"vars\":{\"bond\":{\"mode\":\"802.3ad\"},\"disks\":[\"sda\",\"sdb\"],\"mtu\":9000,\"ssh\":true}}",
+			http.StatusCreated, ""},
+		{"OK_FOUND_STRUCTURED_VARS", http.MethodGet, "/host/host4",
+			"application/json", "",
+			http.StatusOK, "{\"id\":\"host4\",\"hardware-addr\":[\"00-14-22-04-25-41\"],\"trap-mode\":false," +
+				"\"vars\":{\"bond\":{\"mode\":\"802.3ad\"},\"disks\":[\"sda\",\"sdb\"],\"mtu\":9000,\"ssh\":true}," +
+				"\"group-id\":\"\",\"template-id\":\"\"}"},
+		{"OK_CREATE_GROUPS", http.MethodPut, "/host",
+			"application/json",
+			"{\"id\": \"host5\",\"hardware-addr\":[\"00-14-22-04-25-42\"],\"group-ids\":[\"group1\"]}",
+			http.StatusCreated, ""},
+		{"OK_FOUND_GROUPS", http.MethodGet, "/host/host5",
+			"application/json", "",
+			http.StatusOK, "{\"id\":\"host5\",\"hardware-addr\":[\"00-14-22-04-25-42\"],\"trap-mode\":false," +
+				"\"vars\":{},\"group-id\":\"\",\"template-id\":\"\",\"group-ids\":[\"group1\"]}"},
+		{"KO_EMPTY_GROUP_ID", http.MethodPut, "/host",
+			"application/json",
+			"{\"id\": \"host6\",\"hardware-addr\":[\"00-14-22-04-25-43\"],\"group-ids\":[\"\"]}",
+			http.StatusBadRequest, ""},
+		{"KO_MISSING_GROUP_IDS", http.MethodPut, "/host",
+			"application/json",
+			"{\"id\": \"host6\",\"hardware-addr\":[\"00-14-22-04-25-43\"],\"group-ids\":[\"group9\"]}",
+			http.StatusFailedDependency, ""},
+		{"OK_CREATE_LABELS", http.MethodPut, "/host",
+			"application/json",
+			"{\"id\": \"host6\",\"hardware-addr\":[\"00-14-22-04-25-43\"],\"labels\":{\"zone/rack\":\"a1\"}}",
+			http.StatusCreated, ""},
+		{"OK_FOUND_LABELS", http.MethodGet, "/host/host6",
+			"application/json", "",
+			http.StatusOK, "{\"id\":\"host6\",\"hardware-addr\":[\"00-14-22-04-25-43\"],\"trap-mode\":false," +
+				"\"vars\":{},\"group-id\":\"\",\"template-id\":\"\",\"labels\":{\"zone/rack\":\"a1\"}}"},
+		{"KO_INVALID_LABEL_NAME", http.MethodPut, "/host",
+			"application/json",
+			"{\"id\": \"host7\",\"hardware-addr\":[\"00-14-22-04-25-44\"],\"labels\":{\"bad label\":\"a1\"}}",
+			http.StatusBadRequest, ""},
+		{"OK_CREATE_IDENTIFIERS", http.MethodPut, "/host",
+			"application/json",
+			"{\"id\": \"host7\",\"hardware-addr\":[\"00-14-22-04-25-44\"]," +
+				"\"uuid\":\"4C4C4544-0042-3010-8052-B4C04F4E4E32\",\"serial\":\"CN1234\",\"asset-tag\":\"A-1\"}",
+			http.StatusCreated, ""},
+		{"OK_FOUND_IDENTIFIERS", http.MethodGet, "/host/host7",
+			"application/json", "",
+			http.StatusOK, "{\"id\":\"host7\",\"hardware-addr\":[\"00-14-22-04-25-44\"],\"trap-mode\":false," +
+				"\"vars\":{},\"group-id\":\"\",\"template-id\":\"\"," +
+				"\"uuid\":\"4c4c4544-0042-3010-8052-b4c04f4e4e32\",\"serial\":\"CN1234\",\"asset-tag\":\"A-1\"}"},
+		{"KO_DUPLICATED_SERIAL", http.MethodPut, "/host",
+			"application/json",
+			"{\"id\": \"host8\",\"hardware-addr\":[\"00-14-22-04-25-45\"],\"serial\":\"CN1234\"}",
+			http.StatusConflict, ""},
+		{"KO_INVALID_UUID", http.MethodPut, "/host",
+			"application/json",
+			"{\"id\": \"host8\",\"hardware-addr\":[\"00-14-22-04-25-45\"],\"uuid\":\"4c4c4544\"}",
+			http.StatusBadRequest, ""},
+		{"KO_INVALID_SERIAL", http.MethodPut, "/host",
+			"application/json",
+			"{\"id\": \"host8\",\"hardware-addr\":[\"00-14-22-04-25-45\"],\"serial\":\"CN/1234\"}",
+			http.StatusBadRequest, ""},
+		{"OK_CREATE_HYPHEN_ID", http.MethodPut, "/host",
+			"application/json",
+			"{\"id\": \"node-1\",\"hardware-addr\":[\"00-14-22-04-25-46\"]}",
+			http.StatusCreated, ""},
+		{"OK_TRAP_HYPHEN_ID", http.MethodPost, "/host/node-1/trap",
+			"application/json", "",
+			http.StatusNoContent, ""},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -84,3 +193,206 @@ func TestHost(t *testing.T) {
 		})
 	}
 }
+
+func TestHost_Artifact(t *testing.T) {
+	r, _ := repository.NewRepository(map[string]interface{}{"driver": "memory"})
+	_ = r.Write(func(session repository.Session) error {
+		_ = session.Template().Create(entity.Template{ID: "ks", Template: "install {{ .Vars.os }}",
+			Kind: entity.TemplateKindKickstart})
+		_ = session.Template().Create(entity.Template{ID: "ign", Template: `{"os": "{{ .Vars.os }}"}`,
+			Kind: entity.TemplateKindIgnition})
+		_ = session.Template().Create(entity.Template{ID: "bad", Template: `{{ .Vars.os }}`,
+			Kind: entity.TemplateKindIgnition})
+		_ = session.Group().Create(entity.Group{ID: "group1", Vars: map[string]interface{}{"os": "fedora"},
+			Artifacts: map[string]string{"kickstart": "ks", "ignition": "bad"}})
+		_ = session.Host().Create(entity.Host{ID: "host1", HardwareAddr: []string{"00-14-22-04-25-37"},
+			GroupID: "group1", Artifacts: map[string]string{"ignition": "ign"}})
+		_ = session.Host().Create(entity.Host{ID: "host2", HardwareAddr: []string{"00-14-22-04-25-38"},
+			GroupID: "group1", Vars: map[string]interface{}{"os": "centos"}})
+		_ = session.Host().Create(entity.Host{ID: "node-3", HardwareAddr: []string{"00-14-22-04-25-39"},
+			GroupID: "group1"})
+		return nil
+	})
+	ro := mux.NewRouter()
+	ss := Host{Repository: r}
+	ss.Register(ro, server.Config{})
+	tests := []struct {
+		name            string
+		path            string
+		wantStatusCode  int
+		wantContentType string
+		wantResponse    string
+	}{
+		{"OK_GROUP_ARTIFACT", "/host/host1/artifact/kickstart",
+			http.StatusOK, "text/plain; charset=utf-8", "install fedora"},
+		{"OK_HOST_ARTIFACT", "/host/host1/artifact/ignition",
+			http.StatusOK, "application/vnd.coreos.ignition+json", `{"os": "fedora"}`},
+		{"OK_HOST_VARS", "/host/host2/artifact/kickstart",
+			http.StatusOK, "text/plain; charset=utf-8", "install centos"},
+		{"KO_INVALID_IGNITION", "/host/host2/artifact/ignition",
+			http.StatusUnprocessableEntity, "application/json; charset=utf-8", ""},
+		{"KO_ARTIFACT_NOT_FOUND", "/host/host1/artifact/user-data",
+			http.StatusNotFound, "application/json; charset=utf-8", ""},
+		{"KO_HOST_NOT_FOUND", "/host/host3/artifact/kickstart",
+			http.StatusNotFound, "application/json; charset=utf-8", ""},
+		{"OK_HYPHEN_ID", "/host/node-3/artifact/kickstart",
+			http.StatusOK, "text/plain; charset=utf-8", "install fedora"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rr := httptest.NewRecorder()
+			ro.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tt.wantStatusCode {
+				t.Errorf("handler returned wrong status code: got %v want %v",
+					status, tt.wantStatusCode)
+			}
+			if c := rr.Header().Get("Content-Type"); c != tt.wantContentType {
+				t.Errorf("handler returned wrong content type: got %v want %v", c, tt.wantContentType)
+			}
+			if body := rr.Body.String(); tt.wantResponse != "" && body != tt.wantResponse {
+				t.Errorf("handler returned wrong body: got %v want %v",
+					body, tt.wantResponse)
+			}
+		})
+	}
+}
+
+func TestHost_Secrets(t *testing.T) {
+	m, _ := repository.NewRepository(map[string]interface{}{"driver": "memory"})
+	key, _ := secret.GenerateKey()
+	k, _ := secret.NewKeyring(map[string]interface{}{"key": key})
+	r := repository.NewSecretRepository(m, k)
+	ro := mux.NewRouter()
+	ss := Host{Repository: r}
+	ss.Register(ro, server.Config{})
+	tests := []struct {
+		name           string
+		method         string
+		body           string
+		wantStatusCode int
+		wantResponse   string
+		wantSecrets    map[string]string
+	}{
+		{"OK_CREATE", http.MethodPut,
+			"{\"id\": \"host1\",\"hardware-addr\":[\"00-14-22-04-25-37\"],\"secrets\":{\"password\":\"s3cr3t\"}}",
+			http.StatusCreated, "", map[string]string{"password": "s3cr3t"}},
+		{"OK_FOUND_REDACTED", http.MethodGet, "",
+			http.StatusOK, "{\"id\":\"host1\",\"hardware-addr\":[\"00-14-22-04-25-37\"],\"trap-mode\":false," +
+				"\"vars\":{},\"group-id\":\"\",\"template-id\":\"\",\"secrets\":{\"password\":\"********\"}}",
+			map[string]string{"password": "s3cr3t"}},
+		{"OK_KEEP_REDACTED", http.MethodPut,
+			"{\"id\": \"host1\",\"hardware-addr\":[\"00-14-22-04-25-37\"]," +
+				"\"secrets\":{\"password\":\"********\",\"token\":\"t0k3n\",\"unknown\":\"********\"}}",
+			http.StatusCreated, "", map[string]string{"password": "s3cr3t", "token": "t0k3n"}},
+		{"OK_REMOVE", http.MethodPut,
+			"{\"id\": \"host1\",\"hardware-addr\":[\"00-14-22-04-25-37\"],\"secrets\":{\"token\":\"********\"}}",
+			http.StatusCreated, "", map[string]string{"token": "t0k3n"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := "/host"
+			if tt.method == http.MethodGet {
+				path = "/host/host1"
+			}
+			req, err := http.NewRequest(tt.method, path, bytes.NewBuffer([]byte(tt.body)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Add("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+			ro.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tt.wantStatusCode {
+				t.Errorf("handler returned wrong status code: got %v want %v",
+					status, tt.wantStatusCode)
+			}
+			if body := rr.Body.String(); tt.wantResponse != "" && body != tt.wantResponse {
+				t.Errorf("handler returned wrong body: got %v want %v",
+					body, tt.wantResponse)
+			}
+			_ = r.Read(func(session repository.Session) error {
+				h, err := session.Host().Get("host1")
+				if err != nil || !reflect.DeepEqual(h.Secrets, tt.wantSecrets) {
+					t.Errorf("stored secrets = %v, %v, want %v", h.Secrets, err, tt.wantSecrets)
+				}
+				return nil
+			})
+		})
+	}
+}
+
+func TestHost_Effective(t *testing.T) {
+	r, _ := repository.NewRepository(map[string]interface{}{"driver": "memory"})
+	_ = r.Write(func(session repository.Session) error {
+		_ = session.Template().Create(entity.Template{ID: "base", Template: "#!ipxe"})
+		_ = session.Template().Create(entity.Template{ID: "gpu", Template: "#!ipxe"})
+		_ = session.Group().Create(entity.Group{ID: "rack", TemplateID: "base",
+			Vars: map[string]interface{}{"os": "centos", "rack": "12"}})
+		_ = session.Group().Create(entity.Group{ID: "gpu", TemplateID: "gpu",
+			Vars: map[string]interface{}{"os": "ubuntu"}, Secrets: map[string]string{"token": "t0k3n"}})
+		_ = session.Host().Create(entity.Host{ID: "host1", HardwareAddr: []string{"00-14-22-04-25-37"},
+			GroupID: "rack", GroupIDs: []string{"gpu"}, Vars: map[string]interface{}{"os": "fedora"}})
+		_ = session.Host().Create(entity.Host{ID: "host2", HardwareAddr: []string{"00-14-22-04-25-38"},
+			TemplateID: "base", TrapMode: true, TrapTriggered: true})
+		_ = session.Host().Create(entity.Host{ID: "host3", HardwareAddr: []string{"00-14-22-04-25-39"}})
+		_ = session.Rule().Create(entity.Rule{ID: "db", Selector: map[string]string{"role": "db"}, TemplateID: "base"})
+		_ = session.Host().Create(entity.Host{ID: "host5", HardwareAddr: []string{"00-14-22-04-25-3a"},
+			Labels: map[string]string{"role": "db"}})
+		_ = session.Host().Create(entity.Host{ID: "node-6", HardwareAddr: []string{"00-14-22-04-25-3b"},
+			TemplateID: "base"})
+		return nil
+	})
+	ro := mux.NewRouter()
+	ss := Host{Repository: r}
+	ss.Register(ro, server.Config{})
+	tests := []struct {
+		name           string
+		path           string
+		wantStatusCode int
+		wantResponse   string
+	}{
+		{"OK", "/host/host1/effective",
+			http.StatusOK, "{\"host-id\":\"host1\",\"template-id\":\"gpu\",\"template-source\":\"group:gpu\"," +
+				"\"trap-pending\":false,\"state\":\"ready\",\"groups\":[\"rack\",\"gpu\"]," +
+				"\"vars\":{\"os\":\"fedora\",\"rack\":\"12\"}," +
+				"\"provenance\":{\"os\":{\"source\":\"host\",\"overrides\":[{\"source\":\"group:rack\",\"value\":\"centos\"}," +
+				"{\"source\":\"group:gpu\",\"value\":\"ubuntu\"}]},\"rack\":{\"source\":\"group:rack\"}}}"},
+		{"OK_TRAP_TRIGGERED", "/host/host2/effective",
+			http.StatusOK, "{\"host-id\":\"host2\",\"template-id\":\"local-boot\",\"template-source\":\"trap\"," +
+				"\"trap-pending\":false,\"state\":\"ready\",\"groups\":[],\"vars\":{},\"provenance\":{}}"},
+		{"OK_RULE", "/host/host5/effective",
+			http.StatusOK, "{\"host-id\":\"host5\",\"template-id\":\"base\",\"template-source\":\"rule:db\"," +
+				"\"rule\":\"db\",\"trap-pending\":false,\"state\":\"ready\",\"groups\":[],\"vars\":{},\"provenance\":{}}"},
+		{"KO_NO_TEMPLATE", "/host/host3/effective",
+			http.StatusUnprocessableEntity, ""},
+		{"KO_NOT_FOUND", "/host/host4/effective",
+			http.StatusNotFound, ""},
+		{"OK_HYPHEN_ID", "/host/node-6/effective",
+			http.StatusOK, "{\"host-id\":\"node-6\",\"template-id\":\"base\",\"template-source\":\"host\"," +
+				"\"trap-pending\":false,\"state\":\"ready\",\"groups\":[],\"vars\":{},\"provenance\":{}}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rr := httptest.NewRecorder()
+			ro.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tt.wantStatusCode {
+				t.Errorf("handler returned wrong status code: got %v want %v",
+					status, tt.wantStatusCode)
+			}
+			if body := rr.Body.String(); tt.wantResponse != "" && body != tt.wantResponse {
+				t.Errorf("handler returned wrong body: got %v want %v",
+					body, tt.wantResponse)
+			}
+		})
+	}
+}
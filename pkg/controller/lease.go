@@ -0,0 +1,122 @@
+package controller
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+	server "github.com/pxecore/pxecore/pkg/http"
+	"github.com/pxecore/pxecore/pkg/repository"
+	"net/http"
+	"time"
+)
+
+//~ STRUCT - Server -----------------------------------------------------------
+
+// Lease controller for the "/lease" base path operations.
+type Lease struct {
+	Repository repository.Repository // Repository dependency injection.
+}
+
+// Register implements http.Controller interface.
+func (t Lease) Register(r *mux.Router, config server.Config) {
+	r.HandleFunc("/lease/{hardware-addr:(?:[0-9a-f]{2}-){5}[0-9a-f]{2}}", t.Get).Methods(http.MethodGet)
+	r.HandleFunc("/lease/{hardware-addr:(?:[0-9a-f]{2}-){5}[0-9a-f]{2}}", t.Delete).Methods(http.MethodDelete)
+	r.HandleFunc("/lease", t.List).Methods(http.MethodGet)
+}
+
+// Get returns a lease by hardware address.
+func (t Lease) Get(w http.ResponseWriter, r *http.Request) {
+	v := mux.Vars(r)
+	s, _ := v["hardware-addr"]
+
+	lb := new(LeaseBody)
+	if err := t.Repository.Read(func(session repository.Session) error {
+		l, err := session.Lease().Get(s)
+		if err != nil {
+			return err
+		}
+		lb.LoadEntity(l)
+		return nil
+	}); err != nil {
+		if errors.Is(err, errors.ERepositoryKeyNotFound) {
+			server.WriteJSON(w, errors.MarshalJSON(err), http.StatusNotFound)
+		} else {
+			server.WriteJSON(w, errors.MarshalJSON(err), http.StatusInternalServerError)
+		}
+	} else {
+		server.WriteJSON(w, lb.JSON(), http.StatusOK)
+	}
+}
+
+// List returns a page of leases sorted by hardware address.
+func (t Lease) List(w http.ResponseWriter, r *http.Request) {
+	cursor, limit, err := listParams(r)
+	if err != nil {
+		server.WriteJSON(w, errors.MarshalJSON(err), http.StatusBadRequest)
+		return
+	}
+	lb := ListBody{}
+	if err := t.Repository.Read(func(session repository.Session) error {
+		l, err := session.Lease().List(cursor, limit+1)
+		if err != nil {
+			return err
+		}
+		if len(l) > limit {
+			l = l[:limit]
+			lb.NextCursor = l[limit-1].HardwareAddr
+		}
+		items := make([]LeaseBody, 0, len(l))
+		for _, e := range l {
+			b := LeaseBody{}
+			b.LoadEntity(e)
+			items = append(items, b)
+		}
+		lb.Items = items
+		return nil
+	}); err != nil {
+		server.WriteJSON(w, errors.MarshalJSON(err), http.StatusInternalServerError)
+	} else {
+		server.WriteJSON(w, lb.JSON(), http.StatusOK)
+	}
+}
+
+// Delete releases a lease by hardware address.
+func (t Lease) Delete(w http.ResponseWriter, r *http.Request) {
+	v := mux.Vars(r)
+	s, _ := v["hardware-addr"]
+
+	if err := t.Repository.Write(func(session repository.Session) error {
+		return session.Lease().Delete(entity.Lease{HardwareAddr: s})
+	}); err != nil {
+		writeDeleteError(w, err)
+	} else {
+		server.WriteJSON(w, []byte{}, http.StatusNoContent)
+	}
+}
+
+//~ STRUCT - JSON -----------------------------------------------------------
+
+// LeaseBody stores lease response data.
+type LeaseBody struct {
+	HardwareAddr string    `json:"hardware-addr"`
+	IP           string    `json:"ip"`
+	Hostname     string    `json:"hostname"`
+	HostID       string    `json:"host-id"`
+	Expires      time.Time `json:"expires"`
+}
+
+// JSON returns a json representation of the structure.
+func (t LeaseBody) JSON() []byte {
+	j, _ := json.Marshal(t)
+	return j
+}
+
+// LoadEntity adds Entity vars to the LeaseBody
+func (t *LeaseBody) LoadEntity(l entity.Lease) {
+	t.HardwareAddr = l.HardwareAddr
+	t.IP = l.IP
+	t.Hostname = l.Hostname
+	t.HostID = l.HostID
+	t.Expires = l.Expires.UTC()
+}
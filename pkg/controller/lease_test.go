@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"bytes"
+	"github.com/gorilla/mux"
+	"github.com/pxecore/pxecore/pkg/entity"
+	server "github.com/pxecore/pxecore/pkg/http"
+	"github.com/pxecore/pxecore/pkg/repository"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLease(t *testing.T) {
+	r, _ := repository.NewRepository(map[string]interface{}{"driver": "memory"})
+	_ = r.Write(func(session repository.Session) error {
+		_ = session.Lease().Create(entity.Lease{HardwareAddr: "00-14-22-04-25-37", IP: "10.0.0.10",
+			Hostname: "node1", HostID: "host1", Expires: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)})
+		_ = session.Lease().Create(entity.Lease{HardwareAddr: "00-14-22-04-25-38", IP: "10.0.0.11",
+			Expires: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)})
+		return nil
+	})
+	ro := mux.NewRouter()
+	ss := Lease{Repository: r}
+	ss.Register(ro, server.Config{})
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		wantStatusCode int
+		wantResponse   string
+	}{
+		{"OK_FOUND", http.MethodGet, "/lease/00-14-22-04-25-37",
+			http.StatusOK, "{\"hardware-addr\":\"00-14-22-04-25-37\",\"ip\":\"10.0.0.10\",\"hostname\":\"node1\"," +
+				"\"host-id\":\"host1\",\"expires\":\"2020-01-01T00:00:00Z\"}"},
+		{"KO_NOT_FOUND", http.MethodGet, "/lease/00-14-22-04-25-39",
+			http.StatusNotFound, ""},
+		{"OK_LIST_PAGE", http.MethodGet, "/lease?limit=1",
+			http.StatusOK, "{\"items\":[{\"hardware-addr\":\"00-14-22-04-25-37\",\"ip\":\"10.0.0.10\"," +
+				"\"hostname\":\"node1\",\"host-id\":\"host1\",\"expires\":\"2020-01-01T00:00:00Z\"}]," +
+				"\"next-cursor\":\"00-14-22-04-25-37\"}"},
+		{"KO_LIST_LIMIT", http.MethodGet, "/lease?limit=0",
+			http.StatusBadRequest, ""},
+		{"OK_DELETE", http.MethodDelete, "/lease/00-14-22-04-25-37",
+			http.StatusNoContent, ""},
+		{"KO_DELETE_NOT_FOUND", http.MethodDelete, "/lease/00-14-22-04-25-37",
+			http.StatusNotFound, ""},
+		{"OK_LIST", http.MethodGet, "/lease",
+			http.StatusOK, "{\"items\":[{\"hardware-addr\":\"00-14-22-04-25-38\",\"ip\":\"10.0.0.11\"," +
+				"\"hostname\":\"\",\"host-id\":\"\",\"expires\":\"2020-01-01T00:00:00Z\"}],\"next-cursor\":\"\"}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, tt.path, bytes.NewBuffer([]byte{}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			rr := httptest.NewRecorder()
+			ro.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tt.wantStatusCode {
+				t.Errorf("handler returned wrong status code: got %v want %v",
+					status, tt.wantStatusCode)
+			}
+
+			if body := rr.Body.String(); tt.wantResponse != "" && body != tt.wantResponse {
+				t.Errorf("handler returned wrong body: got %v want %v",
+					body, tt.wantResponse)
+			}
+		})
+	}
+}
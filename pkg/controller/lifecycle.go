@@ -0,0 +1,241 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+	server "github.com/pxecore/pxecore/pkg/http"
+	"github.com/pxecore/pxecore/pkg/repository"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// maxCallbackBody is the maximum size read from the body of a callback.
+	maxCallbackBody = 1 << 20
+	// maxCallbackLogs is the size of the callback logs kept on the host.
+	maxCallbackLogs = 64 << 10
+)
+
+//~ STRUCT - Server -----------------------------------------------------------
+
+// Lifecycle controller for the provisioning state of the hosts.
+// Installers report the end of the installation through the callback endpoints,
+// like a kickstart %post calling "/host/{id}/callback/installed".
+type Lifecycle struct {
+	Repository repository.Repository // Repository dependency injection.
+}
+
+// Register implements http.Controller interface.
+func (t Lifecycle) Register(r *mux.Router, config server.Config) {
+	r.HandleFunc("/host/{id:[a-zA-Z0-9_-]+}/state", t.Get).Methods(http.MethodGet)
+	r.HandleFunc("/host/{id:[a-zA-Z0-9_-]+}/state", t.Post).Methods(http.MethodPost)
+	r.HandleFunc("/host/{id:[a-zA-Z0-9_-]+}/callback/{status:installed|failed}", t.Callback).Methods(http.MethodPost)
+}
+
+// Get returns the lifecycle state of a host and its latest transitions.
+func (t Lifecycle) Get(w http.ResponseWriter, r *http.Request) {
+	s := mux.Vars(r)["id"]
+
+	lb := LifecycleBody{}
+	if err := t.Repository.Read(func(session repository.Session) error {
+		h, err := session.Host().Get(s)
+		if err != nil {
+			return err
+		}
+		lb.LoadEntity(h)
+		return nil
+	}); err != nil {
+		writeLifecycleError(w, err)
+		return
+	}
+	server.WriteJSON(w, lb.JSON(), http.StatusOK)
+}
+
+// Post moves a host to the requested state. If "from" is set the host must still be in that state,
+// so concurrent changes are rejected with a conflict instead of being overwritten.
+// The installed and failed states are only reported by the installer, through Callback.
+func (t Lifecycle) Post(w http.ResponseWriter, r *http.Request) {
+	s := mux.Vars(r)["id"]
+
+	body := TransitionRequestBody{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		server.WriteJSON(w, errors.MarshalJSON(err), http.StatusBadRequest)
+		return
+	}
+	if err := body.Validate(); err != nil {
+		server.WriteJSON(w, errors.MarshalJSON(err), http.StatusBadRequest)
+		return
+	}
+	if body.State == entity.HostStateInstalled || body.State == entity.HostStateFailed {
+		writeLifecycleError(w, &errors.Error{Code: errors.EForbidden,
+			Msg: fmt.Sprintf("[controller.Lifecycle] host %v state %v is reported by the installer callback", s,
+				body.State)})
+		return
+	}
+	lb := LifecycleBody{}
+	if err := t.Repository.Write(func(session repository.Session) error {
+		h, err := repository.TransitionHost(session, s, body.From, body.State, entity.TransitionTriggerAPI, body.Reason)
+		if err != nil {
+			return err
+		}
+		lb.LoadEntity(h)
+		return nil
+	}); err != nil {
+		writeLifecycleError(w, err)
+		return
+	}
+	server.WriteJSON(w, lb.JSON(), http.StatusOK)
+}
+
+// Callback moves a host to the status reported by its installer, "installed" or "failed",
+// and records the result on the host. The "token" query param must be the callback token
+// issued when the host booted, it is invalidated once used. The body holds the optional logs,
+// or a JSON CallbackBody with the reason and the logs.
+func (t Lifecycle) Callback(w http.ResponseWriter, r *http.Request) {
+	v := mux.Vars(r)
+	s, status := v["id"], v["status"]
+	token := r.URL.Query().Get("token")
+
+	body := CallbackBody{}
+	if err := body.LoadRequest(r); err != nil {
+		server.WriteJSON(w, errors.MarshalJSON(err), http.StatusBadRequest)
+		return
+	}
+	if body.Reason == "" {
+		body.Reason = fmt.Sprintf("installer reported %v", status)
+	}
+	result := entity.CallbackResult{Time: time.Now(), Status: status, ClientIP: requestContext(r).ClientIP,
+		Logs: body.Logs}
+	if err := t.Repository.Write(func(session repository.Session) error {
+		h, err := session.Host().Get(s)
+		if err != nil {
+			return err
+		}
+		if !h.ValidCallbackToken(token, result.Time) {
+			return &errors.Error{Code: errors.EForbidden,
+				Msg: fmt.Sprintf("[controller.Lifecycle] host %v callback token is not valid", s)}
+		}
+		if h, err = repository.TransitionHost(session, s, "", status, entity.TransitionTriggerCallback,
+			body.Reason); err != nil {
+			return err
+		}
+		h.CallbackToken, h.CallbackTokenExpires, h.LastCallback = "", time.Time{}, result
+		return session.Host().Update(h)
+	}); err != nil {
+		writeLifecycleError(w, err)
+		return
+	}
+	server.WriteJSON(w, []byte{}, http.StatusNoContent)
+}
+
+// writeLifecycleError answers the error of a lifecycle operation.
+func writeLifecycleError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errors.ERepositoryKeyNotFound):
+		server.WriteJSON(w, errors.MarshalJSON(err), http.StatusNotFound)
+	case errors.Is(err, errors.ERepositoryConflict):
+		server.WriteJSON(w, errors.MarshalJSON(err), http.StatusConflict)
+	case errors.Is(err, errors.EForbidden), errors.Is(err, errors.ERepositoryReadOnly):
+		server.WriteJSON(w, errors.MarshalJSON(err), http.StatusForbidden)
+	default:
+		server.WriteJSON(w, errors.MarshalJSON(err), http.StatusInternalServerError)
+	}
+}
+
+//~ STRUCT - JSON -----------------------------------------------------------
+
+// LifecycleBody stores the lifecycle state of a host and its latest transitions, oldest first.
+type LifecycleBody struct {
+	HostID       string              `json:"host-id"`
+	State        string              `json:"state"`
+	History      []TransitionBody    `json:"history"`
+	LastCallback *CallbackResultBody `json:"last-callback,omitempty"`
+}
+
+// TransitionBody stores an audited lifecycle transition.
+type TransitionBody struct {
+	Time    time.Time `json:"time"`
+	From    string    `json:"from"`
+	To      string    `json:"to"`
+	Trigger string    `json:"trigger"` // "api", "boot", "callback" or "discovery".
+	Reason  string    `json:"reason,omitempty"`
+}
+
+// LoadEntity fills the body with the lifecycle of the host.
+func (t *LifecycleBody) LoadEntity(h entity.Host) {
+	t.HostID = h.ID
+	t.State = h.Lifecycle()
+	t.History = make([]TransitionBody, 0, len(h.StateHistory))
+	for _, tr := range h.StateHistory {
+		t.History = append(t.History, TransitionBody{Time: tr.Time, From: tr.From, To: tr.To, Trigger: tr.Trigger,
+			Reason: tr.Reason})
+	}
+	t.LastCallback = nil
+	if c := h.LastCallback; c != (entity.CallbackResult{}) {
+		t.LastCallback = &CallbackResultBody{Time: c.Time, Status: c.Status, ClientIP: c.ClientIP, Logs: c.Logs}
+	}
+}
+
+// JSON returns a json representation of the structure.
+func (t LifecycleBody) JSON() []byte {
+	j, _ := json.Marshal(t)
+	return j
+}
+
+// TransitionRequestBody stores the requested lifecycle state of a host.
+type TransitionRequestBody struct {
+	State  string `json:"state"`
+	From   string `json:"from,omitempty"` // expected current state, any if empty.
+	Reason string `json:"reason,omitempty"`
+}
+
+// Validate checks if the data hold in the instance follows the desired schema.
+func (t TransitionRequestBody) Validate() error {
+	if !entity.ValidHostState(t.State) || (t.From != "" && !entity.ValidHostState(t.From)) {
+		return &errors.Error{
+			Code: errors.EInvalidType,
+			Msg:  "[controller.Lifecycle] State and From should be lifecycle states. ",
+		}
+	}
+	return nil
+}
+
+// CallbackBody stores the optional reason and logs reported by an installer callback.
+type CallbackBody struct {
+	Reason string `json:"reason,omitempty"`
+	Logs   string `json:"logs,omitempty"`
+}
+
+// LoadRequest reads a JSON body, or the plain logs for the other content types.
+// Logs longer than maxCallbackLogs keep their end.
+func (t *CallbackBody) LoadRequest(r *http.Request) error {
+	b, err := ioutil.ReadAll(io.LimitReader(r.Body, maxCallbackBody))
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		t.Logs = string(b)
+	} else if len(b) > 0 {
+		if err := json.Unmarshal(b, t); err != nil {
+			return err
+		}
+	}
+	if len(t.Logs) > maxCallbackLogs {
+		t.Logs = t.Logs[len(t.Logs)-maxCallbackLogs:]
+	}
+	return nil
+}
+
+// CallbackResultBody stores the latest result reported by the installer callbacks.
+type CallbackResultBody struct {
+	Time     time.Time `json:"time"`
+	Status   string    `json:"status"`
+	ClientIP string    `json:"client-ip,omitempty"`
+	Logs     string    `json:"logs,omitempty"`
+}
@@ -0,0 +1,260 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/pxecore/pxecore/pkg/entity"
+	server "github.com/pxecore/pxecore/pkg/http"
+	"github.com/pxecore/pxecore/pkg/repository"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLifecycle(t *testing.T) {
+	r, _ := repository.NewRepository(map[string]interface{}{"driver": "memory"})
+	_ = r.Write(func(session repository.Session) error {
+		_ = session.Template().Create(entity.Template{ID: "template1",
+			Template: "#!ipxe\necho {{ callback \"installed\" }}"})
+		_ = session.Host().Create(entity.Host{ID: "node-1", HardwareAddr: []string{"00-14-22-04-25-ac"}})
+		return session.Host().Create(entity.Host{ID: "host1", HardwareAddr: []string{"00-14-22-04-25-ab"},
+			TemplateID: "template1"})
+	})
+	ro := mux.NewRouter()
+	Boot{Repository: r}.Register(ro, server.Config{})
+	Lifecycle{Repository: r}.Register(ro, server.Config{})
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		wantStatusCode int
+		wantResponse   string
+		wantState      string
+	}{
+		{"OK_READY", http.MethodGet, "/host/host1/state", "", http.StatusOK, "", entity.HostStateReady},
+		{"KO_POST_INSTALLED", http.MethodPost, "/host/host1/state", "{\"state\":\"installed\"}",
+			http.StatusForbidden, "", ""},
+		{"KO_CALLBACK_NO_TOKEN", http.MethodPost, "/host/host1/callback/installed", "",
+			http.StatusForbidden, "", ""},
+		{"OK_BOOT_INSTALL", http.MethodGet, "/boot/mac/00-14-22-04-25-ab.ipxe", "",
+			http.StatusOK, "", ""},
+		{"OK_INSTALLING", http.MethodGet, "/host/host1/state", "", http.StatusOK, "", entity.HostStateInstalling},
+		{"KO_CALLBACK_WRONG_TOKEN", http.MethodPost, "/host/host1/callback/installed?token=0123", "",
+			http.StatusForbidden, "", ""},
+		{"OK_CALLBACK_INSTALLED", http.MethodPost, "/host/host1/callback/installed?token={token}", "done",
+			http.StatusNoContent, "", ""},
+		{"KO_CALLBACK_USED_TOKEN", http.MethodPost, "/host/host1/callback/failed?token={token}", "",
+			http.StatusForbidden, "", ""},
+		{"OK_BOOT_LOCAL", http.MethodGet, "/boot/mac/00-14-22-04-25-ab.ipxe", "",
+			http.StatusOK, "#!ipxe\nexit\n", ""},
+		{"OK_INSTALLED", http.MethodGet, "/host/host1/state", "", http.StatusOK, "", entity.HostStateInstalled},
+		{"KO_CALLBACK_UNKNOWN", http.MethodPost, "/host/host1/callback/started", "", http.StatusNotFound, "", ""},
+		{"KO_POST_INVALID", http.MethodPost, "/host/host1/state", "{\"state\":\"broken\"}",
+			http.StatusBadRequest, "", ""},
+		{"KO_POST_FAILED", http.MethodPost, "/host/host1/state", "{\"state\":\"failed\"}",
+			http.StatusForbidden, "", ""},
+		{"KO_POST_FROM", http.MethodPost, "/host/host1/state", "{\"state\":\"ready\",\"from\":\"failed\"}",
+			http.StatusConflict, "", ""},
+		{"KO_POST_NOT_ALLOWED", http.MethodPost, "/host/host1/state", "{\"state\":\"installing\"}",
+			http.StatusConflict, "", ""},
+		{"OK_POST_REPROVISION", http.MethodPost, "/host/host1/state",
+			"{\"state\":\"ready\",\"from\":\"installed\",\"reason\":\"reinstall\"}",
+			http.StatusOK, "", entity.HostStateReady},
+		{"OK_POST_DECOMMISSION", http.MethodPost, "/host/host1/state", "{\"state\":\"decommissioned\"}",
+			http.StatusOK, "", entity.HostStateDecommissioned},
+		{"OK_BOOT_DECOMMISSIONED", http.MethodGet, "/boot/mac/00-14-22-04-25-ab.ipxe", "",
+			http.StatusOK, "#!ipxe\nexit\n", ""},
+		{"KO_NOT_FOUND", http.MethodGet, "/host/host2/state", "", http.StatusNotFound, "", ""},
+		{"KO_CALLBACK_NOT_FOUND", http.MethodPost, "/host/host2/callback/installed", "", http.StatusNotFound, "", ""},
+		{"OK_HYPHEN_ID", http.MethodGet, "/host/node-1/state", "", http.StatusOK, "", entity.HostStateReady},
+		{"OK_POST_HYPHEN_ID", http.MethodPost, "/host/node-1/state", "{\"state\":\"decommissioned\"}",
+			http.StatusOK, "", entity.HostStateDecommissioned},
+		{"KO_CALLBACK_HYPHEN_ID", http.MethodPost, "/host/node-1/callback/installed", "", http.StatusForbidden, "", ""},
+	}
+	token := ""
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, strings.Replace(tt.path, "{token}", token, 1),
+				bytes.NewBuffer([]byte(tt.body)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Host = "10.0.0.1"
+			rr := httptest.NewRecorder()
+			ro.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tt.wantStatusCode {
+				t.Errorf("handler returned wrong status code: got %v want %v",
+					status, tt.wantStatusCode)
+			}
+			if body := rr.Body.String(); tt.wantResponse != "" && body != tt.wantResponse {
+				t.Errorf("handler returned wrong body: got %v want %v",
+					body, tt.wantResponse)
+			}
+			if prefix := "#!ipxe\necho http://10.0.0.1/host/host1/callback/installed?token="; tt.name == "OK_BOOT_INSTALL" {
+				if body := rr.Body.String(); !strings.HasPrefix(body, prefix) || len(body) == len(prefix) {
+					t.Fatalf("handler returned wrong body: got %v want %v<token>", body, prefix)
+				}
+				token = strings.TrimPrefix(rr.Body.String(), prefix)
+			}
+			if tt.wantState == "" {
+				return
+			}
+			lb := LifecycleBody{}
+			if err := json.Unmarshal(rr.Body.Bytes(), &lb); err != nil {
+				t.Fatal(err)
+			}
+			if lb.State != tt.wantState {
+				t.Errorf("handler returned wrong state: got %v want %v", lb.State, tt.wantState)
+			}
+		})
+	}
+
+	lb := LifecycleBody{}
+	_ = r.Read(func(session repository.Session) error {
+		h, err := session.Host().Get("host1")
+		lb.LoadEntity(h)
+		return err
+	})
+	want := []struct{ to, trigger, reason string }{
+		{entity.HostStateInstalling, entity.TransitionTriggerBoot, "template template1 served"},
+		{entity.HostStateInstalled, entity.TransitionTriggerCallback, "installer reported installed"},
+		{entity.HostStateReady, entity.TransitionTriggerAPI, "reinstall"},
+		{entity.HostStateDecommissioned, entity.TransitionTriggerAPI, ""},
+	}
+	if lb.LastCallback == nil || lb.LastCallback.Status != entity.HostStateInstalled || lb.LastCallback.Logs != "done" {
+		t.Errorf("wrong last callback: got %+v", lb.LastCallback)
+	}
+	if len(lb.History) != len(want) {
+		t.Fatalf("wrong history: got %+v", lb.History)
+	}
+	for i, tr := range lb.History {
+		if tr.To != want[i].to || tr.Trigger != want[i].trigger || tr.Reason != want[i].reason {
+			t.Errorf("wrong transition %v: got %+v want %+v", i, tr, want[i])
+		}
+	}
+}
+
+func TestLifecycle_Trap(t *testing.T) {
+	r, _ := repository.NewRepository(map[string]interface{}{"driver": "memory"})
+	_ = r.Write(func(session repository.Session) error {
+		_ = session.Template().Create(entity.Template{ID: "template1",
+			Template: "#!ipxe\necho {{ callback \"installed\" }}"})
+		return session.Host().Create(entity.Host{ID: "host1", HardwareAddr: []string{"00-14-22-04-25-ab"},
+			TemplateID: "template1", TrapMode: true})
+	})
+	ro := mux.NewRouter()
+	Boot{Repository: r}.Register(ro, server.Config{})
+	Host{Repository: r}.Register(ro, server.Config{})
+	Lifecycle{Repository: r}.Register(ro, server.Config{})
+	prefix := "#!ipxe\necho http://10.0.0.1/host/host1/callback/installed?token="
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		wantStatusCode int
+		wantResponse   string
+		wantState      string
+	}{
+		{"OK_BOOT_INSTALL", http.MethodGet, "/boot/mac/00-14-22-04-25-ab.ipxe", http.StatusOK, prefix, ""},
+		{"OK_CALLBACK_INSTALLED", http.MethodPost, "/host/host1/callback/installed?token={token}",
+			http.StatusNoContent, "", ""},
+		{"OK_BOOT_LOCAL", http.MethodGet, "/boot/mac/00-14-22-04-25-ab.ipxe", http.StatusOK, "#!ipxe\nexit\n", ""},
+		{"OK_TRAP", http.MethodPost, "/host/host1/trap", http.StatusNoContent, "", ""},
+		{"OK_READY", http.MethodGet, "/host/host1/state", http.StatusOK, "", entity.HostStateReady},
+		{"OK_BOOT_REINSTALL", http.MethodGet, "/boot/mac/00-14-22-04-25-ab.ipxe", http.StatusOK, prefix, ""},
+		{"OK_INSTALLING", http.MethodGet, "/host/host1/state", http.StatusOK, "", entity.HostStateInstalling},
+		{"OK_BOOT_TRAPPED", http.MethodGet, "/boot/mac/00-14-22-04-25-ab.ipxe", http.StatusOK, "#!ipxe\nexit\n", ""},
+	}
+	token := ""
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, strings.Replace(tt.path, "{token}", token, 1), new(bytes.Buffer))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Host = "10.0.0.1"
+			rr := httptest.NewRecorder()
+			ro.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tt.wantStatusCode {
+				t.Fatalf("handler returned wrong status code: got %v want %v",
+					status, tt.wantStatusCode)
+			}
+			body := rr.Body.String()
+			if tt.wantResponse == prefix {
+				if !strings.HasPrefix(body, prefix) || len(body) == len(prefix) {
+					t.Fatalf("handler returned wrong body: got %v want %v<token>", body, prefix)
+				}
+				token = strings.TrimPrefix(body, prefix)
+			} else if tt.wantResponse != "" && body != tt.wantResponse {
+				t.Errorf("handler returned wrong body: got %v want %v", body, tt.wantResponse)
+			}
+			if tt.wantState == "" {
+				return
+			}
+			lb := LifecycleBody{}
+			if err := json.Unmarshal(rr.Body.Bytes(), &lb); err != nil {
+				t.Fatal(err)
+			}
+			if lb.State != tt.wantState {
+				t.Errorf("handler returned wrong state: got %v want %v", lb.State, tt.wantState)
+			}
+		})
+	}
+}
+
+func TestLifecycle_Callback(t *testing.T) {
+	r, _ := repository.NewRepository(map[string]interface{}{"driver": "memory"})
+	_ = r.Write(func(session repository.Session) error {
+		_ = session.Host().Create(entity.Host{ID: "host1", State: entity.HostStateInstalling,
+			CallbackToken: "expired", CallbackTokenExpires: time.Now().Add(-time.Minute)})
+		return session.Host().Create(entity.Host{ID: "host2", State: entity.HostStateInstalling,
+			CallbackToken: "valid", CallbackTokenExpires: time.Now().Add(time.Hour)})
+	})
+	ro := mux.NewRouter()
+	Lifecycle{Repository: r}.Register(ro, server.Config{})
+	tests := []struct {
+		name           string
+		path           string
+		contentType    string
+		body           string
+		wantStatusCode int
+	}{
+		{"KO_EXPIRED", "/host/host1/callback/failed?token=expired", "text/plain", "", http.StatusForbidden},
+		{"KO_OTHER_HOST", "/host/host1/callback/failed?token=valid", "text/plain", "", http.StatusForbidden},
+		{"KO_JSON", "/host/host2/callback/failed?token=valid", "application/json", "{", http.StatusBadRequest},
+		{"OK_JSON", "/host/host2/callback/failed?token=valid", "application/json",
+			"{\"reason\":\"disk not found\",\"logs\":\"anaconda error\"}", http.StatusNoContent},
+		{"KO_USED", "/host/host2/callback/installed?token=valid", "text/plain", "", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, tt.path, bytes.NewBuffer([]byte(tt.body)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Add("Content-Type", tt.contentType)
+			rr := httptest.NewRecorder()
+			ro.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tt.wantStatusCode {
+				t.Errorf("handler returned wrong status code: got %v want %v",
+					status, tt.wantStatusCode)
+			}
+		})
+	}
+
+	_ = r.Read(func(session repository.Session) error {
+		h, err := session.Host().Get("host2")
+		if h.State != entity.HostStateFailed || h.CallbackToken != "" || h.LastCallback.Logs != "anaconda error" ||
+			h.StateHistory[0].Reason != "disk not found" {
+			t.Errorf("wrong host: got %+v", h)
+		}
+		return err
+	})
+}
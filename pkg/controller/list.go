@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pxecore/pxecore/pkg/errors"
+	server "github.com/pxecore/pxecore/pkg/http"
+	"net/http"
+	"strconv"
+)
+
+const (
+	// listDefaultLimit is the page size used when the "limit" query param is missing.
+	listDefaultLimit = 100
+	// listMaxLimit is the maximum page size accepted in the "limit" query param.
+	listMaxLimit = 1000
+)
+
+//~ STRUCT - JSON -----------------------------------------------------------
+
+// ListBody stores a page of a list response.
+// NextCursor is empty when there are no more items to fetch.
+type ListBody struct {
+	Items      interface{} `json:"items"`
+	NextCursor string      `json:"next-cursor"`
+}
+
+// JSON returns a json representation of the structure.
+func (l ListBody) JSON() []byte {
+	j, _ := json.Marshal(l)
+	return j
+}
+
+//~ FUNCTIONS ---------------------------------------------------------------
+
+// listParams reads the "cursor" and "limit" query params of a list request.
+func listParams(r *http.Request) (string, int, error) {
+	q := r.URL.Query()
+	limit := listDefaultLimit
+	if l := q.Get("limit"); l != "" {
+		i, err := strconv.Atoi(l)
+		if err != nil || i < 1 || i > listMaxLimit {
+			return "", 0, &errors.Error{
+				Code: errors.EInvalidType,
+				Msg:  fmt.Sprintf("[controller] limit should be a number between 1 and %d", listMaxLimit),
+			}
+		}
+		limit = i
+	}
+	return q.Get("cursor"), limit, nil
+}
+
+// writeDeleteError writes the HTTP response of a failed delete operation.
+func writeDeleteError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errors.ERepositoryKeyNotFound):
+		server.WriteJSON(w, errors.MarshalJSON(err), http.StatusNotFound)
+	case errors.Is(err, errors.ERepositoryDependency):
+		server.WriteJSON(w, errors.MarshalJSON(err), http.StatusConflict)
+	case errors.Is(err, errors.ERepositoryReadOnly):
+		server.WriteJSON(w, errors.MarshalJSON(err), http.StatusForbidden)
+	default:
+		server.WriteJSON(w, errors.MarshalJSON(err), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+	server "github.com/pxecore/pxecore/pkg/http"
+	"github.com/pxecore/pxecore/pkg/repository"
+	"net/http"
+)
+
+//~ STRUCT - Server -----------------------------------------------------------
+
+// Reservation controller for the "/reservation" base path operations.
+// Reservations are the hosts with a static IP, managed through the "/host" path.
+type Reservation struct {
+	Repository repository.Repository // Repository dependency injection.
+}
+
+// Register implements http.Controller interface.
+func (t Reservation) Register(r *mux.Router, config server.Config) {
+	r.HandleFunc("/reservation", t.List).Methods(http.MethodGet)
+}
+
+// List returns a page of reservations sorted by host ID.
+func (t Reservation) List(w http.ResponseWriter, r *http.Request) {
+	cursor, limit, err := listParams(r)
+	if err != nil {
+		server.WriteJSON(w, errors.MarshalJSON(err), http.StatusBadRequest)
+		return
+	}
+	lb := ListBody{}
+	if err := t.Repository.Read(func(session repository.Session) error {
+		l, err := session.Host().List(cursor, 0)
+		if err != nil {
+			return err
+		}
+		items := make([]ReservationBody, 0)
+		for _, e := range l {
+			if e.IP == "" {
+				continue
+			}
+			if len(items) == limit {
+				lb.NextCursor = items[limit-1].HostID
+				break
+			}
+			b := ReservationBody{}
+			b.LoadEntity(e)
+			items = append(items, b)
+		}
+		lb.Items = items
+		return nil
+	}); err != nil {
+		server.WriteJSON(w, errors.MarshalJSON(err), http.StatusInternalServerError)
+	} else {
+		server.WriteJSON(w, lb.JSON(), http.StatusOK)
+	}
+}
+
+//~ STRUCT - JSON -----------------------------------------------------------
+
+// ReservationBody stores reservation response data.
+type ReservationBody struct {
+	HostID       string   `json:"host-id"`
+	HardwareAddr []string `json:"hardware-addr"`
+	IP           string   `json:"ip"`
+	Hostname     string   `json:"hostname"`
+}
+
+// JSON returns a json representation of the structure.
+func (t ReservationBody) JSON() []byte {
+	j, _ := json.Marshal(t)
+	return j
+}
+
+// LoadEntity adds Entity vars to the ReservationBody
+func (t *ReservationBody) LoadEntity(h entity.Host) {
+	t.HostID = h.ID
+	t.HardwareAddr = h.HardwareAddr
+	t.IP = h.IP
+	t.Hostname = h.Hostname
+}
@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"bytes"
+	"github.com/gorilla/mux"
+	"github.com/pxecore/pxecore/pkg/entity"
+	server "github.com/pxecore/pxecore/pkg/http"
+	"github.com/pxecore/pxecore/pkg/repository"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReservation(t *testing.T) {
+	r, _ := repository.NewRepository(map[string]interface{}{"driver": "memory"})
+	_ = r.Write(func(session repository.Session) error {
+		_ = session.Host().Create(entity.Host{ID: "host1", HardwareAddr: []string{"00-14-22-04-25-37"},
+			IP: "10.0.0.5", Hostname: "node1"})
+		_ = session.Host().Create(entity.Host{ID: "host2", HardwareAddr: []string{"00-14-22-04-25-38"}})
+		_ = session.Host().Create(entity.Host{ID: "host3", HardwareAddr: []string{"00-14-22-04-25-39"},
+			IP: "10.0.0.6"})
+		return nil
+	})
+	ro := mux.NewRouter()
+	ss := Reservation{Repository: r}
+	ss.Register(ro, server.Config{})
+	tests := []struct {
+		name           string
+		path           string
+		wantStatusCode int
+		wantResponse   string
+	}{
+		{"OK_LIST", "/reservation",
+			http.StatusOK, "{\"items\":[{\"host-id\":\"host1\",\"hardware-addr\":[\"00-14-22-04-25-37\"]," +
+				"\"ip\":\"10.0.0.5\",\"hostname\":\"node1\"},{\"host-id\":\"host3\"," +
+				"\"hardware-addr\":[\"00-14-22-04-25-39\"],\"ip\":\"10.0.0.6\",\"hostname\":\"\"}],\"next-cursor\":\"\"}"},
+		{"OK_LIST_PAGE", "/reservation?limit=1",
+			http.StatusOK, "{\"items\":[{\"host-id\":\"host1\",\"hardware-addr\":[\"00-14-22-04-25-37\"]," +
+				"\"ip\":\"10.0.0.5\",\"hostname\":\"node1\"}],\"next-cursor\":\"host1\"}"},
+		{"OK_LIST_CURSOR", "/reservation?cursor=host1",
+			http.StatusOK, "{\"items\":[{\"host-id\":\"host3\",\"hardware-addr\":[\"00-14-22-04-25-39\"]," +
+				"\"ip\":\"10.0.0.6\",\"hostname\":\"\"}],\"next-cursor\":\"\"}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, tt.path, bytes.NewBuffer([]byte{}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			rr := httptest.NewRecorder()
+			ro.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tt.wantStatusCode {
+				t.Errorf("handler returned wrong status code: got %v want %v",
+					status, tt.wantStatusCode)
+			}
+
+			if body := rr.Body.String(); body != tt.wantResponse {
+				t.Errorf("handler returned wrong body: got %v want %v",
+					body, tt.wantResponse)
+			}
+		})
+	}
+}
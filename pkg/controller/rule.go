@@ -0,0 +1,190 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+	server "github.com/pxecore/pxecore/pkg/http"
+	"github.com/pxecore/pxecore/pkg/repository"
+	"net/http"
+	"regexp"
+)
+
+var (
+	ruleIDRegex, _ = regexp.Compile("^[a-zA-Z0-9]+(?:[-_][a-zA-Z0-9]+)*$")
+)
+
+//~ STRUCT - Server -----------------------------------------------------------
+
+// Rule controller for the "/rule" base path operations.
+type Rule struct {
+	Repository repository.Repository // Repository dependency injection.
+}
+
+// Register implements http.Controller interface.
+func (t Rule) Register(r *mux.Router, config server.Config) {
+	r.HandleFunc("/rule/{id:[a-zA-Z0-9_-]+}", t.Get).Methods(http.MethodGet)
+	r.HandleFunc("/rule/{id:[a-zA-Z0-9_-]+}", t.Delete).Methods(http.MethodDelete)
+	r.HandleFunc("/rule", t.List).Methods(http.MethodGet)
+	r.HandleFunc("/rule", t.Put).Methods(http.MethodPut)
+}
+
+// Get returns a rule by ID.
+func (t Rule) Get(w http.ResponseWriter, r *http.Request) {
+	v := mux.Vars(r)
+	s, _ := v["id"]
+
+	rb := NewRuleBody()
+	if err := t.Repository.Read(func(session repository.Session) error {
+		e, err := session.Rule().Get(s)
+		if err != nil {
+			return err
+		}
+		rb.LoadEntity(e)
+		return nil
+	}); err != nil {
+		if errors.Is(err, errors.ERepositoryKeyNotFound) {
+			server.WriteText(w, err.Error(), http.StatusNotFound)
+		} else {
+			server.WriteText(w, err.Error(), http.StatusInternalServerError)
+		}
+	} else {
+		server.WriteJSON(w, rb.JSON(), http.StatusOK)
+	}
+}
+
+// Put stores a new rule or updates an existing one.
+func (t Rule) Put(w http.ResponseWriter, r *http.Request) {
+	body := NewRuleBody()
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		server.WriteJSON(w, errors.MarshalJSON(err), http.StatusBadRequest)
+		return
+	}
+	if err := body.Validate(); err != nil {
+		server.WriteJSON(w, errors.MarshalJSON(err), http.StatusBadRequest)
+		return
+	}
+
+	if err := t.Repository.Write(func(session repository.Session) error {
+		err := session.Rule().Create(body.ToEntity())
+		if errors.Is(err, errors.ERepositoryKeyExist) {
+			err = session.Rule().Update(body.ToEntity())
+		}
+		return err
+	}); err != nil {
+		if errors.Is(err, errors.ERepositoryKeyNotFound) {
+			server.WriteJSON(w, errors.MarshalJSON(err), http.StatusFailedDependency)
+		} else {
+			server.WriteJSON(w, errors.MarshalJSON(err), http.StatusInternalServerError)
+		}
+		return
+	}
+	server.WriteJSON(w, []byte{}, http.StatusCreated)
+}
+
+// List returns a page of rules sorted by ID.
+func (t Rule) List(w http.ResponseWriter, r *http.Request) {
+	cursor, limit, err := listParams(r)
+	if err != nil {
+		server.WriteJSON(w, errors.MarshalJSON(err), http.StatusBadRequest)
+		return
+	}
+	lb := ListBody{}
+	if err := t.Repository.Read(func(session repository.Session) error {
+		l, err := session.Rule().List(cursor, limit+1)
+		if err != nil {
+			return err
+		}
+		if len(l) > limit {
+			l = l[:limit]
+			lb.NextCursor = l[limit-1].ID
+		}
+		items := make([]RuleBody, 0, len(l))
+		for _, e := range l {
+			rb := NewRuleBody()
+			rb.LoadEntity(e)
+			items = append(items, rb)
+		}
+		lb.Items = items
+		return nil
+	}); err != nil {
+		server.WriteJSON(w, errors.MarshalJSON(err), http.StatusInternalServerError)
+	} else {
+		server.WriteJSON(w, lb.JSON(), http.StatusOK)
+	}
+}
+
+// Delete removes a rule by ID.
+func (t Rule) Delete(w http.ResponseWriter, r *http.Request) {
+	v := mux.Vars(r)
+	s, _ := v["id"]
+
+	if err := t.Repository.Write(func(session repository.Session) error {
+		return session.Rule().Delete(entity.Rule{ID: s})
+	}); err != nil {
+		writeDeleteError(w, err)
+	} else {
+		server.WriteJSON(w, []byte{}, http.StatusNoContent)
+	}
+}
+
+//~ STRUCT - JSON -----------------------------------------------------------
+
+// RuleBody stores rule request and response data as well
+// hold transformations and validations.
+type RuleBody struct {
+	ID         string            `json:"id"`
+	Priority   int               `json:"priority"` // lower priorities are evaluated first.
+	Selector   map[string]string `json:"selector"` // labels a host must have, empty matches every host.
+	TemplateID string            `json:"template-id"`
+}
+
+// NewRuleBody constructs a new RuleBody
+func NewRuleBody() RuleBody {
+	return RuleBody{
+		Selector: make(map[string]string),
+	}
+}
+
+// LoadEntity fills the body with the entity values.
+func (t *RuleBody) LoadEntity(e entity.Rule) {
+	t.ID = e.ID
+	t.Priority = e.Priority
+	t.Selector = e.Selector
+	t.TemplateID = e.TemplateID
+}
+
+// Validate checks if the data hold in the instance follows the desired schema.
+func (t RuleBody) Validate() error {
+	if !ruleIDRegex.MatchString(t.ID) {
+		return &errors.Error{
+			Code: errors.EInvalidType,
+			Msg:  fmt.Sprint("[controller.Rule] id should follow pattern: ", ruleIDRegex.String()),
+		}
+	}
+	if !templateIDRegex.MatchString(t.TemplateID) {
+		return &errors.Error{
+			Code: errors.EInvalidType,
+			Msg:  fmt.Sprint("[controller.Rule] template id should follow pattern: ", templateIDRegex.String()),
+		}
+	}
+	return validateLabels(t.Selector)
+}
+
+// ToEntity returns an entity from the provided request.
+func (t RuleBody) ToEntity() entity.Rule {
+	return entity.Rule{
+		ID:         t.ID,
+		Priority:   t.Priority,
+		Selector:   t.Selector,
+		TemplateID: t.TemplateID,
+	}
+}
+
+// JSON returns a json representation of the structure.
+func (t RuleBody) JSON() []byte {
+	j, _ := json.Marshal(t)
+	return j
+}
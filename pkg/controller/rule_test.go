@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"bytes"
+	"github.com/gorilla/mux"
+	"github.com/pxecore/pxecore/pkg/entity"
+	server "github.com/pxecore/pxecore/pkg/http"
+	"github.com/pxecore/pxecore/pkg/repository"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRule(t *testing.T) {
+	r, _ := repository.NewRepository(map[string]interface{}{"driver": "memory"})
+	_ = r.Write(func(session repository.Session) error {
+		return session.Template().Create(entity.Template{ID: "template1", Template: "#!ipxe"})
+	})
+	ro := mux.NewRouter()
+	ss := Rule{Repository: r}
+	ss.Register(ro, server.Config{})
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		contentType    string
+		body           string
+		wantStatusCode int
+		wantResponse   string
+	}{
+		{"OK_CREATE", http.MethodPut, "/rule",
+			"application/json", "{\"id\":\"rack-a1\",\"priority\":10,\"selector\":{\"rack\":\"a1\"},\"template-id\":\"template1\"}",
+			http.StatusCreated, ""},
+		{"OK_FOUND", http.MethodGet, "/rule/rack-a1",
+			"application/json", "",
+			http.StatusOK, "{\"id\":\"rack-a1\",\"priority\":10,\"selector\":{\"rack\":\"a1\"},\"template-id\":\"template1\"}"},
+		{"OK_UPDATE", http.MethodPut, "/rule",
+			"application/json", "{\"id\":\"rack-a1\",\"priority\":5,\"selector\":{\"rack\":\"a2\"},\"template-id\":\"template1\"}",
+			http.StatusCreated, ""},
+		{"OK_LIST", http.MethodGet, "/rule",
+			"application/json", "",
+			http.StatusOK, "{\"items\":[{\"id\":\"rack-a1\",\"priority\":5,\"selector\":{\"rack\":\"a2\"}," +
+				"\"template-id\":\"template1\"}],\"next-cursor\":\"\"}"},
+		{"KO_MISSING_TEMPLATE", http.MethodPut, "/rule",
+			"application/json", "{\"id\":\"rule2\",\"template-id\":\"template2\"}",
+			http.StatusFailedDependency, ""},
+		{"KO_INVALID_ID", http.MethodPut, "/rule",
+			"application/json", "{\"id\":\"rule 2\",\"template-id\":\"template1\"}",
+			http.StatusBadRequest, ""},
+		{"KO_EMPTY_TEMPLATE", http.MethodPut, "/rule",
+			"application/json", "{\"id\":\"rule2\"}",
+			http.StatusBadRequest, ""},
+		{"KO_INVALID_SELECTOR", http.MethodPut, "/rule",
+			"application/json", "{\"id\":\"rule2\",\"selector\":{\"\":\"a1\"},\"template-id\":\"template1\"}",
+			http.StatusBadRequest, ""},
+		{"KO_NOT_FOUND", http.MethodGet, "/rule/rule2",
+			"application/json", "",
+			http.StatusNotFound, ""},
+		{"OK_DELETE", http.MethodDelete, "/rule/rack-a1",
+			"application/json", "",
+			http.StatusNoContent, ""},
+		{"KO_DELETE_NOT_FOUND", http.MethodDelete, "/rule/rack-a1",
+			"application/json", "",
+			http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, tt.path, bytes.NewBuffer([]byte(tt.body)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Add("Content-Type", tt.contentType)
+			rr := httptest.NewRecorder()
+			ro.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tt.wantStatusCode {
+				t.Errorf("handler returned wrong status code: got %v want %v",
+					status, tt.wantStatusCode)
+			}
+
+			if body := rr.Body.String(); tt.wantResponse != "" && body != tt.wantResponse {
+				t.Errorf("handler returned wrong body: got %v want %v",
+					body, tt.wantResponse)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"github.com/gorilla/mux"
@@ -8,10 +9,22 @@ import (
 	"github.com/pxecore/pxecore/pkg/errors"
 	server "github.com/pxecore/pxecore/pkg/http"
 	"github.com/pxecore/pxecore/pkg/repository"
+	"github.com/pxecore/pxecore/pkg/template"
+	"github.com/pxecore/pxecore/pkg/util"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
+)
+
+const (
+	// authorHeader is the request header naming the author of a template revision.
+	authorHeader = "X-Author"
+	// diffContext is the number of unchanged lines shown around each change of a diff.
+	diffContext = 3
 )
 
 var (
@@ -27,10 +40,18 @@ type Template struct {
 
 // Register implements http.Controller interface.
 func (t Template) Register(r *mux.Router, config server.Config) {
-	r.HandleFunc("/template/{id:[a-zA-Z0-9]+}", t.Get).Methods(http.MethodGet)
-	r.HandleFunc("/template/{id:[a-zA-Z0-9]+}/template", t.GetTemplate).Methods(http.MethodGet)
-	r.HandleFunc("/template/{id:[a-zA-Z0-9]+}/template", t.PostFile).Methods(http.MethodPut)
+	r.HandleFunc("/template/{id:[a-zA-Z0-9_-]+}", t.Get).Methods(http.MethodGet)
+	r.HandleFunc("/template/{id:[a-zA-Z0-9_-]+}", t.Delete).Methods(http.MethodDelete)
+	r.HandleFunc("/template", t.List).Methods(http.MethodGet)
+	r.HandleFunc("/template/{id:[a-zA-Z0-9_-]+}/template", t.GetTemplate).Methods(http.MethodGet)
+	r.HandleFunc("/template/{id:[a-zA-Z0-9_-]+}/template", t.PostFile).Methods(http.MethodPut)
 	r.HandleFunc("/template", t.Post).Methods(http.MethodPut)
+	r.HandleFunc("/template/{id:[a-zA-Z0-9_-]+}/revision", t.ListRevisions).Methods(http.MethodGet)
+	r.HandleFunc("/template/{id:[a-zA-Z0-9_-]+}/revision/{revision:[0-9]+}", t.GetRevision).Methods(http.MethodGet)
+	r.HandleFunc("/template/{id:[a-zA-Z0-9_-]+}/revision/{revision:[0-9]+}/rollback", t.Rollback).
+		Methods(http.MethodPost)
+	r.HandleFunc("/template/{id:[a-zA-Z0-9_-]+}/diff", t.Diff).Methods(http.MethodGet)
+	r.HandleFunc("/template/{id:[a-zA-Z0-9_-]+}/render", t.Render).Methods(http.MethodPost)
 }
 
 // Get returns a template by ID.
@@ -102,23 +123,14 @@ func (t Template) Post(w http.ResponseWriter, r *http.Request) {
 		server.WriteJSON(w, errors.MarshalJSON(err), http.StatusBadRequest)
 		return
 	}
-	if err := t.Repository.Write(func(session repository.Session) error {
-		if err := session.Template().Create(tp.ToEntity()); err != nil {
-			if errors.Is(err, errors.ERepositoryKeyExist) {
-				if err := session.Template().Update(tp.ToEntity()); err != nil {
-					return err
-				}
-				return nil
-			}
-			return err
-		}
-		return nil
-	}); err != nil {
-		server.WriteJSON(w, errors.MarshalJSON(err), http.StatusInternalServerError)
+	if a := r.Header.Get(authorHeader); a != "" {
+		tp.Author = a
 	}
+	t.save(w, tp.ToEntity())
 }
 
-// PostFile saves a template by reading the ID from the URL and the file from the body.
+// PostFile saves a template by reading the ID from the URL, the kind from the "kind" query param
+// and the file from the body.
 func (t Template) PostFile(w http.ResponseWriter, r *http.Request) {
 	v := mux.Vars(r)
 	s, ok := v["id"]
@@ -135,23 +147,247 @@ func (t Template) PostFile(w http.ResponseWriter, r *http.Request) {
 	tp := TemplateBody{
 		ID:       s,
 		Template: string(body),
+		Kind:     r.URL.Query().Get("kind"),
+		Author:   r.Header.Get(authorHeader),
 	}
 	if err := tp.Validate(); err != nil {
 		server.WriteJSON(w, errors.MarshalJSON(err), http.StatusBadRequest)
 		return
 	}
+	if a := r.Header.Get(authorHeader); a != "" {
+		tp.Author = a
+	}
+	t.save(w, tp.ToEntity())
+}
+
+// save creates or updates a template. Templates saved without a kind keep the stored one.
+// The template is validated against its kind before it's written.
+func (t Template) save(w http.ResponseWriter, e entity.Template) {
 	if err := t.Repository.Write(func(session repository.Session) error {
-		if err := session.Template().Create(tp.ToEntity()); err != nil {
+		current, err := session.Template().Get(e.ID)
+		if err != nil && !errors.Is(err, errors.ERepositoryKeyNotFound) {
+			return err
+		}
+		if e.Kind == "" {
+			e.Kind = current.Kind
+		}
+		if err := template.Validate(e); err != nil {
+			return err
+		}
+		if err := session.Template().Create(e); err != nil {
 			if errors.Is(err, errors.ERepositoryKeyExist) {
-				if err := session.Template().Update(tp.ToEntity()); err != nil {
-					return err
-				}
-				return nil
+				return session.Template().Update(e)
+			}
+			return err
+		}
+		return nil
+	}); err != nil {
+		if errors.Is(err, errors.ETemplateError) {
+			server.WriteJSON(w, errors.MarshalJSON(err), http.StatusBadRequest)
+		} else {
+			server.WriteJSON(w, errors.MarshalJSON(err), http.StatusInternalServerError)
+		}
+	}
+}
+
+// List returns a page of templates sorted by ID.
+func (t Template) List(w http.ResponseWriter, r *http.Request) {
+	cursor, limit, err := listParams(r)
+	if err != nil {
+		server.WriteJSON(w, errors.MarshalJSON(err), http.StatusBadRequest)
+		return
+	}
+	lb := ListBody{}
+	if err := t.Repository.Read(func(session repository.Session) error {
+		l, err := session.Template().List(cursor, limit+1)
+		if err != nil {
+			return err
+		}
+		if len(l) > limit {
+			l = l[:limit]
+			lb.NextCursor = l[limit-1].ID
+		}
+		items := make([]TemplateBody, 0, len(l))
+		for _, e := range l {
+			tb := TemplateBody{}
+			tb.LoadTemplate(e)
+			items = append(items, tb)
+		}
+		lb.Items = items
+		return nil
+	}); err != nil {
+		server.WriteJSON(w, errors.MarshalJSON(err), http.StatusInternalServerError)
+	} else {
+		server.WriteJSON(w, lb.JSON(), http.StatusOK)
+	}
+}
+
+// Delete removes a template by ID.
+func (t Template) Delete(w http.ResponseWriter, r *http.Request) {
+	v := mux.Vars(r)
+	s, _ := v["id"]
+
+	if err := t.Repository.Write(func(session repository.Session) error {
+		return session.Template().Delete(entity.Template{ID: s})
+	}); err != nil {
+		writeDeleteError(w, err)
+	} else {
+		server.WriteJSON(w, []byte{}, http.StatusNoContent)
+	}
+}
+
+// ListRevisions returns all the revisions of a template without their body.
+func (t Template) ListRevisions(w http.ResponseWriter, r *http.Request) {
+	s := mux.Vars(r)["id"]
+	items := make([]TemplateRevisionBody, 0)
+	if err := t.Repository.Read(func(session repository.Session) error {
+		l, err := session.Template().ListRevisions(s)
+		if err != nil {
+			return err
+		}
+		for _, e := range l {
+			rb := TemplateRevisionBody{}
+			rb.LoadTemplateRevision(e)
+			rb.Template = ""
+			items = append(items, rb)
+		}
+		return nil
+	}); err != nil {
+		writeTemplateError(w, err)
+	} else {
+		server.WriteJSON(w, ListBody{Items: items}.JSON(), http.StatusOK)
+	}
+}
+
+// GetRevision returns a template revision.
+func (t Template) GetRevision(w http.ResponseWriter, r *http.Request) {
+	v := mux.Vars(r)
+	rev, _ := strconv.Atoi(v["revision"])
+	var rb TemplateRevisionBody
+	if err := t.Repository.Read(func(session repository.Session) error {
+		e, err := session.Template().GetRevision(v["id"], rev)
+		if err != nil {
+			return err
+		}
+		rb.LoadTemplateRevision(e)
+		return nil
+	}); err != nil {
+		writeTemplateError(w, err)
+	} else {
+		server.WriteJSON(w, rb.JSON(), http.StatusOK)
+	}
+}
+
+// Diff returns the unified diff between the revisions "from" and "to" of a template.
+// If "to" is missing the current revision is used.
+func (t Template) Diff(w http.ResponseWriter, r *http.Request) {
+	s := mux.Vars(r)["id"]
+	from, err := strconv.Atoi(r.URL.Query().Get("from"))
+	if err != nil || from <= 0 {
+		server.WriteJSON(w, errors.MarshalJSON(&errors.Error{Code: errors.EInvalidType,
+			Msg: "[controller.Template] from should be a revision number."}), http.StatusBadRequest)
+		return
+	}
+	to := 0
+	if q := r.URL.Query().Get("to"); q != "" {
+		if to, err = strconv.Atoi(q); err != nil || to <= 0 {
+			server.WriteJSON(w, errors.MarshalJSON(&errors.Error{Code: errors.EInvalidType,
+				Msg: "[controller.Template] to should be a revision number."}), http.StatusBadRequest)
+			return
+		}
+	}
+	var diff string
+	if err := t.Repository.Read(func(session repository.Session) error {
+		if to == 0 {
+			e, err := session.Template().Get(s)
+			if err != nil {
+				return err
 			}
+			to = e.Revision
+		}
+		a, err := session.Template().GetRevision(s, from)
+		if err != nil {
 			return err
 		}
+		b, err := session.Template().GetRevision(s, to)
+		if err != nil {
+			return err
+		}
+		diff = util.UnifiedDiff(a.Template, b.Template, fmt.Sprintf("%v@%v", s, from), fmt.Sprintf("%v@%v", s, to),
+			diffContext)
 		return nil
 	}); err != nil {
+		writeTemplateError(w, err)
+	} else {
+		server.WriteText(w, diff, http.StatusOK)
+	}
+}
+
+// Rollback stores the body of a previous revision as a new revision of the template.
+func (t Template) Rollback(w http.ResponseWriter, r *http.Request) {
+	v := mux.Vars(r)
+	rev, _ := strconv.Atoi(v["revision"])
+	var tb TemplateBody
+	if err := t.Repository.Write(func(session repository.Session) error {
+		e, err := session.Template().GetRevision(v["id"], rev)
+		if err != nil {
+			return err
+		}
+		current, err := session.Template().Get(e.TemplateID)
+		if err != nil {
+			return err
+		}
+		if err := session.Template().Update(entity.Template{ID: e.TemplateID, Template: e.Template,
+			Author: r.Header.Get(authorHeader), Kind: current.Kind}); err != nil {
+			return err
+		}
+		n, err := session.Template().Get(e.TemplateID)
+		if err != nil {
+			return err
+		}
+		tb.LoadTemplate(n)
+		return nil
+	}); err != nil {
+		writeTemplateError(w, err)
+	} else {
+		server.WriteJSON(w, tb.JSON(), http.StatusOK)
+	}
+}
+
+// Render executes a template as a dry run against a host and/or ad-hoc vars and boot context.
+// Nothing is written to the repository. Execution errors are returned as 422 along the undefined vars.
+func (t Template) Render(w http.ResponseWriter, r *http.Request) {
+	rb := RenderBody{}
+	if err := json.NewDecoder(r.Body).Decode(&rb); err != nil && err != io.EOF {
+		server.WriteJSON(w, errors.MarshalJSON(err), http.StatusBadRequest)
+		return
+	}
+	ctx := rb.Context.ToContext(requestContext(r))
+	buf := new(bytes.Buffer)
+	undefined, err := template.Render(buf, t.Repository, mux.Vars(r)["id"], rb.HostID, rb.Vars, ctx)
+	if errors.Is(err, errors.ERepositoryKeyNotFound) {
+		server.WriteJSON(w, errors.MarshalJSON(err), http.StatusNotFound)
+		return
+	}
+	res := RenderResultBody{Output: buf.String(), UndefinedVars: undefined}
+	if err != nil {
+		if e, ok := err.(*errors.Error); ok {
+			res.Error = e
+		} else {
+			res.Error = &errors.Error{Code: errors.EUnknown, Msg: err.Error()}
+		}
+		res.Output = ""
+		server.WriteJSON(w, res.JSON(), http.StatusUnprocessableEntity)
+		return
+	}
+	server.WriteJSON(w, res.JSON(), http.StatusOK)
+}
+
+// writeTemplateError writes a revision lookup error with its status code.
+func writeTemplateError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errors.ERepositoryKeyNotFound) {
+		server.WriteJSON(w, errors.MarshalJSON(err), http.StatusNotFound)
+	} else {
 		server.WriteJSON(w, errors.MarshalJSON(err), http.StatusInternalServerError)
 	}
 }
@@ -160,15 +396,23 @@ func (t Template) PostFile(w http.ResponseWriter, r *http.Request) {
 
 // TemplateBody stores template request and response data as well
 // hold transformations and validations.
+// Revision is read-only, it is assigned by the repository.
+// Kind is one of entity.TemplateContentTypes keys, an empty kind keeps the stored one.
 type TemplateBody struct {
 	ID       string `json:"id"`
 	Template string `json:"template"`
+	Revision int    `json:"revision,omitempty"`
+	Author   string `json:"author,omitempty"`
+	Kind     string `json:"kind,omitempty"`
 }
 
 // LoadTemplate fills the template with an entity values.
 func (t *TemplateBody) LoadTemplate(e entity.Template) {
 	t.ID = e.ID
 	t.Template = e.Template
+	t.Revision = e.Revision
+	t.Author = e.Author
+	t.Kind = e.Kind
 }
 
 // Validate checks if the data hold in the instance follows the desired schema.
@@ -185,6 +429,12 @@ func (t TemplateBody) Validate() error {
 			Msg:  "[controller.Template] template should not be empty.",
 		}
 	}
+	if _, ok := entity.TemplateContentTypes[t.Kind]; t.Kind != "" && !ok {
+		return &errors.Error{
+			Code: errors.EInvalidType,
+			Msg:  fmt.Sprint("[controller.Template] unknown kind: ", t.Kind),
+		}
+	}
 	return nil
 }
 
@@ -193,6 +443,8 @@ func (t TemplateBody) ToEntity() entity.Template {
 	return entity.Template{
 		ID:       t.ID,
 		Template: t.Template,
+		Author:   t.Author,
+		Kind:     t.Kind,
 	}
 }
 
@@ -201,3 +453,82 @@ func (t TemplateBody) JSON() []byte {
 	j, _ := json.Marshal(t)
 	return j
 }
+
+// TemplateRevisionBody stores template revision response data.
+type TemplateRevisionBody struct {
+	TemplateID string    `json:"template-id"`
+	Revision   int       `json:"revision"`
+	Template   string    `json:"template,omitempty"`
+	Author     string    `json:"author"`
+	Created    time.Time `json:"created"`
+	Hash       string    `json:"hash"`
+}
+
+// LoadTemplateRevision fills the revision with an entity values.
+func (t *TemplateRevisionBody) LoadTemplateRevision(e entity.TemplateRevision) {
+	t.TemplateID = e.TemplateID
+	t.Revision = e.Revision
+	t.Template = e.Template
+	t.Author = e.Author
+	t.Created = e.Created.UTC()
+	t.Hash = e.Hash
+}
+
+// JSON returns a json representation of the structure.
+func (t TemplateRevisionBody) JSON() []byte {
+	j, _ := json.Marshal(t)
+	return j
+}
+
+// RenderBody stores a dry run render request.
+type RenderBody struct {
+	HostID  string                 `json:"host-id"`
+	Vars    map[string]interface{} `json:"vars"`
+	Context ContextBody            `json:"context"`
+}
+
+// ContextBody stores the boot context of a dry run render request.
+type ContextBody struct {
+	ClientIP     string `json:"client-ip"`
+	Transport    string `json:"transport"`
+	Path         string `json:"path"`
+	Firmware     string `json:"firmware"`
+	Arch         string `json:"arch"`
+	HardwareAddr string `json:"hardware-addr"`
+	ServerAddr   string `json:"server-addr"`
+	BaseURL      string `json:"base-url"`
+}
+
+// ToContext returns the boot context. The server address and base URL default to the ones of def.
+func (t ContextBody) ToContext(def template.Context) template.Context {
+	ctx := template.Context{
+		ClientIP:     t.ClientIP,
+		Transport:    t.Transport,
+		Path:         t.Path,
+		Firmware:     t.Firmware,
+		Arch:         t.Arch,
+		HardwareAddr: t.HardwareAddr,
+		ServerAddr:   t.ServerAddr,
+		BaseURL:      t.BaseURL,
+	}
+	if ctx.ServerAddr == "" {
+		ctx.ServerAddr = def.ServerAddr
+	}
+	if ctx.BaseURL == "" {
+		ctx.BaseURL = def.BaseURL
+	}
+	return ctx
+}
+
+// RenderResultBody stores a dry run render response.
+type RenderResultBody struct {
+	Output        string        `json:"output"`
+	UndefinedVars []string      `json:"undefined-vars"`
+	Error         *errors.Error `json:"error,omitempty"`
+}
+
+// JSON returns a json representation of the structure.
+func (t RenderResultBody) JSON() []byte {
+	j, _ := json.Marshal(t)
+	return j
+}
@@ -3,10 +3,12 @@ package controller
 import (
 	"bytes"
 	"github.com/gorilla/mux"
+	"github.com/pxecore/pxecore/pkg/entity"
 	server "github.com/pxecore/pxecore/pkg/http"
 	"github.com/pxecore/pxecore/pkg/repository"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -30,15 +32,71 @@ func TestTemplate(t *testing.T) {
 		{"KO_MISSING_PARAMETER", http.MethodPut, "/template",
 			"application/json", "{\"id\":\"id1\"}",
 			http.StatusBadRequest, ""},
+		{"KO_INVALID_TEMPLATE", http.MethodPut, "/template",
+			"application/json", "{\"id\":\"id3\",\"template\":\"kernel\\n{{ if }}\"}",
+			http.StatusBadRequest, "{\"code\":\"ETemplateError\",\"message\":\"template.Helper parse error.\"," +
+				"\"error\":{\"template\":\"id3\",\"line\":2,\"column\":1,\"message\":\"missing value for if\"}}"},
+		{"KO_INVALID_TEMPLATE_FILE", http.MethodPut, "/template/id1/template",
+			"application/text", "kernel {{ foo }}",
+			http.StatusBadRequest, "{\"code\":\"ETemplateError\",\"message\":\"template.Helper parse error.\"," +
+				"\"error\":{\"template\":\"id1\",\"line\":1,\"column\":11,\"message\":\"function \\\"foo\\\" not defined\"}}"},
 		{"OK_UPDATE_TEMPLATE", http.MethodPut, "/template/id1/template",
 			"application/text", "template2\ntemplate2",
 			http.StatusOK, ""},
 		{"OK_GET_TEMPLATE", http.MethodGet, "/template/id1",
 			"application/json", "",
-			http.StatusOK, "{\"id\":\"id1\",\"template\":\"template2\\ntemplate2\"}"},
+			http.StatusOK, "{\"id\":\"id1\",\"template\":\"template2\\ntemplate2\",\"revision\":2}"},
 		{"OK_GET_TEMPLATE_TEXT", http.MethodGet, "/template/id1/template",
 			"application/text", "",
 			http.StatusOK, "template2\ntemplate2"},
+		{"OK_ADD_TEMPLATE_2", http.MethodPut, "/template",
+			"application/json", "{\"id\":\"id2\",\"template\":\"template3\"}",
+			http.StatusOK, ""},
+		{"OK_LIST", http.MethodGet, "/template",
+			"application/json", "",
+			http.StatusOK, "{\"items\":[{\"id\":\"id1\",\"template\":\"template2\\ntemplate2\",\"revision\":2}," +
+				"{\"id\":\"id2\",\"template\":\"template3\",\"revision\":1}],\"next-cursor\":\"\"}"},
+		{"OK_LIST_PAGE", http.MethodGet, "/template?limit=1",
+			"application/json", "",
+			http.StatusOK, "{\"items\":[{\"id\":\"id1\",\"template\":\"template2\\ntemplate2\",\"revision\":2}]," +
+				"\"next-cursor\":\"id1\"}"},
+		{"OK_LIST_CURSOR", http.MethodGet, "/template?limit=1&cursor=id1",
+			"application/json", "",
+			http.StatusOK, "{\"items\":[{\"id\":\"id2\",\"template\":\"template3\",\"revision\":1}],\"next-cursor\":\"\"}"},
+		{"KO_LIST_LIMIT", http.MethodGet, "/template?limit=0",
+			"application/json", "",
+			http.StatusBadRequest, ""},
+		{"OK_DELETE", http.MethodDelete, "/template/id2",
+			"application/json", "",
+			http.StatusNoContent, ""},
+		{"KO_DELETE_NOT_FOUND", http.MethodDelete, "/template/id2",
+			"application/json", "",
+			http.StatusNotFound, ""},
+		{"KO_UNKNOWN_KIND", http.MethodPut, "/template",
+			"application/json", "{\"id\":\"ks\",\"template\":\"install\",\"kind\":\"unknown\"}",
+			http.StatusBadRequest, ""},
+		{"KO_INVALID_IGNITION", http.MethodPut, "/template",
+			"application/json", "{\"id\":\"ign\",\"template\":\"{\\\"a\\\": }\",\"kind\":\"ignition\"}",
+			http.StatusBadRequest, ""},
+		{"OK_ADD_IGNITION_FILE", http.MethodPut, "/template/ign/template?kind=ignition",
+			"application/text", "{\"user\": \"{{ .Vars.user }}\"}",
+			http.StatusOK, ""},
+		{"KO_UPDATE_IGNITION_KEEPS_KIND", http.MethodPut, "/template/ign/template",
+			"application/text", "user {{ .Vars.user }}",
+			http.StatusBadRequest, ""},
+		{"OK_GET_IGNITION", http.MethodGet, "/template/ign",
+			"application/json", "",
+			http.StatusOK, "{\"id\":\"ign\",\"template\":\"{\\\"user\\\": \\\"{{ .Vars.user }}\\\"}\"," +
+				"\"revision\":1,\"kind\":\"ignition\"}"},
+		{"OK_ADD_HYPHEN_ID", http.MethodPut, "/template/local-boot/template",
+			"application/text", "exit",
+			http.StatusOK, ""},
+		{"OK_GET_HYPHEN_ID", http.MethodGet, "/template/local-boot/template",
+			"application/text", "",
+			http.StatusOK, "exit"},
+		{"OK_DELETE_HYPHEN_ID", http.MethodDelete, "/template/local-boot",
+			"application/json", "",
+			http.StatusNoContent, ""},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -62,3 +120,148 @@ func TestTemplate(t *testing.T) {
 		})
 	}
 }
+
+func TestTemplate_Revision(t *testing.T) {
+	r, _ := repository.NewRepository(map[string]interface{}{"driver": "memory"})
+	ro := mux.NewRouter()
+	ss := Template{Repository: r}
+	ss.Register(ro, server.Config{})
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		author         string
+		body           string
+		wantStatusCode int
+		wantResponse   string
+	}{
+		{"OK_CREATE", http.MethodPut, "/template/id1/template", "alice", "kernel\ninitrd\nboot\n",
+			http.StatusOK, ""},
+		{"OK_UPDATE", http.MethodPut, "/template/id1/template", "bob", "kernel quiet\ninitrd\nboot\n",
+			http.StatusOK, ""},
+		{"OK_UPDATE_SAME_BODY", http.MethodPut, "/template/id1/template", "carol", "kernel quiet\ninitrd\nboot\n",
+			http.StatusOK, ""},
+		{"OK_LIST", http.MethodGet, "/template/id1/revision", "", "",
+			http.StatusOK, `"revision":2,"author":"bob"`},
+		{"OK_GET", http.MethodGet, "/template/id1/revision/1", "", "",
+			http.StatusOK, `"template":"kernel\ninitrd\nboot\n","author":"alice"`},
+		{"KO_GET_NOT_FOUND", http.MethodGet, "/template/id1/revision/3", "", "",
+			http.StatusNotFound, ""},
+		{"KO_LIST_NOT_FOUND", http.MethodGet, "/template/id2/revision", "", "",
+			http.StatusNotFound, ""},
+		{"OK_DIFF", http.MethodGet, "/template/id1/diff?from=1&to=2", "", "",
+			http.StatusOK, "--- id1@1\n+++ id1@2\n@@ -1,3 +1,3 @@\n-kernel\n+kernel quiet\n initrd\n boot\n"},
+		{"OK_DIFF_CURRENT", http.MethodGet, "/template/id1/diff?from=1", "", "",
+			http.StatusOK, "+++ id1@2\n"},
+		{"KO_DIFF_FROM", http.MethodGet, "/template/id1/diff", "", "",
+			http.StatusBadRequest, ""},
+		{"KO_DIFF_NOT_FOUND", http.MethodGet, "/template/id1/diff?from=1&to=9", "", "",
+			http.StatusNotFound, ""},
+		{"OK_ROLLBACK", http.MethodPost, "/template/id1/revision/1/rollback", "dave", "",
+			http.StatusOK, `{"id":"id1","template":"kernel\ninitrd\nboot\n","revision":3,"author":"dave"}`},
+		{"OK_DIFF_ROLLBACK", http.MethodGet, "/template/id1/diff?from=2&to=3", "", "",
+			http.StatusOK, "-kernel quiet\n+kernel\n"},
+		{"KO_ROLLBACK_NOT_FOUND", http.MethodPost, "/template/id1/revision/9/rollback", "", "",
+			http.StatusNotFound, ""},
+		{"OK_CREATE_HYPHEN_ID", http.MethodPut, "/template/local-boot/template", "alice", "exit\n",
+			http.StatusOK, ""},
+		{"OK_UPDATE_HYPHEN_ID", http.MethodPut, "/template/local-boot/template", "bob", "sanboot\n",
+			http.StatusOK, ""},
+		{"OK_LIST_HYPHEN_ID", http.MethodGet, "/template/local-boot/revision", "", "",
+			http.StatusOK, `"revision":2,"author":"bob"`},
+		{"OK_DIFF_HYPHEN_ID", http.MethodGet, "/template/local-boot/diff?from=1&to=2", "", "",
+			http.StatusOK, "-exit\n+sanboot\n"},
+		{"OK_ROLLBACK_HYPHEN_ID", http.MethodPost, "/template/local-boot/revision/1/rollback", "carol", "",
+			http.StatusOK, `"revision":3,"author":"carol"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, tt.path, bytes.NewBuffer([]byte(tt.body)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.author != "" {
+				req.Header.Add(authorHeader, tt.author)
+			}
+			rr := httptest.NewRecorder()
+			ro.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tt.wantStatusCode {
+				t.Errorf("handler returned wrong status code: got %v want %v",
+					status, tt.wantStatusCode)
+			}
+			if body := rr.Body.String(); !strings.Contains(body, tt.wantResponse) {
+				t.Errorf("handler returned wrong body: got %v want %v",
+					body, tt.wantResponse)
+			}
+		})
+	}
+}
+
+func TestTemplate_Render(t *testing.T) {
+	r, _ := repository.NewRepository(map[string]interface{}{"driver": "memory"})
+	_ = r.Write(func(s repository.Session) error {
+		_ = s.Template().Create(entity.Template{ID: "install",
+			Template: "{{ .Vars.os }} {{ .GetVar \"disk\" \"sda\" }} {{ .Context.Firmware }}"})
+		_ = s.Template().Create(entity.Template{ID: "strict", Template: "{{ required \"os\" }}"})
+		_ = s.Template().Create(entity.Template{ID: "local-boot", Template: "exit {{ .Vars.os }}"})
+		_ = s.Group().Create(entity.Group{ID: "group1", Vars: map[string]interface{}{"os": "debian"}})
+		return s.Host().Create(entity.Host{ID: "host1", HardwareAddr: []string{"88-99-aa-bb-cc-dd"},
+			GroupID: "group1", TrapMode: true})
+	})
+	ro := mux.NewRouter()
+	ss := Template{Repository: r}
+	ss.Register(ro, server.Config{})
+	tests := []struct {
+		name           string
+		path           string
+		body           string
+		wantStatusCode int
+		wantResponse   string
+	}{
+		{"OK_HOST", "/template/install/render", `{"host-id":"host1","context":{"firmware":"uefi"}}`,
+			http.StatusOK, `{"output":"debian sda uefi","undefined-vars":["disk"]}`},
+		{"OK_VARS_OVERRIDE_HOST", "/template/install/render", `{"host-id":"host1","vars":{"disk":"nvme0n1"}}`,
+			http.StatusOK, `{"output":"debian nvme0n1 ","undefined-vars":[]}`},
+		{"OK_ADHOC_VARS", "/template/install/render", `{"vars":{"os":"alpine"}}`,
+			http.StatusOK, `{"output":"alpine sda ","undefined-vars":["disk"]}`},
+		{"OK_EMPTY_BODY", "/template/install/render", ``,
+			http.StatusOK, `{"output":"\u003cno value\u003e sda ","undefined-vars":["disk","os"]}`},
+		{"KO_REQUIRED", "/template/strict/render", `{}`,
+			http.StatusUnprocessableEntity, `{"output":"","undefined-vars":["os"],"error":{"code":"ETemplateError",` +
+				`"message":"template.Helper required var os is missing"}}`},
+		{"KO_HOST_NOT_FOUND", "/template/install/render", `{"host-id":"unknown"}`,
+			http.StatusNotFound, ""},
+		{"KO_TEMPLATE_NOT_FOUND", "/template/unknown/render", `{}`,
+			http.StatusNotFound, ""},
+		{"KO_INVALID_BODY", "/template/install/render", `{`,
+			http.StatusBadRequest, ""},
+		{"OK_HYPHEN_ID", "/template/local-boot/render", `{"host-id":"host1"}`,
+			http.StatusOK, `{"output":"exit debian","undefined-vars":[]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, tt.path, bytes.NewBuffer([]byte(tt.body)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			rr := httptest.NewRecorder()
+			ro.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tt.wantStatusCode {
+				t.Errorf("handler returned wrong status code: got %v want %v",
+					status, tt.wantStatusCode)
+			}
+			if body := rr.Body.String(); tt.wantResponse != "" && body != tt.wantResponse {
+				t.Errorf("handler returned wrong body: got %v want %v",
+					body, tt.wantResponse)
+			}
+		})
+	}
+	_ = r.Read(func(s repository.Session) error {
+		if h, _ := s.Host().Get("host1"); h.TrapTriggered {
+			t.Error("Render should not trigger the host trap")
+		}
+		return nil
+	})
+}
@@ -0,0 +1,368 @@
+package dhcp
+
+import (
+	"encoding/binary"
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+	"github.com/pxecore/pxecore/pkg/repository"
+	log "github.com/sirupsen/logrus"
+	"net"
+	"strings"
+	"time"
+)
+
+// offerLeaseTime is how long an offered address is held before the client requests it.
+const offerLeaseTime = 60 * time.Second
+
+// Authoritative builds the answer of the authoritative server to a client message.
+// Addresses are chosen from, in order: the IP reserved on the entity.Host owning
+// the hardware address, the current lease, the address requested by the client and
+// the first free address of the pools. Leases are persisted in the repository.
+// Addresses are chosen in a read session, a write session is only opened to store the lease.
+// It returns false when the packet must be ignored.
+func Authoritative(config ServerConfig, req *Packet, now time.Time) (*Packet, bool) {
+	if req.Op != BootRequest || len(req.CHAddr) == 0 {
+		return nil, false
+	}
+	var res *Packet
+	var err error
+	switch req.MessageType() {
+	case MessageTypeDiscover:
+		res, err = offer(config, req, now)
+	case MessageTypeRequest:
+		res, err = acknowledge(config, req, now)
+	case MessageTypeRelease:
+		err = config.Repository.Write(func(session repository.Session) error {
+			return release(session, req)
+		})
+	case MessageTypeDecline:
+		log.WithFields(log.Fields{"hardware-addr": req.CHAddr.String(),
+			"ip": net.IP(req.Options[OptionRequestedIP])}).Warn("DHCP address declined by client.")
+	case MessageTypeInform:
+		res = inform(config, req)
+	}
+	if err != nil {
+		log.WithError(err).WithField("hardware-addr", req.CHAddr.String()).Error("Error handling DHCP message.")
+		return nil, false
+	}
+	return res, res != nil
+}
+
+// offer answers DHCPDISCOVER holding the chosen address for offerLeaseTime.
+// It is ignored if another client took the address before it was stored.
+func offer(config ServerConfig, req *Packet, now time.Time) (*Packet, error) {
+	a, err := readAllocation(config, req, now)
+	if err != nil || a == nil {
+		return nil, err
+	}
+	l := a.lease
+	if l.IP != a.ip.String() || l.Expired(now) {
+		l.Expires = now.Add(offerLeaseTime)
+	}
+	l.IP = a.ip.String()
+	if ok, err := commit(config, a, l, now); err != nil || !ok {
+		return nil, err
+	}
+	return reply(config, req, MessageTypeOffer, a), nil
+}
+
+// acknowledge answers DHCPREQUEST with a DHCPACK if the requested address is the one
+// allocated to the client and a DHCPNAK otherwise.
+func acknowledge(config ServerConfig, req *Packet, now time.Time) (*Packet, error) {
+	if sid, ok := req.Options.Get(OptionServerIdentifier); ok && !net.IP(sid).Equal(config.AdvertiseAddress) {
+		return nil, nil
+	}
+	requested := net.IP(req.Options[OptionRequestedIP])
+	if len(requested) != net.IPv4len {
+		requested = req.CIAddr
+	}
+	a, err := readAllocation(config, req, now)
+	if err != nil {
+		return nil, err
+	}
+	if a == nil || !a.ip.Equal(requested) {
+		return nak(config, req), nil
+	}
+	l := a.lease
+	l.IP = a.ip.String()
+	l.Expires = now.Add(a.pool.LeaseTime)
+	ok, err := commit(config, a, l, now)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nak(config, req), nil
+	}
+	return reply(config, req, MessageTypeAck, a), nil
+}
+
+// release deletes the lease of the client.
+func release(session repository.Session, req *Packet) error {
+	l, err := session.Lease().Get(hardwareAddr(req.CHAddr))
+	if errors.Is(err, errors.ERepositoryKeyNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return session.Lease().Delete(l)
+}
+
+// inform answers DHCPINFORM with the network options of the client pool.
+func inform(config ServerConfig, req *Packet) *Packet {
+	for _, p := range config.Pools {
+		if p.Contains(req.CIAddr) {
+			res := reply(config, req, MessageTypeAck, &allocation{pool: p, ip: net.IPv4zero})
+			delete(res.Options, OptionLeaseTime)
+			delete(res.Options, OptionRenewalTime)
+			delete(res.Options, OptionRebindingTime)
+			return res
+		}
+	}
+	return nil
+}
+
+//~ STRUCT - allocation -------------------------------------------------------
+
+// allocation is the address chosen for a client.
+type allocation struct {
+	ip    net.IP
+	pool  Pool
+	lease entity.Lease
+	host  *entity.Host
+}
+
+// reserved returns true if the address is the one reserved on the entity.Host of the client.
+func (a *allocation) reserved() bool {
+	return a.host != nil && a.host.IP != "" && a.host.IP == a.ip.String()
+}
+
+// readAllocation chooses the address of the client in a read session, see allocate.
+func readAllocation(config ServerConfig, req *Packet, now time.Time) (*allocation, error) {
+	var a *allocation
+	err := config.Repository.Read(func(session repository.Session) error {
+		var err error
+		a, err = allocate(config, session, req, now)
+		return err
+	})
+	return a, err
+}
+
+// commit stores the lease of the allocation in a write session.
+// It returns false, storing nothing, if the address is no longer available to the client.
+func commit(config ServerConfig, a *allocation, l entity.Lease, now time.Time) (bool, error) {
+	ok := true
+	err := config.Repository.Write(func(session repository.Session) error {
+		if !a.reserved() {
+			var err error
+			if ok, err = available(config, session, l.HardwareAddr, a.ip, now); err != nil || !ok {
+				return err
+			}
+		}
+		return saveLease(session, l)
+	})
+	return ok && err == nil, err
+}
+
+// allocate chooses the address of the client or returns nil if none is available.
+func allocate(config ServerConfig, session repository.Session, req *Packet, now time.Time) (*allocation, error) {
+	mac := hardwareAddr(req.CHAddr)
+	a := &allocation{lease: entity.Lease{HardwareAddr: mac}}
+	if h, err := session.Host().FindByHardwareAddr(mac); err == nil {
+		a.host = &h
+		a.lease.HostID = h.ID
+		a.lease.Hostname = h.Hostname
+	} else if !errors.Is(err, errors.ERepositoryKeyNotFound) {
+		return nil, err
+	}
+	if a.lease.Hostname == "" {
+		a.lease.Hostname = req.Options.String(OptionHostname)
+	}
+	current, err := session.Lease().Get(mac)
+	if err == nil {
+		a.lease.Expires = current.Expires
+		a.lease.IP = current.IP
+	} else if !errors.Is(err, errors.ERepositoryKeyNotFound) {
+		return nil, err
+	}
+
+	if a.host != nil && a.host.IP != "" {
+		ip := net.ParseIP(a.host.IP).To4()
+		for _, p := range config.Pools {
+			if ip != nil && p.Contains(ip) {
+				a.ip, a.pool = ip, p
+				return a, nil
+			}
+		}
+		log.WithFields(log.Fields{"host": a.host.ID, "ip": a.host.IP}).Warn("DHCP reservation outside of the pools.")
+		return nil, nil
+	}
+
+	pools := selectPools(config, req)
+	candidates := []net.IP{net.ParseIP(a.lease.IP).To4()}
+	if ip := net.IP(req.Options[OptionRequestedIP]); len(ip) == net.IPv4len {
+		candidates = append(candidates, ip)
+	}
+	for _, ip := range candidates {
+		for _, p := range pools {
+			if !p.InRange(ip) {
+				continue
+			}
+			ok, err := available(config, session, mac, ip, now)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				a.ip, a.pool = ip, p
+				return a, nil
+			}
+		}
+	}
+	for _, p := range pools {
+		var ferr error
+		p.Each(func(ip net.IP) bool {
+			ok, err := available(config, session, mac, ip, now)
+			if err != nil {
+				ferr = err
+				return false
+			}
+			if ok {
+				a.ip, a.pool = ip, p
+			}
+			return !ok
+		})
+		if ferr != nil {
+			return nil, ferr
+		}
+		if a.ip != nil {
+			return a, nil
+		}
+	}
+	log.WithField("hardware-addr", mac).Warn("DHCP pools exhausted.")
+	return nil, nil
+}
+
+// available returns true if the address can be leased to the hardware address: it isn't
+// the server address nor reserved on an entity.Host, and it isn't leased to another client.
+func available(config ServerConfig, session repository.Session, mac string, ip net.IP, now time.Time) (bool, error) {
+	if ip.Equal(config.AdvertiseAddress) {
+		return false, nil
+	}
+	if _, err := session.Host().FindByIP(ip.String()); err == nil {
+		return false, nil
+	} else if !errors.Is(err, errors.ERepositoryKeyNotFound) {
+		return false, err
+	}
+	l, err := session.Lease().FindByIP(ip.String())
+	if errors.Is(err, errors.ERepositoryKeyNotFound) {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+	return l.HardwareAddr == mac || l.Expired(now), nil
+}
+
+// selectPools returns the pools serving the relay agent subnet, or all the pools
+// if the message was not relayed.
+func selectPools(config ServerConfig, req *Packet) []Pool {
+	if req.GIAddr == nil || req.GIAddr.IsUnspecified() {
+		return config.Pools
+	}
+	pools := make([]Pool, 0)
+	for _, p := range config.Pools {
+		if p.Contains(req.GIAddr) {
+			pools = append(pools, p)
+		}
+	}
+	return pools
+}
+
+// saveLease stores the lease, taking the address over from any other client.
+func saveLease(session repository.Session, l entity.Lease) error {
+	if o, err := session.Lease().FindByIP(l.IP); err == nil && o.HardwareAddr != l.HardwareAddr {
+		if err := session.Lease().Delete(o); err != nil {
+			return err
+		}
+	} else if err != nil && !errors.Is(err, errors.ERepositoryKeyNotFound) {
+		return err
+	}
+	if _, err := session.Lease().Get(l.HardwareAddr); errors.Is(err, errors.ERepositoryKeyNotFound) {
+		return session.Lease().Create(l)
+	} else if err != nil {
+		return err
+	}
+	return session.Lease().Update(l)
+}
+
+// reply builds a DHCPOFFER or DHCPACK for the allocation, with the boot
+// options when the client is a PXE client.
+func reply(config ServerConfig, req *Packet, t MessageType, a *allocation) *Packet {
+	sip := config.AdvertiseAddress.To4()
+	res := &Packet{
+		Op:      BootReply,
+		HType:   req.HType,
+		HLen:    req.HLen,
+		XID:     req.XID,
+		Flags:   req.Flags,
+		CIAddr:  req.CIAddr,
+		YIAddr:  a.ip,
+		SIAddr:  net.IPv4zero,
+		GIAddr:  req.GIAddr,
+		CHAddr:  req.CHAddr,
+		Options: a.pool.options(),
+	}
+	res.Options[OptionMessageType] = []byte{byte(t)}
+	res.Options[OptionServerIdentifier] = sip
+	lt := uint32(a.pool.LeaseTime / time.Second)
+	res.Options[OptionLeaseTime] = uint32Bytes(lt)
+	res.Options[OptionRenewalTime] = uint32Bytes(lt / 2)
+	res.Options[OptionRebindingTime] = uint32Bytes(lt / 8 * 7)
+	if a.lease.Hostname != "" {
+		res.Options[OptionHostname] = []byte(a.lease.Hostname)
+	}
+	if strings.HasPrefix(req.Options.String(OptionVendorClass), pxeVendorClass) {
+		if file, ok := BootFile(config, req); ok {
+			res.File = file
+			res.SIAddr = sip
+			res.Options[OptionVendorClass] = []byte(pxeVendorClass)
+			res.Options[OptionVendorSpecific] = pxeVendorOptions
+			if uuid, ok := req.Options.Get(OptionClientUUID); ok {
+				res.Options[OptionClientUUID] = uuid
+			}
+			if config.BootURL != "" {
+				res.Options[OptionBootURL] = []byte(config.BootURL)
+			}
+		}
+	}
+	return res
+}
+
+// nak builds a DHCPNAK.
+func nak(config ServerConfig, req *Packet) *Packet {
+	return &Packet{
+		Op:     BootReply,
+		HType:  req.HType,
+		HLen:   req.HLen,
+		XID:    req.XID,
+		Flags:  req.Flags,
+		CIAddr: net.IPv4zero,
+		YIAddr: net.IPv4zero,
+		SIAddr: net.IPv4zero,
+		GIAddr: req.GIAddr,
+		CHAddr: req.CHAddr,
+		Options: Options{
+			OptionMessageType:      []byte{byte(MessageTypeNak)},
+			OptionServerIdentifier: config.AdvertiseAddress.To4(),
+		},
+	}
+}
+
+// hardwareAddr formats the client hardware address as stored in the repository.
+func hardwareAddr(mac net.HardwareAddr) string {
+	return strings.Replace(mac.String(), ":", "-", -1)
+}
+
+func uint32Bytes(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, v)
+	return b
+}
@@ -0,0 +1,181 @@
+package dhcp
+
+import (
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/repository"
+	"github.com/pxecore/pxecore/pkg/tftp/locator"
+	"net"
+	"testing"
+	"time"
+)
+
+func newAuthoritativeConfig(t *testing.T) ServerConfig {
+	r, err := repository.NewRepository(map[string]interface{}{"driver": "memory"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewPool(map[string]interface{}{"start": "10.0.0.10", "end": "10.0.0.11",
+		"netmask": "255.255.255.0", "router": "10.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := testConfig
+	c.AdvertiseAddress = net.IPv4(10, 0, 0, 1)
+	c.Mode = ModeAuthoritative
+	c.Pools = []Pool{p}
+	c.Repository = r
+	return c
+}
+
+func TestAuthoritative(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name     string
+		setup    func(s repository.Session) error
+		req      *Packet
+		wantType MessageType
+		wantIP   string
+	}{
+		{"OK_DISCOVER_FIRST_FREE", nil,
+			newTestRequest(MessageTypeDiscover, nil), MessageTypeOffer, "10.0.0.10"},
+		{"OK_DISCOVER_REQUESTED", nil,
+			newTestRequest(MessageTypeDiscover, Options{OptionRequestedIP: []byte{10, 0, 0, 11}}),
+			MessageTypeOffer, "10.0.0.11"},
+		{"OK_DISCOVER_RESERVATION", func(s repository.Session) error {
+			return s.Host().Create(entity.Host{ID: "host1", HardwareAddr: []string{"88-99-aa-bb-cc-dd"},
+				IP: "10.0.0.50", Hostname: "node1"})
+		}, newTestRequest(MessageTypeDiscover, nil), MessageTypeOffer, "10.0.0.50"},
+		{"OK_DISCOVER_SKIP_RESERVED", func(s repository.Session) error {
+			return s.Host().Create(entity.Host{ID: "host2", HardwareAddr: []string{"00-00-00-00-00-01"},
+				IP: "10.0.0.10"})
+		}, newTestRequest(MessageTypeDiscover, nil), MessageTypeOffer, "10.0.0.11"},
+		{"OK_DISCOVER_REUSE_EXPIRED", func(s repository.Session) error {
+			if err := s.Lease().Create(entity.Lease{HardwareAddr: "00-00-00-00-00-01", IP: "10.0.0.10",
+				Expires: now.Add(-time.Second)}); err != nil {
+				return err
+			}
+			return s.Lease().Create(entity.Lease{HardwareAddr: "00-00-00-00-00-02", IP: "10.0.0.11",
+				Expires: now.Add(time.Hour)})
+		}, newTestRequest(MessageTypeDiscover, nil), MessageTypeOffer, "10.0.0.10"},
+		{"OK_REQUEST", func(s repository.Session) error {
+			return s.Lease().Create(entity.Lease{HardwareAddr: "88-99-aa-bb-cc-dd", IP: "10.0.0.11",
+				Expires: now.Add(time.Minute)})
+		}, newTestRequest(MessageTypeRequest, Options{OptionRequestedIP: []byte{10, 0, 0, 11},
+			OptionServerIdentifier: []byte{10, 0, 0, 1}}), MessageTypeAck, "10.0.0.11"},
+		{"OK_REQUEST_NAK", nil,
+			newTestRequest(MessageTypeRequest, Options{OptionRequestedIP: []byte{192, 168, 0, 2}}),
+			MessageTypeNak, "0.0.0.0"},
+		{"KO_REQUEST_OTHER_SERVER", nil,
+			newTestRequest(MessageTypeRequest, Options{OptionRequestedIP: []byte{10, 0, 0, 10},
+				OptionServerIdentifier: []byte{10, 0, 0, 2}}), 0, ""},
+		{"KO_POOL_EXHAUSTED", func(s repository.Session) error {
+			if err := s.Lease().Create(entity.Lease{HardwareAddr: "00-00-00-00-00-01", IP: "10.0.0.10",
+				Expires: now.Add(time.Hour)}); err != nil {
+				return err
+			}
+			return s.Lease().Create(entity.Lease{HardwareAddr: "00-00-00-00-00-02", IP: "10.0.0.11",
+				Expires: now.Add(time.Hour)})
+		}, newTestRequest(MessageTypeDiscover, nil), 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newAuthoritativeConfig(t)
+			if tt.setup != nil {
+				if err := c.Repository.Write(tt.setup); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, ok := Authoritative(c, tt.req, now)
+			if ok != (tt.wantType != 0) {
+				t.Fatalf("Authoritative() ok = %v, want %v", ok, tt.wantType != 0)
+			}
+			if !ok {
+				return
+			}
+			if got.MessageType() != tt.wantType || got.YIAddr.String() != tt.wantIP {
+				t.Fatalf("Authoritative() type = %v ip = %v, want %v %v",
+					got.MessageType(), got.YIAddr, tt.wantType, tt.wantIP)
+			}
+			if tt.wantType == MessageTypeNak {
+				return
+			}
+			if got.File != locator.IPXEBiosFilename || !got.SIAddr.Equal(c.AdvertiseAddress) {
+				t.Errorf("Authoritative() file = %v siaddr = %v", got.File, got.SIAddr)
+			}
+			if err := c.Repository.Read(func(s repository.Session) error {
+				l, err := s.Lease().Get("88-99-aa-bb-cc-dd")
+				if err == nil && l.IP != tt.wantIP {
+					t.Errorf("Stored lease = %+v, want ip %v", l, tt.wantIP)
+				}
+				return err
+			}); err != nil {
+				t.Error("Lease not stored - ", err)
+			}
+		})
+	}
+}
+
+func TestAuthoritative_Lifecycle(t *testing.T) {
+	c := newAuthoritativeConfig(t)
+	now := time.Now()
+	o, ok := Authoritative(c, newTestRequest(MessageTypeDiscover, nil), now)
+	if !ok {
+		t.Fatal("no offer")
+	}
+	a, ok := Authoritative(c, newTestRequest(MessageTypeRequest, Options{OptionRequestedIP: o.YIAddr,
+		OptionServerIdentifier: o.Options[OptionServerIdentifier]}), now)
+	if !ok || a.MessageType() != MessageTypeAck || !a.YIAddr.Equal(o.YIAddr) {
+		t.Fatalf("unexpected ack %+v", a)
+	}
+	if lt, _ := a.Options.Get(OptionLeaseTime); len(lt) != 4 {
+		t.Errorf("lease time missing: %v", lt)
+	}
+	if err := c.Repository.Read(func(s repository.Session) error {
+		l, err := s.Lease().Get("88-99-aa-bb-cc-dd")
+		if err == nil && !l.Expires.Equal(now.Add(c.Pools[0].LeaseTime)) {
+			t.Errorf("lease expires = %v", l.Expires)
+		}
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := Authoritative(c, newTestRequest(MessageTypeRelease, nil), now); ok {
+		t.Error("release should not be answered")
+	}
+	if err := c.Repository.Read(func(s repository.Session) error {
+		_, err := s.Lease().Get("88-99-aa-bb-cc-dd")
+		return err
+	}); err == nil {
+		t.Error("lease not released")
+	}
+}
+
+func TestCommit_Taken(t *testing.T) {
+	c := newAuthoritativeConfig(t)
+	now := time.Now()
+	a, err := readAllocation(c, newTestRequest(MessageTypeDiscover, nil), now)
+	if err != nil || a == nil || a.ip.String() != "10.0.0.10" {
+		t.Fatalf("readAllocation() = %+v, %v", a, err)
+	}
+	if err := c.Repository.Write(func(s repository.Session) error {
+		return s.Lease().Create(entity.Lease{HardwareAddr: "00-00-00-00-00-01", IP: "10.0.0.10",
+			Expires: now.Add(time.Hour)})
+	}); err != nil {
+		t.Fatal(err)
+	}
+	l := a.lease
+	l.IP = a.ip.String()
+	l.Expires = now.Add(offerLeaseTime)
+	if ok, err := commit(c, a, l, now); ok || err != nil {
+		t.Fatalf("commit() = %v, %v, want false", ok, err)
+	}
+	if err := c.Repository.Read(func(s repository.Session) error {
+		l, err := s.Lease().FindByIP("10.0.0.10")
+		if err == nil && l.HardwareAddr != "00-00-00-00-00-01" {
+			t.Errorf("lease taken over: %+v", l)
+		}
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,249 @@
+package dhcp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"github.com/pxecore/pxecore/pkg/errors"
+	"net"
+	"sort"
+)
+
+const (
+	// BootRequest is the op code of the messages sent by a client.
+	BootRequest uint8 = 1
+	// BootReply is the op code of the messages sent by a server.
+	BootReply uint8 = 2
+)
+
+// MessageType is the value of the OptionMessageType option.
+type MessageType uint8
+
+// DHCP message types. See RFC 2132 section 9.6.
+const (
+	MessageTypeDiscover MessageType = 1
+	MessageTypeOffer    MessageType = 2
+	MessageTypeRequest  MessageType = 3
+	MessageTypeDecline  MessageType = 4
+	MessageTypeAck      MessageType = 5
+	MessageTypeNak      MessageType = 6
+	MessageTypeRelease  MessageType = 7
+	MessageTypeInform   MessageType = 8
+)
+
+// DHCP options used by pxecore. See RFC 2132 and RFC 4578.
+const (
+	OptionPad                  uint8 = 0
+	OptionSubnetMask           uint8 = 1
+	OptionRouter               uint8 = 3
+	OptionDNS                  uint8 = 6
+	OptionHostname             uint8 = 12
+	OptionDomainName           uint8 = 15
+	OptionVendorSpecific       uint8 = 43
+	OptionRequestedIP          uint8 = 50
+	OptionLeaseTime            uint8 = 51
+	OptionMessageType          uint8 = 53
+	OptionServerIdentifier     uint8 = 54
+	OptionParameterRequestList uint8 = 55
+	OptionRenewalTime          uint8 = 58
+	OptionRebindingTime        uint8 = 59
+	OptionVendorClass          uint8 = 60
+	OptionUserClass            uint8 = 77
+	OptionClientArch           uint8 = 93
+	OptionClientNDI            uint8 = 94
+	OptionClientUUID           uint8 = 97
+	// OptionBootURL is a site-specific option holding the pxecore HTTP base URL,
+	// read by the embedded iPXE script to chain over HTTP.
+	OptionBootURL uint8 = 224
+	OptionEnd     uint8 = 255
+)
+
+const (
+	// headerSize is the size of the fixed BOOTP header without the magic cookie.
+	headerSize = 236
+	// minPacketSize is the minimum BOOTP packet size most clients expect.
+	minPacketSize = 300
+)
+
+var magicCookie = []byte{99, 130, 83, 99}
+
+//~ STRUCT - Packet -----------------------------------------------------------
+
+// Packet is a decoded DHCP message. See RFC 2131 section 2.
+type Packet struct {
+	Op      uint8
+	HType   uint8
+	HLen    uint8
+	Hops    uint8
+	XID     uint32
+	Secs    uint16
+	Flags   uint16
+	CIAddr  net.IP
+	YIAddr  net.IP
+	SIAddr  net.IP
+	GIAddr  net.IP
+	CHAddr  net.HardwareAddr
+	SName   string
+	File    string
+	Options Options
+}
+
+// Decode parses the wire representation of a DHCP message.
+func Decode(b []byte) (*Packet, error) {
+	if len(b) < headerSize+len(magicCookie) {
+		return nil, &errors.Error{Code: errors.EInvalidType,
+			Msg: fmt.Sprintf("[dhcp] packet too short: %d bytes", len(b))}
+	}
+	if !bytes.Equal(b[headerSize:headerSize+len(magicCookie)], magicCookie) {
+		return nil, &errors.Error{Code: errors.EInvalidType, Msg: "[dhcp] invalid magic cookie"}
+	}
+	p := &Packet{
+		Op:     b[0],
+		HType:  b[1],
+		HLen:   b[2],
+		Hops:   b[3],
+		XID:    binary.BigEndian.Uint32(b[4:8]),
+		Secs:   binary.BigEndian.Uint16(b[8:10]),
+		Flags:  binary.BigEndian.Uint16(b[10:12]),
+		CIAddr: net.IP(append([]byte{}, b[12:16]...)),
+		YIAddr: net.IP(append([]byte{}, b[16:20]...)),
+		SIAddr: net.IP(append([]byte{}, b[20:24]...)),
+		GIAddr: net.IP(append([]byte{}, b[24:28]...)),
+		SName:  cString(b[44:108]),
+		File:   cString(b[108:236]),
+	}
+	hl := int(p.HLen)
+	if hl > 16 {
+		hl = 16
+	}
+	p.CHAddr = net.HardwareAddr(append([]byte{}, b[28:28+hl]...))
+	o, err := decodeOptions(b[headerSize+len(magicCookie):])
+	if err != nil {
+		return nil, err
+	}
+	p.Options = o
+	return p, nil
+}
+
+// Encode returns the wire representation of the DHCP message.
+func (p *Packet) Encode() []byte {
+	b := make([]byte, headerSize, minPacketSize)
+	b[0] = p.Op
+	b[1] = p.HType
+	b[2] = p.HLen
+	b[3] = p.Hops
+	binary.BigEndian.PutUint32(b[4:8], p.XID)
+	binary.BigEndian.PutUint16(b[8:10], p.Secs)
+	binary.BigEndian.PutUint16(b[10:12], p.Flags)
+	copy(b[12:16], p.CIAddr.To4())
+	copy(b[16:20], p.YIAddr.To4())
+	copy(b[20:24], p.SIAddr.To4())
+	copy(b[24:28], p.GIAddr.To4())
+	copy(b[28:44], p.CHAddr)
+	copy(b[44:107], p.SName)
+	copy(b[108:235], p.File)
+	b = append(b, magicCookie...)
+	b = append(b, p.Options.encode()...)
+	for len(b) < minPacketSize {
+		b = append(b, OptionPad)
+	}
+	return b
+}
+
+// MessageType returns the value of the OptionMessageType option or 0 if missing.
+func (p *Packet) MessageType() MessageType {
+	v := p.Options[OptionMessageType]
+	if len(v) != 1 {
+		return 0
+	}
+	return MessageType(v[0])
+}
+
+//~ STRUCT - Options ----------------------------------------------------------
+
+// Options holds the DHCP options of a packet indexed by code.
+type Options map[uint8][]byte
+
+// Get returns the option value and whether it was present.
+func (o Options) Get(code uint8) ([]byte, bool) {
+	v, ok := o[code]
+	return v, ok
+}
+
+// String returns the option value as a string.
+func (o Options) String(code uint8) string {
+	return string(o[code])
+}
+
+// Uint16 returns the option value as a big endian uint16.
+func (o Options) Uint16(code uint8) (uint16, bool) {
+	v, ok := o[code]
+	if !ok || len(v) < 2 {
+		return 0, false
+	}
+	return binary.BigEndian.Uint16(v), true
+}
+
+// encode serializes the options sorted by code, OptionMessageType first,
+// splitting values longer than 255 bytes as described in RFC 3396.
+func (o Options) encode() []byte {
+	codes := make([]int, 0, len(o))
+	for c := range o {
+		if c != OptionMessageType && c != OptionPad && c != OptionEnd {
+			codes = append(codes, int(c))
+		}
+	}
+	sort.Ints(codes)
+	if _, ok := o[OptionMessageType]; ok {
+		codes = append([]int{int(OptionMessageType)}, codes...)
+	}
+	b := make([]byte, 0)
+	for _, c := range codes {
+		v := o[uint8(c)]
+		for {
+			n := len(v)
+			if n > 255 {
+				n = 255
+			}
+			b = append(b, uint8(c), uint8(n))
+			b = append(b, v[:n]...)
+			v = v[n:]
+			if len(v) == 0 {
+				break
+			}
+		}
+	}
+	return append(b, OptionEnd)
+}
+
+// decodeOptions parses the options section of a packet.
+// Repeated options are concatenated as described in RFC 3396.
+func decodeOptions(b []byte) (Options, error) {
+	o := make(Options)
+	for i := 0; i < len(b); {
+		c := b[i]
+		if c == OptionEnd {
+			break
+		}
+		if c == OptionPad {
+			i++
+			continue
+		}
+		if i+1 >= len(b) || i+2+int(b[i+1]) > len(b) {
+			return nil, &errors.Error{Code: errors.EInvalidType,
+				Msg: fmt.Sprintf("[dhcp] option %d overflows the packet", c)}
+		}
+		l := int(b[i+1])
+		o[c] = append(o[c], b[i+2:i+2+l]...)
+		i += 2 + l
+	}
+	return o, nil
+}
+
+// cString returns the string up to the first NUL byte.
+func cString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		return string(b[:i])
+	}
+	return string(b)
+}
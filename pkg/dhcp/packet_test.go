@@ -0,0 +1,74 @@
+package dhcp
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestPacket_EncodeDecode(t *testing.T) {
+	mac, _ := net.ParseMAC("88:99:aa:bb:cc:dd")
+	p := &Packet{
+		Op:     BootRequest,
+		HType:  1,
+		HLen:   6,
+		XID:    0xdeadbeef,
+		Secs:   4,
+		Flags:  0x8000,
+		CIAddr: net.IPv4(0, 0, 0, 0).To4(),
+		YIAddr: net.IPv4(0, 0, 0, 0).To4(),
+		SIAddr: net.IPv4(10, 0, 0, 1).To4(),
+		GIAddr: net.IPv4(10, 0, 0, 254).To4(),
+		CHAddr: mac,
+		SName:  "pxecore",
+		File:   "undionly.kpxe",
+		Options: Options{
+			OptionMessageType: []byte{byte(MessageTypeDiscover)},
+			OptionVendorClass: []byte("PXEClient:Arch:00000:UNDI:002001"),
+			OptionClientArch:  []byte{0, 7},
+			OptionUserClass:   make([]byte, 300),
+		},
+	}
+	b := p.Encode()
+	if len(b) < minPacketSize {
+		t.Errorf("Encode() length = %v, want at least %v", len(b), minPacketSize)
+	}
+	if b[headerSize+len(magicCookie)] != OptionMessageType {
+		t.Errorf("Encode() first option = %v, want %v", b[headerSize+len(magicCookie)], OptionMessageType)
+	}
+	got, err := Decode(b)
+	if err != nil {
+		t.Fatal("Decode() error = ", err)
+	}
+	if !reflect.DeepEqual(got, p) {
+		t.Errorf("Decode() got = %+v, want %+v", got, p)
+	}
+	if arch, _ := got.Options.Uint16(OptionClientArch); arch != ArchEFIx8664 {
+		t.Errorf("Options.Uint16() got = %v, want %v", arch, ArchEFIx8664)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	valid := (&Packet{Op: BootRequest, HLen: 6, CHAddr: make([]byte, 6)}).Encode()
+	overflow := append([]byte{}, valid[:headerSize+len(magicCookie)]...)
+	overflow = append(overflow, OptionVendorClass, 10, 'P')
+	badCookie := append([]byte{}, valid...)
+	badCookie[headerSize] = 0
+	tests := []struct {
+		name    string
+		b       []byte
+		wantErr bool
+	}{
+		{"OK", valid, false},
+		{"KO_SHORT", valid[:headerSize], true},
+		{"KO_COOKIE", badCookie, true},
+		{"KO_OPTION_OVERFLOW", overflow, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Decode(tt.b); (err != nil) != tt.wantErr {
+				t.Errorf("Decode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
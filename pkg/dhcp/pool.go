@@ -0,0 +1,165 @@
+package dhcp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"github.com/pxecore/pxecore/pkg/errors"
+	"github.com/pxecore/pxecore/pkg/util"
+	"net"
+	"time"
+)
+
+// Pool is a range of addresses the authoritative server leases from,
+// together with the network options sent to the clients.
+type Pool struct {
+	// Start is the first address of the range.
+	Start net.IP
+	// End is the last address of the range.
+	End net.IP
+	// Netmask of the pool subnet, sent as the subnet mask option.
+	Netmask net.IPMask
+	// Router is the default gateway, optional.
+	Router net.IP
+	// DNS servers, optional.
+	DNS []net.IP
+	// DomainName sent to the clients, optional.
+	DomainName string
+	// LeaseTime is the duration of the acknowledged leases.
+	LeaseTime time.Duration
+}
+
+// NewPool creates a new Pool extracting and checking type of the required fields.
+func NewPool(config map[string]interface{}) (Pool, error) {
+	p := Pool{}
+	var err error
+	if p.Start, err = ipFromMap(config, "start", true); err != nil {
+		return p, err
+	}
+	if p.End, err = ipFromMap(config, "end", true); err != nil {
+		return p, err
+	}
+	if bytes.Compare(p.Start, p.End) > 0 {
+		return p, &errors.Error{Code: errors.EInvalidType,
+			Msg: fmt.Sprintf("[dhcp] pool start %v is after end %v", p.Start, p.End)}
+	}
+	mask, err := ipFromMap(config, "netmask", true)
+	if err != nil {
+		return p, err
+	}
+	p.Netmask = net.IPMask(mask)
+	if !p.Contains(p.End) {
+		return p, &errors.Error{Code: errors.EInvalidType,
+			Msg: fmt.Sprintf("[dhcp] pool %v-%v is not in a single subnet", p.Start, p.End)}
+	}
+	if p.Router, err = ipFromMap(config, "router", false); err != nil {
+		return p, err
+	}
+	dns, err := util.StringSliceFromMap(config, "dns", []string{})
+	if err != nil {
+		return p, err
+	}
+	for _, s := range dns {
+		ip := net.ParseIP(s).To4()
+		if ip == nil {
+			return p, &errors.Error{Code: errors.EInvalidType, Msg: fmt.Sprintf("[dhcp] invalid dns address %v", s)}
+		}
+		p.DNS = append(p.DNS, ip)
+	}
+	if p.DomainName, err = util.StringFromMap(config, "domain-name", ""); err != nil {
+		return p, err
+	}
+	lt, err := util.IntFromMap(config, "lease-time", 3600)
+	if err != nil {
+		return p, err
+	}
+	p.LeaseTime = time.Duration(lt) * time.Second
+	return p, nil
+}
+
+// NewPools creates the pools from a decoded configuration list.
+func NewPools(config interface{}) ([]Pool, error) {
+	if config == nil {
+		return []Pool{}, nil
+	}
+	l, ok := config.([]interface{})
+	if !ok {
+		return nil, &errors.Error{Code: errors.EInvalidType, Msg: "[dhcp] pools should be a list"}
+	}
+	pools := make([]Pool, 0, len(l))
+	for _, i := range l {
+		m, ok := util.StringMap(i)
+		if !ok {
+			return nil, &errors.Error{Code: errors.EInvalidType, Msg: "[dhcp] pool should be a map"}
+		}
+		p, err := NewPool(m)
+		if err != nil {
+			return nil, err
+		}
+		pools = append(pools, p)
+	}
+	return pools, nil
+}
+
+// Contains returns true if the address is in the pool subnet.
+func (p Pool) Contains(ip net.IP) bool {
+	n := net.IPNet{IP: p.Start.Mask(p.Netmask), Mask: p.Netmask}
+	return n.Contains(ip)
+}
+
+// InRange returns true if the address is between Start and End.
+func (p Pool) InRange(ip net.IP) bool {
+	ip = ip.To4()
+	return ip != nil && bytes.Compare(ip, p.Start) >= 0 && bytes.Compare(ip, p.End) <= 0
+}
+
+// Each calls f with every address of the range until f returns false.
+func (p Pool) Each(f func(ip net.IP) bool) {
+	s := binary.BigEndian.Uint32(p.Start)
+	e := binary.BigEndian.Uint32(p.End)
+	for i := s; i <= e && i >= s; i++ {
+		ip := make(net.IP, 4)
+		binary.BigEndian.PutUint32(ip, i)
+		if !f(ip) {
+			return
+		}
+	}
+}
+
+// options returns the network options of the pool.
+func (p Pool) options() Options {
+	o := Options{OptionSubnetMask: []byte(p.Netmask)}
+	if p.Router != nil {
+		o[OptionRouter] = p.Router
+	}
+	if len(p.DNS) > 0 {
+		dns := make([]byte, 0, 4*len(p.DNS))
+		for _, ip := range p.DNS {
+			dns = append(dns, ip...)
+		}
+		o[OptionDNS] = dns
+	}
+	if p.DomainName != "" {
+		o[OptionDomainName] = []byte(p.DomainName)
+	}
+	return o
+}
+
+// ipFromMap extracts an IPv4 address from a map.
+func ipFromMap(config map[string]interface{}, k string, required bool) (net.IP, error) {
+	s, err := util.StringFromMap(config, k, "")
+	if err != nil {
+		return nil, err
+	}
+	if s == "" {
+		if required {
+			return nil, &errors.Error{Code: errors.EInvalidType, Msg: fmt.Sprintf("[dhcp] pool missing key %v", k)}
+		}
+		return nil, nil
+	}
+	ip := net.ParseIP(s).To4()
+	if ip == nil {
+		return nil, &errors.Error{Code: errors.EInvalidType, Msg: fmt.Sprintf("[dhcp] pool invalid %v %v", k, s)}
+	}
+	return ip, nil
+}
@@ -0,0 +1,44 @@
+package dhcp
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestNewPool(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  map[string]interface{}
+		wantErr bool
+	}{
+		{"OK", map[string]interface{}{"start": "10.0.0.10", "end": "10.0.0.20", "netmask": "255.255.255.0",
+			"router": "10.0.0.1", "dns": []interface{}{"10.0.0.1"}, "domain-name": "lab", "lease-time": 60}, false},
+		{"KO_MISSING_NETMASK", map[string]interface{}{"start": "10.0.0.10", "end": "10.0.0.20"}, true},
+		{"KO_START_AFTER_END", map[string]interface{}{"start": "10.0.0.20", "end": "10.0.0.10",
+			"netmask": "255.255.255.0"}, true},
+		{"KO_SUBNET", map[string]interface{}{"start": "10.0.0.10", "end": "10.0.1.20",
+			"netmask": "255.255.255.0"}, true},
+		{"KO_INVALID_DNS", map[string]interface{}{"start": "10.0.0.10", "end": "10.0.0.20",
+			"netmask": "255.255.255.0", "dns": []interface{}{"invalid"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewPool(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewPool() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if p.LeaseTime != time.Minute || !p.InRange(net.IPv4(10, 0, 0, 15)) || p.InRange(net.IPv4(10, 0, 0, 21)) {
+				t.Errorf("NewPool() = %+v", p)
+			}
+			n := 0
+			p.Each(func(ip net.IP) bool { n++; return true })
+			if n != 11 {
+				t.Errorf("Each() visited %v addresses, want 11", n)
+			}
+		})
+	}
+}
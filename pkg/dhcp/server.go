@@ -0,0 +1,280 @@
+// Package dhcp exposes a DHCP server that hands PXE clients the boot file
+// served by pxecore. In proxy mode an existing DHCP server assigns the
+// addresses, in authoritative mode pxecore leases them from its own pools.
+// See: Preboot Execution Environment (PXE) Specification v2.1
+package dhcp
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/pxecore/pxecore/pkg/errors"
+	"github.com/pxecore/pxecore/pkg/repository"
+	"github.com/pxecore/pxecore/pkg/tftp/locator"
+	log "github.com/sirupsen/logrus"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server modes.
+const (
+	// ModeProxy only sends boot options, addresses are assigned by another server.
+	ModeProxy = "proxy"
+	// ModeAuthoritative assigns addresses from the configured pools.
+	ModeAuthoritative = "authoritative"
+)
+
+// Client system architectures. See RFC 4578 section 2.1.
+const (
+	ArchIntelX86PC uint16 = 0
+	ArchEFIx8664   uint16 = 7
+	ArchEFIBC      uint16 = 9
+)
+
+const (
+	// pxeVendorClass is the OptionVendorClass prefix sent by PXE clients.
+	pxeVendorClass = "PXEClient"
+	// ipxeUserClass is the OptionUserClass sent by iPXE.
+	ipxeUserClass = "iPXE"
+	// clientPort is the UDP port where DHCP clients listen.
+	clientPort = 68
+)
+
+// pxeVendorOptions sets PXE_DISCOVERY_CONTROL to download the boot file
+// without boot server discovery.
+var pxeVendorOptions = []byte{6, 1, 8, OptionEnd}
+
+// ServerConfig holds the information that will be used to configure the DHCP Server.
+type ServerConfig struct {
+	// Address of the offer-only listener. Example: ":67".
+	Address string
+	// ProxyAddress of the PXE boot server listener. Example: ":4011".
+	ProxyAddress string
+	// AdvertiseAddress is the IPv4 address of pxecore sent to the clients.
+	AdvertiseAddress net.IP
+	// ChainURL is the boot file handed to iPXE clients.
+	// Example: "tftp://10.0.0.1/mac-${net0/mac:hexhyp}.ipxe".
+	ChainURL string
+	// BootURL is the pxecore HTTP base URL sent in OptionBootURL, optional.
+	// Example: "http://10.0.0.1:80".
+	BootURL string
+	// LogRequests allows to log all made requests.
+	LogRequests bool
+	// Mode is either ModeProxy or ModeAuthoritative. Defaults to ModeProxy.
+	Mode string
+	// Pools the addresses are leased from in ModeAuthoritative.
+	Pools []Pool
+	// Repository stores the leases and host reservations in ModeAuthoritative.
+	Repository repository.Repository
+}
+
+// Server is the representation of the DHCP server for this domain.
+type Server struct {
+	config *ServerConfig
+	lock   sync.Mutex
+	conns  []net.PacketConn
+}
+
+// StartInBackground starts the DHCP server in a different goroutine.
+func (s *Server) StartInBackground(config ServerConfig) error {
+	go func() {
+		if err := s.Start(config); err != nil {
+			log.WithError(err).Error("DHCP server stopped.")
+		}
+	}()
+	return nil
+}
+
+// Start initiates the DHCP server blocking the current goroutine.
+func (s *Server) Start(config ServerConfig) error {
+	s.lock.Lock()
+	if s.config != nil {
+		s.lock.Unlock()
+		return &errors.Error{Code: errors.EUnknown, Msg: "Server already started"}
+	}
+	if config.AdvertiseAddress.To4() == nil {
+		s.lock.Unlock()
+		return &errors.Error{Code: errors.EInvalidType,
+			Msg: fmt.Sprintf("[dhcp] invalid advertise address %v", config.AdvertiseAddress)}
+	}
+	switch config.Mode {
+	case "", ModeProxy:
+	case ModeAuthoritative:
+		if config.Repository == nil || len(config.Pools) == 0 {
+			s.lock.Unlock()
+			return &errors.Error{Code: errors.EInvalidType,
+				Msg: "[dhcp] authoritative mode requires a repository and at least one pool"}
+		}
+	default:
+		s.lock.Unlock()
+		return &errors.Error{Code: errors.EInvalidType, Msg: fmt.Sprintf("[dhcp] invalid mode %v", config.Mode)}
+	}
+	s.config = &config
+	s.lock.Unlock()
+
+	errs := make(chan error, 2)
+	for _, a := range []struct {
+		address string
+		proxy   bool
+	}{{config.Address, false}, {config.ProxyAddress, true}} {
+		c, err := net.ListenPacket("udp4", a.address)
+		if err != nil {
+			_ = s.Shutdown()
+			return err
+		}
+		s.lock.Lock()
+		s.conns = append(s.conns, c)
+		s.lock.Unlock()
+		log.WithField("address", a.address).Info("DHCP server starting.")
+		go func(c net.PacketConn, proxy bool) { errs <- s.serve(c, proxy) }(c, a.proxy)
+	}
+	err := <-errs
+	_ = s.Shutdown()
+	return err
+}
+
+// Shutdown stops the current server.
+func (s *Server) Shutdown() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.config == nil {
+		return &errors.Error{Code: errors.EUnknown, Msg: "Server not started"}
+	}
+	for _, c := range s.conns {
+		_ = c.Close()
+	}
+	s.conns = nil
+	s.config = nil
+	return nil
+}
+
+// serve reads and answers DHCP packets until the connection is closed.
+// The proxy flag marks the PXE boot server port, which replies by unicast.
+func (s *Server) serve(c net.PacketConn, proxy bool) error {
+	b := make([]byte, 1500)
+	for {
+		n, addr, err := c.ReadFrom(b)
+		if err != nil {
+			if strings.Contains(err.Error(), "use of closed network connection") {
+				return nil
+			}
+			return err
+		}
+		req, err := Decode(b[:n])
+		if err != nil {
+			log.WithError(err).WithField("client", addr).Debug("Invalid DHCP packet.")
+			continue
+		}
+		s.lock.Lock()
+		config := s.config
+		s.lock.Unlock()
+		if config == nil {
+			return nil
+		}
+		var res *Packet
+		var ok bool
+		if !proxy && config.Mode == ModeAuthoritative {
+			res, ok = Authoritative(*config, req, time.Now())
+		} else {
+			res, ok = Reply(*config, req, proxy)
+		}
+		if !ok {
+			continue
+		}
+		dst := addr
+		if !proxy {
+			dst = replyAddr(req, res)
+		}
+		if config.LogRequests {
+			log.WithFields(log.Fields{"hardware-addr": req.CHAddr.String(),
+				"type": res.MessageType(), "ip": res.YIAddr, "filename": res.File,
+				"client": dst}).Debug("DHCP Request.")
+		}
+		if _, err := c.WriteTo(res.Encode(), dst); err != nil {
+			log.WithError(err).Error("Error sending DHCP response")
+		}
+	}
+}
+
+// Reply builds the ProxyDHCP answer to a PXE client request.
+// The offer-only port answers DHCPDISCOVER with a DHCPOFFER and the proxy port
+// answers DHCPREQUEST with a DHCPACK. No client address is ever assigned.
+// It returns false when the packet must be ignored.
+func Reply(config ServerConfig, req *Packet, proxy bool) (*Packet, bool) {
+	if req.Op != BootRequest || !strings.HasPrefix(req.Options.String(OptionVendorClass), pxeVendorClass) {
+		return nil, false
+	}
+	var t MessageType
+	switch {
+	case !proxy && req.MessageType() == MessageTypeDiscover:
+		t = MessageTypeOffer
+	case proxy && req.MessageType() == MessageTypeRequest:
+		t = MessageTypeAck
+	default:
+		return nil, false
+	}
+	file, ok := BootFile(config, req)
+	if !ok {
+		return nil, false
+	}
+	sip := config.AdvertiseAddress.To4()
+	res := &Packet{
+		Op:     BootReply,
+		HType:  req.HType,
+		HLen:   req.HLen,
+		XID:    req.XID,
+		Flags:  req.Flags,
+		CIAddr: net.IPv4zero,
+		YIAddr: net.IPv4zero,
+		SIAddr: sip,
+		GIAddr: req.GIAddr,
+		CHAddr: req.CHAddr,
+		File:   file,
+		Options: Options{
+			OptionMessageType:      []byte{byte(t)},
+			OptionServerIdentifier: sip,
+			OptionVendorClass:      []byte(pxeVendorClass),
+			OptionVendorSpecific:   pxeVendorOptions,
+		},
+	}
+	if uuid, ok := req.Options.Get(OptionClientUUID); ok {
+		res.Options[OptionClientUUID] = uuid
+	}
+	if config.BootURL != "" {
+		res.Options[OptionBootURL] = []byte(config.BootURL)
+	}
+	return res, true
+}
+
+// BootFile returns the boot file for the client: the chain URL for iPXE and
+// the iPXE firmware matching the client architecture otherwise.
+func BootFile(config ServerConfig, req *Packet) (string, bool) {
+	if uc, ok := req.Options.Get(OptionUserClass); ok && bytes.Contains(uc, []byte(ipxeUserClass)) {
+		return config.ChainURL, config.ChainURL != ""
+	}
+	arch, ok := req.Options.Uint16(OptionClientArch)
+	if !ok {
+		arch = ArchIntelX86PC
+	}
+	switch arch {
+	case ArchIntelX86PC:
+		return locator.IPXEBiosFilename, true
+	case ArchEFIx8664, ArchEFIBC:
+		return locator.IPXEEFIFilename, true
+	}
+	return "", false
+}
+
+// replyAddr returns the destination of a reply sent from the main port: the
+// relay agent if any, the client if it already has an address, the broadcast
+// address otherwise. See RFC 2131 section 4.1.
+func replyAddr(req *Packet, res *Packet) net.Addr {
+	if req.GIAddr != nil && !req.GIAddr.IsUnspecified() {
+		return &net.UDPAddr{IP: req.GIAddr, Port: 67}
+	}
+	if req.CIAddr != nil && !req.CIAddr.IsUnspecified() && res.MessageType() != MessageTypeNak {
+		return &net.UDPAddr{IP: req.CIAddr, Port: clientPort}
+	}
+	return &net.UDPAddr{IP: net.IPv4bcast, Port: clientPort}
+}
@@ -0,0 +1,143 @@
+package dhcp
+
+import (
+	"github.com/pxecore/pxecore/pkg/tftp/locator"
+	"net"
+	"testing"
+	"time"
+)
+
+var testConfig = ServerConfig{
+	AdvertiseAddress: net.IPv4(127, 0, 0, 1),
+	ChainURL:         "tftp://127.0.0.1/mac-${net0/mac:hexhyp}.ipxe",
+}
+
+func newTestRequest(t MessageType, options Options) *Packet {
+	mac, _ := net.ParseMAC("88:99:aa:bb:cc:dd")
+	o := Options{
+		OptionMessageType: []byte{byte(t)},
+		OptionVendorClass: []byte("PXEClient:Arch:00000:UNDI:002001"),
+	}
+	for k, v := range options {
+		o[k] = v
+	}
+	return &Packet{Op: BootRequest, HType: 1, HLen: 6, XID: 42, CHAddr: mac,
+		CIAddr: net.IPv4zero.To4(), YIAddr: net.IPv4zero.To4(), SIAddr: net.IPv4zero.To4(),
+		GIAddr: net.IPv4zero.To4(), Options: o}
+}
+
+func TestReply(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      *Packet
+		proxy    bool
+		want     bool
+		wantType MessageType
+		wantFile string
+	}{
+		{"OK_BIOS", newTestRequest(MessageTypeDiscover, Options{OptionClientArch: []byte{0, 0}}),
+			false, true, MessageTypeOffer, locator.IPXEBiosFilename},
+		{"OK_NO_ARCH", newTestRequest(MessageTypeDiscover, nil),
+			false, true, MessageTypeOffer, locator.IPXEBiosFilename},
+		{"OK_EFI", newTestRequest(MessageTypeDiscover, Options{OptionClientArch: []byte{0, 7}}),
+			false, true, MessageTypeOffer, locator.IPXEEFIFilename},
+		{"OK_EFI_BC", newTestRequest(MessageTypeRequest, Options{OptionClientArch: []byte{0, 9}}),
+			true, true, MessageTypeAck, locator.IPXEEFIFilename},
+		{"OK_IPXE", newTestRequest(MessageTypeDiscover, Options{OptionUserClass: []byte("iPXE")}),
+			false, true, MessageTypeOffer, testConfig.ChainURL},
+		{"KO_UNSUPPORTED_ARCH", newTestRequest(MessageTypeDiscover, Options{OptionClientArch: []byte{0, 11}}),
+			false, false, 0, ""},
+		{"KO_NOT_PXE", newTestRequest(MessageTypeDiscover, Options{OptionVendorClass: []byte("MSFT 5.0")}),
+			false, false, 0, ""},
+		{"KO_REQUEST_OFFER_PORT", newTestRequest(MessageTypeRequest, nil),
+			false, false, 0, ""},
+		{"KO_DISCOVER_PROXY_PORT", newTestRequest(MessageTypeDiscover, nil),
+			true, false, 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Reply(testConfig, tt.req, tt.proxy)
+			if ok != tt.want {
+				t.Fatalf("Reply() ok = %v, want %v", ok, tt.want)
+			}
+			if !ok {
+				return
+			}
+			if got.MessageType() != tt.wantType {
+				t.Errorf("Reply() type = %v, want %v", got.MessageType(), tt.wantType)
+			}
+			if got.File != tt.wantFile {
+				t.Errorf("Reply() file = %v, want %v", got.File, tt.wantFile)
+			}
+			if !got.SIAddr.Equal(testConfig.AdvertiseAddress) || !got.YIAddr.IsUnspecified() {
+				t.Errorf("Reply() siaddr = %v yiaddr = %v", got.SIAddr, got.YIAddr)
+			}
+			if got.XID != tt.req.XID || got.CHAddr.String() != tt.req.CHAddr.String() {
+				t.Errorf("Reply() xid = %v chaddr = %v", got.XID, got.CHAddr)
+			}
+		})
+	}
+}
+
+func TestServer_ProxyLoopback(t *testing.T) {
+	sc, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{config: &testConfig, conns: []net.PacketConn{sc}}
+	done := make(chan error)
+	go func() { done <- s.serve(sc, true) }()
+
+	cc, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cc.Close()
+	_ = cc.SetDeadline(time.Now().Add(2 * time.Second))
+	for _, b := range [][]byte{
+		[]byte("garbage"),
+		newTestRequest(MessageTypeRequest, Options{OptionClientArch: []byte{0, 7},
+			OptionClientUUID: []byte{0, 1, 2}}).Encode(),
+	} {
+		if _, err := cc.WriteTo(b, sc.LocalAddr()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b := make([]byte, 1500)
+	n, _, err := cc.ReadFrom(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := Decode(b[:n])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.MessageType() != MessageTypeAck || res.File != locator.IPXEEFIFilename || res.XID != 42 {
+		t.Errorf("unexpected reply %+v", res)
+	}
+	if uuid, _ := res.Options.Get(OptionClientUUID); len(uuid) != 3 {
+		t.Errorf("client UUID not echoed: %v", uuid)
+	}
+	if err := s.Shutdown(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Error("serve() error = ", err)
+	}
+}
+
+func TestReply_BootURL(t *testing.T) {
+	c := testConfig
+	c.BootURL = "http://127.0.0.1:80"
+	got, ok := Reply(c, newTestRequest(MessageTypeDiscover, Options{OptionUserClass: []byte("iPXE")}), false)
+	if !ok {
+		t.Fatal("Reply() ignored the request")
+	}
+	if u := got.Options.String(OptionBootURL); u != c.BootURL {
+		t.Errorf("Reply() boot url = %v, want %v", u, c.BootURL)
+	}
+	got, _ = Reply(testConfig, newTestRequest(MessageTypeDiscover, nil), false)
+	if _, ok := got.Options.Get(OptionBootURL); ok {
+		t.Error("Reply() boot url sent without BootURL")
+	}
+}
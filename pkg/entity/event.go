@@ -0,0 +1,38 @@
+package entity
+
+import "time"
+
+// Outcomes of a BootEvent.
+const (
+	BootOutcomeServed   = "served"
+	BootOutcomeNotFound = "not-found"
+	BootOutcomeFailed   = "failed"
+)
+
+// Transports of a BootEvent.
+const (
+	BootTransportTFTP = "tftp"
+	BootTransportHTTP = "http"
+)
+
+// BootEvent entity, a file requested by a booting machine over TFTP or HTTP.
+// HostID is empty when the request matched no host.
+type BootEvent struct {
+	// Sequence orders the events of a host, it is set by the repository.
+	Sequence     int
+	Time         time.Time
+	HostID       string
+	HardwareAddr string
+	ClientIP     string
+	// Transport is BootTransportTFTP or BootTransportHTTP.
+	Transport string
+	Path      string
+	// TemplateID and TemplateRevision are the template served, revision 0 is the latest one.
+	TemplateID       string
+	TemplateRevision int
+	// Outcome is BootOutcomeServed, BootOutcomeNotFound or BootOutcomeFailed with Error.
+	Outcome string
+	Error   string
+	// Bytes is the size of the served file.
+	Bytes int64
+}
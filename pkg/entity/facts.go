@@ -0,0 +1,69 @@
+package entity
+
+import "time"
+
+// Firmware types of the hosts reported in Facts.
+const (
+	FirmwareBIOS = "bios"
+	FirmwareUEFI = "uefi"
+)
+
+// Facts entity, a timestamped revision of the hardware inventory of a host.
+// Revision and Created are maintained by the repository.
+type Facts struct {
+	HostID   string
+	Revision int
+	Created  time.Time
+	// Source tells who reported the facts, like "agent", "ipxe" or "discovery".
+	Source       string
+	Manufacturer string
+	Product      string
+	Serial       string
+	UUID         string
+	AssetTag     string
+	// Firmware is FirmwareBIOS or FirmwareUEFI.
+	Firmware string
+	// Arch is the architecture reported by iPXE. Example: "x86_64".
+	Arch string
+	CPU  FactsCPU
+	// Memory is the total memory in bytes.
+	Memory uint64
+	Disks  []FactsDisk
+	NICs   []FactsNIC
+}
+
+// FactsCPU describes the processors of a host.
+type FactsCPU struct {
+	Model   string
+	Cores   int
+	Threads int
+}
+
+// FactsDisk describes a block device of a host. Size is in bytes.
+type FactsDisk struct {
+	Name       string
+	Model      string
+	Serial     string
+	Size       uint64
+	Rotational bool
+	Removable  bool
+}
+
+// FactsNIC describes a network interface of a host. Speed is in Mbit/s.
+type FactsNIC struct {
+	Name         string
+	HardwareAddr string
+	Speed        int
+}
+
+// SmallestDisk returns the smallest non-removable disk, a usual choice for the install disk.
+// The zero FactsDisk is returned if there is none.
+func (f Facts) SmallestDisk() FactsDisk {
+	var d FactsDisk
+	for _, disk := range f.Disks {
+		if !disk.Removable && disk.Size > 0 && (d.Name == "" || disk.Size < d.Size) {
+			d = disk
+		}
+	}
+	return d
+}
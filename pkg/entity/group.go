@@ -3,13 +3,20 @@ package entity
 import "github.com/pxecore/pxecore/pkg/util"
 
 // Group entity
+// Vars hold JSON values: strings, numbers, booleans, lists and maps.
 type Group struct {
 	ID         string
-	Vars       map[string]string
+	Vars       map[string]interface{}
 	ParentID   string
 	TemplateID string
 	HostsIDs   []string
 	GroupIDs   []string
+	// TemplateRevision pins TemplateID to a revision, 0 follows the latest one.
+	TemplateRevision int
+	// Artifacts maps artifact names, like "kickstart" or "user-data", to template IDs.
+	Artifacts map[string]string
+	// Secrets maps secret var names to values, encrypted at rest by repository.NewSecretRepository.
+	Secrets map[string]string
 }
 
 // AddHost add host to the entity list.
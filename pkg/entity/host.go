@@ -1,12 +1,101 @@
 package entity
 
+import (
+	"crypto/subtle"
+	"github.com/pxecore/pxecore/pkg/util"
+	"strings"
+	"time"
+)
+
+// Kinds of the SMBIOS identifiers of a Host, matched at boot besides the hardware addresses.
+const (
+	IdentifierUUID     = "uuid"
+	IdentifierSerial   = "serial"
+	IdentifierAssetTag = "asset"
+)
+
 // Host entity
+// Vars hold JSON values: strings, numbers, booleans, lists and maps.
 type Host struct {
 	ID            string
 	HardwareAddr  []string
 	TrapMode      bool
 	TrapTriggered bool
-	Vars          map[string]string
+	Vars          map[string]interface{}
 	GroupID       string
 	TemplateID    string
+	IP            string
+	Hostname      string
+	// TemplateRevision pins TemplateID to a revision, 0 follows the latest one.
+	TemplateRevision int
+	// Artifacts maps artifact names, like "kickstart" or "user-data", to template IDs.
+	Artifacts map[string]string
+	// Secrets maps secret var names to values, encrypted at rest by repository.NewSecretRepository.
+	Secrets map[string]string
+	// GroupIDs are the groups of the host besides GroupID, see Groups for their precedence.
+	GroupIDs []string
+	// Labels select the entity.Rule that assigns the template when neither the host nor its groups set it.
+	Labels map[string]string
+	// Pending is true for the hosts registered by discovery until an operator approves them.
+	Pending bool
+	// UUID, Serial and AssetTag are the SMBIOS identifiers of the host, unique across hosts when set.
+	UUID     string
+	Serial   string
+	AssetTag string
+	// State is the provisioning lifecycle state, see Lifecycle. It is changed through Transition.
+	State string
+	// StateHistory are the latest lifecycle transitions, oldest first.
+	StateHistory []HostTransition
+	// CallbackToken authenticates the installer callbacks of the current boot until CallbackTokenExpires.
+	// It is issued when the host template is served and cleared once used.
+	CallbackToken        string
+	CallbackTokenExpires time.Time
+	// LastCallback is the latest result reported by the installer callbacks.
+	LastCallback CallbackResult
+}
+
+// Identifiers returns the SMBIOS identifiers set in the host by kind.
+// UUIDs are compared case insensitively and returned in lower case.
+func (h Host) Identifiers() map[string]string {
+	ids := make(map[string]string)
+	for k, v := range map[string]string{IdentifierUUID: h.UUID, IdentifierSerial: h.Serial,
+		IdentifierAssetTag: h.AssetTag} {
+		if v != "" {
+			ids[k] = NormalizeIdentifier(k, v)
+		}
+	}
+	return ids
+}
+
+// NormalizeIdentifier returns the form of an identifier value used to match hosts.
+func NormalizeIdentifier(kind string, value string) string {
+	if kind == IdentifierUUID {
+		return strings.ToLower(value)
+	}
+	return value
+}
+
+// ValidCallbackToken returns true if token is the callback token of the host and it isn't expired at t.
+func (h Host) ValidCallbackToken(token string, t time.Time) bool {
+	return h.CallbackToken != "" && t.Before(h.CallbackTokenExpires) &&
+		subtle.ConstantTimeCompare([]byte(token), []byte(h.CallbackToken)) == 1
+}
+
+// PlaceholderUUID returns true for the UUIDs left unset by the vendors, all zeros or all ones.
+func PlaceholderUUID(value string) bool {
+	v := strings.Replace(strings.ToLower(value), "-", "", -1)
+	return len(value) == 36 && (strings.Trim(v, "0") == "" || strings.Trim(v, "f") == "")
+}
+
+// Groups returns the groups of the host in merge order: GroupID followed by GroupIDs.
+// Later groups override the vars, template, artifacts and secrets of the earlier ones
+// and the host overrides all of them.
+func (h Host) Groups() []string {
+	groups := make([]string, 0, len(h.GroupIDs)+1)
+	for _, g := range append([]string{h.GroupID}, h.GroupIDs...) {
+		if g != "" {
+			groups = util.AddUniqueStringToSlice(groups, g)
+		}
+	}
+	return groups
 }
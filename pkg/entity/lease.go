@@ -0,0 +1,17 @@
+package entity
+
+import "time"
+
+// Lease entity, an IP address assigned by the DHCP server to a hardware address.
+type Lease struct {
+	HardwareAddr string
+	IP           string
+	Hostname     string
+	HostID       string
+	Expires      time.Time
+}
+
+// Expired returns true if the lease is no longer valid at the provided time.
+func (l Lease) Expired(t time.Time) bool {
+	return !t.Before(l.Expires)
+}
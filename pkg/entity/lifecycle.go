@@ -0,0 +1,92 @@
+package entity
+
+import "time"
+
+// Provisioning lifecycle states of a Host.
+const (
+	HostStateDiscovered     = "discovered"
+	HostStateReady          = "ready"
+	HostStateInstalling     = "installing"
+	HostStateInstalled      = "installed"
+	HostStateFailed         = "failed"
+	HostStateDecommissioned = "decommissioned"
+)
+
+// Triggers of the HostTransition.
+const (
+	TransitionTriggerAPI       = "api"
+	TransitionTriggerBoot      = "boot"
+	TransitionTriggerCallback  = "callback"
+	TransitionTriggerDiscovery = "discovery"
+)
+
+// MaxStateHistory is the number of transitions kept in Host.StateHistory.
+const MaxStateHistory = 50
+
+// hostStateTransitions maps every state to the states it can move to.
+var hostStateTransitions = map[string][]string{
+	HostStateDiscovered:     {HostStateReady, HostStateDecommissioned},
+	HostStateReady:          {HostStateInstalling, HostStateInstalled, HostStateDecommissioned},
+	HostStateInstalling:     {HostStateInstalled, HostStateFailed, HostStateReady},
+	HostStateInstalled:      {HostStateReady, HostStateDecommissioned},
+	HostStateFailed:         {HostStateInstalling, HostStateReady, HostStateDecommissioned},
+	HostStateDecommissioned: {HostStateReady},
+}
+
+// HostTransition is an audited change of the lifecycle state of a host.
+type HostTransition struct {
+	Time time.Time
+	From string
+	To   string
+	// Trigger is TransitionTriggerAPI, TransitionTriggerBoot, TransitionTriggerCallback or TransitionTriggerDiscovery.
+	Trigger string
+	Reason  string
+}
+
+// CallbackResult is the installation result reported by an installer callback.
+type CallbackResult struct {
+	Time time.Time
+	// Status is HostStateInstalled or HostStateFailed.
+	Status   string
+	ClientIP string
+	Logs     string
+}
+
+// ValidHostState returns true if s is a lifecycle state.
+func ValidHostState(s string) bool {
+	_, ok := hostStateTransitions[s]
+	return ok
+}
+
+// CanTransition returns true if the lifecycle allows a host to move between the states.
+func CanTransition(from string, to string) bool {
+	for _, s := range hostStateTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Lifecycle returns the lifecycle state of the host. Hosts without state are
+// HostStateDiscovered while pending and HostStateReady otherwise.
+func (h Host) Lifecycle() string {
+	if h.State != "" {
+		return h.State
+	}
+	if h.Pending {
+		return HostStateDiscovered
+	}
+	return HostStateReady
+}
+
+// Transition moves the host to the state and records the change in StateHistory,
+// keeping the latest MaxStateHistory transitions. It doesn't check the change is allowed, see CanTransition.
+func (h *Host) Transition(to string, trigger string, reason string, t time.Time) {
+	history := append(make([]HostTransition, 0, len(h.StateHistory)+1), h.StateHistory...)
+	history = append(history, HostTransition{Time: t, From: h.Lifecycle(), To: to, Trigger: trigger, Reason: reason})
+	if len(history) > MaxStateHistory {
+		history = history[len(history)-MaxStateHistory:]
+	}
+	h.State, h.StateHistory = to, history
+}
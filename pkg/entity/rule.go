@@ -0,0 +1,22 @@
+package entity
+
+// Rule entity, assigns a template to the hosts whose labels match its selector.
+// Rules are only evaluated for the hosts whose template is not set by the host or its groups,
+// in ascending Priority and then ID order. The first matching rule wins.
+type Rule struct {
+	ID       string
+	Priority int
+	// Selector holds the labels, and their values, a host must have to match. An empty selector matches every host.
+	Selector   map[string]string
+	TemplateID string
+}
+
+// Matches returns true if the labels contain all the labels of the selector with the same values.
+func (r Rule) Matches(labels map[string]string) bool {
+	for k, v := range r.Selector {
+		if l, ok := labels[k]; !ok || l != v {
+			return false
+		}
+	}
+	return true
+}
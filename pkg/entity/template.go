@@ -1,7 +1,70 @@
 package entity
 
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// Template kinds, the format of the rendered template.
+const (
+	TemplateKindIPXE        = "ipxe"
+	TemplateKindKickstart   = "kickstart"
+	TemplateKindPreseed     = "preseed"
+	TemplateKindAutoinstall = "autoinstall"
+	TemplateKindCloudInit   = "cloud-init"
+	TemplateKindIgnition    = "ignition"
+)
+
+// TemplateContentTypes maps every template kind to the Content-Type it is served with.
+var TemplateContentTypes = map[string]string{
+	TemplateKindIPXE:        "text/plain; charset=utf-8",
+	TemplateKindKickstart:   "text/plain; charset=utf-8",
+	TemplateKindPreseed:     "text/plain; charset=utf-8",
+	TemplateKindAutoinstall: "text/yaml; charset=utf-8",
+	TemplateKindCloudInit:   "text/cloud-config; charset=utf-8",
+	TemplateKindIgnition:    "application/vnd.coreos.ignition+json",
+}
+
 // Template entity
+// Revision and Author describe the current TemplateRevision and are maintained by the repository.
+// An empty Kind is a TemplateKindIPXE template.
 type Template struct {
 	ID       string
 	Template string
+	Revision int
+	Author   string
+	Kind     string
+}
+
+// ContentType returns the Content-Type the rendered template is served with.
+func (t Template) ContentType() string {
+	if c, ok := TemplateContentTypes[t.Kind]; ok {
+		return c
+	}
+	return TemplateContentTypes[TemplateKindIPXE]
+}
+
+// TemplateRevision entity, an immutable version of a Template body.
+type TemplateRevision struct {
+	TemplateID string
+	Revision   int
+	Template   string
+	Author     string
+	Created    time.Time
+	Hash       string
+}
+
+// NewTemplateRevision builds the revision of the current template body.
+// Hash is the hex encoded SHA-256 of the body.
+func NewTemplateRevision(t Template, created time.Time) TemplateRevision {
+	sum := sha256.Sum256([]byte(t.Template))
+	return TemplateRevision{
+		TemplateID: t.ID,
+		Revision:   t.Revision,
+		Template:   t.Template,
+		Author:     t.Author,
+		Created:    created,
+		Hash:       hex.EncodeToString(sum[:]),
+	}
 }
@@ -14,6 +14,8 @@ const (
 	EInvalidType string = "EInvalidType"
 	// ENotFound code for a not found resource.
 	ENotFound string = "ENotFound"
+	// EForbidden code for requests without valid credentials.
+	EForbidden string = "EForbidden"
 	// EAlreadyRunning code for already running resources.
 	EAlreadyRunning string = "EAlreadyRunning"
 	// ERepositoryKeyExist code when a key exists where it shouldn't.
@@ -22,10 +24,16 @@ const (
 	ERepositoryKeyNotFound string = "ERepositoryKeyDontExist"
 	// ERepositoryEmptyKey code when a key should have been provided.
 	ERepositoryEmptyKey string = "ERepositoryEmptyKey"
+	// ERepositoryDependency code when a key is still referenced by other entities.
+	ERepositoryDependency string = "ERepositoryDependency"
+	// ERepositoryConflict code when a change conflicts with the current state of an entity.
+	ERepositoryConflict string = "ERepositoryConflict"
 	// ERepositoryReadOnly read only mode activated.
 	ERepositoryReadOnly string = "ERepositoryReadOnly"
 	// ETemplateError code for template compilation error.
 	ETemplateError string = "ETemplateError"
+	// ESecretError code for secret encryption and decryption errors.
+	ESecretError string = "ESecretError"
 )
 
 // Error data structure
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// boltUnmatchedEventsKey stores the events with an empty HostID, bolt keys can't be empty.
+	boltUnmatchedEventsKey = "-"
+)
+
+// boltEventRepository defines the CRUD procedure for entity.BootEvent
+// The kept events of a host are stored together under the host ID.
+type boltEventRepository struct {
+	session Session
+	config  BoltConfig
+	tx      *bolt.Tx
+}
+
+// newBoltEventRepository instantiates a new repository for entity.BootEvent
+func newBoltEventRepository(s Session, config BoltConfig, tx *bolt.Tx) *EventRepository {
+	var er EventRepository
+	er = &boltEventRepository{
+		s,
+		config,
+		tx,
+	}
+	return &er
+}
+
+// Create implements repository.EventRepository interface
+func (r *boltEventRepository) Create(event entity.BootEvent) error {
+	if r.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	history := make([]entity.BootEvent, 0)
+	if _, err := boltGet(r.tx.Bucket(boltEventBucket), boltEventsKey(event.HostID), &history); err != nil {
+		return err
+	}
+	latest := 0
+	if len(history) > 0 {
+		latest = history[len(history)-1].Sequence
+	}
+	history = keptBootEvents(append(history, nextBootEvent(latest, event)))
+	return boltPut(r.tx.Bucket(boltEventBucket), boltEventsKey(event.HostID), history)
+}
+
+// List implements repository.EventRepository interface
+func (r *boltEventRepository) List(hostID string) ([]entity.BootEvent, error) {
+	history := make([]entity.BootEvent, 0)
+	if _, err := boltGet(r.tx.Bucket(boltEventBucket), boltEventsKey(hostID), &history); err != nil {
+		return nil, err
+	}
+	return reversedBootEvents(history), nil
+}
+
+// Recent implements repository.EventRepository interface
+func (r *boltEventRepository) Recent(limit int) ([]entity.BootEvent, error) {
+	l := make([]entity.BootEvent, 0)
+	if err := r.tx.Bucket(boltEventBucket).ForEach(func(k, v []byte) error {
+		history := make([]entity.BootEvent, 0)
+		if err := json.Unmarshal(v, &history); err != nil {
+			return &errors.Error{Code: errors.EInvalidType,
+				Msg: fmt.Sprintf("entity.BootEvent host %v can't be decoded", string(k)), Err: err}
+		}
+		l = append(l, history...)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return newestBootEvents(l, limit), nil
+}
+
+// Delete implements repository.EventRepository interface
+func (r *boltEventRepository) Delete(hostID string) error {
+	if r.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	if err := r.tx.Bucket(boltEventBucket).Delete([]byte(boltEventsKey(hostID))); err != nil {
+		return &errors.Error{Code: errors.EUnknown,
+			Msg: fmt.Sprintf("entity.BootEvent host %v can't be deleted", hostID), Err: err}
+	}
+	return nil
+}
+
+// boltEventsKey returns the key of the events of a host.
+func boltEventsKey(hostID string) string {
+	if hostID == "" {
+		return boltUnmatchedEventsKey
+	}
+	return hostID
+}
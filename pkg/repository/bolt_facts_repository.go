@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"fmt"
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltFactsRepository defines the CRUD procedure for entity.Facts
+// The kept revisions of a host are stored together under the host ID.
+type boltFactsRepository struct {
+	session Session
+	config  BoltConfig
+	tx      *bolt.Tx
+}
+
+// newBoltFactsRepository instantiates a new repository for entity.Facts
+func newBoltFactsRepository(s Session, config BoltConfig, tx *bolt.Tx) *FactsRepository {
+	var fr FactsRepository
+	fr = &boltFactsRepository{
+		s,
+		config,
+		tx,
+	}
+	return &fr
+}
+
+// Create implements repository.FactsRepository interface
+func (f *boltFactsRepository) Create(facts entity.Facts) error {
+	if f.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	if facts.HostID == "" {
+		return &errors.Error{Code: errors.ERepositoryEmptyKey,
+			Msg: "entity.Facts host key is empty"}
+	}
+	if err := checkFactsHost(f.session, facts); err != nil {
+		return err
+	}
+	history := make([]entity.Facts, 0)
+	if _, err := boltGet(f.tx.Bucket(boltFactsBucket), facts.HostID, &history); err != nil {
+		return err
+	}
+	latest := 0
+	if len(history) > 0 {
+		latest = history[len(history)-1].Revision
+	}
+	history = keptFactsRevisions(append(history, nextFactsRevision(latest, facts)))
+	return boltPut(f.tx.Bucket(boltFactsBucket), facts.HostID, history)
+}
+
+// Get implements repository.FactsRepository interface
+func (f *boltFactsRepository) Get(hostID string) (entity.Facts, error) {
+	history, err := f.ListRevisions(hostID)
+	if err != nil {
+		return entity.Facts{}, err
+	}
+	return history[len(history)-1], nil
+}
+
+// GetRevision implements repository.FactsRepository interface
+func (f *boltFactsRepository) GetRevision(hostID string, revision int) (entity.Facts, error) {
+	history, err := f.ListRevisions(hostID)
+	if err != nil && !errors.Is(err, errors.ERepositoryKeyNotFound) {
+		return entity.Facts{}, err
+	}
+	for _, r := range history {
+		if r.Revision == revision {
+			return r, nil
+		}
+	}
+	return entity.Facts{}, factsRevisionNotFound(hostID, revision)
+}
+
+// ListRevisions implements repository.FactsRepository interface
+func (f *boltFactsRepository) ListRevisions(hostID string) ([]entity.Facts, error) {
+	history := make([]entity.Facts, 0)
+	ok, err := boltGet(f.tx.Bucket(boltFactsBucket), hostID, &history)
+	if err != nil {
+		return nil, err
+	}
+	if !ok || len(history) == 0 {
+		return nil, factsNotFound(hostID)
+	}
+	return history, nil
+}
+
+// Delete implements repository.FactsRepository interface
+func (f *boltFactsRepository) Delete(hostID string) error {
+	if f.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	bucket := f.tx.Bucket(boltFactsBucket)
+	if bucket.Get([]byte(hostID)) == nil {
+		return factsNotFound(hostID)
+	}
+	if err := bucket.Delete([]byte(hostID)); err != nil {
+		return &errors.Error{Code: errors.EUnknown,
+			Msg: fmt.Sprintf("entity.Facts host %v can't be deleted", hostID), Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltGroupRepository defines the CRUD procedure for entity.Group
+type boltGroupRepository struct {
+	session Session
+	config  BoltConfig
+	tx      *bolt.Tx
+}
+
+// newBoltGroupRepository instantiates a new repository for entity.Group
+func newBoltGroupRepository(s Session, config BoltConfig, tx *bolt.Tx) *GroupRepository {
+	var gr GroupRepository
+	gr = &boltGroupRepository{
+		s,
+		config,
+		tx,
+	}
+	return &gr
+}
+
+// Create implements repository.GroupRepository interface
+func (g *boltGroupRepository) Create(group entity.Group) error {
+	if g.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	e := group
+	if e.ID == "" {
+		return &errors.Error{Code: errors.ERepositoryEmptyKey,
+			Msg: "entity.Group key is empty"}
+	}
+	groups := g.tx.Bucket(boltGroupBucket)
+	if groups.Get([]byte(e.ID)) != nil {
+		return &errors.Error{Code: errors.ERepositoryKeyExist,
+			Msg: fmt.Sprintf("entity.Group key %v already exists ", e.ID)}
+	}
+	if e.ParentID != "" {
+		parent, err := g.Get(e.ParentID)
+		if err != nil {
+			return &errors.Error{Code: errors.ERepositoryKeyNotFound,
+				Msg: fmt.Sprintf("repository.boltGroupRepository parent group %v does't exist.", e.ParentID)}
+		}
+		parent.AddGroup(e.ID)
+		if err := boltPut(groups, parent.ID, parent); err != nil {
+			return err
+		}
+	}
+	if e.HostsIDs == nil {
+		e.HostsIDs = make([]string, 0)
+	}
+	if e.GroupIDs == nil {
+		e.GroupIDs = make([]string, 0)
+	}
+	return boltPut(groups, e.ID, e)
+}
+
+// Get implements repository.GroupRepository interface
+func (g *boltGroupRepository) Get(ID string) (entity.Group, error) {
+	e := entity.Group{}
+	ok, err := boltGet(g.tx.Bucket(boltGroupBucket), ID, &e)
+	if err != nil {
+		return entity.Group{}, err
+	}
+	if !ok {
+		return entity.Group{}, &errors.Error{Code: errors.ERepositoryKeyNotFound,
+			Msg: fmt.Sprintf("entity.Group key %v not found", ID)}
+	}
+	return e, nil
+}
+
+// Update implements repository.GroupRepository interface
+func (g *boltGroupRepository) Update(group entity.Group) error {
+	if g.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	e := group
+	if e.ID == "" {
+		return &errors.Error{Code: errors.ERepositoryEmptyKey,
+			Msg: "entity.Group key is empty"}
+	}
+	og, err := g.Get(e.ID)
+	if err != nil {
+		return err
+	}
+	groups := g.tx.Bucket(boltGroupBucket)
+	if e.ParentID != og.ParentID {
+		if e.ParentID != "" {
+			np, err := g.Get(e.ParentID)
+			if err != nil {
+				return &errors.Error{Code: errors.ERepositoryKeyNotFound,
+					Msg: fmt.Sprintf("entity.Group key %v not found ", e.ParentID)}
+			}
+			np.AddGroup(e.ID)
+			if err := boltPut(groups, np.ID, np); err != nil {
+				return err
+			}
+		}
+		if op, err := g.Get(og.ParentID); err == nil {
+			op.RemoveGroup(e.ID)
+			if err := boltPut(groups, op.ID, op); err != nil {
+				return err
+			}
+		}
+	}
+	if e.HostsIDs == nil {
+		e.HostsIDs = og.HostsIDs
+	}
+	if e.GroupIDs == nil {
+		e.GroupIDs = og.GroupIDs
+	}
+	return boltPut(groups, e.ID, e)
+}
+
+// Delete implements repository.GroupRepository interface
+func (g *boltGroupRepository) Delete(group entity.Group) error {
+	if g.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	if group.ID == "" {
+		return &errors.Error{Code: errors.ERepositoryEmptyKey,
+			Msg: "entity.Group key is empty"}
+	}
+	oe, err := g.Get(group.ID)
+	if err != nil {
+		return err
+	}
+	if err := checkGroupDependencies(g.session, oe.ID); err != nil {
+		return err
+	}
+	groups := g.tx.Bucket(boltGroupBucket)
+	if oe.ParentID != "" {
+		if op, err := g.Get(oe.ParentID); err == nil {
+			op.RemoveGroup(oe.ID)
+			if err := boltPut(groups, op.ID, op); err != nil {
+				return err
+			}
+		}
+	}
+	if err := groups.Delete([]byte(oe.ID)); err != nil {
+		return &errors.Error{Code: errors.EUnknown,
+			Msg: fmt.Sprintf("entity.Group key %v can't be deleted", oe.ID), Err: err}
+	}
+	return nil
+}
+
+// List implements repository.GroupRepository interface
+func (g *boltGroupRepository) List(after string, limit int) ([]entity.Group, error) {
+	l := make([]entity.Group, 0)
+	c := g.tx.Bucket(boltGroupBucket).Cursor()
+	k, v := c.Seek([]byte(after))
+	if k != nil && string(k) == after {
+		k, v = c.Next()
+	}
+	for ; k != nil && (limit <= 0 || len(l) < limit); k, v = c.Next() {
+		e := entity.Group{}
+		if err := json.Unmarshal(v, &e); err != nil {
+			return nil, &errors.Error{Code: errors.EInvalidType,
+				Msg: fmt.Sprintf("entity.Group key %v can't be decoded", string(k)), Err: err}
+		}
+		l = append(l, e)
+	}
+	return l, nil
+}
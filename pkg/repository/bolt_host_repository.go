@@ -0,0 +1,310 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltHostRepository defines the CRUD procedure for entity.Host
+type boltHostRepository struct {
+	session Session
+	config  BoltConfig
+	tx      *bolt.Tx
+}
+
+// newBoltHostRepository instantiates a new repository for entity.Host
+func newBoltHostRepository(s Session, config BoltConfig, tx *bolt.Tx) *HostRepository {
+	var hr HostRepository
+	hr = &boltHostRepository{
+		s,
+		config,
+		tx,
+	}
+	return &hr
+}
+
+// Create implements repository.HostRepository interface
+func (h *boltHostRepository) Create(host entity.Host) error {
+	if h.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	e := host
+	if e.ID == "" {
+		return &errors.Error{Code: errors.ERepositoryEmptyKey,
+			Msg: "entity.Host key is empty"}
+	}
+	hosts := h.tx.Bucket(boltHostBucket)
+	if hosts.Get([]byte(e.ID)) != nil {
+		return &errors.Error{Code: errors.ERepositoryKeyExist,
+			Msg: fmt.Sprintf("entity.Host key %v already exists ", e.ID)}
+	}
+	index := h.tx.Bucket(boltHardwareAddrBucket)
+	for _, m := range e.HardwareAddr {
+		if index.Get([]byte(m)) != nil {
+			return &errors.Error{Code: errors.ERepositoryKeyExist,
+				Msg: fmt.Sprintf("entity.Host HardwareAddr %v already exists ", m)}
+		}
+	}
+	if err := h.checkIdentifiers(e); err != nil {
+		return err
+	}
+	if err := checkHostReservation(h.session, e); err != nil {
+		return err
+	}
+	if e.TemplateID != "" {
+		if _, err := h.session.Template().Get(e.TemplateID); err != nil {
+			return &errors.Error{Code: errors.ERepositoryKeyNotFound,
+				Msg: fmt.Sprintf("entity.Host TemplateID %v not found.", e.TemplateID)}
+		}
+	}
+	if err := checkHostGroups(h.session, e); err != nil {
+		return err
+	}
+	if err := updateHostGroups(h.session, e.ID, nil, e.Groups()); err != nil {
+		return err
+	}
+
+	if err := boltPut(hosts, e.ID, e); err != nil {
+		return err
+	}
+	for _, m := range e.HardwareAddr {
+		if err := index.Put([]byte(m), []byte(e.ID)); err != nil {
+			return &errors.Error{Code: errors.EUnknown,
+				Msg: fmt.Sprintf("entity.Host HardwareAddr %v can't be indexed", m), Err: err}
+		}
+	}
+	if err := h.indexIdentifiers(entity.Host{}, e); err != nil {
+		return err
+	}
+	return h.indexIP(entity.Host{}, e)
+}
+
+// Get implements repository.HostRepository interface
+func (h *boltHostRepository) Get(ID string) (entity.Host, error) {
+	e := entity.Host{}
+	ok, err := boltGet(h.tx.Bucket(boltHostBucket), ID, &e)
+	if err != nil {
+		return entity.Host{}, err
+	}
+	if !ok {
+		return entity.Host{}, &errors.Error{Code: errors.ERepositoryKeyNotFound,
+			Msg: fmt.Sprintf("entity.Host key %v not found", ID)}
+	}
+	return e, nil
+}
+
+// FindByHardwareAddr implements repository.HostRepository interface
+func (h *boltHostRepository) FindByHardwareAddr(hardwareAddr string) (entity.Host, error) {
+	id := h.tx.Bucket(boltHardwareAddrBucket).Get([]byte(hardwareAddr))
+	if id == nil {
+		return entity.Host{}, &errors.Error{Code: errors.ERepositoryKeyNotFound,
+			Msg: fmt.Sprintf("entity.Host key %v not found", hardwareAddr)}
+	}
+	return h.Get(string(id))
+}
+
+// FindByIdentifier implements repository.HostRepository interface
+func (h *boltHostRepository) FindByIdentifier(kind string, value string) (entity.Host, error) {
+	id := h.tx.Bucket(boltIdentifierBucket).Get([]byte(hostIdentifierKey(kind, value)))
+	if id == nil {
+		return entity.Host{}, hostIdentifierNotFound(kind, value)
+	}
+	return h.Get(string(id))
+}
+
+// FindByIP implements repository.HostRepository interface
+func (h *boltHostRepository) FindByIP(ip string) (entity.Host, error) {
+	id := h.tx.Bucket(boltHostIPBucket).Get([]byte(ip))
+	if ip == "" || id == nil {
+		return entity.Host{}, hostIPNotFound(ip)
+	}
+	return h.Get(string(id))
+}
+
+// Update implements repository.HostRepository interface
+func (h *boltHostRepository) Update(host entity.Host) error {
+	if h.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	e := host
+	if e.ID == "" {
+		return &errors.Error{Code: errors.ERepositoryEmptyKey,
+			Msg: "entity.Host key is empty"}
+	}
+	oe, err := h.Get(e.ID)
+	if err != nil {
+		return err
+	}
+	index := h.tx.Bucket(boltHardwareAddrBucket)
+	for _, m := range e.HardwareAddr {
+		if id := index.Get([]byte(m)); id != nil && string(id) != e.ID {
+			return &errors.Error{Code: errors.ERepositoryKeyExist,
+				Msg: fmt.Sprintf("entity.Host HardwareAddr %v already exists ", m)}
+		}
+	}
+	if err := h.checkIdentifiers(e); err != nil {
+		return err
+	}
+	if err := checkHostReservation(h.session, e); err != nil {
+		return err
+	}
+	if e.TemplateID != "" {
+		if _, err := h.session.Template().Get(e.TemplateID); err != nil {
+			return &errors.Error{Code: errors.ERepositoryKeyNotFound,
+				Msg: fmt.Sprintf("entity.Host TemplateID %v not found.", e.TemplateID)}
+		}
+	}
+	if err := checkHostGroups(h.session, e); err != nil {
+		return err
+	}
+	if err := updateHostGroups(h.session, e.ID, oe.Groups(), e.Groups()); err != nil {
+		return err
+	}
+
+	for _, m := range oe.HardwareAddr {
+		if err := index.Delete([]byte(m)); err != nil {
+			return &errors.Error{Code: errors.EUnknown,
+				Msg: fmt.Sprintf("entity.Host HardwareAddr %v can't be removed from index", m), Err: err}
+		}
+	}
+	for _, m := range e.HardwareAddr {
+		if err := index.Put([]byte(m), []byte(e.ID)); err != nil {
+			return &errors.Error{Code: errors.EUnknown,
+				Msg: fmt.Sprintf("entity.Host HardwareAddr %v can't be indexed", m), Err: err}
+		}
+	}
+	if err := h.indexIdentifiers(oe, e); err != nil {
+		return err
+	}
+	if err := h.indexIP(oe, e); err != nil {
+		return err
+	}
+	return boltPut(h.tx.Bucket(boltHostBucket), e.ID, e)
+}
+
+// Delete implements repository.HostRepository interface
+func (h *boltHostRepository) Delete(host entity.Host) error {
+	if h.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	if host.ID == "" {
+		return &errors.Error{Code: errors.ERepositoryEmptyKey,
+			Msg: "entity.Host key is empty"}
+	}
+	oe, err := h.Get(host.ID)
+	if err != nil {
+		return err
+	}
+	index := h.tx.Bucket(boltHardwareAddrBucket)
+	for _, m := range oe.HardwareAddr {
+		if err := index.Delete([]byte(m)); err != nil {
+			return &errors.Error{Code: errors.EUnknown,
+				Msg: fmt.Sprintf("entity.Host HardwareAddr %v can't be removed from index", m), Err: err}
+		}
+	}
+	if err := h.indexIdentifiers(oe, entity.Host{}); err != nil {
+		return err
+	}
+	if err := h.indexIP(oe, entity.Host{}); err != nil {
+		return err
+	}
+	if err := updateHostGroups(h.session, oe.ID, oe.Groups(), nil); err != nil {
+		return err
+	}
+	if err := deleteHostFacts(h.session, oe.ID); err != nil {
+		return err
+	}
+	if err := deleteHostEvents(h.session, oe.ID); err != nil {
+		return err
+	}
+	if err := h.tx.Bucket(boltHostBucket).Delete([]byte(oe.ID)); err != nil {
+		return &errors.Error{Code: errors.EUnknown,
+			Msg: fmt.Sprintf("entity.Host key %v can't be deleted", oe.ID), Err: err}
+	}
+	return nil
+}
+
+// checkIdentifiers returns errors.ERepositoryKeyExist if any identifier belongs to another host.
+func (h *boltHostRepository) checkIdentifiers(e entity.Host) error {
+	index := h.tx.Bucket(boltIdentifierBucket)
+	for _, k := range hostIdentifierKeys(e) {
+		if id := index.Get([]byte(k)); id != nil && string(id) != e.ID {
+			return hostIdentifierExist(k)
+		}
+	}
+	return nil
+}
+
+// indexIdentifiers replaces the indexed identifiers of oe by the ones of e.
+func (h *boltHostRepository) indexIdentifiers(oe entity.Host, e entity.Host) error {
+	index := h.tx.Bucket(boltIdentifierBucket)
+	for _, k := range hostIdentifierKeys(oe) {
+		if err := index.Delete([]byte(k)); err != nil {
+			return &errors.Error{Code: errors.EUnknown,
+				Msg: fmt.Sprintf("entity.Host identifier %v can't be removed from index", k), Err: err}
+		}
+	}
+	for _, k := range hostIdentifierKeys(e) {
+		if err := index.Put([]byte(k), []byte(e.ID)); err != nil {
+			return &errors.Error{Code: errors.EUnknown,
+				Msg: fmt.Sprintf("entity.Host identifier %v can't be indexed", k), Err: err}
+		}
+	}
+	return nil
+}
+
+// indexIP replaces the indexed IP reservation of oe by the one of e.
+func (h *boltHostRepository) indexIP(oe entity.Host, e entity.Host) error {
+	index := h.tx.Bucket(boltHostIPBucket)
+	if oe.IP != "" {
+		if err := index.Delete([]byte(oe.IP)); err != nil {
+			return &errors.Error{Code: errors.EUnknown,
+				Msg: fmt.Sprintf("entity.Host IP %v can't be removed from index", oe.IP), Err: err}
+		}
+	}
+	if e.IP != "" {
+		if err := index.Put([]byte(e.IP), []byte(e.ID)); err != nil {
+			return &errors.Error{Code: errors.EUnknown,
+				Msg: fmt.Sprintf("entity.Host IP %v can't be indexed", e.IP), Err: err}
+		}
+	}
+	return nil
+}
+
+// boltIndexHostIPs indexes the IP reservations of the hosts stored before the index existed.
+func boltIndexHostIPs(tx *bolt.Tx) error {
+	index := tx.Bucket(boltHostIPBucket)
+	return tx.Bucket(boltHostBucket).ForEach(func(k, v []byte) error {
+		e := entity.Host{}
+		if err := json.Unmarshal(v, &e); err != nil {
+			return &errors.Error{Code: errors.EInvalidType,
+				Msg: fmt.Sprintf("entity.Host key %v can't be decoded", string(k)), Err: err}
+		}
+		if e.IP == "" {
+			return nil
+		}
+		return index.Put([]byte(e.IP), k)
+	})
+}
+
+// List implements repository.HostRepository interface
+func (h *boltHostRepository) List(after string, limit int) ([]entity.Host, error) {
+	l := make([]entity.Host, 0)
+	c := h.tx.Bucket(boltHostBucket).Cursor()
+	k, v := c.Seek([]byte(after))
+	if k != nil && string(k) == after {
+		k, v = c.Next()
+	}
+	for ; k != nil && (limit <= 0 || len(l) < limit); k, v = c.Next() {
+		e := entity.Host{}
+		if err := json.Unmarshal(v, &e); err != nil {
+			return nil, &errors.Error{Code: errors.EInvalidType,
+				Msg: fmt.Sprintf("entity.Host key %v can't be decoded", string(k)), Err: err}
+		}
+		l = append(l, e)
+	}
+	return l, nil
+}
@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltLeaseRepository defines the CRUD procedure for entity.Lease
+type boltLeaseRepository struct {
+	session Session
+	config  BoltConfig
+	tx      *bolt.Tx
+}
+
+// newBoltLeaseRepository instantiates a new repository for entity.Lease
+func newBoltLeaseRepository(s Session, config BoltConfig, tx *bolt.Tx) *LeaseRepository {
+	var lr LeaseRepository
+	lr = &boltLeaseRepository{
+		s,
+		config,
+		tx,
+	}
+	return &lr
+}
+
+// Create implements repository.LeaseRepository interface
+func (l *boltLeaseRepository) Create(lease entity.Lease) error {
+	if l.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	e := lease
+	if e.HardwareAddr == "" {
+		return &errors.Error{Code: errors.ERepositoryEmptyKey,
+			Msg: "entity.Lease key is empty"}
+	}
+	leases := l.tx.Bucket(boltLeaseBucket)
+	if leases.Get([]byte(e.HardwareAddr)) != nil {
+		return &errors.Error{Code: errors.ERepositoryKeyExist,
+			Msg: fmt.Sprintf("entity.Lease key %v already exists ", e.HardwareAddr)}
+	}
+	ips := l.tx.Bucket(boltLeaseIPBucket)
+	if ips.Get([]byte(e.IP)) != nil {
+		return &errors.Error{Code: errors.ERepositoryKeyExist,
+			Msg: fmt.Sprintf("entity.Lease IP %v already exists ", e.IP)}
+	}
+	if err := ips.Put([]byte(e.IP), []byte(e.HardwareAddr)); err != nil {
+		return &errors.Error{Code: errors.EUnknown,
+			Msg: fmt.Sprintf("entity.Lease IP %v can't be indexed", e.IP), Err: err}
+	}
+	return boltPut(leases, e.HardwareAddr, e)
+}
+
+// Get implements repository.LeaseRepository interface
+func (l *boltLeaseRepository) Get(hardwareAddr string) (entity.Lease, error) {
+	e := entity.Lease{}
+	ok, err := boltGet(l.tx.Bucket(boltLeaseBucket), hardwareAddr, &e)
+	if err != nil {
+		return entity.Lease{}, err
+	}
+	if !ok {
+		return entity.Lease{}, &errors.Error{Code: errors.ERepositoryKeyNotFound,
+			Msg: fmt.Sprintf("entity.Lease key %v not found", hardwareAddr)}
+	}
+	return e, nil
+}
+
+// FindByIP implements repository.LeaseRepository interface
+func (l *boltLeaseRepository) FindByIP(ip string) (entity.Lease, error) {
+	v := l.tx.Bucket(boltLeaseIPBucket).Get([]byte(ip))
+	if v == nil {
+		return entity.Lease{}, &errors.Error{Code: errors.ERepositoryKeyNotFound,
+			Msg: fmt.Sprintf("entity.Lease IP %v not found", ip)}
+	}
+	return l.Get(string(v))
+}
+
+// Update implements repository.LeaseRepository interface
+func (l *boltLeaseRepository) Update(lease entity.Lease) error {
+	if l.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	e := lease
+	if e.HardwareAddr == "" {
+		return &errors.Error{Code: errors.ERepositoryEmptyKey,
+			Msg: "entity.Lease key is empty"}
+	}
+	oe, err := l.Get(e.HardwareAddr)
+	if err != nil {
+		return err
+	}
+	ips := l.tx.Bucket(boltLeaseIPBucket)
+	if v := ips.Get([]byte(e.IP)); v != nil && string(v) != e.HardwareAddr {
+		return &errors.Error{Code: errors.ERepositoryKeyExist,
+			Msg: fmt.Sprintf("entity.Lease IP %v already exists ", e.IP)}
+	}
+	if err := ips.Delete([]byte(oe.IP)); err != nil {
+		return &errors.Error{Code: errors.EUnknown,
+			Msg: fmt.Sprintf("entity.Lease IP %v can't be unindexed", oe.IP), Err: err}
+	}
+	if err := ips.Put([]byte(e.IP), []byte(e.HardwareAddr)); err != nil {
+		return &errors.Error{Code: errors.EUnknown,
+			Msg: fmt.Sprintf("entity.Lease IP %v can't be indexed", e.IP), Err: err}
+	}
+	return boltPut(l.tx.Bucket(boltLeaseBucket), e.HardwareAddr, e)
+}
+
+// Delete implements repository.LeaseRepository interface
+func (l *boltLeaseRepository) Delete(lease entity.Lease) error {
+	if l.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	if lease.HardwareAddr == "" {
+		return &errors.Error{Code: errors.ERepositoryEmptyKey,
+			Msg: "entity.Lease key is empty"}
+	}
+	oe, err := l.Get(lease.HardwareAddr)
+	if err != nil {
+		return err
+	}
+	if err := l.tx.Bucket(boltLeaseIPBucket).Delete([]byte(oe.IP)); err != nil {
+		return &errors.Error{Code: errors.EUnknown,
+			Msg: fmt.Sprintf("entity.Lease IP %v can't be unindexed", oe.IP), Err: err}
+	}
+	if err := l.tx.Bucket(boltLeaseBucket).Delete([]byte(oe.HardwareAddr)); err != nil {
+		return &errors.Error{Code: errors.EUnknown,
+			Msg: fmt.Sprintf("entity.Lease key %v can't be deleted", oe.HardwareAddr), Err: err}
+	}
+	return nil
+}
+
+// List implements repository.LeaseRepository interface
+func (l *boltLeaseRepository) List(after string, limit int) ([]entity.Lease, error) {
+	r := make([]entity.Lease, 0)
+	c := l.tx.Bucket(boltLeaseBucket).Cursor()
+	k, v := c.Seek([]byte(after))
+	if k != nil && string(k) == after {
+		k, v = c.Next()
+	}
+	for ; k != nil && (limit <= 0 || len(r) < limit); k, v = c.Next() {
+		e := entity.Lease{}
+		if err := json.Unmarshal(v, &e); err != nil {
+			return nil, &errors.Error{Code: errors.EInvalidType,
+				Msg: fmt.Sprintf("entity.Lease key %v can't be decoded", string(k)), Err: err}
+		}
+		r = append(r, e)
+	}
+	return r, nil
+}
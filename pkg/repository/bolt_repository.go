@@ -0,0 +1,295 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pxecore/pxecore/pkg/errors"
+	"github.com/pxecore/pxecore/pkg/util"
+	bolt "go.etcd.io/bbolt"
+	"sync"
+	"time"
+)
+
+var (
+	boltHostBucket         = []byte("hosts")
+	boltHardwareAddrBucket = []byte("hardware-addrs")
+	boltGroupBucket        = []byte("groups")
+	boltTemplateBucket     = []byte("templates")
+	boltRevisionBucket     = []byte("template-revisions")
+	boltLeaseBucket        = []byte("leases")
+	boltLeaseIPBucket      = []byte("lease-ips")
+	boltRuleBucket         = []byte("rules")
+	boltFactsBucket        = []byte("facts")
+	boltIdentifierBucket   = []byte("host-identifiers")
+	boltEventBucket        = []byte("boot-events")
+	boltHostIPBucket       = []byte("host-ips")
+)
+
+//~ STRUCT - boltRepository ---------------------------------------------------
+
+// boltRepository stores all entities in an embedded bolt database file.
+type boltRepository struct {
+	lock   *sync.RWMutex
+	config BoltConfig
+	db     *bolt.DB
+}
+
+func (b *boltRepository) Open(write bool) (Session, error) {
+	if write {
+		b.lock.Lock()
+	} else {
+		b.lock.RLock()
+	}
+	tx, err := b.db.Begin(write)
+	if err != nil {
+		if write {
+			b.lock.Unlock()
+		} else {
+			b.lock.RUnlock()
+		}
+		return nil, &errors.Error{Code: errors.EUnknown, Msg: "bolt transaction can't be started", Err: err}
+	}
+	return newBoltSession(b, b.config, tx, !write), nil
+}
+
+func (b *boltRepository) Read(f func(session Session) error) error {
+	s, err := b.Open(false)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	return f(s)
+}
+
+func (b *boltRepository) Write(f func(session Session) error) error {
+	s, err := b.Open(true)
+	if err != nil {
+		return err
+	}
+	bs := s.(*BoltSession)
+	if err := f(bs); err != nil {
+		_ = bs.rollback()
+		return err
+	}
+	return bs.Close()
+}
+
+// newBoltRepository creates a new repository for the driver bolt.
+func newBoltRepository(config map[string]interface{}) (Repository, error) {
+	r := new(boltRepository)
+	var ri Repository = r
+
+	c, err := NewBoltConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	r.config = c
+	r.lock = new(sync.RWMutex)
+	r.db, err = bolt.Open(c.path, 0600, &bolt.Options{Timeout: c.timeout})
+	if err != nil {
+		return nil, &errors.Error{Code: errors.EUnknown,
+			Msg: fmt.Sprintf("bolt database %v can't be opened", c.path), Err: err}
+	}
+	if err := r.db.Update(func(tx *bolt.Tx) error {
+		reindex := tx.Bucket(boltHostIPBucket) == nil
+		for _, n := range [][]byte{boltHostBucket, boltHardwareAddrBucket, boltGroupBucket, boltTemplateBucket,
+			boltRevisionBucket, boltLeaseBucket, boltLeaseIPBucket, boltRuleBucket, boltFactsBucket,
+			boltIdentifierBucket, boltEventBucket, boltHostIPBucket} {
+			if _, err := tx.CreateBucketIfNotExists(n); err != nil {
+				return err
+			}
+		}
+		if reindex {
+			return boltIndexHostIPs(tx)
+		}
+		return nil
+	}); err != nil {
+		_ = r.db.Close()
+		return nil, &errors.Error{Code: errors.EUnknown, Msg: "bolt buckets can't be created", Err: err}
+	}
+	return ri, nil
+}
+
+//~ STRUCT - BoltSession ------------------------------------------------------
+
+// BoltSession holds a single bolt transaction.
+type BoltSession struct {
+	open               bool
+	readOnly           bool
+	repository         *boltRepository
+	config             BoltConfig
+	tx                 *bolt.Tx
+	hostRepository     *HostRepository
+	templateRepository *TemplateRepository
+	groupRepository    *GroupRepository
+	leaseRepository    *LeaseRepository
+	ruleRepository     *RuleRepository
+	factsRepository    *FactsRepository
+	eventRepository    *EventRepository
+}
+
+// Close terminates the session committing the write transactions.
+func (b *BoltSession) Close() error {
+	if !b.open {
+		return &errors.Error{Code: errors.EUnknown, Msg: "repository already closed"}
+	}
+	var err error
+	if b.readOnly {
+		err = b.tx.Rollback()
+		b.repository.lock.RUnlock()
+	} else {
+		err = b.tx.Commit()
+		b.repository.lock.Unlock()
+	}
+	b.open = false
+	if err != nil {
+		return &errors.Error{Code: errors.EUnknown, Msg: "bolt transaction can't be closed", Err: err}
+	}
+	return nil
+}
+
+// rollback terminates the session discarding all changes.
+func (b *BoltSession) rollback() error {
+	if !b.open {
+		return &errors.Error{Code: errors.EUnknown, Msg: "repository already closed"}
+	}
+	err := b.tx.Rollback()
+	if b.readOnly {
+		b.repository.lock.RUnlock()
+	} else {
+		b.repository.lock.Unlock()
+	}
+	b.open = false
+	return err
+}
+
+// Host returns HostRepository
+func (b *BoltSession) Host() HostRepository {
+	if b.hostRepository == nil {
+		b.hostRepository = newBoltHostRepository(b, b.config, b.tx)
+	}
+	return *b.hostRepository
+}
+
+// Template returns TemplateRepository
+func (b *BoltSession) Template() TemplateRepository {
+	if b.templateRepository == nil {
+		b.templateRepository = newBoltTemplateRepository(b, b.config, b.tx)
+	}
+	return *b.templateRepository
+}
+
+// Group returns GroupRepository
+func (b *BoltSession) Group() GroupRepository {
+	if b.groupRepository == nil {
+		b.groupRepository = newBoltGroupRepository(b, b.config, b.tx)
+	}
+	return *b.groupRepository
+}
+
+// Lease returns LeaseRepository
+func (b *BoltSession) Lease() LeaseRepository {
+	if b.leaseRepository == nil {
+		b.leaseRepository = newBoltLeaseRepository(b, b.config, b.tx)
+	}
+	return *b.leaseRepository
+}
+
+// Rule returns RuleRepository
+func (b *BoltSession) Rule() RuleRepository {
+	if b.ruleRepository == nil {
+		b.ruleRepository = newBoltRuleRepository(b, b.config, b.tx)
+	}
+	return *b.ruleRepository
+}
+
+// Facts returns FactsRepository
+func (b *BoltSession) Facts() FactsRepository {
+	if b.factsRepository == nil {
+		b.factsRepository = newBoltFactsRepository(b, b.config, b.tx)
+	}
+	return *b.factsRepository
+}
+
+// Event returns EventRepository
+func (b *BoltSession) Event() EventRepository {
+	if b.eventRepository == nil {
+		b.eventRepository = newBoltEventRepository(b, b.config, b.tx)
+	}
+	return *b.eventRepository
+}
+
+// IsReadOnly returns true is the session is for read only.
+func (b *BoltSession) IsReadOnly() bool {
+	return b.readOnly
+}
+
+// IsOpen returns true is the session is open for transactions.
+func (b *BoltSession) IsOpen() bool {
+	return b.open
+}
+
+func newBoltSession(r *boltRepository, config BoltConfig, tx *bolt.Tx, readOnly bool) Session {
+	b := BoltSession{
+		repository: r,
+		open:       true,
+		readOnly:   readOnly,
+		config:     config,
+		tx:         tx,
+	}
+	return &b
+}
+
+// boltGet decodes the JSON stored under key into v. Returns false if the key is missing.
+func boltGet(b *bolt.Bucket, key string, v interface{}) (bool, error) {
+	data := b.Get([]byte(key))
+	if data == nil {
+		return false, nil
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return true, &errors.Error{Code: errors.EInvalidType,
+			Msg: fmt.Sprintf("bolt key %v can't be decoded", key), Err: err}
+	}
+	return true, nil
+}
+
+// boltPut encodes v as JSON and stores it under key.
+func boltPut(b *bolt.Bucket, key string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return &errors.Error{Code: errors.EInvalidType,
+			Msg: fmt.Sprintf("bolt key %v can't be encoded", key), Err: err}
+	}
+	if err := b.Put([]byte(key), data); err != nil {
+		return &errors.Error{Code: errors.EUnknown,
+			Msg: fmt.Sprintf("bolt key %v can't be stored", key), Err: err}
+	}
+	return nil
+}
+
+//~ STRUCT - BoltConfig -------------------------------------------------------
+
+// BoltConfig stores bolt driver config for all repositories.
+type BoltConfig struct {
+	path    string
+	timeout time.Duration
+}
+
+// NewBoltConfig creates a new BoltConfig extracting and checking type of the required fields.
+func NewBoltConfig(config map[string]interface{}) (BoltConfig, error) {
+	c := BoltConfig{}
+
+	s, err := util.StringFromMap(config, "path", "pxecore.db")
+	if err != nil {
+		return c, &errors.Error{Code: errors.EInvalidType, Msg: "config invalid type for key path"}
+	}
+	c.path = s
+
+	i, err := util.IntFromMap(config, "timeout", 1)
+	if err != nil {
+		return c, &errors.Error{Code: errors.EInvalidType, Msg: "config invalid type for key timeout"}
+	}
+	c.timeout = time.Duration(i) * time.Second
+
+	return c, nil
+}
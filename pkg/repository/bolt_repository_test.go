@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestBoltRepository_Durability(t *testing.T) {
+	d, err := ioutil.TempDir(testDir, "durability")
+	if err != nil {
+		t.Fatal("Error creating test directory - ", err)
+	}
+	c := map[string]interface{}{"path": filepath.Join(d, "durability.db")}
+	r, err := newBoltRepository(c)
+	if err != nil {
+		t.Fatal("Error opening bolt repository - ", err)
+	}
+	if err := r.Write(func(s Session) error {
+		if err := s.Template().Create(entity.Template{ID: "template", Template: "A"}); err != nil {
+			return err
+		}
+		return s.Host().Create(entity.Host{ID: "host", HardwareAddr: []string{"88-99-aa-bb-cc-dd"},
+			TemplateID: "template"})
+	}); err != nil {
+		t.Fatal("Error writing bolt repository - ", err)
+	}
+	if err := r.Write(func(s Session) error {
+		if err := s.Template().Create(entity.Template{ID: "rollback", Template: "B"}); err != nil {
+			return err
+		}
+		return &errors.Error{Code: errors.EUnknown, Msg: "forced rollback"}
+	}); err == nil {
+		t.Fatal("Forced rollback returned no error")
+	}
+	_ = r.(*boltRepository).db.Close()
+
+	r, err = newBoltRepository(c)
+	if err != nil {
+		t.Fatal("Error reopening bolt repository - ", err)
+	}
+	defer r.(*boltRepository).db.Close()
+	if err := r.Read(func(s Session) error {
+		h, err := s.Host().FindByHardwareAddr("88-99-aa-bb-cc-dd")
+		if err != nil {
+			return err
+		}
+		if h.ID != "host" || h.TemplateID != "template" {
+			t.Error("Invalid stored data - ", h)
+		}
+		if _, err := s.Template().Get("rollback"); !errors.Is(err, errors.ERepositoryKeyNotFound) {
+			t.Error("Rolled back template was stored - ", err)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal("Error reading bolt repository - ", err)
+	}
+}
+
+func TestBoltRepository_HostIPIndex(t *testing.T) {
+	d, err := ioutil.TempDir(testDir, "host-ips")
+	if err != nil {
+		t.Fatal("Error creating test directory - ", err)
+	}
+	c := map[string]interface{}{"path": filepath.Join(d, "host-ips.db")}
+	r, err := newBoltRepository(c)
+	if err != nil {
+		t.Fatal("Error opening bolt repository - ", err)
+	}
+	if err := r.Write(func(s Session) error {
+		return s.Host().Create(entity.Host{ID: "host", HardwareAddr: []string{"88-99-aa-bb-cc-ee"},
+			IP: "10.0.2.1"})
+	}); err != nil {
+		t.Fatal("Error writing bolt repository - ", err)
+	}
+	db := r.(*boltRepository).db
+	if err := db.Update(func(tx *bolt.Tx) error {
+		return tx.DeleteBucket(boltHostIPBucket)
+	}); err != nil {
+		t.Fatal("Error deleting the host IP index - ", err)
+	}
+	_ = db.Close()
+
+	r, err = newBoltRepository(c)
+	if err != nil {
+		t.Fatal("Error reopening bolt repository - ", err)
+	}
+	defer r.(*boltRepository).db.Close()
+	if err := r.Read(func(s Session) error {
+		h, err := s.Host().FindByIP("10.0.2.1")
+		if err != nil {
+			return err
+		}
+		if h.ID != "host" {
+			t.Error("Invalid indexed host - ", h)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal("Error reading bolt repository - ", err)
+	}
+}
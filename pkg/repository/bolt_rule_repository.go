@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltRuleRepository defines the CRUD procedure for entity.Rule
+type boltRuleRepository struct {
+	session Session
+	config  BoltConfig
+	tx      *bolt.Tx
+}
+
+// newBoltRuleRepository instantiates a new repository for entity.Rule
+func newBoltRuleRepository(s Session, config BoltConfig, tx *bolt.Tx) *RuleRepository {
+	var rr RuleRepository
+	rr = &boltRuleRepository{
+		s,
+		config,
+		tx,
+	}
+	return &rr
+}
+
+// Create implements repository.RuleRepository interface
+func (r *boltRuleRepository) Create(rule entity.Rule) error {
+	if r.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	e := rule
+	if e.ID == "" {
+		return &errors.Error{Code: errors.ERepositoryEmptyKey,
+			Msg: "entity.Rule key is empty"}
+	}
+	rules := r.tx.Bucket(boltRuleBucket)
+	if rules.Get([]byte(e.ID)) != nil {
+		return &errors.Error{Code: errors.ERepositoryKeyExist,
+			Msg: fmt.Sprintf("entity.Rule key %v already exists ", e.ID)}
+	}
+	if err := checkRuleTemplate(r.session, e); err != nil {
+		return err
+	}
+	return boltPut(rules, e.ID, e)
+}
+
+// Get implements repository.RuleRepository interface
+func (r *boltRuleRepository) Get(ID string) (entity.Rule, error) {
+	e := entity.Rule{}
+	ok, err := boltGet(r.tx.Bucket(boltRuleBucket), ID, &e)
+	if err != nil {
+		return entity.Rule{}, err
+	}
+	if !ok {
+		return entity.Rule{}, &errors.Error{Code: errors.ERepositoryKeyNotFound,
+			Msg: fmt.Sprintf("entity.Rule key %v not found", ID)}
+	}
+	return e, nil
+}
+
+// Update implements repository.RuleRepository interface
+func (r *boltRuleRepository) Update(rule entity.Rule) error {
+	if r.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	e := rule
+	if e.ID == "" {
+		return &errors.Error{Code: errors.ERepositoryEmptyKey,
+			Msg: "entity.Rule key is empty"}
+	}
+	if _, err := r.Get(e.ID); err != nil {
+		return err
+	}
+	if err := checkRuleTemplate(r.session, e); err != nil {
+		return err
+	}
+	return boltPut(r.tx.Bucket(boltRuleBucket), e.ID, e)
+}
+
+// Delete implements repository.RuleRepository interface
+func (r *boltRuleRepository) Delete(rule entity.Rule) error {
+	if r.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	if rule.ID == "" {
+		return &errors.Error{Code: errors.ERepositoryEmptyKey,
+			Msg: "entity.Rule key is empty"}
+	}
+	oe, err := r.Get(rule.ID)
+	if err != nil {
+		return err
+	}
+	if err := r.tx.Bucket(boltRuleBucket).Delete([]byte(oe.ID)); err != nil {
+		return &errors.Error{Code: errors.EUnknown,
+			Msg: fmt.Sprintf("entity.Rule key %v can't be deleted", oe.ID), Err: err}
+	}
+	return nil
+}
+
+// List implements repository.RuleRepository interface
+func (r *boltRuleRepository) List(after string, limit int) ([]entity.Rule, error) {
+	l := make([]entity.Rule, 0)
+	c := r.tx.Bucket(boltRuleBucket).Cursor()
+	k, v := c.Seek([]byte(after))
+	if k != nil && string(k) == after {
+		k, v = c.Next()
+	}
+	for ; k != nil && (limit <= 0 || len(l) < limit); k, v = c.Next() {
+		e := entity.Rule{}
+		if err := json.Unmarshal(v, &e); err != nil {
+			return nil, &errors.Error{Code: errors.EInvalidType,
+				Msg: fmt.Sprintf("entity.Rule key %v can't be decoded", string(k)), Err: err}
+		}
+		l = append(l, e)
+	}
+	return l, nil
+}
@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltTemplateRepository defines the CRUD procedure for entity.Template
+type boltTemplateRepository struct {
+	session Session
+	config  BoltConfig
+	tx      *bolt.Tx
+}
+
+// newBoltTemplateRepository instantiates a new repository for entity.Template
+func newBoltTemplateRepository(s Session, config BoltConfig, tx *bolt.Tx) *TemplateRepository {
+	var tr TemplateRepository
+	tr = &boltTemplateRepository{
+		s,
+		config,
+		tx,
+	}
+	return &tr
+}
+
+// Create implements repository.TemplateRepository interface
+func (t *boltTemplateRepository) Create(template entity.Template) error {
+	if t.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	e := template
+	if e.ID == "" {
+		return &errors.Error{Code: errors.ERepositoryEmptyKey,
+			Msg: "entity.Template key is empty"}
+	}
+	templates := t.tx.Bucket(boltTemplateBucket)
+	if templates.Get([]byte(e.ID)) != nil {
+		return &errors.Error{Code: errors.ERepositoryKeyExist,
+			Msg: fmt.Sprintf("entity.Template key %v already exists ", e.ID)}
+	}
+	e, r := firstTemplateRevision(e)
+	if err := t.putRevision(r); err != nil {
+		return err
+	}
+	return boltPut(templates, e.ID, e)
+}
+
+// Get implements repository.TemplateRepository interface
+func (t *boltTemplateRepository) Get(ID string) (entity.Template, error) {
+	e := entity.Template{}
+	ok, err := boltGet(t.tx.Bucket(boltTemplateBucket), ID, &e)
+	if err != nil {
+		return entity.Template{}, err
+	}
+	if !ok {
+		return entity.Template{}, &errors.Error{Code: errors.ERepositoryKeyNotFound,
+			Msg: fmt.Sprintf("entity.Template key %v not found", ID)}
+	}
+	return e, nil
+}
+
+// Update implements repository.TemplateRepository interface
+func (t *boltTemplateRepository) Update(template entity.Template) error {
+	if t.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	e := template
+	if e.ID == "" {
+		return &errors.Error{Code: errors.ERepositoryEmptyKey,
+			Msg: "entity.Template key is empty"}
+	}
+	current, err := t.Get(e.ID)
+	if err != nil {
+		return err
+	}
+	e, r := nextTemplateRevision(current, e)
+	if r != nil {
+		if err := t.putRevision(*r); err != nil {
+			return err
+		}
+	}
+	return boltPut(t.tx.Bucket(boltTemplateBucket), e.ID, e)
+}
+
+// Delete implements repository.TemplateRepository interface
+func (t *boltTemplateRepository) Delete(template entity.Template) error {
+	if t.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	if template.ID == "" {
+		return &errors.Error{Code: errors.ERepositoryEmptyKey,
+			Msg: "entity.Template key is empty"}
+	}
+	templates := t.tx.Bucket(boltTemplateBucket)
+	if templates.Get([]byte(template.ID)) == nil {
+		return &errors.Error{Code: errors.ERepositoryKeyNotFound,
+			Msg: fmt.Sprintf("entity.Template key %v not found ", template.ID)}
+	}
+	if err := checkTemplateDependencies(t.session, template.ID); err != nil {
+		return err
+	}
+	if err := templates.Delete([]byte(template.ID)); err != nil {
+		return &errors.Error{Code: errors.EUnknown,
+			Msg: fmt.Sprintf("entity.Template key %v can't be deleted", template.ID), Err: err}
+	}
+	revisions := t.tx.Bucket(boltRevisionBucket)
+	if revisions.Bucket([]byte(template.ID)) != nil {
+		if err := revisions.DeleteBucket([]byte(template.ID)); err != nil {
+			return &errors.Error{Code: errors.EUnknown,
+				Msg: fmt.Sprintf("entity.TemplateRevision key %v can't be deleted", template.ID), Err: err}
+		}
+	}
+	return nil
+}
+
+// List implements repository.TemplateRepository interface
+func (t *boltTemplateRepository) List(after string, limit int) ([]entity.Template, error) {
+	l := make([]entity.Template, 0)
+	c := t.tx.Bucket(boltTemplateBucket).Cursor()
+	k, v := c.Seek([]byte(after))
+	if k != nil && string(k) == after {
+		k, v = c.Next()
+	}
+	for ; k != nil && (limit <= 0 || len(l) < limit); k, v = c.Next() {
+		e := entity.Template{}
+		if err := json.Unmarshal(v, &e); err != nil {
+			return nil, &errors.Error{Code: errors.EInvalidType,
+				Msg: fmt.Sprintf("entity.Template key %v can't be decoded", string(k)), Err: err}
+		}
+		l = append(l, e)
+	}
+	return l, nil
+}
+
+// GetRevision implements repository.TemplateRepository interface
+func (t *boltTemplateRepository) GetRevision(ID string, revision int) (entity.TemplateRevision, error) {
+	b := t.tx.Bucket(boltRevisionBucket).Bucket([]byte(ID))
+	if b == nil {
+		return entity.TemplateRevision{}, templateRevisionNotFound(ID, revision)
+	}
+	e := entity.TemplateRevision{}
+	ok, err := boltGet(b, boltRevisionKey(revision), &e)
+	if err != nil {
+		return entity.TemplateRevision{}, err
+	}
+	if !ok {
+		return entity.TemplateRevision{}, templateRevisionNotFound(ID, revision)
+	}
+	return e, nil
+}
+
+// ListRevisions implements repository.TemplateRepository interface
+func (t *boltTemplateRepository) ListRevisions(ID string) ([]entity.TemplateRevision, error) {
+	if _, err := t.Get(ID); err != nil {
+		return nil, err
+	}
+	l := make([]entity.TemplateRevision, 0)
+	b := t.tx.Bucket(boltRevisionBucket).Bucket([]byte(ID))
+	if b == nil {
+		return l, nil
+	}
+	err := b.ForEach(func(k, v []byte) error {
+		e := entity.TemplateRevision{}
+		if err := json.Unmarshal(v, &e); err != nil {
+			return &errors.Error{Code: errors.EInvalidType,
+				Msg: fmt.Sprintf("entity.TemplateRevision key %v/%v can't be decoded", ID, string(k)), Err: err}
+		}
+		l = append(l, e)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// putRevision stores a revision in the bucket of its template.
+func (t *boltTemplateRepository) putRevision(r entity.TemplateRevision) error {
+	b, err := t.tx.Bucket(boltRevisionBucket).CreateBucketIfNotExists([]byte(r.TemplateID))
+	if err != nil {
+		return &errors.Error{Code: errors.EUnknown,
+			Msg: fmt.Sprintf("entity.TemplateRevision key %v can't be stored", r.TemplateID), Err: err}
+	}
+	return boltPut(b, boltRevisionKey(r.Revision), r)
+}
+
+// boltRevisionKey zero pads the revision so the keys are sorted by revision.
+func boltRevisionKey(revision int) string {
+	return fmt.Sprintf("%010d", revision)
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"github.com/pxecore/pxecore/pkg/entity"
+	"sort"
+	"time"
+)
+
+const (
+	// MaxHostEvents is the number of entity.BootEvent kept per host, older ones are dropped.
+	// The events of the requests matching no host share one more buffer of the same size.
+	MaxHostEvents = 100
+)
+
+// RecordBootEvent stores a boot event in its own write transaction.
+func RecordBootEvent(r Repository, event entity.BootEvent) error {
+	return r.Write(func(session Session) error {
+		return session.Event().Create(event)
+	})
+}
+
+// nextBootEvent returns the event to store after the latest sequence of the host, 0 if there is none.
+func nextBootEvent(latest int, e entity.BootEvent) entity.BootEvent {
+	e.Sequence = latest + 1
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	return e
+}
+
+// keptBootEvents returns the last MaxHostEvents events of the sorted history.
+func keptBootEvents(history []entity.BootEvent) []entity.BootEvent {
+	if len(history) > MaxHostEvents {
+		return history[len(history)-MaxHostEvents:]
+	}
+	return history
+}
+
+// reversedBootEvents returns a copy of the sorted history of a host, newest first.
+func reversedBootEvents(history []entity.BootEvent) []entity.BootEvent {
+	l := make([]entity.BootEvent, len(history))
+	for i, e := range history {
+		l[len(history)-1-i] = e
+	}
+	return l
+}
+
+// newestBootEvents sorts the events newest first and returns up to limit of them.
+// A limit lower or equal than 0 returns all of them.
+func newestBootEvents(l []entity.BootEvent, limit int) []entity.BootEvent {
+	sort.SliceStable(l, func(i, j int) bool {
+		if !l[i].Time.Equal(l[j].Time) {
+			return l[i].Time.After(l[j].Time)
+		}
+		return l[i].Sequence > l[j].Sequence
+	})
+	if limit > 0 && len(l) > limit {
+		l = l[:limit]
+	}
+	return l
+}
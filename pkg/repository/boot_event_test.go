@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"fmt"
+	"github.com/pxecore/pxecore/pkg/entity"
+	"testing"
+	"time"
+)
+
+func TestBootEvent(t *testing.T) {
+	runDriverTest(t, runEventTest)
+}
+
+func runEventTest(t *testing.T, m Repository) {
+	start := time.Now()
+	if err := m.Write(func(s Session) error {
+		if err := s.Host().Create(entity.Host{ID: "eh", HardwareAddr: []string{"00-00-00-00-00-f4"}}); err != nil {
+			return err
+		}
+		for i := 0; i < MaxHostEvents+2; i++ {
+			if err := s.Event().Create(entity.BootEvent{HostID: "eh", Path: fmt.Sprint("p", i),
+				Time: start.Add(time.Duration(i) * time.Second), Outcome: entity.BootOutcomeServed}); err != nil {
+				return err
+			}
+		}
+		if err := s.Event().Create(entity.BootEvent{Path: "unmatched", Time: start.Add(time.Hour),
+			Outcome: entity.BootOutcomeNotFound}); err != nil {
+			return err
+		}
+		return s.Event().Create(entity.BootEvent{HostID: "eh", Path: "last", Time: start.Add(2 * time.Hour)})
+	}); err != nil {
+		t.Fatal("runEventTest - error creating ", err)
+	}
+	if err := m.Read(func(s Session) error {
+		l, err := s.Event().List("eh")
+		if err != nil {
+			return err
+		}
+		if len(l) != MaxHostEvents || l[0].Path != "last" || l[0].Sequence != MaxHostEvents+3 ||
+			l[len(l)-1].Path != "p3" {
+			t.Errorf("runEventTest - invalid kept events %v from %v", len(l), l[len(l)-1].Path)
+		}
+		r, err := s.Event().Recent(2)
+		if err != nil {
+			return err
+		}
+		if len(r) != 2 || r[0].Path != "last" || r[1].Path != "unmatched" {
+			t.Error("runEventTest - invalid recent events ", r)
+		}
+		if l, err := s.Event().List("none"); err != nil || len(l) != 0 {
+			t.Error("runEventTest - invalid empty events ", l, err)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal("runEventTest - error reading ", err)
+	}
+	if err := m.Write(func(s Session) error {
+		if err := s.Host().Delete(entity.Host{ID: "eh"}); err != nil {
+			return err
+		}
+		if l, err := s.Event().List("eh"); err != nil || len(l) != 0 {
+			t.Error("runEventTest - events not deleted with the host ", len(l), err)
+		}
+		return s.Event().Delete("")
+	}); err != nil {
+		t.Fatal("runEventTest - error deleting ", err)
+	}
+}
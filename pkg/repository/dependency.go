@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"fmt"
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+	"github.com/pxecore/pxecore/pkg/util"
+)
+
+// checkTemplateDependencies returns errors.ERepositoryDependency if any
+// entity.Host or entity.Group references the template.
+func checkTemplateDependencies(session Session, ID string) error {
+	hosts, err := session.Host().List("", 0)
+	if err != nil {
+		return err
+	}
+	for _, h := range hosts {
+		if h.TemplateID == ID || hasArtifact(h.Artifacts, ID) {
+			return &errors.Error{Code: errors.ERepositoryDependency,
+				Msg: fmt.Sprintf("entity.Template key %v is referenced by entity.Host %v", ID, h.ID)}
+		}
+	}
+	groups, err := session.Group().List("", 0)
+	if err != nil {
+		return err
+	}
+	for _, g := range groups {
+		if g.TemplateID == ID || hasArtifact(g.Artifacts, ID) {
+			return &errors.Error{Code: errors.ERepositoryDependency,
+				Msg: fmt.Sprintf("entity.Template key %v is referenced by entity.Group %v", ID, g.ID)}
+		}
+	}
+	rules, err := session.Rule().List("", 0)
+	if err != nil {
+		return err
+	}
+	for _, r := range rules {
+		if r.TemplateID == ID {
+			return &errors.Error{Code: errors.ERepositoryDependency,
+				Msg: fmt.Sprintf("entity.Template key %v is referenced by entity.Rule %v", ID, r.ID)}
+		}
+	}
+	return nil
+}
+
+// hasArtifact returns true if any artifact is rendered with the template.
+func hasArtifact(artifacts map[string]string, ID string) bool {
+	for _, t := range artifacts {
+		if t == ID {
+			return true
+		}
+	}
+	return false
+}
+
+// checkGroupDependencies returns errors.ERepositoryDependency if any
+// entity.Host or child entity.Group references the group.
+func checkGroupDependencies(session Session, ID string) error {
+	hosts, err := session.Host().List("", 0)
+	if err != nil {
+		return err
+	}
+	for _, h := range hosts {
+		if util.ContainsString(h.Groups(), ID) {
+			return &errors.Error{Code: errors.ERepositoryDependency,
+				Msg: fmt.Sprintf("entity.Group key %v is referenced by entity.Host %v", ID, h.ID)}
+		}
+	}
+	groups, err := session.Group().List("", 0)
+	if err != nil {
+		return err
+	}
+	for _, g := range groups {
+		if g.ParentID == ID {
+			return &errors.Error{Code: errors.ERepositoryDependency,
+				Msg: fmt.Sprintf("entity.Group key %v is the parent of entity.Group %v", ID, g.ID)}
+		}
+	}
+	return nil
+}
+
+// checkHostReservation returns errors.ERepositoryKeyExist if the IP of the
+// entity.Host is already reserved by another entity.Host.
+func checkHostReservation(session Session, host entity.Host) error {
+	if host.IP == "" {
+		return nil
+	}
+	h, err := session.Host().FindByIP(host.IP)
+	if errors.Is(err, errors.ERepositoryKeyNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if h.ID != host.ID {
+		return &errors.Error{Code: errors.ERepositoryKeyExist,
+			Msg: fmt.Sprintf("entity.Host IP %v already reserved by entity.Host %v", host.IP, h.ID)}
+	}
+	return nil
+}
+
+// hostIPNotFound returns the error of an IP reserved by no host.
+func hostIPNotFound(ip string) error {
+	return &errors.Error{Code: errors.ERepositoryKeyNotFound,
+		Msg: fmt.Sprintf("entity.Host IP %v not found", ip)}
+}
+
+// checkHostGroups returns errors.ERepositoryKeyNotFound if any group of the entity.Host doesn't exist.
+func checkHostGroups(session Session, host entity.Host) error {
+	for _, ID := range host.Groups() {
+		if _, err := session.Group().Get(ID); err != nil {
+			return &errors.Error{Code: errors.ERepositoryKeyNotFound,
+				Msg: fmt.Sprintf("entity.Host GroupID %v not found.", ID),
+				Err: err}
+		}
+	}
+	return nil
+}
+
+// updateHostGroups removes the entity.Host from the HostsIDs of the leaving groups
+// and adds it to the joining ones, so entity.Group.HostsIDs reflects entity.Host.Groups.
+func updateHostGroups(session Session, ID string, leaving []string, joining []string) error {
+	for _, gID := range leaving {
+		if util.ContainsString(joining, gID) {
+			continue
+		}
+		if g, err := session.Group().Get(gID); err == nil {
+			g.RemoveHost(ID)
+			if err := session.Group().Update(g); err != nil {
+				return &errors.Error{Code: errors.EUnknown,
+					Msg: fmt.Sprintf("entity.Host GroupID %v can't be removed from the leaving group.", gID),
+					Err: err}
+			}
+		}
+	}
+	for _, gID := range joining {
+		g, err := session.Group().Get(gID)
+		if err != nil {
+			return &errors.Error{Code: errors.ERepositoryKeyNotFound,
+				Msg: fmt.Sprintf("entity.Host GroupID %v not found.", gID),
+				Err: err}
+		}
+		g.AddHost(ID)
+		if err := session.Group().Update(g); err != nil {
+			return &errors.Error{Code: errors.EUnknown,
+				Msg: fmt.Sprintf("entity.Host GroupID %v can't be updated.", gID),
+				Err: err}
+		}
+	}
+	return nil
+}
+
+// checkRuleTemplate returns errors.ERepositoryKeyNotFound if the template of the entity.Rule doesn't exist.
+func checkRuleTemplate(session Session, rule entity.Rule) error {
+	if _, err := session.Template().Get(rule.TemplateID); err != nil {
+		return &errors.Error{Code: errors.ERepositoryKeyNotFound,
+			Msg: fmt.Sprintf("entity.Rule TemplateID %v not found.", rule.TemplateID),
+			Err: err}
+	}
+	return nil
+}
+
+// checkFactsHost returns errors.ERepositoryKeyNotFound if the host of the entity.Facts doesn't exist.
+func checkFactsHost(session Session, facts entity.Facts) error {
+	if _, err := session.Host().Get(facts.HostID); err != nil {
+		return &errors.Error{Code: errors.ERepositoryKeyNotFound,
+			Msg: fmt.Sprintf("entity.Facts HostID %v not found.", facts.HostID),
+			Err: err}
+	}
+	return nil
+}
+
+// deleteHostFacts deletes the entity.Facts revisions of a deleted host.
+func deleteHostFacts(session Session, ID string) error {
+	if err := session.Facts().Delete(ID); err != nil && !errors.Is(err, errors.ERepositoryKeyNotFound) {
+		return err
+	}
+	return nil
+}
+
+// deleteHostEvents deletes the entity.BootEvent of a deleted host.
+func deleteHostEvents(session Session, ID string) error {
+	return session.Event().Delete(ID)
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+)
+
+// directoryGroupRepository writes back entity.Group changes as YAML files.
+type directoryGroupRepository struct {
+	session *DirectorySession
+	config  DirectoryConfig
+	memory  GroupRepository
+}
+
+// newDirectoryGroupRepository instantiates a new repository for entity.Group
+func newDirectoryGroupRepository(s *DirectorySession, config DirectoryConfig, memory GroupRepository) *GroupRepository {
+	var gr GroupRepository
+	gr = &directoryGroupRepository{
+		s,
+		config,
+		memory,
+	}
+	return &gr
+}
+
+// Create implements repository.GroupRepository interface
+func (g *directoryGroupRepository) Create(group entity.Group) error {
+	if g.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	if err := g.memory.Create(group); err != nil {
+		return err
+	}
+	return directoryWriteYAML(g.config.groupDir(), group.ID, newDirectoryGroup(group))
+}
+
+// Get implements repository.GroupRepository interface
+func (g *directoryGroupRepository) Get(ID string) (entity.Group, error) {
+	return g.memory.Get(ID)
+}
+
+// Update implements repository.GroupRepository interface
+func (g *directoryGroupRepository) Update(group entity.Group) error {
+	if g.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	if err := g.memory.Update(group); err != nil {
+		return err
+	}
+	return directoryWriteYAML(g.config.groupDir(), group.ID, newDirectoryGroup(group))
+}
+
+// Delete implements repository.GroupRepository interface
+func (g *directoryGroupRepository) Delete(group entity.Group) error {
+	if g.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	if err := g.memory.Delete(group); err != nil {
+		return err
+	}
+	return directoryRemoveFile(g.config.groupDir(), group.ID)
+}
+
+// List implements repository.GroupRepository interface
+func (g *directoryGroupRepository) List(after string, limit int) ([]entity.Group, error) {
+	return g.memory.List(after, limit)
+}
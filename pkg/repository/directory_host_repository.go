@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+	"reflect"
+)
+
+// directoryHostRepository writes back entity.Host changes as YAML files.
+type directoryHostRepository struct {
+	session *DirectorySession
+	config  DirectoryConfig
+	memory  HostRepository
+}
+
+// newDirectoryHostRepository instantiates a new repository for entity.Host
+func newDirectoryHostRepository(s *DirectorySession, config DirectoryConfig, memory HostRepository) *HostRepository {
+	var hr HostRepository
+	hr = &directoryHostRepository{
+		s,
+		config,
+		memory,
+	}
+	return &hr
+}
+
+// Create implements repository.HostRepository interface
+func (h *directoryHostRepository) Create(host entity.Host) error {
+	if h.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	if err := h.memory.Create(host); err != nil {
+		return err
+	}
+	if err := directoryWriteYAML(h.config.hostDir(), host.ID, newDirectoryHost(host)); err != nil {
+		return err
+	}
+	if reflect.DeepEqual(newDirectoryHostState(host), directoryHostState{}) {
+		return nil
+	}
+	return h.session.repository.saveState()
+}
+
+// Get implements repository.HostRepository interface
+func (h *directoryHostRepository) Get(ID string) (entity.Host, error) {
+	return h.memory.Get(ID)
+}
+
+// FindByHardwareAddr implements repository.HostRepository interface
+func (h *directoryHostRepository) FindByHardwareAddr(hardwareAddr string) (entity.Host, error) {
+	return h.memory.FindByHardwareAddr(hardwareAddr)
+}
+
+// FindByIdentifier implements repository.HostRepository interface
+func (h *directoryHostRepository) FindByIdentifier(kind string, value string) (entity.Host, error) {
+	return h.memory.FindByIdentifier(kind, value)
+}
+
+// FindByIP implements repository.HostRepository interface
+func (h *directoryHostRepository) FindByIP(ip string) (entity.Host, error) {
+	return h.memory.FindByIP(ip)
+}
+
+// Update implements repository.HostRepository interface
+// Updates that don't change the host file, like the lifecycle state, only change the
+// runtime state: it is written to the state file and is writable even in read-only mode.
+func (h *directoryHostRepository) Update(host entity.Host) error {
+	old, err := h.memory.Get(host.ID)
+	if err != nil {
+		return h.memory.Update(host)
+	}
+	file := !reflect.DeepEqual(newDirectoryHost(old), newDirectoryHost(host))
+	if file && h.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	if err := h.memory.Update(host); err != nil {
+		return err
+	}
+	if file {
+		if err := directoryWriteYAML(h.config.hostDir(), host.ID, newDirectoryHost(host)); err != nil {
+			return err
+		}
+	}
+	if reflect.DeepEqual(newDirectoryHostState(old), newDirectoryHostState(host)) {
+		return nil
+	}
+	return h.session.repository.saveState()
+}
+
+// Delete implements repository.HostRepository interface
+func (h *directoryHostRepository) Delete(host entity.Host) error {
+	if h.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	old, err := h.memory.Get(host.ID)
+	if err != nil {
+		return h.memory.Delete(host)
+	}
+	if err := h.memory.Delete(host); err != nil {
+		return err
+	}
+	if err := directoryRemoveFile(h.config.hostDir(), host.ID); err != nil {
+		return err
+	}
+	if reflect.DeepEqual(newDirectoryHostState(old), directoryHostState{}) {
+		return nil
+	}
+	return h.session.repository.saveState()
+}
+
+// List implements repository.HostRepository interface
+func (h *directoryHostRepository) List(after string, limit int) ([]entity.Host, error) {
+	return h.memory.List(after, limit)
+}
@@ -0,0 +1,868 @@
+package repository
+
+import (
+	"fmt"
+	"github.com/fsnotify/fsnotify"
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+	"github.com/pxecore/pxecore/pkg/util"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	directoryHostDir     string = "hosts"
+	directoryGroupDir    string = "groups"
+	directoryRuleDir     string = "rules"
+	directoryTemplateDir string = "templates"
+	// directoryTemplateExt is the extension used when a new iPXE template file is written.
+	directoryTemplateExt string = ".ipxe"
+)
+
+//~ STRUCT - directoryRepository ----------------------------------------------
+
+// directoryRepository loads all entities from a directory tree:
+//
+//	hosts/<id>.yaml
+//	groups/<id>.yaml
+//	templates/<id>.ipxe
+//	templates/<id>.<kind>
+//
+// The tree is loaded into a memoryRepository which is atomically replaced
+// every time the directory changes. Writes are rejected with
+// errors.ERepositoryReadOnly or written back as files depending on the config.
+// The runtime state of the hosts, like the triggered traps, isn't stored in the host
+// files but in the state file, which is written even in read-only mode.
+type directoryRepository struct {
+	lock    *sync.RWMutex
+	config  DirectoryConfig
+	memory  *memoryRepository
+	watcher *fsnotify.Watcher
+}
+
+func (d *directoryRepository) Open(write bool) (Session, error) {
+	if write {
+		d.lock.Lock()
+	} else {
+		d.lock.RLock()
+	}
+	ms, err := d.memory.Open(write)
+	if err != nil {
+		if write {
+			d.lock.Unlock()
+		} else {
+			d.lock.RUnlock()
+		}
+		return nil, err
+	}
+	return newDirectorySession(d, d.config, ms, !write), nil
+}
+
+func (d *directoryRepository) Read(f func(session Session) error) error {
+	s, err := d.Open(false)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	return f(s)
+}
+
+func (d *directoryRepository) Write(f func(session Session) error) error {
+	s, err := d.Open(true)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	return f(s)
+}
+
+// reload replaces the loaded entities with the current directory content.
+// If the directory content is invalid the previous entities are kept.
+func (d *directoryRepository) reload() {
+	m, err := loadDirectory(d.config)
+	if err != nil {
+		log.WithError(err).WithField("path", d.config.path).
+			Error("Directory repository reload failed, keeping previous state.")
+		return
+	}
+	d.lock.Lock()
+	m.leases = d.memory.leases
+	m.leaseIPIndex = d.memory.leaseIPIndex
+	m.facts = d.memory.facts
+	m.events = d.memory.events
+	keepTemplateRevisions(m, d.memory)
+	keepHostState(m, d.memory)
+	d.memory = m
+	d.lock.Unlock()
+	log.WithField("path", d.config.path).Info("Directory repository reloaded.")
+}
+
+// watch reloads the repository every time the directory changes.
+// Events are debounced so a burst of changes, like a git checkout, triggers a single reload.
+func (d *directoryRepository) watch() {
+	var timer *time.Timer
+	for {
+		select {
+		case event, ok := <-d.watcher.Events:
+			if !ok {
+				return
+			}
+			if strings.HasPrefix(filepath.Base(event.Name), ".") {
+				continue
+			}
+			if event.Op&fsnotify.Create == fsnotify.Create {
+				if fi, err := os.Stat(event.Name); err == nil && fi.IsDir() {
+					_ = d.watcher.Add(event.Name)
+				}
+			}
+			if timer != nil {
+				timer.Stop()
+			}
+			timer = time.AfterFunc(d.config.debounce, d.reload)
+		case err, ok := <-d.watcher.Errors:
+			if !ok {
+				return
+			}
+			log.WithError(err).Error("Directory repository watcher error.")
+		}
+	}
+}
+
+// newDirectoryRepository creates a new repository for the driver directory.
+func newDirectoryRepository(config map[string]interface{}) (Repository, error) {
+	r := new(directoryRepository)
+	var ri Repository = r
+
+	c, err := NewDirectoryConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	r.config = c
+	r.lock = new(sync.RWMutex)
+	if r.memory, err = loadDirectory(c); err != nil {
+		return nil, err
+	}
+	if err := loadDirectoryState(c, r.memory); err != nil {
+		return nil, err
+	}
+	if c.watch {
+		if r.watcher, err = fsnotify.NewWatcher(); err != nil {
+			return nil, &errors.Error{Code: errors.EUnknown, Msg: "directory watcher can't be created", Err: err}
+		}
+		for _, p := range []string{c.path, c.hostDir(), c.groupDir(), c.templateDir(), c.ruleDir()} {
+			if _, err := os.Stat(p); err == nil {
+				if err := r.watcher.Add(p); err != nil {
+					return nil, &errors.Error{Code: errors.EUnknown,
+						Msg: fmt.Sprintf("directory %v can't be watched", p), Err: err}
+				}
+			}
+		}
+		go r.watch()
+	}
+	return ri, nil
+}
+
+// loadDirectory reads the whole directory tree into a new memoryRepository.
+// References are validated by the memory repositories in the same way as API writes.
+func loadDirectory(c DirectoryConfig) (*memoryRepository, error) {
+	ri, err := newMemoryRepository(make(map[string]interface{}))
+	if err != nil {
+		return nil, err
+	}
+	m := ri.(*memoryRepository)
+	err = m.Write(func(session Session) error {
+		files, err := directoryFiles(c.templateDir())
+		if err != nil {
+			return err
+		}
+		for _, f := range files {
+			b, err := ioutil.ReadFile(f)
+			if err != nil {
+				return directoryError(f, err)
+			}
+			e := entity.Template{ID: directoryID(f), Template: string(b), Kind: directoryTemplateKind(f)}
+			if err := session.Template().Create(e); err != nil {
+				return directoryError(f, err)
+			}
+		}
+
+		files, err = directoryFiles(c.ruleDir())
+		if err != nil {
+			return err
+		}
+		for _, f := range files {
+			r := directoryRule{}
+			if err := directoryReadYAML(f, &r); err != nil {
+				return err
+			}
+			if err := session.Rule().Create(r.toEntity(f)); err != nil {
+				return directoryError(f, err)
+			}
+		}
+
+		files, err = directoryFiles(c.groupDir())
+		if err != nil {
+			return err
+		}
+		groups := make(map[string]directoryGroup)
+		for _, f := range files {
+			g := directoryGroup{}
+			if err := directoryReadYAML(f, &g); err != nil {
+				return err
+			}
+			groups[f] = g
+		}
+		// Parents are created before their children.
+		for len(groups) > 0 {
+			created := 0
+			for _, f := range directorySortedKeys(groups) {
+				e := groups[f].toEntity(f)
+				if e.ParentID != "" {
+					if _, err := session.Group().Get(e.ParentID); err != nil {
+						continue
+					}
+				}
+				if err := session.Group().Create(e); err != nil {
+					return directoryError(f, err)
+				}
+				delete(groups, f)
+				created++
+			}
+			if created == 0 {
+				f := directorySortedKeys(groups)[0]
+				return directoryError(f, &errors.Error{Code: errors.ERepositoryKeyNotFound,
+					Msg: fmt.Sprintf("entity.Group parent group %v not found or recursive.", groups[f].ParentID)})
+			}
+		}
+
+		files, err = directoryFiles(c.hostDir())
+		if err != nil {
+			return err
+		}
+		for _, f := range files {
+			h := directoryHost{}
+			if err := directoryReadYAML(f, &h); err != nil {
+				return err
+			}
+			if err := session.Host().Create(h.toEntity(f)); err != nil {
+				return directoryError(f, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// keepTemplateRevisions carries the revision history of the previous load over to m.
+// Templates whose file changed since are recorded as a new revision.
+// History lives in memory only, a restart starts every template again at revision 1.
+func keepTemplateRevisions(m *memoryRepository, previous *memoryRepository) {
+	for id, t := range m.templates {
+		history := previous.templateRevisions[id]
+		if len(history) == 0 {
+			continue
+		}
+		last := history[len(history)-1]
+		revisions := make([]entity.TemplateRevision, len(history), len(history)+1)
+		copy(revisions, history)
+		if last.Template == t.Template {
+			t.Revision, t.Author = last.Revision, last.Author
+		} else {
+			t.Revision, t.Author = last.Revision+1, ""
+			revisions = append(revisions, entity.NewTemplateRevision(*t, time.Now()))
+		}
+		m.templateRevisions[id] = revisions
+	}
+}
+
+// keepHostState carries the runtime state of the hosts of the previous load over to m.
+func keepHostState(m *memoryRepository, previous *memoryRepository) {
+	for id, h := range m.hosts {
+		if p, ok := previous.hosts[id]; ok {
+			newDirectoryHostState(*p).apply(h)
+		}
+	}
+}
+
+// loadDirectoryState applies the runtime state stored in the state file to the hosts of m.
+// A missing state file is empty and the state of unknown hosts is ignored.
+func loadDirectoryState(c DirectoryConfig, m *memoryRepository) error {
+	if _, err := os.Stat(c.stateFile); os.IsNotExist(err) {
+		return nil
+	}
+	s := directoryState{}
+	if err := directoryReadYAML(c.stateFile, &s); err != nil {
+		return err
+	}
+	for id, hs := range s.Hosts {
+		if h, ok := m.hosts[id]; ok {
+			hs.apply(h)
+		}
+	}
+	return nil
+}
+
+// saveState writes the runtime state of the loaded hosts to the state file.
+func (d *directoryRepository) saveState() error {
+	s := directoryState{Hosts: make(map[string]directoryHostState)}
+	for id, h := range d.memory.hosts {
+		if hs := newDirectoryHostState(*h); !reflect.DeepEqual(hs, directoryHostState{}) {
+			s.Hosts[id] = hs
+		}
+	}
+	b, err := yaml.Marshal(s)
+	if err != nil {
+		return &errors.Error{Code: errors.EInvalidType, Msg: "directory state can't be encoded", Err: err}
+	}
+	ext := filepath.Ext(d.config.stateFile)
+	return directoryWriteFile(filepath.Dir(d.config.stateFile),
+		strings.TrimSuffix(filepath.Base(d.config.stateFile), ext), ext, b)
+}
+
+// directoryFiles returns the sorted regular files of a directory. A missing directory is empty.
+func directoryFiles(dir string) ([]string, error) {
+	fis, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return make([]string, 0), nil
+	}
+	if err != nil {
+		return nil, directoryError(dir, err)
+	}
+	files := make([]string, 0, len(fis))
+	for _, fi := range fis {
+		if fi.Mode().IsRegular() && !strings.HasPrefix(fi.Name(), ".") {
+			files = append(files, filepath.Join(dir, fi.Name()))
+		}
+	}
+	return files, nil
+}
+
+// directoryID returns the entity ID of a file: the file name without extension.
+func directoryID(path string) string {
+	b := filepath.Base(path)
+	return strings.TrimSuffix(b, filepath.Ext(b))
+}
+
+// directoryTemplateKind returns the template kind named by the file extension.
+// Unknown extensions are iPXE templates.
+func directoryTemplateKind(path string) string {
+	kind := strings.TrimPrefix(filepath.Ext(path), ".")
+	if _, ok := entity.TemplateContentTypes[kind]; !ok || kind == entity.TemplateKindIPXE {
+		return ""
+	}
+	return kind
+}
+
+// directoryTemplateFileExt returns the extension of the template files of a kind.
+func directoryTemplateFileExt(kind string) string {
+	if _, ok := entity.TemplateContentTypes[kind]; !ok || kind == entity.TemplateKindIPXE {
+		return directoryTemplateExt
+	}
+	return "." + kind
+}
+
+// directoryVars converts the YAML decoded vars into JSON values,
+// so they are the same whatever the repository driver.
+// Maps keys are strings and numbers are float64.
+func directoryVars(vars map[string]interface{}) map[string]interface{} {
+	if vars == nil {
+		return nil
+	}
+	r := make(map[string]interface{}, len(vars))
+	for k, v := range vars {
+		r[k] = directoryJSONValue(v)
+	}
+	return r
+}
+
+// directoryJSONValue converts a YAML decoded value into a JSON value.
+func directoryJSONValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		r := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			r[fmt.Sprint(k)] = directoryJSONValue(e)
+		}
+		return r
+	case map[string]interface{}:
+		return directoryVars(v)
+	case []interface{}:
+		r := make([]interface{}, len(v))
+		for i, e := range v {
+			r[i] = directoryJSONValue(e)
+		}
+		return r
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	}
+	return v
+}
+
+// directoryError wraps an error with the file that caused it.
+func directoryError(path string, err error) error {
+	return &errors.Error{Code: errors.Code(err), Msg: fmt.Sprintf("directory file %v", path), Err: err}
+}
+
+// directoryReadYAML decodes a YAML file.
+func directoryReadYAML(path string, v interface{}) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return directoryError(path, err)
+	}
+	if err := yaml.UnmarshalStrict(b, v); err != nil {
+		return directoryError(path, &errors.Error{Code: errors.EInvalidType, Msg: "invalid YAML", Err: err})
+	}
+	return nil
+}
+
+// directoryWriteFile atomically replaces the file of an entity with the provided content.
+// Existing files of the same entity with a different extension are replaced.
+func directoryWriteFile(dir string, id string, ext string, data []byte) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return directoryError(dir, err)
+	}
+	path := filepath.Join(dir, id+ext)
+	if matches, _ := filepath.Glob(filepath.Join(dir, id+".*")); len(matches) > 0 {
+		sort.Strings(matches)
+		path = matches[0]
+	}
+	tmp, err := ioutil.TempFile(dir, ".tmp-")
+	if err != nil {
+		return directoryError(path, err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return directoryError(path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return directoryError(path, err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		_ = os.Remove(tmp.Name())
+		return directoryError(path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		_ = os.Remove(tmp.Name())
+		return directoryError(path, err)
+	}
+	return nil
+}
+
+// directoryWriteYAML atomically replaces the YAML file of an entity.
+func directoryWriteYAML(dir string, id string, v interface{}) error {
+	b, err := yaml.Marshal(v)
+	if err != nil {
+		return &errors.Error{Code: errors.EInvalidType, Msg: fmt.Sprintf("entity %v can't be encoded", id), Err: err}
+	}
+	return directoryWriteFile(dir, id, ".yaml", b)
+}
+
+// directoryRemoveFile removes all the files of an entity.
+func directoryRemoveFile(dir string, id string) error {
+	matches, _ := filepath.Glob(filepath.Join(dir, id+".*"))
+	for _, m := range matches {
+		if err := os.Remove(m); err != nil && !os.IsNotExist(err) {
+			return directoryError(m, err)
+		}
+	}
+	return nil
+}
+
+func directorySortedKeys(m map[string]directoryGroup) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+//~ STRUCT - directorySession -------------------------------------------------
+
+// DirectorySession holds a single use of the repository.
+type DirectorySession struct {
+	open               bool
+	readOnly           bool
+	repository         *directoryRepository
+	config             DirectoryConfig
+	memorySession      Session
+	hostRepository     *HostRepository
+	templateRepository *TemplateRepository
+	groupRepository    *GroupRepository
+	ruleRepository     *RuleRepository
+}
+
+// Close terminates the session
+func (d *DirectorySession) Close() error {
+	if !d.open {
+		return &errors.Error{Code: errors.EUnknown, Msg: "repository already closed"}
+	}
+	err := d.memorySession.Close()
+	if d.readOnly {
+		d.repository.lock.RUnlock()
+	} else {
+		d.repository.lock.Unlock()
+	}
+	d.open = false
+	return err
+}
+
+// Host returns HostRepository
+func (d *DirectorySession) Host() HostRepository {
+	if d.hostRepository == nil {
+		d.hostRepository = newDirectoryHostRepository(d, d.config, d.memorySession.Host())
+	}
+	return *d.hostRepository
+}
+
+// Template returns TemplateRepository
+func (d *DirectorySession) Template() TemplateRepository {
+	if d.templateRepository == nil {
+		d.templateRepository = newDirectoryTemplateRepository(d, d.config, d.memorySession.Template())
+	}
+	return *d.templateRepository
+}
+
+// Group returns GroupRepository
+func (d *DirectorySession) Group() GroupRepository {
+	if d.groupRepository == nil {
+		d.groupRepository = newDirectoryGroupRepository(d, d.config, d.memorySession.Group())
+	}
+	return *d.groupRepository
+}
+
+// Rule returns RuleRepository
+func (d *DirectorySession) Rule() RuleRepository {
+	if d.ruleRepository == nil {
+		d.ruleRepository = newDirectoryRuleRepository(d, d.config, d.memorySession.Rule())
+	}
+	return *d.ruleRepository
+}
+
+// Lease returns LeaseRepository
+// Leases are runtime state, they are kept in memory and are writable even in read-only mode.
+func (d *DirectorySession) Lease() LeaseRepository {
+	return d.memorySession.Lease()
+}
+
+// Facts returns FactsRepository
+// Facts are reported by the hosts, they are kept in memory and are writable even in read-only mode.
+func (d *DirectorySession) Facts() FactsRepository {
+	return d.memorySession.Facts()
+}
+
+// Event returns EventRepository
+// Boot events are runtime state, they are kept in memory and are writable even in read-only mode.
+func (d *DirectorySession) Event() EventRepository {
+	return d.memorySession.Event()
+}
+
+// IsReadOnly returns true is the session is for read only.
+func (d *DirectorySession) IsReadOnly() bool {
+	return d.readOnly || d.config.readOnly
+}
+
+// IsOpen returns true is the session is open for transactions.
+func (d *DirectorySession) IsOpen() bool {
+	return d.open
+}
+
+func newDirectorySession(r *directoryRepository, config DirectoryConfig, ms Session, readOnly bool) Session {
+	d := DirectorySession{
+		repository:    r,
+		open:          true,
+		readOnly:      readOnly,
+		config:        config,
+		memorySession: ms,
+	}
+	return &d
+}
+
+//~ STRUCT - directory files --------------------------------------------------
+
+// directoryHost is the YAML representation of entity.Host.
+type directoryHost struct {
+	ID               string                 `yaml:"id,omitempty"`
+	HardwareAddr     []string               `yaml:"hardware-addr"`
+	TrapMode         bool                   `yaml:"trap-mode,omitempty"`
+	Vars             map[string]interface{} `yaml:"vars,omitempty"`
+	GroupID          string                 `yaml:"group-id,omitempty"`
+	TemplateID       string                 `yaml:"template-id,omitempty"`
+	IP               string                 `yaml:"ip,omitempty"`
+	Hostname         string                 `yaml:"hostname,omitempty"`
+	TemplateRevision int                    `yaml:"template-revision,omitempty"`
+	Artifacts        map[string]string      `yaml:"artifacts,omitempty"`
+	Secrets          map[string]string      `yaml:"secrets,omitempty"`
+	GroupIDs         []string               `yaml:"group-ids,omitempty"`
+	Labels           map[string]string      `yaml:"labels,omitempty"`
+	Pending          bool                   `yaml:"pending,omitempty"`
+	UUID             string                 `yaml:"uuid,omitempty"`
+	Serial           string                 `yaml:"serial,omitempty"`
+	AssetTag         string                 `yaml:"asset-tag,omitempty"`
+}
+
+func newDirectoryHost(e entity.Host) directoryHost {
+	return directoryHost{
+		ID:               e.ID,
+		HardwareAddr:     e.HardwareAddr,
+		TrapMode:         e.TrapMode,
+		Vars:             e.Vars,
+		GroupID:          e.GroupID,
+		TemplateID:       e.TemplateID,
+		IP:               e.IP,
+		Hostname:         e.Hostname,
+		TemplateRevision: e.TemplateRevision,
+		Artifacts:        e.Artifacts,
+		Secrets:          e.Secrets,
+		GroupIDs:         e.GroupIDs,
+		Labels:           e.Labels,
+		Pending:          e.Pending,
+		UUID:             e.UUID,
+		Serial:           e.Serial,
+		AssetTag:         e.AssetTag,
+	}
+}
+
+func (h directoryHost) toEntity(path string) entity.Host {
+	id := h.ID
+	if id == "" {
+		id = directoryID(path)
+	}
+	return entity.Host{
+		ID:               id,
+		HardwareAddr:     h.HardwareAddr,
+		TrapMode:         h.TrapMode,
+		Vars:             directoryVars(h.Vars),
+		GroupID:          h.GroupID,
+		TemplateID:       h.TemplateID,
+		IP:               h.IP,
+		Hostname:         h.Hostname,
+		TemplateRevision: h.TemplateRevision,
+		Artifacts:        h.Artifacts,
+		Secrets:          h.Secrets,
+		GroupIDs:         h.GroupIDs,
+		Labels:           h.Labels,
+		Pending:          h.Pending,
+		UUID:             h.UUID,
+		Serial:           h.Serial,
+		AssetTag:         h.AssetTag,
+	}
+}
+
+// directoryState is the YAML representation of the state file, the runtime state of the hosts by ID.
+type directoryState struct {
+	Hosts map[string]directoryHostState `yaml:"hosts,omitempty"`
+}
+
+// directoryHostState is the runtime state of an entity.Host, it is not stored in the host file.
+type directoryHostState struct {
+	TrapTriggered   bool                  `yaml:"trap-triggered,omitempty"`
+	State           string                `yaml:"state,omitempty"`
+	StateHistory    []directoryTransition `yaml:"state-history,omitempty"`
+	CallbackToken   string                `yaml:"callback-token,omitempty"`
+	CallbackExpires time.Time             `yaml:"callback-token-expires,omitempty"`
+	LastCallback    *directoryCallback    `yaml:"last-callback,omitempty"`
+}
+
+// directoryCallback is the YAML representation of entity.CallbackResult.
+type directoryCallback struct {
+	Time     time.Time `yaml:"time"`
+	Status   string    `yaml:"status"`
+	ClientIP string    `yaml:"client-ip,omitempty"`
+	Logs     string    `yaml:"logs,omitempty"`
+}
+
+// directoryTransition is the YAML representation of entity.HostTransition.
+type directoryTransition struct {
+	Time    time.Time `yaml:"time"`
+	From    string    `yaml:"from"`
+	To      string    `yaml:"to"`
+	Trigger string    `yaml:"trigger"`
+	Reason  string    `yaml:"reason,omitempty"`
+}
+
+func newDirectoryHostState(e entity.Host) directoryHostState {
+	s := directoryHostState{
+		TrapTriggered:   e.TrapTriggered,
+		State:           e.State,
+		CallbackToken:   e.CallbackToken,
+		CallbackExpires: e.CallbackTokenExpires,
+	}
+	for _, t := range e.StateHistory {
+		s.StateHistory = append(s.StateHistory, directoryTransition{Time: t.Time, From: t.From, To: t.To,
+			Trigger: t.Trigger, Reason: t.Reason})
+	}
+	if c := e.LastCallback; c != (entity.CallbackResult{}) {
+		s.LastCallback = &directoryCallback{Time: c.Time, Status: c.Status, ClientIP: c.ClientIP, Logs: c.Logs}
+	}
+	return s
+}
+
+// apply sets the runtime state of the host.
+func (s directoryHostState) apply(e *entity.Host) {
+	e.TrapTriggered = s.TrapTriggered
+	e.State, e.StateHistory = s.State, nil
+	e.CallbackToken, e.CallbackTokenExpires = s.CallbackToken, s.CallbackExpires
+	for _, t := range s.StateHistory {
+		e.StateHistory = append(e.StateHistory, entity.HostTransition{Time: t.Time, From: t.From, To: t.To,
+			Trigger: t.Trigger, Reason: t.Reason})
+	}
+	e.LastCallback = entity.CallbackResult{}
+	if c := s.LastCallback; c != nil {
+		e.LastCallback = entity.CallbackResult{Time: c.Time, Status: c.Status, ClientIP: c.ClientIP, Logs: c.Logs}
+	}
+}
+
+// directoryGroup is the YAML representation of entity.Group.
+// HostsIDs and GroupIDs are derived from the hosts and groups files.
+type directoryGroup struct {
+	ID               string                 `yaml:"id,omitempty"`
+	Vars             map[string]interface{} `yaml:"vars,omitempty"`
+	ParentID         string                 `yaml:"parent-id,omitempty"`
+	TemplateID       string                 `yaml:"template-id,omitempty"`
+	TemplateRevision int                    `yaml:"template-revision,omitempty"`
+	Artifacts        map[string]string      `yaml:"artifacts,omitempty"`
+	Secrets          map[string]string      `yaml:"secrets,omitempty"`
+}
+
+func newDirectoryGroup(e entity.Group) directoryGroup {
+	return directoryGroup{
+		ID:               e.ID,
+		Vars:             e.Vars,
+		ParentID:         e.ParentID,
+		TemplateID:       e.TemplateID,
+		TemplateRevision: e.TemplateRevision,
+		Artifacts:        e.Artifacts,
+		Secrets:          e.Secrets,
+	}
+}
+
+func (g directoryGroup) toEntity(path string) entity.Group {
+	id := g.ID
+	if id == "" {
+		id = directoryID(path)
+	}
+	return entity.Group{
+		ID:               id,
+		Vars:             directoryVars(g.Vars),
+		ParentID:         g.ParentID,
+		TemplateID:       g.TemplateID,
+		TemplateRevision: g.TemplateRevision,
+		Artifacts:        g.Artifacts,
+		Secrets:          g.Secrets,
+	}
+}
+
+// directoryRule is the YAML representation of entity.Rule.
+type directoryRule struct {
+	ID         string            `yaml:"id,omitempty"`
+	Priority   int               `yaml:"priority,omitempty"`
+	Selector   map[string]string `yaml:"selector,omitempty"`
+	TemplateID string            `yaml:"template-id"`
+}
+
+func newDirectoryRule(e entity.Rule) directoryRule {
+	return directoryRule{
+		ID:         e.ID,
+		Priority:   e.Priority,
+		Selector:   e.Selector,
+		TemplateID: e.TemplateID,
+	}
+}
+
+func (r directoryRule) toEntity(path string) entity.Rule {
+	id := r.ID
+	if id == "" {
+		id = directoryID(path)
+	}
+	return entity.Rule{
+		ID:         id,
+		Priority:   r.Priority,
+		Selector:   r.Selector,
+		TemplateID: r.TemplateID,
+	}
+}
+
+//~ STRUCT - DirectoryConfig --------------------------------------------------
+
+// DirectoryConfig stores directory driver config for all repositories.
+type DirectoryConfig struct {
+	path      string
+	readOnly  bool
+	watch     bool
+	debounce  time.Duration
+	stateFile string
+}
+
+func (c DirectoryConfig) hostDir() string {
+	return filepath.Join(c.path, directoryHostDir)
+}
+
+func (c DirectoryConfig) groupDir() string {
+	return filepath.Join(c.path, directoryGroupDir)
+}
+
+func (c DirectoryConfig) ruleDir() string {
+	return filepath.Join(c.path, directoryRuleDir)
+}
+
+func (c DirectoryConfig) templateDir() string {
+	return filepath.Join(c.path, directoryTemplateDir)
+}
+
+// NewDirectoryConfig creates a new DirectoryConfig extracting and checking type of the required fields.
+func NewDirectoryConfig(config map[string]interface{}) (DirectoryConfig, error) {
+	c := DirectoryConfig{}
+
+	s, err := util.StringFromMap(config, "path", "")
+	if err != nil {
+		return c, &errors.Error{Code: errors.EInvalidType, Msg: "config invalid type for key path"}
+	}
+	if s == "" {
+		return c, &errors.Error{Code: errors.EInvalidType, Msg: "config missing key path"}
+	}
+	c.path = s
+
+	b, err := util.BoolFromMap(config, "read-only", true)
+	if err != nil {
+		return c, &errors.Error{Code: errors.EInvalidType, Msg: "config invalid type for key read-only"}
+	}
+	c.readOnly = b
+
+	b, err = util.BoolFromMap(config, "watch", true)
+	if err != nil {
+		return c, &errors.Error{Code: errors.EInvalidType, Msg: "config invalid type for key watch"}
+	}
+	c.watch = b
+
+	i, err := util.IntFromMap(config, "debounce", 250)
+	if err != nil {
+		return c, &errors.Error{Code: errors.EInvalidType, Msg: "config invalid type for key debounce"}
+	}
+	c.debounce = time.Duration(i) * time.Millisecond
+
+	s, err = util.StringFromMap(config, "state-file", filepath.Join(c.path, ".state.yaml"))
+	if err != nil {
+		return c, &errors.Error{Code: errors.EInvalidType, Msg: "config invalid type for key state-file"}
+	}
+	c.stateFile = s
+
+	return c, nil
+}
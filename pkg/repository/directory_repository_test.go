@@ -0,0 +1,261 @@
+package repository
+
+import (
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+	"github.com/pxecore/pxecore/pkg/secret"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newDirectoryTree(t *testing.T, files map[string]string) string {
+	d, err := ioutil.TempDir(testDir, "tree")
+	if err != nil {
+		t.Fatal("Error creating directory tree - ", err)
+	}
+	for name, content := range files {
+		p := filepath.Join(d, name)
+		_ = os.MkdirAll(filepath.Dir(p), 0755)
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal("Error creating directory tree - ", err)
+		}
+	}
+	return d
+}
+
+func TestDirectoryRepository_Load(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		wantErr bool
+	}{
+		{"OK", map[string]string{
+			"templates/default.ipxe": "#!ipxe",
+			"templates/ks.kickstart": "install",
+			"groups/child.yaml":      "parent-id: parent\nvars:\n  a: child\n  disks: [sda]\n  bond: {mode: 4}\n",
+			"groups/parent.yaml":     "template-id: default\n",
+			"hosts/host1.yaml":       "hardware-addr: [88-99-aa-bb-cc-dd]\ngroup-id: child\nartifacts:\n  kickstart: ks\n",
+			"rules/db.yaml":          "priority: 1\nselector:\n  role: db\ntemplate-id: default\n",
+		}, false},
+		{"KO_RULE_MISSING_TEMPLATE", map[string]string{
+			"rules/db.yaml": "template-id: missing\n",
+		}, true},
+		{"KO_MISSING_TEMPLATE", map[string]string{
+			"hosts/host1.yaml": "hardware-addr: [88-99-aa-bb-cc-dd]\ntemplate-id: missing\n",
+		}, true},
+		{"KO_MISSING_GROUP", map[string]string{
+			"hosts/host1.yaml": "hardware-addr: [88-99-aa-bb-cc-dd]\ngroup-id: missing\n",
+		}, true},
+		{"KO_RECURSIVE_PARENT", map[string]string{
+			"groups/a.yaml": "parent-id: b\n",
+			"groups/b.yaml": "parent-id: a\n",
+		}, true},
+		{"KO_UNKNOWN_FIELD", map[string]string{
+			"hosts/host1.yaml": "hardware-address: [88-99-aa-bb-cc-dd]\n",
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newDirectoryTree(t, tt.files)
+			r, err := newDirectoryRepository(map[string]interface{}{"path": d, "watch": false})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newDirectoryRepository() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if err := r.Read(func(s Session) error {
+				h, err := s.Host().FindByHardwareAddr("88-99-aa-bb-cc-dd")
+				if err != nil {
+					return err
+				}
+				if h.ID != "host1" || h.GroupID != "child" || h.Artifacts["kickstart"] != "ks" {
+					t.Error("Invalid loaded host - ", h)
+				}
+				tp, err := s.Template().Get("ks")
+				if err != nil {
+					return err
+				}
+				if tp.Kind != entity.TemplateKindKickstart {
+					t.Error("Invalid loaded template kind - ", tp)
+				}
+				g, err := s.Group().Get("child")
+				if err != nil {
+					return err
+				}
+				if g.ParentID != "parent" || g.Vars["a"] != "child" || g.HostsIDs[0] != "host1" {
+					t.Error("Invalid loaded group - ", g)
+				}
+				if !reflect.DeepEqual(g.Vars["disks"], []interface{}{"sda"}) ||
+					!reflect.DeepEqual(g.Vars["bond"], map[string]interface{}{"mode": float64(4)}) {
+					t.Error("Invalid loaded group vars - ", g.Vars)
+				}
+				rl, err := s.Rule().Get("db")
+				if err != nil {
+					return err
+				}
+				if rl.Priority != 1 || rl.Selector["role"] != "db" || rl.TemplateID != "default" {
+					t.Error("Invalid loaded rule - ", rl)
+				}
+				return nil
+			}); err != nil {
+				t.Error("Error reading directory repository - ", err)
+			}
+		})
+	}
+}
+
+func TestDirectoryRepository_ReadOnly(t *testing.T) {
+	d := newDirectoryTree(t, map[string]string{"templates/default.ipxe": "#!ipxe"})
+	r, err := newDirectoryRepository(map[string]interface{}{"path": d, "watch": false})
+	if err != nil {
+		t.Fatal("Error opening directory repository - ", err)
+	}
+	err = r.Write(func(s Session) error {
+		return s.Template().Update(entity.Template{ID: "default", Template: "changed"})
+	})
+	if !errors.Is(err, errors.ERepositoryReadOnly) {
+		t.Error("Write in read-only mode returned - ", err)
+	}
+	if err := r.Write(func(s Session) error {
+		return s.Lease().Create(entity.Lease{HardwareAddr: "88-99-aa-bb-cc-dd", IP: "10.0.0.1"})
+	}); err != nil {
+		t.Error("Lease write in read-only mode returned - ", err)
+	}
+}
+
+func TestDirectoryRepository_WriteBackAndWatch(t *testing.T) {
+	d := newDirectoryTree(t, map[string]string{"templates/default.ipxe": "#!ipxe"})
+	r, err := newDirectoryRepository(map[string]interface{}{"path": d, "read-only": false, "debounce": 10})
+	if err != nil {
+		t.Fatal("Error opening directory repository - ", err)
+	}
+	defer r.(*directoryRepository).watcher.Close()
+	if err := r.Write(func(s Session) error {
+		if err := s.Lease().Create(entity.Lease{HardwareAddr: "88-99-aa-bb-cc-dd", IP: "10.0.0.1"}); err != nil {
+			return err
+		}
+		return s.Host().Create(entity.Host{ID: "host1", HardwareAddr: []string{"88-99-aa-bb-cc-dd"},
+			TemplateID: "default"})
+	}); err != nil {
+		t.Fatal("Error writing directory repository - ", err)
+	}
+	if _, err := os.Stat(filepath.Join(d, "hosts", "host1.yaml")); err != nil {
+		t.Error("Host file not written - ", err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(d, "templates", "default.ipxe"), []byte("reloaded"), 0644); err != nil {
+		t.Fatal("Error changing template file - ", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var body string
+		_ = r.Read(func(s Session) error {
+			tp, err := s.Template().Get("default")
+			body = tp.Template
+			return err
+		})
+		if body == "reloaded" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Directory repository was not reloaded - ", body)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if err := r.Read(func(s Session) error {
+		_, err := s.Host().Get("host1")
+		return err
+	}); err != nil {
+		t.Error("Written host lost after reload - ", err)
+	}
+	if err := r.Read(func(s Session) error {
+		_, err := s.Lease().FindByIP("10.0.0.1")
+		return err
+	}); err != nil {
+		t.Error("Lease lost after reload - ", err)
+	}
+	if err := r.Read(func(s Session) error {
+		l, err := s.Template().ListRevisions("default")
+		if err != nil {
+			return err
+		}
+		if len(l) != 2 || l[0].Template != "#!ipxe" || l[1].Template != "reloaded" || l[1].Revision != 2 {
+			t.Error("Template history lost after reload - ", l)
+		}
+		return nil
+	}); err != nil {
+		t.Error("Error listing template revisions - ", err)
+	}
+}
+
+func TestDirectoryRepository_HostLifecycle(t *testing.T) {
+	key, _ := secret.GenerateKey()
+	k, _ := secret.NewKeyring(map[string]interface{}{"key": key})
+	encrypted, _ := k.Encrypt("admin:admin")
+	hostFile := "hardware-addr: [88-99-aa-bb-cc-dd]\nsecrets:\n  bmc: " + encrypted + "\n  token: plain\n"
+	d := newDirectoryTree(t, map[string]string{"hosts/host1.yaml": hostFile})
+	c := map[string]interface{}{"path": d, "watch": false}
+	dr, err := newDirectoryRepository(c)
+	if err != nil {
+		t.Fatal("Error opening directory repository - ", err)
+	}
+	r := NewSecretRepository(dr, k)
+	expires := time.Now().Add(time.Hour)
+	if err := r.Write(func(s Session) error {
+		if _, err := TransitionHost(s, "host1", entity.HostStateReady, entity.HostStateInstalling,
+			entity.TransitionTriggerBoot, "test"); err != nil {
+			return err
+		}
+		h, err := s.Host().Get("host1")
+		if err != nil {
+			return err
+		}
+		h.CallbackToken, h.CallbackTokenExpires = "0a1b", expires
+		return s.Host().Update(h)
+	}); err != nil {
+		t.Fatal("Lifecycle write in read-only mode returned - ", err)
+	}
+	err = r.Write(func(s Session) error {
+		h, err := s.Host().Get("host1")
+		if err != nil {
+			return err
+		}
+		h.Hostname = "changed"
+		return s.Host().Update(h)
+	})
+	if !errors.Is(err, errors.ERepositoryReadOnly) {
+		t.Error("Host write in read-only mode returned - ", err)
+	}
+	if b, _ := ioutil.ReadFile(filepath.Join(d, "hosts", "host1.yaml")); string(b) != hostFile {
+		t.Error("Host file changed - ", string(b))
+	}
+	if _, err := os.Stat(filepath.Join(d, ".state.yaml")); err != nil {
+		t.Error("State file not written - ", err)
+	}
+
+	dr.(*directoryRepository).reload()
+	reopened, err := newDirectoryRepository(c)
+	if err != nil {
+		t.Fatal("Error reopening directory repository - ", err)
+	}
+	for name, r := range map[string]Repository{"reload": dr, "restart": reopened} {
+		if err := r.Read(func(s Session) error {
+			h, err := s.Host().Get("host1")
+			if err != nil {
+				return err
+			}
+			if h.State != entity.HostStateInstalling || len(h.StateHistory) != 1 ||
+				!h.ValidCallbackToken("0a1b", time.Now()) {
+				t.Errorf("Host lifecycle lost after %v - %+v", name, h)
+			}
+			return nil
+		}); err != nil {
+			t.Error("Error reading directory repository - ", err)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+)
+
+// directoryRuleRepository writes back entity.Rule changes as YAML files.
+type directoryRuleRepository struct {
+	session *DirectorySession
+	config  DirectoryConfig
+	memory  RuleRepository
+}
+
+// newDirectoryRuleRepository instantiates a new repository for entity.Rule
+func newDirectoryRuleRepository(s *DirectorySession, config DirectoryConfig, memory RuleRepository) *RuleRepository {
+	var rr RuleRepository
+	rr = &directoryRuleRepository{
+		s,
+		config,
+		memory,
+	}
+	return &rr
+}
+
+// Create implements repository.RuleRepository interface
+func (r *directoryRuleRepository) Create(rule entity.Rule) error {
+	if r.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	if err := r.memory.Create(rule); err != nil {
+		return err
+	}
+	return directoryWriteYAML(r.config.ruleDir(), rule.ID, newDirectoryRule(rule))
+}
+
+// Get implements repository.RuleRepository interface
+func (r *directoryRuleRepository) Get(ID string) (entity.Rule, error) {
+	return r.memory.Get(ID)
+}
+
+// Update implements repository.RuleRepository interface
+func (r *directoryRuleRepository) Update(rule entity.Rule) error {
+	if r.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	if err := r.memory.Update(rule); err != nil {
+		return err
+	}
+	return directoryWriteYAML(r.config.ruleDir(), rule.ID, newDirectoryRule(rule))
+}
+
+// Delete implements repository.RuleRepository interface
+func (r *directoryRuleRepository) Delete(rule entity.Rule) error {
+	if r.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	if err := r.memory.Delete(rule); err != nil {
+		return err
+	}
+	return directoryRemoveFile(r.config.ruleDir(), rule.ID)
+}
+
+// List implements repository.RuleRepository interface
+func (r *directoryRuleRepository) List(after string, limit int) ([]entity.Rule, error) {
+	return r.memory.List(after, limit)
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+)
+
+// directoryTemplateRepository writes back entity.Template changes as template files.
+type directoryTemplateRepository struct {
+	session *DirectorySession
+	config  DirectoryConfig
+	memory  TemplateRepository
+}
+
+// newDirectoryTemplateRepository instantiates a new repository for entity.Template
+func newDirectoryTemplateRepository(s *DirectorySession, config DirectoryConfig, memory TemplateRepository) *TemplateRepository {
+	var tr TemplateRepository
+	tr = &directoryTemplateRepository{
+		s,
+		config,
+		memory,
+	}
+	return &tr
+}
+
+// Create implements repository.TemplateRepository interface
+func (t *directoryTemplateRepository) Create(template entity.Template) error {
+	if t.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	if err := t.memory.Create(template); err != nil {
+		return err
+	}
+	return directoryWriteFile(t.config.templateDir(), template.ID, directoryTemplateFileExt(template.Kind),
+		[]byte(template.Template))
+}
+
+// Get implements repository.TemplateRepository interface
+func (t *directoryTemplateRepository) Get(ID string) (entity.Template, error) {
+	return t.memory.Get(ID)
+}
+
+// Update implements repository.TemplateRepository interface
+func (t *directoryTemplateRepository) Update(template entity.Template) error {
+	if t.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	current, err := t.memory.Get(template.ID)
+	if err != nil {
+		return err
+	}
+	if err := t.memory.Update(template); err != nil {
+		return err
+	}
+	// The kind is stored as the file extension.
+	if directoryTemplateFileExt(current.Kind) != directoryTemplateFileExt(template.Kind) {
+		if err := directoryRemoveFile(t.config.templateDir(), template.ID); err != nil {
+			return err
+		}
+	}
+	return directoryWriteFile(t.config.templateDir(), template.ID, directoryTemplateFileExt(template.Kind),
+		[]byte(template.Template))
+}
+
+// Delete implements repository.TemplateRepository interface
+func (t *directoryTemplateRepository) Delete(template entity.Template) error {
+	if t.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	if err := t.memory.Delete(template); err != nil {
+		return err
+	}
+	return directoryRemoveFile(t.config.templateDir(), template.ID)
+}
+
+// List implements repository.TemplateRepository interface
+func (t *directoryTemplateRepository) List(after string, limit int) ([]entity.Template, error) {
+	return t.memory.List(after, limit)
+}
+
+// GetRevision implements repository.TemplateRepository interface
+func (t *directoryTemplateRepository) GetRevision(ID string, revision int) (entity.TemplateRevision, error) {
+	return t.memory.GetRevision(ID, revision)
+}
+
+// ListRevisions implements repository.TemplateRepository interface
+func (t *directoryTemplateRepository) ListRevisions(ID string) ([]entity.TemplateRevision, error) {
+	return t.memory.ListRevisions(ID)
+}
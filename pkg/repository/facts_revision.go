@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"fmt"
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+	"time"
+)
+
+const (
+	// MaxFactsRevisions is the number of entity.Facts revisions kept per host, older ones are dropped.
+	MaxFactsRevisions = 20
+)
+
+// nextFactsRevision returns the facts to store after the latest revision of the host, 0 if there is none.
+func nextFactsRevision(latest int, f entity.Facts) entity.Facts {
+	f.Revision = latest + 1
+	f.Created = time.Now()
+	return f
+}
+
+// keptFactsRevisions returns the last MaxFactsRevisions revisions of the sorted history.
+func keptFactsRevisions(history []entity.Facts) []entity.Facts {
+	if len(history) > MaxFactsRevisions {
+		return history[len(history)-MaxFactsRevisions:]
+	}
+	return history
+}
+
+// factsNotFound builds the error returned when a host has no facts.
+func factsNotFound(hostID string) error {
+	return &errors.Error{Code: errors.ERepositoryKeyNotFound,
+		Msg: fmt.Sprintf("entity.Facts host %v not found", hostID)}
+}
+
+// factsRevisionNotFound builds the error returned when a revision is missing.
+func factsRevisionNotFound(hostID string, revision int) error {
+	return &errors.Error{Code: errors.ERepositoryKeyNotFound,
+		Msg: fmt.Sprintf("entity.Facts host %v revision %v not found", hostID, revision)}
+}
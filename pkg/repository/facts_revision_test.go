@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"fmt"
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+	"testing"
+)
+
+func TestFactsRevision(t *testing.T) {
+	runDriverTest(t, runFactsTest)
+}
+
+func runFactsTest(t *testing.T, m Repository) {
+	if err := m.Write(func(s Session) error {
+		if err := s.Host().Create(entity.Host{ID: "fh", HardwareAddr: []string{"00-00-00-00-00-f2"}}); err != nil {
+			return err
+		}
+		for i := 0; i < MaxFactsRevisions+2; i++ {
+			if err := s.Facts().Create(entity.Facts{HostID: "fh", Serial: fmt.Sprint("S", i), Memory: 1 << 30,
+				Disks: []entity.FactsDisk{{Name: "sda", Size: 1 << 40}}}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		t.Fatal("runFactsTest - error creating ", err)
+	}
+	if err := m.Read(func(s Session) error {
+		f, err := s.Facts().Get("fh")
+		if err != nil {
+			return err
+		}
+		if f.Revision != MaxFactsRevisions+2 || f.Serial != fmt.Sprint("S", MaxFactsRevisions+1) ||
+			f.Created.IsZero() || f.Disks[0].Size != 1<<40 {
+			t.Error("runFactsTest - invalid latest facts ", f)
+		}
+		l, err := s.Facts().ListRevisions("fh")
+		if err != nil {
+			return err
+		}
+		if len(l) != MaxFactsRevisions || l[0].Revision != 3 || l[len(l)-1].Revision != MaxFactsRevisions+2 {
+			t.Errorf("runFactsTest - invalid kept revisions %v from %v", len(l), l[0].Revision)
+		}
+		if f, err := s.Facts().GetRevision("fh", 3); err != nil || f.Serial != "S2" {
+			t.Error("runFactsTest - invalid revision ", f, err)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal("runFactsTest - error reading ", err)
+	}
+
+	tests := []struct {
+		name     string
+		call     func(s Session) error
+		wantCode string
+	}{
+		{"KO_EMPTY_KEY", func(s Session) error { return s.Facts().Create(entity.Facts{}) }, errors.ERepositoryEmptyKey},
+		{"KO_HOST_NOT_FOUND", func(s Session) error {
+			return s.Facts().Create(entity.Facts{HostID: "missing"})
+		}, errors.ERepositoryKeyNotFound},
+		{"KO_DROPPED_REVISION", func(s Session) error {
+			_, err := s.Facts().GetRevision("fh", 1)
+			return err
+		}, errors.ERepositoryKeyNotFound},
+		{"OK_HOST_DELETE", func(s Session) error { return s.Host().Delete(entity.Host{ID: "fh"}) }, ""},
+		{"KO_DELETED_WITH_HOST", func(s Session) error {
+			_, err := s.Facts().Get("fh")
+			return err
+		}, errors.ERepositoryKeyNotFound},
+		{"KO_LIST_NOT_FOUND", func(s Session) error {
+			_, err := s.Facts().ListRevisions("fh")
+			return err
+		}, errors.ERepositoryKeyNotFound},
+		{"KO_DELETE_NOT_FOUND", func(s Session) error { return s.Facts().Delete("fh") }, errors.ERepositoryKeyNotFound},
+	}
+	for _, tt := range tests {
+		err := m.Write(tt.call)
+		if tt.wantCode == "" && err != nil {
+			t.Errorf("runFactsTest %v - unexpected error %v", tt.name, err)
+		}
+		if tt.wantCode != "" && !errors.Is(err, tt.wantCode) {
+			t.Errorf("runFactsTest %v - got %v want %v", tt.name, err, tt.wantCode)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"fmt"
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+	"sort"
+)
+
+// hostIdentifierKey returns the index key of an entity.Host identifier, "<kind>/<value>".
+func hostIdentifierKey(kind string, value string) string {
+	return kind + "/" + entity.NormalizeIdentifier(kind, value)
+}
+
+// hostIdentifierKeys returns the sorted index keys of the identifiers set in the host.
+func hostIdentifierKeys(e entity.Host) []string {
+	keys := make([]string, 0, 3)
+	for k, v := range e.Identifiers() {
+		keys = append(keys, hostIdentifierKey(k, v))
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// hostIdentifierExist returns the error of an identifier key that belongs to another host.
+func hostIdentifierExist(key string) error {
+	return &errors.Error{Code: errors.ERepositoryKeyExist,
+		Msg: fmt.Sprintf("entity.Host identifier %v already exists ", key)}
+}
+
+// hostIdentifierNotFound returns the error of an identifier that belongs to no host.
+func hostIdentifierNotFound(kind string, value string) error {
+	return &errors.Error{Code: errors.ERepositoryKeyNotFound,
+		Msg: fmt.Sprintf("entity.Host %v %v not found", kind, value)}
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+	"testing"
+)
+
+func TestHostIdentifier(t *testing.T) {
+	runDriverTest(t, runHostIdentifierTest)
+}
+
+func runHostIdentifierTest(t *testing.T, m Repository) {
+	if err := m.Write(func(s Session) error {
+		return s.Host().Create(entity.Host{ID: "ih", HardwareAddr: []string{"00-00-00-00-00-f3"},
+			UUID: "4C4C4544-0042-3010-8052-B4C04F4E4E32", Serial: "CN1234", AssetTag: "A-1"})
+	}); err != nil {
+		t.Fatal("runHostIdentifierTest - error creating ", err)
+	}
+
+	find := func(kind string, value string) func(s Session) error {
+		return func(s Session) error {
+			h, err := s.Host().FindByIdentifier(kind, value)
+			if err == nil && h.ID != "ih" {
+				t.Errorf("runHostIdentifierTest - FindByIdentifier(%v, %v) = %v", kind, value, h.ID)
+			}
+			return err
+		}
+	}
+	tests := []struct {
+		name     string
+		call     func(s Session) error
+		wantCode string
+	}{
+		{"OK_UUID", find(entity.IdentifierUUID, "4c4c4544-0042-3010-8052-b4c04f4e4e32"), ""},
+		{"OK_SERIAL", find(entity.IdentifierSerial, "CN1234"), ""},
+		{"OK_ASSET_TAG", find(entity.IdentifierAssetTag, "A-1"), ""},
+		{"KO_SERIAL_CASE", find(entity.IdentifierSerial, "cn1234"), errors.ERepositoryKeyNotFound},
+		{"KO_KIND", find(entity.IdentifierAssetTag, "CN1234"), errors.ERepositoryKeyNotFound},
+		{"KO_CREATE_DUPLICATED", func(s Session) error {
+			return s.Host().Create(entity.Host{ID: "ih2", Serial: "CN1234"})
+		}, errors.ERepositoryKeyExist},
+		{"OK_CREATE_OTHER", func(s Session) error {
+			return s.Host().Create(entity.Host{ID: "ih2", Serial: "CN5678"})
+		}, ""},
+		{"KO_UPDATE_DUPLICATED", func(s Session) error {
+			return s.Host().Update(entity.Host{ID: "ih2", UUID: "4c4c4544-0042-3010-8052-b4c04f4e4e32"})
+		}, errors.ERepositoryKeyExist},
+		{"OK_UPDATE", func(s Session) error {
+			return s.Host().Update(entity.Host{ID: "ih", HardwareAddr: []string{"00-00-00-00-00-f3"}, Serial: "CN9999"})
+		}, ""},
+		{"KO_UPDATED_UUID", find(entity.IdentifierUUID, "4c4c4544-0042-3010-8052-b4c04f4e4e32"),
+			errors.ERepositoryKeyNotFound},
+		{"OK_UPDATED_SERIAL", find(entity.IdentifierSerial, "CN9999"), ""},
+		{"OK_DELETE", func(s Session) error { return s.Host().Delete(entity.Host{ID: "ih"}) }, ""},
+		{"KO_DELETED", find(entity.IdentifierSerial, "CN9999"), errors.ERepositoryKeyNotFound},
+		{"OK_DELETE_OTHER", func(s Session) error { return s.Host().Delete(entity.Host{ID: "ih2"}) }, ""},
+	}
+	for _, tt := range tests {
+		err := m.Write(tt.call)
+		if tt.wantCode == "" && err != nil {
+			t.Errorf("runHostIdentifierTest %v - unexpected error %v", tt.name, err)
+		}
+		if tt.wantCode != "" && !errors.Is(err, tt.wantCode) {
+			t.Errorf("runHostIdentifierTest %v - got %v want %v", tt.name, err, tt.wantCode)
+		}
+	}
+}
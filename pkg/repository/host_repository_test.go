@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+	"reflect"
+	"testing"
+)
+
+func TestHostRepository_StructuredVars(t *testing.T) {
+	runDriverTest(t, runStructuredVarsTest)
+}
+
+func runStructuredVarsTest(t *testing.T, m Repository) {
+	vars := map[string]interface{}{
+		"disks": []interface{}{"sda", "sdb"},
+		"bond":  map[string]interface{}{"mode": "802.3ad", "slaves": []interface{}{"eth0", "eth1"}},
+		"mtu":   float64(9000),
+		"ssh":   true,
+	}
+	if err := m.Write(func(s Session) error {
+		if err := s.Group().Create(entity.Group{ID: "vg", Vars: vars}); err != nil {
+			return err
+		}
+		return s.Host().Create(entity.Host{ID: "vh", HardwareAddr: []string{"00-00-00-00-00-f1"}, Vars: vars})
+	}); err != nil {
+		t.Fatal("runStructuredVarsTest - error creating ", err)
+	}
+	if err := m.Read(func(s Session) error {
+		g, err := s.Group().Get("vg")
+		if err != nil {
+			return err
+		}
+		if !reflect.DeepEqual(g.Vars, vars) {
+			t.Error("runStructuredVarsTest - invalid stored group vars ", g.Vars)
+		}
+		h, err := s.Host().Get("vh")
+		if err != nil {
+			return err
+		}
+		if !reflect.DeepEqual(h.Vars, vars) {
+			t.Error("runStructuredVarsTest - invalid stored host vars ", h.Vars)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal("runStructuredVarsTest - error reading ", err)
+	}
+	if err := m.Write(func(s Session) error {
+		if err := s.Host().Delete(entity.Host{ID: "vh"}); err != nil {
+			return err
+		}
+		return s.Group().Delete(entity.Group{ID: "vg"})
+	}); err != nil {
+		t.Fatal("runStructuredVarsTest - error deleting ", err)
+	}
+}
+
+func TestHostRepository_Groups(t *testing.T) {
+	runDriverTest(t, runHostGroupsTest)
+}
+
+func runHostGroupsTest(t *testing.T, m Repository) {
+	if err := m.Write(func(s Session) error {
+		for _, g := range []entity.Group{{ID: "hg1"}, {ID: "hg2"}, {ID: "hg2c", ParentID: "hg2"}} {
+			if err := s.Group().Create(g); err != nil {
+				return err
+			}
+		}
+		return s.Host().Create(entity.Host{ID: "hgh", HardwareAddr: []string{"00-00-00-00-00-e1"}, GroupID: "hg1",
+			GroupIDs: []string{"hg2c", "hg2"}})
+	}); err != nil {
+		t.Fatal("runHostGroupsTest - error creating ", err)
+	}
+	checkMembers := func(name string, want map[string][]string) {
+		if err := m.Read(func(s Session) error {
+			for ID, hosts := range want {
+				g, err := s.Group().Get(ID)
+				if err != nil {
+					return err
+				}
+				if len(g.HostsIDs) != len(hosts) || (len(hosts) > 0 && !reflect.DeepEqual(g.HostsIDs, hosts)) {
+					t.Errorf("runHostGroupsTest %v - group %v hosts %v want %v", name, ID, g.HostsIDs, hosts)
+				}
+			}
+			return nil
+		}); err != nil {
+			t.Fatal("runHostGroupsTest - error reading ", err)
+		}
+	}
+	if err := m.Read(func(s Session) error {
+		h, err := s.Host().Get("hgh")
+		if err != nil {
+			return err
+		}
+		if !reflect.DeepEqual(h.GroupIDs, []string{"hg2c", "hg2"}) {
+			t.Error("runHostGroupsTest - invalid stored host groups ", h.GroupIDs)
+		}
+		g, err := s.Group().Get("hg2")
+		if err != nil {
+			return err
+		}
+		if !reflect.DeepEqual(g.GroupIDs, []string{"hg2c"}) {
+			t.Error("runHostGroupsTest - invalid stored children groups ", g.GroupIDs)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal("runHostGroupsTest - error reading ", err)
+	}
+	checkMembers("CREATE", map[string][]string{"hg1": {"hgh"}, "hg2": {"hgh"}, "hg2c": {"hgh"}})
+
+	if err := m.Write(func(s Session) error {
+		return s.Host().Update(entity.Host{ID: "hgh", HardwareAddr: []string{"00-00-00-00-00-e1"}, GroupID: "hg1",
+			GroupIDs: []string{"hg2"}})
+	}); err != nil {
+		t.Fatal("runHostGroupsTest - error updating ", err)
+	}
+	checkMembers("UPDATE", map[string][]string{"hg1": {"hgh"}, "hg2": {"hgh"}, "hg2c": {}})
+
+	err := m.Write(func(s Session) error {
+		return s.Host().Update(entity.Host{ID: "hgh", HardwareAddr: []string{"00-00-00-00-00-e1"},
+			GroupIDs: []string{"hg2", "hg3"}})
+	})
+	if !errors.Is(err, errors.ERepositoryKeyNotFound) {
+		t.Errorf("runHostGroupsTest - update with missing group got %v want %v", err, errors.ERepositoryKeyNotFound)
+	}
+	err = m.Write(func(s Session) error { return s.Group().Delete(entity.Group{ID: "hg2"}) })
+	if !errors.Is(err, errors.ERepositoryDependency) {
+		t.Errorf("runHostGroupsTest - delete used group got %v want %v", err, errors.ERepositoryDependency)
+	}
+
+	if err := m.Write(func(s Session) error {
+		return s.Host().Delete(entity.Host{ID: "hgh"})
+	}); err != nil {
+		t.Fatal("runHostGroupsTest - error deleting ", err)
+	}
+	checkMembers("DELETE", map[string][]string{"hg1": {}, "hg2": {}})
+	if err := m.Write(func(s Session) error {
+		for _, ID := range []string{"hg2c", "hg2", "hg1"} {
+			if err := s.Group().Delete(entity.Group{ID: ID}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		t.Fatal("runHostGroupsTest - error deleting ", err)
+	}
+}
+
+func TestHostRepository_Pending(t *testing.T) {
+	runDriverTest(t, runHostPendingTest)
+}
+
+func runHostPendingTest(t *testing.T, m Repository) {
+	if err := m.Write(func(s Session) error {
+		return s.Host().Create(entity.Host{ID: "pending-1", HardwareAddr: []string{"86-53-25-6A-E0-F1"}, Pending: true})
+	}); err != nil {
+		t.Fatal("runHostPendingTest - error creating ", err)
+	}
+	if err := m.Read(func(s Session) error {
+		h, err := s.Host().Get("pending-1")
+		if err != nil {
+			return err
+		}
+		if !h.Pending {
+			t.Fatal("Invalid stored discovery data - ", h)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal("runHostPendingTest - error reading ", err)
+	}
+
+	if err := m.Write(func(s Session) error {
+		return s.Host().Update(entity.Host{ID: "pending-1", HardwareAddr: []string{"86-53-25-6A-E0-F1"}})
+	}); err != nil {
+		t.Fatal("runHostPendingTest - error approving ", err)
+	}
+	if err := m.Read(func(s Session) error {
+		h, err := s.Host().Get("pending-1")
+		if err != nil {
+			return err
+		}
+		if h.Pending {
+			t.Fatal("Invalid approved discovery data - ", h)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal("runHostPendingTest - error reading ", err)
+	}
+}
@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"fmt"
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+	"time"
+)
+
+// TransitionHost moves the host to a lifecycle state within a write session, see entity.Host.Transition.
+// When from is not empty it must match the current state of the host, so concurrent changes
+// are detected. Unknown states, mismatching states and transitions not allowed by
+// entity.CanTransition return errors.ERepositoryConflict.
+func TransitionHost(session Session, ID string, from string, to string, trigger string,
+	reason string) (entity.Host, error) {
+	h, err := session.Host().Get(ID)
+	if err != nil {
+		return entity.Host{}, err
+	}
+	current := h.Lifecycle()
+	if from != "" && from != current {
+		return entity.Host{}, &errors.Error{Code: errors.ERepositoryConflict,
+			Msg: fmt.Sprintf("entity.Host %v state is %v, not %v", ID, current, from)}
+	}
+	if !entity.CanTransition(current, to) {
+		return entity.Host{}, &errors.Error{Code: errors.ERepositoryConflict,
+			Msg: fmt.Sprintf("entity.Host %v can't move from %v to %v", ID, current, to)}
+	}
+	h.Transition(to, trigger, reason, time.Now())
+	if err := session.Host().Update(h); err != nil {
+		return entity.Host{}, err
+	}
+	return h, nil
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+	"testing"
+	"time"
+)
+
+func TestHostState(t *testing.T) {
+	runDriverTest(t, runHostStateTest)
+}
+
+func runHostStateTest(t *testing.T, m Repository) {
+	if err := m.Write(func(s Session) error {
+		return s.Host().Create(entity.Host{ID: "sh", HardwareAddr: []string{"00-00-00-00-00-f5"}, Pending: true})
+	}); err != nil {
+		t.Fatal("runHostStateTest - error creating ", err)
+	}
+
+	transition := func(from string, to string) func(s Session) error {
+		return func(s Session) error {
+			h, err := TransitionHost(s, "sh", from, to, entity.TransitionTriggerAPI, "test")
+			if err == nil && h.State != to {
+				t.Errorf("runHostStateTest - TransitionHost(%v, %v) = %v", from, to, h.State)
+			}
+			return err
+		}
+	}
+	tests := []struct {
+		name     string
+		call     func(s Session) error
+		wantCode string
+	}{
+		{"KO_FROM", transition(entity.HostStateReady, entity.HostStateInstalling), errors.ERepositoryConflict},
+		{"KO_NOT_ALLOWED", transition("", entity.HostStateInstalled), errors.ERepositoryConflict},
+		{"KO_UNKNOWN", transition("", "unknown"), errors.ERepositoryConflict},
+		{"OK_APPROVED", transition(entity.HostStateDiscovered, entity.HostStateReady), ""},
+		{"OK_INSTALLING", transition("", entity.HostStateInstalling), ""},
+		{"OK_INSTALLED", transition(entity.HostStateInstalling, entity.HostStateInstalled), ""},
+		{"KO_INSTALLED_AGAIN", transition("", entity.HostStateInstalled), errors.ERepositoryConflict},
+		{"KO_HOST_NOT_FOUND", func(s Session) error {
+			_, err := TransitionHost(s, "sh2", "", entity.HostStateReady, entity.TransitionTriggerAPI, "")
+			return err
+		}, errors.ERepositoryKeyNotFound},
+	}
+	for _, tt := range tests {
+		err := m.Write(tt.call)
+		if tt.wantCode == "" && err != nil {
+			t.Errorf("runHostStateTest %v - unexpected error %v", tt.name, err)
+		}
+		if tt.wantCode != "" && !errors.Is(err, tt.wantCode) {
+			t.Errorf("runHostStateTest %v - got %v want %v", tt.name, err, tt.wantCode)
+		}
+	}
+
+	if err := m.Read(func(s Session) error {
+		h, err := s.Host().Get("sh")
+		if err != nil {
+			return err
+		}
+		want := []string{entity.HostStateDiscovered, entity.HostStateReady, entity.HostStateInstalling,
+			entity.HostStateInstalled}
+		if h.State != entity.HostStateInstalled || len(h.StateHistory) != len(want)-1 {
+			t.Fatalf("runHostStateTest - got %v %+v", h.State, h.StateHistory)
+		}
+		for i, tr := range h.StateHistory {
+			if tr.From != want[i] || tr.To != want[i+1] || tr.Trigger != entity.TransitionTriggerAPI ||
+				tr.Reason != "test" || tr.Time.IsZero() {
+				t.Errorf("runHostStateTest - transition %v got %+v", i, tr)
+			}
+		}
+		return nil
+	}); err != nil {
+		t.Error("runHostStateTest - error reading ", err)
+	}
+
+	expires := time.Unix(time.Now().Add(time.Hour).Unix(), 0)
+	callback := entity.CallbackResult{Time: time.Unix(1600000000, 0).UTC(), Status: entity.HostStateInstalled,
+		ClientIP: "10.0.0.2", Logs: "done"}
+	if err := m.Write(func(s Session) error {
+		h, err := s.Host().Get("sh")
+		if err != nil {
+			return err
+		}
+		h.CallbackToken, h.CallbackTokenExpires, h.LastCallback = "0a1b", expires, callback
+		return s.Host().Update(h)
+	}); err != nil {
+		t.Error("runHostStateTest - error updating ", err)
+	}
+	if err := m.Read(func(s Session) error {
+		h, err := s.Host().Get("sh")
+		if err != nil {
+			return err
+		}
+		if !h.ValidCallbackToken("0a1b", time.Now()) || !h.CallbackTokenExpires.Equal(expires) ||
+			!h.LastCallback.Time.Equal(callback.Time) || h.LastCallback.Logs != "done" ||
+			h.LastCallback.Status != callback.Status || h.LastCallback.ClientIP != callback.ClientIP {
+			t.Errorf("runHostStateTest - got callback %v %v %+v", h.CallbackToken, h.CallbackTokenExpires,
+				h.LastCallback)
+		}
+		return nil
+	}); err != nil {
+		t.Error("runHostStateTest - error reading ", err)
+	}
+	if err := m.Write(func(s Session) error { return s.Host().Delete(entity.Host{ID: "sh"}) }); err != nil {
+		t.Error("runHostStateTest - error deleting ", err)
+	}
+}
@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+	"testing"
+	"time"
+)
+
+func TestLeaseRepository(t *testing.T) {
+	runDriverTest(t, runIndividualLeaseCRUD)
+}
+
+func TestLeaseRepository_Reservation(t *testing.T) {
+	runDriverTest(t, runHostReservationTest)
+}
+
+func runIndividualLeaseCRUD(t *testing.T, m Repository) {
+	expires := time.Unix(1600000000, 0)
+	if err := m.Write(func(s Session) error {
+		if err := s.Lease().Create(entity.Lease{HardwareAddr: "00-00-00-00-00-02", IP: "10.0.0.2",
+			Hostname: "h2", Expires: expires}); err != nil {
+			return err
+		}
+		return s.Lease().Create(entity.Lease{HardwareAddr: "00-00-00-00-00-01", IP: "10.0.0.1", Expires: expires})
+	}); err != nil {
+		t.Fatal("runIndividualLeaseCRUD - error creating ", err)
+	}
+	tests := []struct {
+		name     string
+		f        func(s Session) error
+		wantCode string
+	}{
+		{"KO_DUPLICATED_KEY", func(s Session) error {
+			return s.Lease().Create(entity.Lease{HardwareAddr: "00-00-00-00-00-01", IP: "10.0.0.9"})
+		}, errors.ERepositoryKeyExist},
+		{"KO_DUPLICATED_IP", func(s Session) error {
+			return s.Lease().Create(entity.Lease{HardwareAddr: "00-00-00-00-00-09", IP: "10.0.0.1"})
+		}, errors.ERepositoryKeyExist},
+		{"KO_UPDATE_DUPLICATED_IP", func(s Session) error {
+			return s.Lease().Update(entity.Lease{HardwareAddr: "00-00-00-00-00-02", IP: "10.0.0.1"})
+		}, errors.ERepositoryKeyExist},
+		{"OK_UPDATE", func(s Session) error {
+			return s.Lease().Update(entity.Lease{HardwareAddr: "00-00-00-00-00-02", IP: "10.0.0.3",
+				Hostname: "h2", HostID: "host2", Expires: expires})
+		}, ""},
+		{"OK_GET", func(s Session) error {
+			l, err := s.Lease().Get("00-00-00-00-00-02")
+			if err != nil {
+				return err
+			}
+			if l.IP != "10.0.0.3" || l.HostID != "host2" || !l.Expires.Equal(expires) {
+				t.Error("Invalid stored data - ", l)
+			}
+			return nil
+		}, ""},
+		{"OK_FIND_BY_IP", func(s Session) error {
+			l, err := s.Lease().FindByIP("10.0.0.3")
+			if err != nil {
+				return err
+			}
+			if l.HardwareAddr != "00-00-00-00-00-02" {
+				t.Error("Invalid stored data - ", l)
+			}
+			return nil
+		}, ""},
+		{"KO_FIND_OLD_IP", func(s Session) error {
+			_, err := s.Lease().FindByIP("10.0.0.2")
+			return err
+		}, errors.ERepositoryKeyNotFound},
+		{"OK_LIST", func(s Session) error {
+			l, err := s.Lease().List("", 0)
+			if err != nil {
+				return err
+			}
+			if len(l) != 2 || l[0].HardwareAddr != "00-00-00-00-00-01" {
+				t.Error("Invalid list - ", l)
+			}
+			return nil
+		}, ""},
+		{"OK_DELETE", func(s Session) error {
+			return s.Lease().Delete(entity.Lease{HardwareAddr: "00-00-00-00-00-01"})
+		}, ""},
+		{"KO_GET_DELETED", func(s Session) error {
+			_, err := s.Lease().FindByIP("10.0.0.1")
+			return err
+		}, errors.ERepositoryKeyNotFound},
+	}
+	for _, tt := range tests {
+		err := m.Write(tt.f)
+		if tt.wantCode == "" && err != nil {
+			t.Errorf("runIndividualLeaseCRUD %v - unexpected error %v", tt.name, err)
+		}
+		if tt.wantCode != "" && !errors.Is(err, tt.wantCode) {
+			t.Errorf("runIndividualLeaseCRUD %v - got %v want %v", tt.name, err, tt.wantCode)
+		}
+	}
+}
+
+func runHostReservationTest(t *testing.T, m Repository) {
+	if err := m.Write(func(s Session) error {
+		return s.Host().Create(entity.Host{ID: "r1", HardwareAddr: []string{"00-00-00-00-00-r1"},
+			IP: "10.0.1.1", Hostname: "r1"})
+	}); err != nil {
+		t.Fatal("runHostReservationTest - error creating ", err)
+	}
+	if err := m.Write(func(s Session) error {
+		return s.Host().Create(entity.Host{ID: "r2", HardwareAddr: []string{"00-00-00-00-00-r2"}, IP: "10.0.1.1"})
+	}); !errors.Is(err, errors.ERepositoryKeyExist) {
+		t.Error("runHostReservationTest - duplicated IP got ", err)
+	}
+	if err := m.Read(func(s Session) error {
+		h, err := s.Host().Get("r1")
+		if err != nil {
+			return err
+		}
+		if h.IP != "10.0.1.1" || h.Hostname != "r1" {
+			t.Error("Invalid stored data - ", h)
+		}
+		if h, err = s.Host().FindByIP("10.0.1.1"); err != nil || h.ID != "r1" {
+			t.Error("runHostReservationTest - FindByIP got ", h.ID, err)
+		}
+		if _, err = s.Host().FindByIP("10.0.1.2"); !errors.Is(err, errors.ERepositoryKeyNotFound) {
+			t.Error("runHostReservationTest - FindByIP of a free IP got ", err)
+		}
+		return nil
+	}); err != nil {
+		t.Error("runHostReservationTest - error reading ", err)
+	}
+	if err := m.Write(func(s Session) error {
+		h, _ := s.Host().Get("r1")
+		h.Vars = map[string]interface{}{"a": "b"}
+		return s.Host().Update(h)
+	}); err != nil {
+		t.Error("runHostReservationTest - updating own reservation ", err)
+	}
+	if err := m.Write(func(s Session) error {
+		h, _ := s.Host().Get("r1")
+		h.IP = "10.0.1.2"
+		return s.Host().Update(h)
+	}); err != nil {
+		t.Error("runHostReservationTest - moving the reservation ", err)
+	}
+	if err := m.Read(func(s Session) error {
+		if _, err := s.Host().FindByIP("10.0.1.1"); !errors.Is(err, errors.ERepositoryKeyNotFound) {
+			t.Error("runHostReservationTest - FindByIP of the previous IP got ", err)
+		}
+		if h, err := s.Host().FindByIP("10.0.1.2"); err != nil || h.ID != "r1" {
+			t.Error("runHostReservationTest - FindByIP of the new IP got ", h.ID, err)
+		}
+		return nil
+	}); err != nil {
+		t.Error("runHostReservationTest - error reading ", err)
+	}
+	if err := m.Write(func(s Session) error {
+		return s.Host().Delete(entity.Host{ID: "r1"})
+	}); err != nil {
+		t.Error("runHostReservationTest - error deleting ", err)
+	}
+	if err := m.Read(func(s Session) error {
+		if _, err := s.Host().FindByIP("10.0.1.2"); !errors.Is(err, errors.ERepositoryKeyNotFound) {
+			t.Error("runHostReservationTest - FindByIP of a deleted host got ", err)
+		}
+		return nil
+	}); err != nil {
+		t.Error("runHostReservationTest - error reading ", err)
+	}
+}
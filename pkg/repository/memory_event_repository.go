@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+)
+
+// memoryEventRepository defines the CRUD procedure for entity.BootEvent
+type memoryEventRepository struct {
+	session Session
+	config  MemoryConfig
+	events  map[string][]entity.BootEvent
+}
+
+// newMemoryEventRepository instantiates a new repository for entity.BootEvent
+func newMemoryEventRepository(s Session, config MemoryConfig, events map[string][]entity.BootEvent) *EventRepository {
+	var er EventRepository
+	er = &memoryEventRepository{
+		s,
+		config,
+		events,
+	}
+	return &er
+}
+
+// Create implements repository.EventRepository interface
+func (r *memoryEventRepository) Create(event entity.BootEvent) error {
+	if r.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	history := r.events[event.HostID]
+	latest := 0
+	if len(history) > 0 {
+		latest = history[len(history)-1].Sequence
+	}
+	events := make([]entity.BootEvent, len(history), len(history)+1)
+	copy(events, history)
+	r.events[event.HostID] = keptBootEvents(append(events, nextBootEvent(latest, event)))
+	return nil
+}
+
+// List implements repository.EventRepository interface
+func (r *memoryEventRepository) List(hostID string) ([]entity.BootEvent, error) {
+	return reversedBootEvents(r.events[hostID]), nil
+}
+
+// Recent implements repository.EventRepository interface
+func (r *memoryEventRepository) Recent(limit int) ([]entity.BootEvent, error) {
+	l := make([]entity.BootEvent, 0)
+	for _, history := range r.events {
+		l = append(l, history...)
+	}
+	return newestBootEvents(l, limit), nil
+}
+
+// Delete implements repository.EventRepository interface
+func (r *memoryEventRepository) Delete(hostID string) error {
+	if r.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	delete(r.events, hostID)
+	return nil
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+)
+
+// memoryFactsRepository defines the CRUD procedure for entity.Facts
+type memoryFactsRepository struct {
+	session Session
+	config  MemoryConfig
+	facts   map[string][]entity.Facts
+}
+
+// newMemoryFactsRepository instantiates a new repository for entity.Facts
+func newMemoryFactsRepository(s Session, config MemoryConfig, facts map[string][]entity.Facts) *FactsRepository {
+	var fr FactsRepository
+	fr = &memoryFactsRepository{
+		s,
+		config,
+		facts,
+	}
+	return &fr
+}
+
+// Create implements repository.FactsRepository interface
+func (f *memoryFactsRepository) Create(facts entity.Facts) error {
+	if f.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	if facts.HostID == "" {
+		return &errors.Error{Code: errors.ERepositoryEmptyKey,
+			Msg: "entity.Facts host key is empty"}
+	}
+	if err := checkFactsHost(f.session, facts); err != nil {
+		return err
+	}
+	history := f.facts[facts.HostID]
+	latest := 0
+	if len(history) > 0 {
+		latest = history[len(history)-1].Revision
+	}
+	revisions := make([]entity.Facts, len(history), len(history)+1)
+	copy(revisions, history)
+	f.facts[facts.HostID] = keptFactsRevisions(append(revisions, nextFactsRevision(latest, facts)))
+	return nil
+}
+
+// Get implements repository.FactsRepository interface
+func (f *memoryFactsRepository) Get(hostID string) (entity.Facts, error) {
+	history, ok := f.facts[hostID]
+	if !ok || len(history) == 0 {
+		return entity.Facts{}, factsNotFound(hostID)
+	}
+	return history[len(history)-1], nil
+}
+
+// GetRevision implements repository.FactsRepository interface
+func (f *memoryFactsRepository) GetRevision(hostID string, revision int) (entity.Facts, error) {
+	for _, r := range f.facts[hostID] {
+		if r.Revision == revision {
+			return r, nil
+		}
+	}
+	return entity.Facts{}, factsRevisionNotFound(hostID, revision)
+}
+
+// ListRevisions implements repository.FactsRepository interface
+func (f *memoryFactsRepository) ListRevisions(hostID string) ([]entity.Facts, error) {
+	history, ok := f.facts[hostID]
+	if !ok || len(history) == 0 {
+		return nil, factsNotFound(hostID)
+	}
+	l := make([]entity.Facts, len(history))
+	copy(l, history)
+	return l, nil
+}
+
+// Delete implements repository.FactsRepository interface
+func (f *memoryFactsRepository) Delete(hostID string) error {
+	if f.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	if _, ok := f.facts[hostID]; !ok {
+		return factsNotFound(hostID)
+	}
+	delete(f.facts, hostID)
+	return nil
+}
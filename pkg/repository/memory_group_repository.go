@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/pxecore/pxecore/pkg/entity"
 	"github.com/pxecore/pxecore/pkg/errors"
+	"sort"
 )
 
 // GroupRepository defines the CRUD procedure for entity.Group
@@ -34,17 +35,17 @@ func (h *memoryGroupRepository) Create(Group entity.Group) error {
 		return &errors.Error{Code: errors.ERepositoryEmptyKey,
 			Msg: "entity.Group key is empty"}
 	}
+	if _, ok := h.groups[e.ID]; ok {
+		return &errors.Error{Code: errors.ERepositoryKeyExist,
+			Msg: fmt.Sprintf("entity.Group key %v already exists ", e.ID)}
+	}
 	if e.ParentID != "" {
 		parent, ok := h.groups[e.ParentID]
 		if !ok {
 			return &errors.Error{Code: errors.ERepositoryKeyNotFound,
 				Msg: fmt.Sprintf("repository.memoryGroupRepository parent group %v does't exist.", e.ParentID)}
 		}
-		parent.AddGroup(e.ParentID)
-	}
-	if _, ok := h.groups[e.ID]; ok {
-		return &errors.Error{Code: errors.ERepositoryKeyExist,
-			Msg: fmt.Sprintf("entity.Group key %v already exists ", e.ID)}
+		parent.AddGroup(e.ID)
 	}
 	if e.HostsIDs == nil {
 		e.HostsIDs = make([]string, 0)
@@ -80,18 +81,17 @@ func (h *memoryGroupRepository) Update(Group entity.Group) error {
 		return &errors.Error{Code: errors.ERepositoryKeyNotFound,
 			Msg: fmt.Sprintf("entity.Group key %v not found ", e.ID)}
 	}
-	if e.ParentID != "" && e.ParentID != og.ParentID {
-		if _, ok := h.groups[e.ParentID]; !ok {
-			return &errors.Error{Code: errors.ERepositoryKeyNotFound,
-				Msg: fmt.Sprintf("entity.Group key %v not found ", e.ID)}
-		}
-		if ogp, ok := h.groups[og.ParentID]; ok {
-			og.RemoveGroup(e.ID)
-			h.groups[e.ParentID] = ogp
+	if e.ParentID != og.ParentID {
+		if e.ParentID != "" {
+			np, ok := h.groups[e.ParentID]
+			if !ok {
+				return &errors.Error{Code: errors.ERepositoryKeyNotFound,
+					Msg: fmt.Sprintf("entity.Group key %v not found ", e.ParentID)}
+			}
+			np.AddGroup(e.ID)
 		}
-		if ngp, ok := h.groups[e.ParentID]; ok {
-			og.AddGroup(e.ID)
-			h.groups[e.ParentID] = ngp
+		if op, ok := h.groups[og.ParentID]; ok {
+			op.RemoveGroup(e.ID)
 		}
 	}
 	if e.HostsIDs == nil {
@@ -119,12 +119,31 @@ func (h *memoryGroupRepository) Delete(Group entity.Group) error {
 		return &errors.Error{Code: errors.ERepositoryKeyNotFound,
 			Msg: fmt.Sprintf("entity.Group key %v not found ", e.ID)}
 	}
-	if e.ParentID != "" {
-		if ogp, ok := h.groups[e.ParentID]; ok {
-			e.RemoveHost(e.ID)
-			h.groups[e.ParentID] = ogp
-		}
+	if err := checkGroupDependencies(h.session, oe.ID); err != nil {
+		return err
+	}
+	if ogp, ok := h.groups[oe.ParentID]; ok {
+		ogp.RemoveGroup(oe.ID)
 	}
 	delete(h.groups, oe.ID)
 	return nil
 }
+
+// List implements repository.GroupRepository interface
+func (h *memoryGroupRepository) List(after string, limit int) ([]entity.Group, error) {
+	keys := make([]string, 0, len(h.groups))
+	for k := range h.groups {
+		if k > after {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	if limit > 0 && len(keys) > limit {
+		keys = keys[:limit]
+	}
+	l := make([]entity.Group, 0, len(keys))
+	for _, k := range keys {
+		l = append(l, *h.groups[k])
+	}
+	return l, nil
+}
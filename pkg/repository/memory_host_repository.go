@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/pxecore/pxecore/pkg/entity"
 	"github.com/pxecore/pxecore/pkg/errors"
+	"sort"
 )
 
 // HostRepository defines the CRUD procedure for entity.Host
@@ -12,6 +13,8 @@ type memoryHostRepository struct {
 	config            MemoryConfig
 	hosts             map[string]*entity.Host
 	hardwareAddrIndex map[string]*entity.Host
+	identifierIndex   map[string]*entity.Host
+	ipIndex           map[string]*entity.Host
 }
 
 // NewHostRepository instantiates a new repository for entity.Host
@@ -19,13 +22,17 @@ func newMemoryHostRepository(
 	s Session,
 	config MemoryConfig,
 	hosts map[string]*entity.Host,
-	hardwareAddrIndex map[string]*entity.Host) *HostRepository {
+	hardwareAddrIndex map[string]*entity.Host,
+	identifierIndex map[string]*entity.Host,
+	ipIndex map[string]*entity.Host) *HostRepository {
 	var hr HostRepository
 	hr = &memoryHostRepository{
 		s,
 		config,
 		hosts,
 		hardwareAddrIndex,
+		identifierIndex,
+		ipIndex,
 	}
 	return &hr
 }
@@ -50,33 +57,39 @@ func (h *memoryHostRepository) Create(host entity.Host) error {
 				Msg: fmt.Sprintf("entity.Host HardwareAddr %v already exists ", e)}
 		}
 	}
+	for _, k := range hostIdentifierKeys(e) {
+		if _, ok := h.identifierIndex[k]; ok {
+			return hostIdentifierExist(k)
+		}
+	}
 
 	var err error
+	if err := checkHostReservation(h.session, e); err != nil {
+		return err
+	}
 	if e.TemplateID != "" {
 		if _, err = h.session.Template().Get(e.TemplateID); err != nil {
 			return &errors.Error{Code: errors.ERepositoryKeyNotFound,
 				Msg: fmt.Sprintf("entity.Host TemplateID %v not found.", e)}
 		}
 	}
-	if e.GroupID != "" {
-		g, err := h.session.Group().Get(e.GroupID)
-		if g, err = h.session.Group().Get(e.GroupID); err != nil {
-			return &errors.Error{Code: errors.ERepositoryKeyNotFound,
-				Msg: fmt.Sprintf("entity.Host GroupID %v not found.", e),
-				Err: err}
-		}
-		g.AddHost(e.ID)
-		if err := h.session.Group().Update(g); err != nil {
-			return &errors.Error{Code: errors.EUnknown,
-				Msg: fmt.Sprintf("entity.Host GroupID %v can't be updated.", e),
-				Err: err}
-		}
+	if err := checkHostGroups(h.session, e); err != nil {
+		return err
+	}
+	if err := updateHostGroups(h.session, e.ID, nil, e.Groups()); err != nil {
+		return err
 	}
 
 	h.hosts[e.ID] = &e
 	for _, m := range e.HardwareAddr {
 		h.hardwareAddrIndex[m] = &e
 	}
+	for _, k := range hostIdentifierKeys(e) {
+		h.identifierIndex[k] = &e
+	}
+	if e.IP != "" {
+		h.ipIndex[e.IP] = &e
+	}
 	return nil
 }
 
@@ -98,6 +111,22 @@ func (h *memoryHostRepository) FindByHardwareAddr(hardwareAddr string) (entity.H
 		Msg: fmt.Sprintf("entity.Host key %v not found", hardwareAddr)}
 }
 
+// FindByIdentifier implements repository.HostRepository interface
+func (h *memoryHostRepository) FindByIdentifier(kind string, value string) (entity.Host, error) {
+	if val, ok := h.identifierIndex[hostIdentifierKey(kind, value)]; ok {
+		return *val, nil
+	}
+	return entity.Host{}, hostIdentifierNotFound(kind, value)
+}
+
+// FindByIP implements repository.HostRepository interface
+func (h *memoryHostRepository) FindByIP(ip string) (entity.Host, error) {
+	if val, ok := h.ipIndex[ip]; ok {
+		return *val, nil
+	}
+	return entity.Host{}, hostIPNotFound(ip)
+}
+
 // Update implements repository.HostRepository interface
 func (h *memoryHostRepository) Update(host entity.Host) error {
 	if h.session.IsReadOnly() {
@@ -121,6 +150,14 @@ func (h *memoryHostRepository) Update(host entity.Host) error {
 			}
 		}
 	}
+	for _, k := range hostIdentifierKeys(e) {
+		if val, ok := h.identifierIndex[k]; ok && val.ID != e.ID {
+			return hostIdentifierExist(k)
+		}
+	}
+	if err := checkHostReservation(h.session, e); err != nil {
+		return err
+	}
 	if e.TemplateID != "" {
 		if _, err := h.session.Template().Get(e.TemplateID); err != nil {
 			return &errors.Error{Code: errors.ERepositoryKeyNotFound,
@@ -128,32 +165,13 @@ func (h *memoryHostRepository) Update(host entity.Host) error {
 		}
 	}
 
-	if e.GroupID != "" {
-		g, err := h.session.Group().Get(e.GroupID)
-		if err != nil {
-			return &errors.Error{Code: errors.ERepositoryKeyNotFound,
-				Msg: fmt.Sprintf("entity.Host GroupID %v not found.", e),
-				Err: err}
-		}
-		g.AddHost(e.ID)
-		if err := h.session.Group().Update(g); err != nil {
-			return &errors.Error{Code: errors.EUnknown,
-				Msg: fmt.Sprintf("entity.Host GroupID %v can't be updated.", e),
-				Err: err}
-		}
+	if err := checkHostGroups(h.session, e); err != nil {
+		return err
 	}
-
-	if oe.GroupID != "" {
-		g, err := h.session.Group().Get(oe.GroupID)
-		if err == nil {
-			g.RemoveHost(e.ID)
-			if err := h.session.Group().Update(g); err != nil {
-				return &errors.Error{Code: errors.EUnknown,
-					Msg: fmt.Sprintf("entity.Host GroupID %v can't be removed from the leaving group.", e),
-					Err: err}
-			}
-		}
+	if err := updateHostGroups(h.session, e.ID, oe.Groups(), e.Groups()); err != nil {
+		return err
 	}
+
 	h.hosts[e.ID] = &e
 	for _, val := range oe.HardwareAddr {
 		delete(h.hardwareAddrIndex, val)
@@ -161,6 +179,16 @@ func (h *memoryHostRepository) Update(host entity.Host) error {
 	for _, m := range e.HardwareAddr {
 		h.hardwareAddrIndex[m] = &e
 	}
+	for _, k := range hostIdentifierKeys(*oe) {
+		delete(h.identifierIndex, k)
+	}
+	for _, k := range hostIdentifierKeys(e) {
+		h.identifierIndex[k] = &e
+	}
+	delete(h.ipIndex, oe.IP)
+	if e.IP != "" {
+		h.ipIndex[e.IP] = &e
+	}
 	return nil
 }
 
@@ -179,25 +207,41 @@ func (h *memoryHostRepository) Delete(host entity.Host) error {
 		return &errors.Error{Code: errors.ERepositoryKeyNotFound,
 			Msg: fmt.Sprintf("entity.Host key %v not found ", e.ID)}
 	}
-	for _, e := range e.HardwareAddr {
-		if _, ok := h.hardwareAddrIndex[e]; ok {
-			return &errors.Error{Code: errors.ERepositoryKeyExist,
-				Msg: fmt.Sprintf("entity.Host HardwareAddr %v already exists ", e)}
-		}
-	}
 	for _, val := range oe.HardwareAddr {
 		delete(h.hardwareAddrIndex, val)
 	}
-	if oe.GroupID != "" {
-		if g, err := h.session.Group().Get(oe.GroupID); err == nil {
-			g.RemoveHost(e.ID)
-			if err := h.session.Group().Update(g); err != nil {
-				return &errors.Error{Code: errors.EUnknown,
-					Msg: fmt.Sprintf("entity.Host GroupID %v can't be removed from the leaving group.", e),
-					Err: err}
-			}
-		}
+	for _, k := range hostIdentifierKeys(*oe) {
+		delete(h.identifierIndex, k)
+	}
+	delete(h.ipIndex, oe.IP)
+	if err := updateHostGroups(h.session, oe.ID, oe.Groups(), nil); err != nil {
+		return err
+	}
+	if err := deleteHostFacts(h.session, oe.ID); err != nil {
+		return err
+	}
+	if err := deleteHostEvents(h.session, oe.ID); err != nil {
+		return err
 	}
 	delete(h.hosts, oe.ID)
 	return nil
 }
+
+// List implements repository.HostRepository interface
+func (h *memoryHostRepository) List(after string, limit int) ([]entity.Host, error) {
+	keys := make([]string, 0, len(h.hosts))
+	for k := range h.hosts {
+		if k > after {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	if limit > 0 && len(keys) > limit {
+		keys = keys[:limit]
+	}
+	l := make([]entity.Host, 0, len(keys))
+	for _, k := range keys {
+		l = append(l, *h.hosts[k])
+	}
+	return l, nil
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"fmt"
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+	"sort"
+)
+
+// memoryLeaseRepository defines the CRUD procedure for entity.Lease
+type memoryLeaseRepository struct {
+	session Session
+	config  MemoryConfig
+	leases  map[string]*entity.Lease
+	ipIndex map[string]*entity.Lease
+}
+
+// newMemoryLeaseRepository instantiates a new repository for entity.Lease
+func newMemoryLeaseRepository(s Session, config MemoryConfig, leases map[string]*entity.Lease,
+	ipIndex map[string]*entity.Lease) *LeaseRepository {
+	var lr LeaseRepository
+	lr = &memoryLeaseRepository{
+		s,
+		config,
+		leases,
+		ipIndex,
+	}
+	return &lr
+}
+
+// Create implements repository.LeaseRepository interface
+func (l *memoryLeaseRepository) Create(lease entity.Lease) error {
+	if l.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	e := lease
+	if e.HardwareAddr == "" {
+		return &errors.Error{Code: errors.ERepositoryEmptyKey,
+			Msg: "entity.Lease key is empty"}
+	}
+	if _, ok := l.leases[e.HardwareAddr]; ok {
+		return &errors.Error{Code: errors.ERepositoryKeyExist,
+			Msg: fmt.Sprintf("entity.Lease key %v already exists ", e.HardwareAddr)}
+	}
+	if _, ok := l.ipIndex[e.IP]; ok {
+		return &errors.Error{Code: errors.ERepositoryKeyExist,
+			Msg: fmt.Sprintf("entity.Lease IP %v already exists ", e.IP)}
+	}
+	l.leases[e.HardwareAddr] = &e
+	l.ipIndex[e.IP] = &e
+	return nil
+}
+
+// Get implements repository.LeaseRepository interface
+func (l *memoryLeaseRepository) Get(hardwareAddr string) (entity.Lease, error) {
+	if val, ok := l.leases[hardwareAddr]; ok {
+		return *val, nil
+	}
+	return entity.Lease{}, &errors.Error{Code: errors.ERepositoryKeyNotFound,
+		Msg: fmt.Sprintf("entity.Lease key %v not found", hardwareAddr)}
+}
+
+// FindByIP implements repository.LeaseRepository interface
+func (l *memoryLeaseRepository) FindByIP(ip string) (entity.Lease, error) {
+	if val, ok := l.ipIndex[ip]; ok {
+		return *val, nil
+	}
+	return entity.Lease{}, &errors.Error{Code: errors.ERepositoryKeyNotFound,
+		Msg: fmt.Sprintf("entity.Lease IP %v not found", ip)}
+}
+
+// Update implements repository.LeaseRepository interface
+func (l *memoryLeaseRepository) Update(lease entity.Lease) error {
+	if l.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	e := lease
+	if e.HardwareAddr == "" {
+		return &errors.Error{Code: errors.ERepositoryEmptyKey,
+			Msg: "entity.Lease key is empty"}
+	}
+	oe, ok := l.leases[e.HardwareAddr]
+	if !ok {
+		return &errors.Error{Code: errors.ERepositoryKeyNotFound,
+			Msg: fmt.Sprintf("entity.Lease key %v not found ", e.HardwareAddr)}
+	}
+	if val, ok := l.ipIndex[e.IP]; ok && val.HardwareAddr != e.HardwareAddr {
+		return &errors.Error{Code: errors.ERepositoryKeyExist,
+			Msg: fmt.Sprintf("entity.Lease IP %v already exists ", e.IP)}
+	}
+	delete(l.ipIndex, oe.IP)
+	l.leases[e.HardwareAddr] = &e
+	l.ipIndex[e.IP] = &e
+	return nil
+}
+
+// Delete implements repository.LeaseRepository interface
+func (l *memoryLeaseRepository) Delete(lease entity.Lease) error {
+	if l.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	if lease.HardwareAddr == "" {
+		return &errors.Error{Code: errors.ERepositoryEmptyKey,
+			Msg: "entity.Lease key is empty"}
+	}
+	oe, ok := l.leases[lease.HardwareAddr]
+	if !ok {
+		return &errors.Error{Code: errors.ERepositoryKeyNotFound,
+			Msg: fmt.Sprintf("entity.Lease key %v not found ", lease.HardwareAddr)}
+	}
+	delete(l.ipIndex, oe.IP)
+	delete(l.leases, oe.HardwareAddr)
+	return nil
+}
+
+// List implements repository.LeaseRepository interface
+func (l *memoryLeaseRepository) List(after string, limit int) ([]entity.Lease, error) {
+	keys := make([]string, 0, len(l.leases))
+	for k := range l.leases {
+		if k > after {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	if limit > 0 && len(keys) > limit {
+		keys = keys[:limit]
+	}
+	r := make([]entity.Lease, 0, len(keys))
+	for _, k := range keys {
+		r = append(r, *l.leases[k])
+	}
+	return r, nil
+}
@@ -14,8 +14,16 @@ type memoryRepository struct {
 	config            MemoryConfig
 	hosts             map[string]*entity.Host
 	hardwareAddrIndex map[string]*entity.Host
+	identifierIndex   map[string]*entity.Host
+	hostIPIndex       map[string]*entity.Host
 	groups            map[string]*entity.Group
 	templates         map[string]*entity.Template
+	templateRevisions map[string][]entity.TemplateRevision
+	leases            map[string]*entity.Lease
+	leaseIPIndex      map[string]*entity.Lease
+	rules             map[string]*entity.Rule
+	facts             map[string][]entity.Facts
+	events            map[string][]entity.BootEvent
 }
 
 func (m *memoryRepository) Open(write bool) (Session, error) {
@@ -52,8 +60,16 @@ func newMemoryRepository(config map[string]interface{}) (Repository, error) {
 	r.lock = new(sync.RWMutex)
 	r.hosts = make(map[string]*entity.Host)
 	r.hardwareAddrIndex = make(map[string]*entity.Host)
+	r.identifierIndex = make(map[string]*entity.Host)
+	r.hostIPIndex = make(map[string]*entity.Host)
 	r.groups = make(map[string]*entity.Group)
 	r.templates = make(map[string]*entity.Template)
+	r.templateRevisions = make(map[string][]entity.TemplateRevision)
+	r.leases = make(map[string]*entity.Lease)
+	r.leaseIPIndex = make(map[string]*entity.Lease)
+	r.rules = make(map[string]*entity.Rule)
+	r.facts = make(map[string][]entity.Facts)
+	r.events = make(map[string][]entity.BootEvent)
 	return ri, nil
 }
 
@@ -69,6 +85,10 @@ type MemorySession struct {
 	hostRepository     *HostRepository
 	templateRepository *TemplateRepository
 	groupRepository    *GroupRepository
+	leaseRepository    *LeaseRepository
+	ruleRepository     *RuleRepository
+	factsRepository    *FactsRepository
+	eventRepository    *EventRepository
 }
 
 // Close terminates the session
@@ -92,7 +112,9 @@ func (m *MemorySession) Host() HostRepository {
 			m,
 			m.config,
 			m.repository.hosts,
-			m.repository.hardwareAddrIndex)
+			m.repository.hardwareAddrIndex,
+			m.repository.identifierIndex,
+			m.repository.hostIPIndex)
 	}
 	return *m.hostRepository
 }
@@ -100,7 +122,8 @@ func (m *MemorySession) Host() HostRepository {
 // Template returns TemplateRepository
 func (m *MemorySession) Template() TemplateRepository {
 	if m.templateRepository == nil {
-		m.templateRepository = newMemoryTemplateRepository(m, m.config, m.repository.templates)
+		m.templateRepository = newMemoryTemplateRepository(m, m.config, m.repository.templates,
+			m.repository.templateRevisions)
 	}
 	return *m.templateRepository
 }
@@ -116,6 +139,42 @@ func (m *MemorySession) Group() GroupRepository {
 	return *m.groupRepository
 }
 
+// Lease returns LeaseRepository
+func (m *MemorySession) Lease() LeaseRepository {
+	if m.leaseRepository == nil {
+		m.leaseRepository = newMemoryLeaseRepository(
+			m,
+			m.config,
+			m.repository.leases,
+			m.repository.leaseIPIndex)
+	}
+	return *m.leaseRepository
+}
+
+// Rule returns RuleRepository
+func (m *MemorySession) Rule() RuleRepository {
+	if m.ruleRepository == nil {
+		m.ruleRepository = newMemoryRuleRepository(m, m.config, m.repository.rules)
+	}
+	return *m.ruleRepository
+}
+
+// Facts returns FactsRepository
+func (m *MemorySession) Facts() FactsRepository {
+	if m.factsRepository == nil {
+		m.factsRepository = newMemoryFactsRepository(m, m.config, m.repository.facts)
+	}
+	return *m.factsRepository
+}
+
+// Event returns EventRepository
+func (m *MemorySession) Event() EventRepository {
+	if m.eventRepository == nil {
+		m.eventRepository = newMemoryEventRepository(m, m.config, m.repository.events)
+	}
+	return *m.eventRepository
+}
+
 // IsReadOnly returns true is the session is for read only.
 func (m *MemorySession) IsReadOnly() bool {
 	return m.readOnly
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"fmt"
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+	"sort"
+)
+
+// memoryRuleRepository defines the CRUD procedure for entity.Rule
+type memoryRuleRepository struct {
+	session Session
+	config  MemoryConfig
+	rules   map[string]*entity.Rule
+}
+
+// newMemoryRuleRepository instantiates a new repository for entity.Rule
+func newMemoryRuleRepository(s Session, config MemoryConfig, rules map[string]*entity.Rule) *RuleRepository {
+	var rr RuleRepository
+	rr = &memoryRuleRepository{
+		s,
+		config,
+		rules,
+	}
+	return &rr
+}
+
+// Create implements repository.RuleRepository interface
+func (r *memoryRuleRepository) Create(rule entity.Rule) error {
+	if r.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	e := rule
+	if e.ID == "" {
+		return &errors.Error{Code: errors.ERepositoryEmptyKey,
+			Msg: "entity.Rule key is empty"}
+	}
+	if _, ok := r.rules[e.ID]; ok {
+		return &errors.Error{Code: errors.ERepositoryKeyExist,
+			Msg: fmt.Sprintf("entity.Rule key %v already exists ", e.ID)}
+	}
+	if err := checkRuleTemplate(r.session, e); err != nil {
+		return err
+	}
+	r.rules[e.ID] = &e
+	return nil
+}
+
+// Get implements repository.RuleRepository interface
+func (r *memoryRuleRepository) Get(ID string) (entity.Rule, error) {
+	if val, ok := r.rules[ID]; ok {
+		return *val, nil
+	}
+	return entity.Rule{}, &errors.Error{Code: errors.ERepositoryKeyNotFound,
+		Msg: fmt.Sprintf("entity.Rule key %v not found", ID)}
+}
+
+// Update implements repository.RuleRepository interface
+func (r *memoryRuleRepository) Update(rule entity.Rule) error {
+	if r.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	e := rule
+	if e.ID == "" {
+		return &errors.Error{Code: errors.ERepositoryEmptyKey,
+			Msg: "entity.Rule key is empty"}
+	}
+	if _, ok := r.rules[e.ID]; !ok {
+		return &errors.Error{Code: errors.ERepositoryKeyNotFound,
+			Msg: fmt.Sprintf("entity.Rule key %v not found ", e.ID)}
+	}
+	if err := checkRuleTemplate(r.session, e); err != nil {
+		return err
+	}
+	r.rules[e.ID] = &e
+	return nil
+}
+
+// Delete implements repository.RuleRepository interface
+func (r *memoryRuleRepository) Delete(rule entity.Rule) error {
+	if r.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	if rule.ID == "" {
+		return &errors.Error{Code: errors.ERepositoryEmptyKey,
+			Msg: "entity.Rule key is empty"}
+	}
+	if _, ok := r.rules[rule.ID]; !ok {
+		return &errors.Error{Code: errors.ERepositoryKeyNotFound,
+			Msg: fmt.Sprintf("entity.Rule key %v not found ", rule.ID)}
+	}
+	delete(r.rules, rule.ID)
+	return nil
+}
+
+// List implements repository.RuleRepository interface
+func (r *memoryRuleRepository) List(after string, limit int) ([]entity.Rule, error) {
+	keys := make([]string, 0, len(r.rules))
+	for k := range r.rules {
+		if k > after {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	if limit > 0 && len(keys) > limit {
+		keys = keys[:limit]
+	}
+	l := make([]entity.Rule, 0, len(keys))
+	for _, k := range keys {
+		l = append(l, *r.rules[k])
+	}
+	return l, nil
+}
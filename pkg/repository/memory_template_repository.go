@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/pxecore/pxecore/pkg/entity"
 	"github.com/pxecore/pxecore/pkg/errors"
+	"sort"
 )
 
 // TemplateRepository defines the CRUD procedure for entity.Template
@@ -11,15 +12,18 @@ type memoryTemplateRepository struct {
 	session   Session
 	config    MemoryConfig
 	templates map[string]*entity.Template
+	revisions map[string][]entity.TemplateRevision
 }
 
 // NewTemplateRepository instantiates a new repository for entity.Template
-func newMemoryTemplateRepository(s Session, config MemoryConfig, templates map[string]*entity.Template) *TemplateRepository {
+func newMemoryTemplateRepository(s Session, config MemoryConfig, templates map[string]*entity.Template,
+	revisions map[string][]entity.TemplateRevision) *TemplateRepository {
 	var hr TemplateRepository
 	hr = &memoryTemplateRepository{
 		s,
 		config,
 		templates,
+		revisions,
 	}
 	return &hr
 }
@@ -38,7 +42,9 @@ func (h *memoryTemplateRepository) Create(template entity.Template) error {
 		return &errors.Error{Code: errors.ERepositoryKeyExist,
 			Msg: fmt.Sprintf("entity.Template key %v already exists ", e.ID)}
 	}
+	e, r := firstTemplateRevision(e)
 	h.templates[e.ID] = &e
+	h.revisions[e.ID] = []entity.TemplateRevision{r}
 	return nil
 }
 
@@ -61,11 +67,16 @@ func (h *memoryTemplateRepository) Update(template entity.Template) error {
 		return &errors.Error{Code: errors.ERepositoryEmptyKey,
 			Msg: "entity.Template key is empty"}
 	}
-	if _, ok := h.templates[e.ID]; !ok {
+	current, ok := h.templates[e.ID]
+	if !ok {
 		return &errors.Error{Code: errors.ERepositoryKeyNotFound,
 			Msg: fmt.Sprintf("entity.Template key %v not found ", e.ID)}
 	}
+	e, r := nextTemplateRevision(*current, e)
 	h.templates[e.ID] = &e
+	if r != nil {
+		h.revisions[e.ID] = append(h.revisions[e.ID], *r)
+	}
 	return nil
 }
 
@@ -84,6 +95,49 @@ func (h *memoryTemplateRepository) Delete(template entity.Template) error {
 		return &errors.Error{Code: errors.ERepositoryKeyNotFound,
 			Msg: fmt.Sprintf("entity.Template key %v not found ", e.ID)}
 	}
+	if err := checkTemplateDependencies(h.session, oe.ID); err != nil {
+		return err
+	}
 	delete(h.templates, oe.ID)
+	delete(h.revisions, oe.ID)
 	return nil
 }
+
+// List implements repository.TemplateRepository interface
+func (h *memoryTemplateRepository) List(after string, limit int) ([]entity.Template, error) {
+	keys := make([]string, 0, len(h.templates))
+	for k := range h.templates {
+		if k > after {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	if limit > 0 && len(keys) > limit {
+		keys = keys[:limit]
+	}
+	l := make([]entity.Template, 0, len(keys))
+	for _, k := range keys {
+		l = append(l, *h.templates[k])
+	}
+	return l, nil
+}
+
+// GetRevision implements repository.TemplateRepository interface
+func (h *memoryTemplateRepository) GetRevision(ID string, revision int) (entity.TemplateRevision, error) {
+	for _, r := range h.revisions[ID] {
+		if r.Revision == revision {
+			return r, nil
+		}
+	}
+	return entity.TemplateRevision{}, templateRevisionNotFound(ID, revision)
+}
+
+// ListRevisions implements repository.TemplateRepository interface
+func (h *memoryTemplateRepository) ListRevisions(ID string) ([]entity.TemplateRevision, error) {
+	if _, err := h.Get(ID); err != nil {
+		return nil, err
+	}
+	l := make([]entity.TemplateRevision, len(h.revisions[ID]))
+	copy(l, h.revisions[ID])
+	return l, nil
+}
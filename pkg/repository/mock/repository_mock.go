@@ -184,6 +184,62 @@ func (mr *MockSessionMockRecorder) Group() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Group", reflect.TypeOf((*MockSession)(nil).Group))
 }
 
+// Lease mocks base method
+func (m *MockSession) Lease() repository.LeaseRepository {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Lease")
+	ret0, _ := ret[0].(repository.LeaseRepository)
+	return ret0
+}
+
+// Lease indicates an expected call of Lease
+func (mr *MockSessionMockRecorder) Lease() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Lease", reflect.TypeOf((*MockSession)(nil).Lease))
+}
+
+// Rule mocks base method
+func (m *MockSession) Rule() repository.RuleRepository {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Rule")
+	ret0, _ := ret[0].(repository.RuleRepository)
+	return ret0
+}
+
+// Rule indicates an expected call of Rule
+func (mr *MockSessionMockRecorder) Rule() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Rule", reflect.TypeOf((*MockSession)(nil).Rule))
+}
+
+// Facts mocks base method
+func (m *MockSession) Facts() repository.FactsRepository {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Facts")
+	ret0, _ := ret[0].(repository.FactsRepository)
+	return ret0
+}
+
+// Facts indicates an expected call of Facts
+func (mr *MockSessionMockRecorder) Facts() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Facts", reflect.TypeOf((*MockSession)(nil).Facts))
+}
+
+// Event mocks base method
+func (m *MockSession) Event() repository.EventRepository {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Event")
+	ret0, _ := ret[0].(repository.EventRepository)
+	return ret0
+}
+
+// Event indicates an expected call of Event
+func (mr *MockSessionMockRecorder) Event() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Event", reflect.TypeOf((*MockSession)(nil).Event))
+}
+
 // MockHostRepository is a mock of HostRepository interface
 type MockHostRepository struct {
 	ctrl     *gomock.Controller
@@ -251,6 +307,36 @@ func (mr *MockHostRepositoryMockRecorder) FindByHardwareAddr(hardwareAddr interf
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByHardwareAddr", reflect.TypeOf((*MockHostRepository)(nil).FindByHardwareAddr), hardwareAddr)
 }
 
+// FindByIdentifier mocks base method
+func (m *MockHostRepository) FindByIdentifier(kind, value string) (entity.Host, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByIdentifier", kind, value)
+	ret0, _ := ret[0].(entity.Host)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByIdentifier indicates an expected call of FindByIdentifier
+func (mr *MockHostRepositoryMockRecorder) FindByIdentifier(kind, value interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByIdentifier", reflect.TypeOf((*MockHostRepository)(nil).FindByIdentifier), kind, value)
+}
+
+// FindByIP mocks base method
+func (m *MockHostRepository) FindByIP(ip string) (entity.Host, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByIP", ip)
+	ret0, _ := ret[0].(entity.Host)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByIP indicates an expected call of FindByIP
+func (mr *MockHostRepositoryMockRecorder) FindByIP(ip interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByIP", reflect.TypeOf((*MockHostRepository)(nil).FindByIP), ip)
+}
+
 // Update mocks base method
 func (m *MockHostRepository) Update(host entity.Host) error {
 	m.ctrl.T.Helper()
@@ -279,6 +365,21 @@ func (mr *MockHostRepositoryMockRecorder) Delete(host interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockHostRepository)(nil).Delete), host)
 }
 
+// List mocks base method
+func (m *MockHostRepository) List(after string, limit int) ([]entity.Host, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", after, limit)
+	ret0, _ := ret[0].([]entity.Host)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List
+func (mr *MockHostRepositoryMockRecorder) List(after, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockHostRepository)(nil).List), after, limit)
+}
+
 // MockGroupRepository is a mock of GroupRepository interface
 type MockGroupRepository struct {
 	ctrl     *gomock.Controller
@@ -359,6 +460,21 @@ func (mr *MockGroupRepositoryMockRecorder) Delete(host interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockGroupRepository)(nil).Delete), host)
 }
 
+// List mocks base method
+func (m *MockGroupRepository) List(after string, limit int) ([]entity.Group, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", after, limit)
+	ret0, _ := ret[0].([]entity.Group)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List
+func (mr *MockGroupRepositoryMockRecorder) List(after, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockGroupRepository)(nil).List), after, limit)
+}
+
 // MockTemplateRepository is a mock of TemplateRepository interface
 type MockTemplateRepository struct {
 	ctrl     *gomock.Controller
@@ -438,3 +554,430 @@ func (mr *MockTemplateRepositoryMockRecorder) Delete(host interface{}) *gomock.C
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockTemplateRepository)(nil).Delete), host)
 }
+
+// List mocks base method
+func (m *MockTemplateRepository) List(after string, limit int) ([]entity.Template, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", after, limit)
+	ret0, _ := ret[0].([]entity.Template)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List
+func (mr *MockTemplateRepositoryMockRecorder) List(after, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockTemplateRepository)(nil).List), after, limit)
+}
+
+// GetRevision mocks base method
+func (m *MockTemplateRepository) GetRevision(ID string, revision int) (entity.TemplateRevision, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRevision", ID, revision)
+	ret0, _ := ret[0].(entity.TemplateRevision)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRevision indicates an expected call of GetRevision
+func (mr *MockTemplateRepositoryMockRecorder) GetRevision(ID, revision interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRevision", reflect.TypeOf((*MockTemplateRepository)(nil).GetRevision), ID, revision)
+}
+
+// ListRevisions mocks base method
+func (m *MockTemplateRepository) ListRevisions(ID string) ([]entity.TemplateRevision, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListRevisions", ID)
+	ret0, _ := ret[0].([]entity.TemplateRevision)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListRevisions indicates an expected call of ListRevisions
+func (mr *MockTemplateRepositoryMockRecorder) ListRevisions(ID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListRevisions", reflect.TypeOf((*MockTemplateRepository)(nil).ListRevisions), ID)
+}
+
+// MockLeaseRepository is a mock of LeaseRepository interface
+type MockLeaseRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockLeaseRepositoryMockRecorder
+}
+
+// MockLeaseRepositoryMockRecorder is the mock recorder for MockLeaseRepository
+type MockLeaseRepositoryMockRecorder struct {
+	mock *MockLeaseRepository
+}
+
+// NewMockLeaseRepository creates a new mock instance
+func NewMockLeaseRepository(ctrl *gomock.Controller) *MockLeaseRepository {
+	mock := &MockLeaseRepository{ctrl: ctrl}
+	mock.recorder = &MockLeaseRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockLeaseRepository) EXPECT() *MockLeaseRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method
+func (m *MockLeaseRepository) Create(lease entity.Lease) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", lease)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create
+func (mr *MockLeaseRepositoryMockRecorder) Create(lease interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockLeaseRepository)(nil).Create), lease)
+}
+
+// Get mocks base method
+func (m *MockLeaseRepository) Get(hardwareAddr string) (entity.Lease, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", hardwareAddr)
+	ret0, _ := ret[0].(entity.Lease)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get
+func (mr *MockLeaseRepositoryMockRecorder) Get(hardwareAddr interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockLeaseRepository)(nil).Get), hardwareAddr)
+}
+
+// FindByIP mocks base method
+func (m *MockLeaseRepository) FindByIP(ip string) (entity.Lease, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByIP", ip)
+	ret0, _ := ret[0].(entity.Lease)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByIP indicates an expected call of FindByIP
+func (mr *MockLeaseRepositoryMockRecorder) FindByIP(ip interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByIP", reflect.TypeOf((*MockLeaseRepository)(nil).FindByIP), ip)
+}
+
+// Update mocks base method
+func (m *MockLeaseRepository) Update(lease entity.Lease) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", lease)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update
+func (mr *MockLeaseRepositoryMockRecorder) Update(lease interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockLeaseRepository)(nil).Update), lease)
+}
+
+// Delete mocks base method
+func (m *MockLeaseRepository) Delete(lease entity.Lease) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", lease)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete
+func (mr *MockLeaseRepositoryMockRecorder) Delete(lease interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockLeaseRepository)(nil).Delete), lease)
+}
+
+// List mocks base method
+func (m *MockLeaseRepository) List(after string, limit int) ([]entity.Lease, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", after, limit)
+	ret0, _ := ret[0].([]entity.Lease)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List
+func (mr *MockLeaseRepositoryMockRecorder) List(after, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockLeaseRepository)(nil).List), after, limit)
+}
+
+// MockRuleRepository is a mock of RuleRepository interface
+type MockRuleRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockRuleRepositoryMockRecorder
+}
+
+// MockRuleRepositoryMockRecorder is the mock recorder for MockRuleRepository
+type MockRuleRepositoryMockRecorder struct {
+	mock *MockRuleRepository
+}
+
+// NewMockRuleRepository creates a new mock instance
+func NewMockRuleRepository(ctrl *gomock.Controller) *MockRuleRepository {
+	mock := &MockRuleRepository{ctrl: ctrl}
+	mock.recorder = &MockRuleRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockRuleRepository) EXPECT() *MockRuleRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method
+func (m *MockRuleRepository) Create(rule entity.Rule) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", rule)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create
+func (mr *MockRuleRepositoryMockRecorder) Create(rule interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockRuleRepository)(nil).Create), rule)
+}
+
+// Get mocks base method
+func (m *MockRuleRepository) Get(ID string) (entity.Rule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ID)
+	ret0, _ := ret[0].(entity.Rule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get
+func (mr *MockRuleRepositoryMockRecorder) Get(ID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockRuleRepository)(nil).Get), ID)
+}
+
+// Update mocks base method
+func (m *MockRuleRepository) Update(rule entity.Rule) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", rule)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update
+func (mr *MockRuleRepositoryMockRecorder) Update(rule interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockRuleRepository)(nil).Update), rule)
+}
+
+// Delete mocks base method
+func (m *MockRuleRepository) Delete(rule entity.Rule) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", rule)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete
+func (mr *MockRuleRepositoryMockRecorder) Delete(rule interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockRuleRepository)(nil).Delete), rule)
+}
+
+// List mocks base method
+func (m *MockRuleRepository) List(after string, limit int) ([]entity.Rule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", after, limit)
+	ret0, _ := ret[0].([]entity.Rule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List
+func (mr *MockRuleRepositoryMockRecorder) List(after, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockRuleRepository)(nil).List), after, limit)
+}
+
+// MockFactsRepository is a mock of FactsRepository interface
+type MockFactsRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockFactsRepositoryMockRecorder
+}
+
+// MockFactsRepositoryMockRecorder is the mock recorder for MockFactsRepository
+type MockFactsRepositoryMockRecorder struct {
+	mock *MockFactsRepository
+}
+
+// NewMockFactsRepository creates a new mock instance
+func NewMockFactsRepository(ctrl *gomock.Controller) *MockFactsRepository {
+	mock := &MockFactsRepository{ctrl: ctrl}
+	mock.recorder = &MockFactsRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockFactsRepository) EXPECT() *MockFactsRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method
+func (m *MockFactsRepository) Create(facts entity.Facts) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", facts)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create
+func (mr *MockFactsRepositoryMockRecorder) Create(facts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockFactsRepository)(nil).Create), facts)
+}
+
+// Get mocks base method
+func (m *MockFactsRepository) Get(hostID string) (entity.Facts, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", hostID)
+	ret0, _ := ret[0].(entity.Facts)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get
+func (mr *MockFactsRepositoryMockRecorder) Get(hostID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockFactsRepository)(nil).Get), hostID)
+}
+
+// GetRevision mocks base method
+func (m *MockFactsRepository) GetRevision(hostID string, revision int) (entity.Facts, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRevision", hostID, revision)
+	ret0, _ := ret[0].(entity.Facts)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRevision indicates an expected call of GetRevision
+func (mr *MockFactsRepositoryMockRecorder) GetRevision(hostID, revision interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRevision", reflect.TypeOf((*MockFactsRepository)(nil).GetRevision), hostID, revision)
+}
+
+// ListRevisions mocks base method
+func (m *MockFactsRepository) ListRevisions(hostID string) ([]entity.Facts, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListRevisions", hostID)
+	ret0, _ := ret[0].([]entity.Facts)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListRevisions indicates an expected call of ListRevisions
+func (mr *MockFactsRepositoryMockRecorder) ListRevisions(hostID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListRevisions", reflect.TypeOf((*MockFactsRepository)(nil).ListRevisions), hostID)
+}
+
+// Delete mocks base method
+func (m *MockFactsRepository) Delete(hostID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", hostID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete
+func (mr *MockFactsRepositoryMockRecorder) Delete(hostID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockFactsRepository)(nil).Delete), hostID)
+}
+
+// MockEventRepository is a mock of EventRepository interface
+type MockEventRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockEventRepositoryMockRecorder
+}
+
+// MockEventRepositoryMockRecorder is the mock recorder for MockEventRepository
+type MockEventRepositoryMockRecorder struct {
+	mock *MockEventRepository
+}
+
+// NewMockEventRepository creates a new mock instance
+func NewMockEventRepository(ctrl *gomock.Controller) *MockEventRepository {
+	mock := &MockEventRepository{ctrl: ctrl}
+	mock.recorder = &MockEventRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockEventRepository) EXPECT() *MockEventRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method
+func (m *MockEventRepository) Create(event entity.BootEvent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", event)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create
+func (mr *MockEventRepositoryMockRecorder) Create(event interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockEventRepository)(nil).Create), event)
+}
+
+// List mocks base method
+func (m *MockEventRepository) List(hostID string) ([]entity.BootEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", hostID)
+	ret0, _ := ret[0].([]entity.BootEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List
+func (mr *MockEventRepositoryMockRecorder) List(hostID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockEventRepository)(nil).List), hostID)
+}
+
+// Recent mocks base method
+func (m *MockEventRepository) Recent(limit int) ([]entity.BootEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Recent", limit)
+	ret0, _ := ret[0].([]entity.BootEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Recent indicates an expected call of Recent
+func (mr *MockEventRepositoryMockRecorder) Recent(limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Recent", reflect.TypeOf((*MockEventRepository)(nil).Recent), limit)
+}
+
+// Delete mocks base method
+func (m *MockEventRepository) Delete(hostID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", hostID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete
+func (mr *MockEventRepositoryMockRecorder) Delete(hostID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockEventRepository)(nil).Delete), hostID)
+}
@@ -34,6 +34,14 @@ type Repository interface {
 // Host() returns entity.Host repository.
 //
 // Template() returns entity.Template repository.
+//
+// Lease() returns entity.Lease repository.
+//
+// Rule() returns entity.Rule repository.
+//
+// Facts() returns entity.Facts repository.
+//
+// Event() returns entity.BootEvent repository.
 type Session interface {
 	Close() error
 	IsReadOnly() bool
@@ -41,13 +49,17 @@ type Session interface {
 	Host() HostRepository
 	Template() TemplateRepository
 	Group() GroupRepository
+	Lease() LeaseRepository
+	Rule() RuleRepository
+	Facts() FactsRepository
+	Event() EventRepository
 }
 
 // HostRepository defines the CRUD procedure for entity.Host
 //
 // Create() adds a new entity.Host into the repository or returns error
 // errors.ERepositoryEmptyKey if the key is not provided,
-// errors.ERepositoryKeyExist if the key or HardwareAddr already exists in the repository.
+// errors.ERepositoryKeyExist if the key, HardwareAddr, identifier or IP already exists in the repository.
 //
 // Get() searches a entity.Host into by id or returns error
 // errors.ERepositoryKeyNotFound if the key is not found.
@@ -55,19 +67,31 @@ type Session interface {
 // FindByHardwareAddr() searches a entity.Host by HardwareAddr or returns error
 // errors.ERepositoryKeyNotFound if the HardwareAddr is not found.
 //
+// FindByIdentifier() searches a entity.Host by the SMBIOS identifier of a kind, see entity.Host.Identifiers,
+// or returns error errors.ERepositoryKeyNotFound if the identifier is not found.
+//
+// FindByIP() searches the entity.Host reserving an IP or returns error
+// errors.ERepositoryKeyNotFound if the IP is not reserved.
+//
 // Update() update an existing entity.Host or returns error
 // errors.ERepositoryEmptyKey if the key is not provided,
 // errors.ERepositoryKeyNotFound if the key is not found,
-// errors.ERepositoryKeyExist if the HardwareAddr already exists in the repository.
+// errors.ERepositoryKeyExist if the HardwareAddr, identifier or IP already exists in the repository.
 //
 // Delete() deletes an entry of entity.Host or returns error
 // errors.ERepositoryKeyNotFound if the HardwareAddr is not found.
+//
+// List() returns up to limit entity.Host sorted by ID whose ID is greater than after.
+// A limit lower or equal than 0 returns all the remaining entries.
 type HostRepository interface {
 	Create(host entity.Host) error
 	Get(ID string) (entity.Host, error)
 	FindByHardwareAddr(hardwareAddr string) (entity.Host, error)
+	FindByIdentifier(kind string, value string) (entity.Host, error)
+	FindByIP(ip string) (entity.Host, error)
 	Update(host entity.Host) error
 	Delete(host entity.Host) error
+	List(after string, limit int) ([]entity.Host, error)
 }
 
 // GroupRepository defines the CRUD procedure for entity.Group
@@ -84,35 +108,159 @@ type HostRepository interface {
 // errors.ERepositoryKeyNotFound if the key is not found
 //
 // Delete() deletes an entry of entity.Group or returns error
-// errors.ERepositoryKeyNotFound if the HardwareAddr is not found.
+// errors.ERepositoryKeyNotFound if the HardwareAddr is not found,
+// errors.ERepositoryDependency if an entity.Host or a child entity.Group still references it.
+//
+// List() returns up to limit entity.Group sorted by ID whose ID is greater than after.
+// A limit lower or equal than 0 returns all the remaining entries.
 type GroupRepository interface {
 	Create(host entity.Group) error
 	Get(ID string) (entity.Group, error)
 	Update(host entity.Group) error
 	Delete(host entity.Group) error
+	List(after string, limit int) ([]entity.Group, error)
 }
 
 // TemplateRepository defines the CRUD procedure for entity.Template
 //
-// Create() adds a new entity.Template into the repository or returns error
+// Every body stored is recorded as an immutable entity.TemplateRevision.
+//
+// Create() adds a new entity.Template into the repository as revision 1 or returns error
 // errors.ERepositoryEmptyKey if the key is not provided,
 // errors.ERepositoryKeyExist if the key or HardwareAddr already exists in the repository.
 //
 // Get() searches a entity.Template into by id or returns error
 // errors.ERepositoryKeyNotFound if the key is not found.
 //
-// Update() update an existing entity.Template or returns error
+// Update() update an existing entity.Template, recording a new revision if the body changed, or returns error
 // errors.ERepositoryEmptyKey if the key is not provided,
 // errors.ERepositoryKeyNotFound if the key is not found,
 // errors.ERepositoryKeyExist if the HardwareAddr already exists in the repository.
 //
-// Delete() deletes an entry of entity.Template or returns error
-// errors.ERepositoryKeyNotFound if the HardwareAddr is not found.
+// Delete() deletes an entry of entity.Template and its revisions or returns error
+// errors.ERepositoryKeyNotFound if the HardwareAddr is not found,
+// errors.ERepositoryDependency if an entity.Host or entity.Group still references it.
+//
+// List() returns up to limit entity.Template sorted by ID whose ID is greater than after.
+// A limit lower or equal than 0 returns all the remaining entries.
+//
+// GetRevision() searches a entity.TemplateRevision by template id and revision or returns error
+// errors.ERepositoryKeyNotFound if the revision is not found.
+//
+// ListRevisions() returns all the entity.TemplateRevision of a template sorted by revision or returns error
+// errors.ERepositoryKeyNotFound if the key is not found.
 type TemplateRepository interface {
 	Create(host entity.Template) error
 	Get(ID string) (entity.Template, error)
 	Update(host entity.Template) error
 	Delete(host entity.Template) error
+	List(after string, limit int) ([]entity.Template, error)
+	GetRevision(ID string, revision int) (entity.TemplateRevision, error)
+	ListRevisions(ID string) ([]entity.TemplateRevision, error)
+}
+
+// LeaseRepository defines the CRUD procedure for entity.Lease
+// Leases are identified by their HardwareAddr.
+//
+// Create() adds a new entity.Lease into the repository or returns error
+// errors.ERepositoryEmptyKey if the key is not provided,
+// errors.ERepositoryKeyExist if the key or IP already exists in the repository.
+//
+// Get() searches a entity.Lease by HardwareAddr or returns error
+// errors.ERepositoryKeyNotFound if the key is not found.
+//
+// FindByIP() searches a entity.Lease by IP or returns error
+// errors.ERepositoryKeyNotFound if the IP is not found.
+//
+// Update() update an existing entity.Lease or returns error
+// errors.ERepositoryEmptyKey if the key is not provided,
+// errors.ERepositoryKeyNotFound if the key is not found,
+// errors.ERepositoryKeyExist if the IP already exists in the repository.
+//
+// Delete() deletes an entry of entity.Lease or returns error
+// errors.ERepositoryKeyNotFound if the key is not found.
+//
+// List() returns up to limit entity.Lease sorted by HardwareAddr whose HardwareAddr is greater than after.
+// A limit lower or equal than 0 returns all the remaining entries.
+type LeaseRepository interface {
+	Create(lease entity.Lease) error
+	Get(hardwareAddr string) (entity.Lease, error)
+	FindByIP(ip string) (entity.Lease, error)
+	Update(lease entity.Lease) error
+	Delete(lease entity.Lease) error
+	List(after string, limit int) ([]entity.Lease, error)
+}
+
+// RuleRepository defines the CRUD procedure for entity.Rule
+//
+// Create() adds a new entity.Rule into the repository or returns error
+// errors.ERepositoryEmptyKey if the key is not provided,
+// errors.ERepositoryKeyExist if the key already exists in the repository,
+// errors.ERepositoryKeyNotFound if the TemplateID is not found.
+//
+// Get() searches a entity.Rule by id or returns error
+// errors.ERepositoryKeyNotFound if the key is not found.
+//
+// Update() update an existing entity.Rule or returns error
+// errors.ERepositoryEmptyKey if the key is not provided,
+// errors.ERepositoryKeyNotFound if the key or the TemplateID is not found.
+//
+// Delete() deletes an entry of entity.Rule or returns error
+// errors.ERepositoryKeyNotFound if the key is not found.
+//
+// List() returns up to limit entity.Rule sorted by ID whose ID is greater than after.
+// A limit lower or equal than 0 returns all the remaining entries.
+type RuleRepository interface {
+	Create(rule entity.Rule) error
+	Get(ID string) (entity.Rule, error)
+	Update(rule entity.Rule) error
+	Delete(rule entity.Rule) error
+	List(after string, limit int) ([]entity.Rule, error)
+}
+
+// FactsRepository stores the versioned entity.Facts of the hosts.
+// Only the last MaxFactsRevisions revisions of every host are kept.
+//
+// Create() records the facts as the next revision of the host, setting Revision and Created, or returns error
+// errors.ERepositoryEmptyKey if the HostID is not provided,
+// errors.ERepositoryKeyNotFound if the host is not found.
+//
+// Get() returns the latest entity.Facts of a host or returns error
+// errors.ERepositoryKeyNotFound if the host has no facts.
+//
+// GetRevision() searches the entity.Facts of a host by revision or returns error
+// errors.ERepositoryKeyNotFound if the revision is not found.
+//
+// ListRevisions() returns the kept entity.Facts of a host sorted by revision or returns error
+// errors.ERepositoryKeyNotFound if the host has no facts.
+//
+// Delete() deletes all the entity.Facts of a host or returns error
+// errors.ERepositoryKeyNotFound if the host has no facts.
+type FactsRepository interface {
+	Create(facts entity.Facts) error
+	Get(hostID string) (entity.Facts, error)
+	GetRevision(hostID string, revision int) (entity.Facts, error)
+	ListRevisions(hostID string) ([]entity.Facts, error)
+	Delete(hostID string) error
+}
+
+// EventRepository stores the entity.BootEvent of the hosts in bounded buffers.
+// Only the last MaxHostEvents events of every host are kept.
+//
+// Create() records the event as the next one of its host, setting Sequence and Time if empty.
+// Events with an empty HostID are kept together.
+//
+// List() returns the kept entity.BootEvent of a host newest first, empty if there is none.
+//
+// Recent() returns up to limit entity.BootEvent of all the hosts newest first.
+// A limit lower or equal than 0 returns all of them.
+//
+// Delete() deletes all the entity.BootEvent of a host, it is a no-op if there is none.
+type EventRepository interface {
+	Create(event entity.BootEvent) error
+	List(hostID string) ([]entity.BootEvent, error)
+	Recent(limit int) ([]entity.BootEvent, error)
+	Delete(hostID string) error
 }
 
 // NewRepository instantiates a new repository.
@@ -124,6 +272,12 @@ func NewRepository(config map[string]interface{}) (Repository, error) {
 			switch strings.ToLower(driver) {
 			case "memory":
 				return newMemoryRepository(config)
+			case "bolt":
+				return newBoltRepository(config)
+			case "sql":
+				return newSQLRepository(config)
+			case "directory":
+				return newDirectoryRepository(config)
 			}
 		}
 		return nil, errors.New("invalid type in repository driver")
@@ -3,13 +3,30 @@ package repository
 import (
 	"fmt"
 	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
 	"go.uber.org/atomic"
+	"io/ioutil"
+	"os"
 	"reflect"
 	"sync"
 	"testing"
 	"time"
 )
 
+var testDir string
+
+func TestMain(m *testing.M) {
+	d, err := ioutil.TempDir("", "pxecore-repository")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error creating test directory - ", err)
+		os.Exit(1)
+	}
+	testDir = d
+	c := m.Run()
+	_ = os.RemoveAll(testDir)
+	os.Exit(c)
+}
+
 func TestConcurrency(t *testing.T) {
 	repositories := [...]Repository{newMemoryRepositoryTest(t)}
 
@@ -33,6 +50,42 @@ func TestCRUD(t *testing.T) {
 	}
 }
 
+func TestDriverConcurrency(t *testing.T) {
+	repositories := [...]Repository{newBoltRepositoryTest(t), newSQLRepositoryTest(t), newDirectoryRepositoryTest(t)}
+
+	for _, repository := range repositories {
+		t.Run(fmt.Sprint("Test_", reflect.TypeOf(repository).Elem().Name()), func(t *testing.T) {
+			runOpenConcurrencyTest(t, repository)
+			runReadWriteConcurrencyTest(t, repository)
+		})
+	}
+}
+
+func TestDriverCRUD(t *testing.T) {
+	repositories := [...]Repository{newMemoryRepositoryTest(t), newBoltRepositoryTest(t),
+		newSQLRepositoryTest(t), newDirectoryRepositoryTest(t)}
+
+	for _, repository := range repositories {
+		t.Run(fmt.Sprint("Test_", reflect.TypeOf(repository).Elem().Name()), func(t *testing.T) {
+			runIndividualHostCRUD(t, repository)
+			runIndividualGroupCRUD(t, repository)
+			runIndividualTemplateCRUD(t, repository)
+		})
+	}
+}
+
+func TestListAndDelete(t *testing.T) {
+	repositories := [...]Repository{newMemoryRepositoryTest(t), newBoltRepositoryTest(t),
+		newSQLRepositoryTest(t), newDirectoryRepositoryTest(t)}
+
+	for _, repository := range repositories {
+		t.Run(fmt.Sprint("Test_", reflect.TypeOf(repository).Elem().Name()), func(t *testing.T) {
+			runListTest(t, repository)
+			runDependencyTest(t, repository)
+		})
+	}
+}
+
 func newMemoryRepositoryTest(t *testing.T) Repository {
 	m, err := newMemoryRepository(make(map[string]interface{}))
 	if err != nil {
@@ -41,6 +94,56 @@ func newMemoryRepositoryTest(t *testing.T) Repository {
 	return m
 }
 
+func newBoltRepositoryTest(t *testing.T) Repository {
+	f, err := ioutil.TempFile(testDir, "*.db")
+	if err != nil {
+		t.Fatal("Error newBoltRepositoryTest - ", err)
+	}
+	_ = f.Close()
+	m, err := newBoltRepository(map[string]interface{}{"path": f.Name()})
+	if err != nil {
+		t.Fatal("Error newBoltRepositoryTest - ", err)
+	}
+	return m
+}
+
+func newSQLRepositoryTest(t *testing.T) Repository {
+	f, err := ioutil.TempFile(testDir, "*.sqlite")
+	if err != nil {
+		t.Fatal("Error newSQLRepositoryTest - ", err)
+	}
+	_ = f.Close()
+	m, err := newSQLRepository(map[string]interface{}{"dialect": "sqlite3", "dsn": f.Name()})
+	if err != nil {
+		t.Fatal("Error newSQLRepositoryTest - ", err)
+	}
+	return m
+}
+
+func newDirectoryRepositoryTest(t *testing.T) Repository {
+	d, err := ioutil.TempDir(testDir, "directory")
+	if err != nil {
+		t.Fatal("Error newDirectoryRepositoryTest - ", err)
+	}
+	m, err := newDirectoryRepository(map[string]interface{}{"path": d, "read-only": false, "watch": false})
+	if err != nil {
+		t.Fatal("Error newDirectoryRepositoryTest - ", err)
+	}
+	return m
+}
+
+// runDriverTest runs f as a subtest against a new repository of every driver.
+func runDriverTest(t *testing.T, f func(t *testing.T, m Repository)) {
+	repositories := [...]Repository{newMemoryRepositoryTest(t), newBoltRepositoryTest(t),
+		newSQLRepositoryTest(t), newDirectoryRepositoryTest(t)}
+
+	for _, repository := range repositories {
+		t.Run(fmt.Sprint("Test_", reflect.TypeOf(repository).Elem().Name()), func(t *testing.T) {
+			f(t, repository)
+		})
+	}
+}
+
 func runIndividualHostCRUD(t *testing.T, m Repository) {
 	if err := m.Write(func(s Session) error {
 		return s.Host().Create(entity.Host{
@@ -48,7 +151,7 @@ func runIndividualHostCRUD(t *testing.T, m Repository) {
 			HardwareAddr:  []string{"86-53-25-6A-E0-D4"},
 			TrapMode:      true,
 			TrapTriggered: true,
-			Vars:          map[string]string{"foo": "bar"},
+			Vars:          map[string]interface{}{"foo": "bar"},
 			GroupID:       "",
 			TemplateID:    "",
 		})
@@ -81,7 +184,7 @@ func runIndividualHostCRUD(t *testing.T, m Repository) {
 			HardwareAddr:  []string{"86-53-25-6A-E0-D5"},
 			TrapMode:      false,
 			TrapTriggered: false,
-			Vars:          map[string]string{"bar": "foo"},
+			Vars:          map[string]interface{}{"bar": "foo"},
 			GroupID:       "",
 			TemplateID:    "",
 		})
@@ -111,7 +214,7 @@ func runIndividualGroupCRUD(t *testing.T, m Repository) {
 	if err := m.Write(func(s Session) error {
 		return s.Group().Create(entity.Group{
 			ID:                "11",
-			Vars:              map[string]string{"foo": "bar"},
+			Vars:              map[string]interface{}{"foo": "bar"},
 			HostsIDs:          []string{"host"},
 			GroupIDs:          []string{"GroupID"},
 			ParentID:          "",
@@ -142,7 +245,7 @@ func runIndividualGroupCRUD(t *testing.T, m Repository) {
 	if err := m.Write(func(s Session) error {
 		return s.Group().Update(entity.Group{
 			ID:                "11",
-			Vars:              map[string]string{"foo2": "bar2"},
+			Vars:              map[string]interface{}{"foo2": "bar2"},
 			HostsIDs:          []string{"host2"},
 			GroupIDs:          []string{"GroupID2"},
 			ParentID:          "",
@@ -231,6 +334,7 @@ func runOpenConcurrencyTest(t *testing.T, m Repository) {
 func runReadWriteConcurrencyTest(t *testing.T, m Repository) {
 	var wg sync.WaitGroup
 	var a atomic.Bool
+	errs := make(chan error, 5)
 	for i := 1; i <= 5; i++ {
 		id := i
 		wg.Add(1)
@@ -238,19 +342,19 @@ func runReadWriteConcurrencyTest(t *testing.T, m Repository) {
 			defer wg.Done()
 			switch id {
 			case 3, 4:
-				_ = m.Write(func(s Session) error {
+				errs <- m.Write(func(s Session) error {
 					if !a.CAS(false, true) {
-						t.Fatal("write operation did't lock. Expected: ", id, " - Returned:", a)
+						return fmt.Errorf("write operation did't lock. Expected: %v - Returned: %v", id, a.Load())
 					}
 					time.Sleep(100)
 					if !a.CAS(true, false) {
-						t.Fatal("write operation did't lock. Expected: ", id, " - Returned:", a)
+						return fmt.Errorf("write operation did't lock. Expected: %v - Returned: %v", id, a.Load())
 					}
 					return nil
 				})
 				break
 			default:
-				_ = m.Read(func(s Session) error {
+				errs <- m.Read(func(s Session) error {
 					a.CAS(false, true)
 					time.Sleep(100)
 					a.CAS(true, false)
@@ -260,4 +364,98 @@ func runReadWriteConcurrencyTest(t *testing.T, m Repository) {
 		}(id, &wg)
 	}
 	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func runListTest(t *testing.T, m Repository) {
+	if err := m.Write(func(s Session) error {
+		for _, id := range []string{"l3", "l1", "l2"} {
+			if err := s.Template().Create(entity.Template{ID: id, Template: id}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		t.Fatal("runListTest - error creating ", err)
+	}
+	tests := []struct {
+		name  string
+		after string
+		limit int
+		want  []string
+	}{
+		{"OK_ALL", "", 0, []string{"l1", "l2", "l3"}},
+		{"OK_LIMIT", "", 2, []string{"l1", "l2"}},
+		{"OK_CURSOR", "l2", 0, []string{"l3"}},
+		{"OK_CURSOR_NOT_STORED", "l15", 1, []string{"l2"}},
+		{"OK_END", "l3", 10, []string{}},
+	}
+	for _, tt := range tests {
+		if err := m.Read(func(s Session) error {
+			l, err := s.Template().List(tt.after, tt.limit)
+			if err != nil {
+				return err
+			}
+			got := make([]string, 0, len(l))
+			for _, e := range l {
+				got = append(got, e.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("runListTest %v - got %v want %v", tt.name, got, tt.want)
+			}
+			return nil
+		}); err != nil {
+			t.Error("runListTest - error listing ", err)
+		}
+	}
 }
+
+func runDependencyTest(t *testing.T, m Repository) {
+	if err := m.Write(func(s Session) error {
+		if err := s.Template().Create(entity.Template{ID: "dt", Template: "dt"}); err != nil {
+			return err
+		}
+		if err := s.Group().Create(entity.Group{ID: "dg", TemplateID: "dt"}); err != nil {
+			return err
+		}
+		if err := s.Group().Create(entity.Group{ID: "dgc", ParentID: "dg"}); err != nil {
+			return err
+		}
+		return s.Host().Create(entity.Host{ID: "dh", HardwareAddr: []string{"00-00-00-00-00-d1"}, GroupID: "dgc"})
+	}); err != nil {
+		t.Fatal("runDependencyTest - error creating ", err)
+	}
+	tests := []struct {
+		name     string
+		delete   func(s Session) error
+		wantCode string
+	}{
+		{"KO_TEMPLATE_USED", func(s Session) error { return s.Template().Delete(entity.Template{ID: "dt"}) },
+			errors.ERepositoryDependency},
+		{"KO_GROUP_PARENT", func(s Session) error { return s.Group().Delete(entity.Group{ID: "dg"}) },
+			errors.ERepositoryDependency},
+		{"KO_GROUP_USED", func(s Session) error { return s.Group().Delete(entity.Group{ID: "dgc"}) },
+			errors.ERepositoryDependency},
+		{"OK_HOST", func(s Session) error { return s.Host().Delete(entity.Host{ID: "dh"}) }, ""},
+		{"KO_HOST_NOT_FOUND", func(s Session) error { return s.Host().Delete(entity.Host{ID: "dh"}) },
+			errors.ERepositoryKeyNotFound},
+		{"OK_GROUP_CHILD", func(s Session) error { return s.Group().Delete(entity.Group{ID: "dgc"}) }, ""},
+		{"OK_GROUP_PARENT", func(s Session) error { return s.Group().Delete(entity.Group{ID: "dg"}) }, ""},
+		{"OK_TEMPLATE", func(s Session) error { return s.Template().Delete(entity.Template{ID: "dt"}) }, ""},
+	}
+	for _, tt := range tests {
+		err := m.Write(tt.delete)
+		if tt.wantCode == "" && err != nil {
+			t.Errorf("runDependencyTest %v - unexpected error %v", tt.name, err)
+		}
+		if tt.wantCode != "" && !errors.Is(err, tt.wantCode) {
+			t.Errorf("runDependencyTest %v - got %v want %v", tt.name, err, tt.wantCode)
+		}
+	}
+}
+
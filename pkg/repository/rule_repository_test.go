@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+	"reflect"
+	"testing"
+)
+
+func TestRuleRepository(t *testing.T) {
+	runDriverTest(t, runRuleTest)
+}
+
+func runRuleTest(t *testing.T, m Repository) {
+	if err := m.Write(func(s Session) error {
+		if err := s.Template().Create(entity.Template{ID: "rt", Template: "rt"}); err != nil {
+			return err
+		}
+		if err := s.Rule().Create(entity.Rule{ID: "r1", Priority: 10, Selector: map[string]string{"rack": "a1"},
+			TemplateID: "rt"}); err != nil {
+			return err
+		}
+		return s.Host().Create(entity.Host{ID: "rh", HardwareAddr: []string{"00-00-00-00-00-f1"},
+			Labels: map[string]string{"rack": "a1", "role": "db"}})
+	}); err != nil {
+		t.Fatal("runRuleTest - error creating ", err)
+	}
+	if err := m.Read(func(s Session) error {
+		r, err := s.Rule().Get("r1")
+		if err != nil {
+			return err
+		}
+		want := entity.Rule{ID: "r1", Priority: 10, Selector: map[string]string{"rack": "a1"}, TemplateID: "rt"}
+		if !reflect.DeepEqual(r, want) {
+			t.Errorf("runRuleTest - invalid stored rule %v want %v", r, want)
+		}
+		h, err := s.Host().Get("rh")
+		if err != nil {
+			return err
+		}
+		if !reflect.DeepEqual(h.Labels, map[string]string{"rack": "a1", "role": "db"}) {
+			t.Error("runRuleTest - invalid stored host labels ", h.Labels)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal("runRuleTest - error reading ", err)
+	}
+
+	tests := []struct {
+		name     string
+		write    func(s Session) error
+		wantCode string
+	}{
+		{"KO_EXISTS", func(s Session) error {
+			return s.Rule().Create(entity.Rule{ID: "r1", TemplateID: "rt"})
+		}, errors.ERepositoryKeyExist},
+		{"KO_EMPTY_KEY", func(s Session) error {
+			return s.Rule().Create(entity.Rule{TemplateID: "rt"})
+		}, errors.ERepositoryEmptyKey},
+		{"KO_TEMPLATE_NOT_FOUND", func(s Session) error {
+			return s.Rule().Create(entity.Rule{ID: "r2", TemplateID: "missing"})
+		}, errors.ERepositoryKeyNotFound},
+		{"KO_UPDATE_NOT_FOUND", func(s Session) error {
+			return s.Rule().Update(entity.Rule{ID: "r2", TemplateID: "rt"})
+		}, errors.ERepositoryKeyNotFound},
+		{"OK_UPDATE", func(s Session) error {
+			return s.Rule().Update(entity.Rule{ID: "r1", Priority: 5, TemplateID: "rt"})
+		}, ""},
+		{"KO_TEMPLATE_USED", func(s Session) error {
+			return s.Template().Delete(entity.Template{ID: "rt"})
+		}, errors.ERepositoryDependency},
+		{"OK_DELETE", func(s Session) error { return s.Rule().Delete(entity.Rule{ID: "r1"}) }, ""},
+		{"KO_DELETE_NOT_FOUND", func(s Session) error {
+			return s.Rule().Delete(entity.Rule{ID: "r1"})
+		}, errors.ERepositoryKeyNotFound},
+		{"OK_TEMPLATE", func(s Session) error {
+			if err := s.Host().Delete(entity.Host{ID: "rh"}); err != nil {
+				return err
+			}
+			return s.Template().Delete(entity.Template{ID: "rt"})
+		}, ""},
+	}
+	for _, tt := range tests {
+		err := m.Write(tt.write)
+		if tt.wantCode == "" && err != nil {
+			t.Errorf("runRuleTest %v - unexpected error %v", tt.name, err)
+		}
+		if tt.wantCode != "" && !errors.Is(err, tt.wantCode) {
+			t.Errorf("runRuleTest %v - got %v want %v", tt.name, err, tt.wantCode)
+		}
+	}
+}
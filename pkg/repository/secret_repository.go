@@ -0,0 +1,365 @@
+package repository
+
+import (
+	"fmt"
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+	"github.com/pxecore/pxecore/pkg/secret"
+)
+
+//~ STRUCT - secretRepository -------------------------------------------------
+
+// secretRepository decorates a Repository encrypting the entity.Host and entity.Group
+// secrets before they are stored and decrypting them when they are read.
+//
+// Plain text secrets, like the ones written by hand in the directory driver files,
+// are read as they are until RotateSecrets encrypts them. Updates store the secrets
+// that didn't change as they are stored, so they aren't encrypted again. Without keyring,
+// storing new secrets returns errors.ESecretError and stored secrets are read as they are.
+type secretRepository struct {
+	repository Repository
+	keyring    *secret.Keyring
+}
+
+// NewSecretRepository decorates r to encrypt the secrets at rest with the keyring.
+func NewSecretRepository(r Repository, keyring *secret.Keyring) Repository {
+	return &secretRepository{r, keyring}
+}
+
+func (r *secretRepository) Open(write bool) (Session, error) {
+	s, err := r.repository.Open(write)
+	if err != nil {
+		return nil, err
+	}
+	return newSecretSession(s, r.keyring), nil
+}
+
+func (r *secretRepository) Read(f func(session Session) error) error {
+	return r.repository.Read(func(s Session) error {
+		return f(newSecretSession(s, r.keyring))
+	})
+}
+
+func (r *secretRepository) Write(f func(session Session) error) error {
+	return r.repository.Write(func(s Session) error {
+		return f(newSecretSession(s, r.keyring))
+	})
+}
+
+// RotateSecrets re-encrypts with the current key the secrets stored in r that are in
+// plain text or encrypted with a previous key. r is the repository decorated by NewSecretRepository.
+// It returns the number of entities updated.
+func RotateSecrets(r Repository, keyring *secret.Keyring) (int, error) {
+	n := 0
+	err := r.Write(func(s Session) error {
+		hosts, err := s.Host().List("", 0)
+		if err != nil {
+			return err
+		}
+		for _, h := range hosts {
+			secrets, changed, err := rotateSecrets(keyring, h.Secrets)
+			if err != nil {
+				return secretError("entity.Host", h.ID, err)
+			}
+			if !changed {
+				continue
+			}
+			h.Secrets = secrets
+			if err := s.Host().Update(h); err != nil {
+				return err
+			}
+			n++
+		}
+		groups, err := s.Group().List("", 0)
+		if err != nil {
+			return err
+		}
+		for _, g := range groups {
+			secrets, changed, err := rotateSecrets(keyring, g.Secrets)
+			if err != nil {
+				return secretError("entity.Group", g.ID, err)
+			}
+			if !changed {
+				continue
+			}
+			g.Secrets = secrets
+			if err := s.Group().Update(g); err != nil {
+				return err
+			}
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+// rotateSecrets returns the secrets encrypted with the current key and true if any of them changed.
+func rotateSecrets(keyring *secret.Keyring, secrets map[string]string) (map[string]string, bool, error) {
+	r := make(map[string]string, len(secrets))
+	changed := false
+	for k, v := range secrets {
+		if keyring.IsCurrent(v) {
+			r[k] = v
+			continue
+		}
+		plain := v
+		if secret.IsEncrypted(v) {
+			var err error
+			if plain, err = keyring.Decrypt(v); err != nil {
+				return nil, false, err
+			}
+		}
+		e, err := keyring.Encrypt(plain)
+		if err != nil {
+			return nil, false, err
+		}
+		r[k] = e
+		changed = true
+	}
+	return r, changed, nil
+}
+
+// encryptSecrets returns a copy of the secrets encrypted with the current key.
+func encryptSecrets(keyring *secret.Keyring, secrets map[string]string) (map[string]string, error) {
+	if len(secrets) == 0 {
+		return secrets, nil
+	}
+	if keyring == nil {
+		return nil, &errors.Error{Code: errors.ESecretError, Msg: "secrets key is not configured."}
+	}
+	r := make(map[string]string, len(secrets))
+	for k, v := range secrets {
+		e, err := keyring.Encrypt(v)
+		if err != nil {
+			return nil, err
+		}
+		r[k] = e
+	}
+	return r, nil
+}
+
+// updateSecrets returns a copy of the secrets to store in place of the stored ones.
+// The secrets that didn't change are kept as they are stored and the others are encrypted.
+func updateSecrets(keyring *secret.Keyring, stored map[string]string,
+	secrets map[string]string) (map[string]string, error) {
+	if len(secrets) == 0 {
+		return secrets, nil
+	}
+	plain, err := decryptSecrets(keyring, stored)
+	if err != nil {
+		return nil, err
+	}
+	r := make(map[string]string, len(secrets))
+	changed := make(map[string]string)
+	for k, v := range secrets {
+		if s, ok := stored[k]; ok && plain[k] == v {
+			r[k] = s
+		} else {
+			changed[k] = v
+		}
+	}
+	if changed, err = encryptSecrets(keyring, changed); err != nil {
+		return nil, err
+	}
+	for k, v := range changed {
+		r[k] = v
+	}
+	return r, nil
+}
+
+// decryptSecrets returns a copy of the secrets decrypted, plain text secrets are kept as they are.
+func decryptSecrets(keyring *secret.Keyring, secrets map[string]string) (map[string]string, error) {
+	if len(secrets) == 0 || keyring == nil {
+		return secrets, nil
+	}
+	r := make(map[string]string, len(secrets))
+	for k, v := range secrets {
+		if !secret.IsEncrypted(v) {
+			r[k] = v
+			continue
+		}
+		d, err := keyring.Decrypt(v)
+		if err != nil {
+			return nil, err
+		}
+		r[k] = d
+	}
+	return r, nil
+}
+
+// secretError wraps a secret error with the entity that holds the secret.
+func secretError(kind string, ID string, err error) error {
+	return &errors.Error{Code: errors.ESecretError, Msg: fmt.Sprintf("%v key %v secrets error", kind, ID), Err: err}
+}
+
+//~ STRUCT - secretSession ----------------------------------------------------
+
+// secretSession decorates a Session replacing its host and group repositories.
+type secretSession struct {
+	Session
+	host  *HostRepository
+	group *GroupRepository
+}
+
+func newSecretSession(s Session, keyring *secret.Keyring) Session {
+	return &secretSession{
+		Session: s,
+		host:    newSecretHostRepository(s.Host(), keyring),
+		group:   newSecretGroupRepository(s.Group(), keyring),
+	}
+}
+
+// Host implements repository.Session interface
+func (s *secretSession) Host() HostRepository {
+	return *s.host
+}
+
+// Group implements repository.Session interface
+func (s *secretSession) Group() GroupRepository {
+	return *s.group
+}
+
+//~ STRUCT - secretHostRepository ---------------------------------------------
+
+// secretHostRepository encrypts the entity.Host secrets.
+type secretHostRepository struct {
+	HostRepository
+	keyring *secret.Keyring
+}
+
+func newSecretHostRepository(r HostRepository, keyring *secret.Keyring) *HostRepository {
+	var hr HostRepository
+	hr = &secretHostRepository{r, keyring}
+	return &hr
+}
+
+// Create implements repository.HostRepository interface
+func (h *secretHostRepository) Create(host entity.Host) error {
+	var err error
+	if host.Secrets, err = encryptSecrets(h.keyring, host.Secrets); err != nil {
+		return secretError("entity.Host", host.ID, err)
+	}
+	return h.HostRepository.Create(host)
+}
+
+// Get implements repository.HostRepository interface
+func (h *secretHostRepository) Get(ID string) (entity.Host, error) {
+	return h.decrypt(h.HostRepository.Get(ID))
+}
+
+// FindByHardwareAddr implements repository.HostRepository interface
+func (h *secretHostRepository) FindByHardwareAddr(hardwareAddr string) (entity.Host, error) {
+	return h.decrypt(h.HostRepository.FindByHardwareAddr(hardwareAddr))
+}
+
+// FindByIdentifier implements repository.HostRepository interface
+func (h *secretHostRepository) FindByIdentifier(kind string, value string) (entity.Host, error) {
+	return h.decrypt(h.HostRepository.FindByIdentifier(kind, value))
+}
+
+// FindByIP implements repository.HostRepository interface
+func (h *secretHostRepository) FindByIP(ip string) (entity.Host, error) {
+	return h.decrypt(h.HostRepository.FindByIP(ip))
+}
+
+// Update implements repository.HostRepository interface
+func (h *secretHostRepository) Update(host entity.Host) error {
+	stored, err := h.HostRepository.Get(host.ID)
+	if err != nil && !errors.Is(err, errors.ERepositoryKeyNotFound) {
+		return err
+	}
+	if host.Secrets, err = updateSecrets(h.keyring, stored.Secrets, host.Secrets); err != nil {
+		return secretError("entity.Host", host.ID, err)
+	}
+	return h.HostRepository.Update(host)
+}
+
+// List implements repository.HostRepository interface
+func (h *secretHostRepository) List(after string, limit int) ([]entity.Host, error) {
+	l, err := h.HostRepository.List(after, limit)
+	if err != nil {
+		return nil, err
+	}
+	for i := range l {
+		if l[i], err = h.decrypt(l[i], nil); err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// decrypt decrypts the secrets of the host returned by the decorated repository.
+func (h *secretHostRepository) decrypt(host entity.Host, err error) (entity.Host, error) {
+	if err != nil {
+		return host, err
+	}
+	if host.Secrets, err = decryptSecrets(h.keyring, host.Secrets); err != nil {
+		return entity.Host{}, secretError("entity.Host", host.ID, err)
+	}
+	return host, nil
+}
+
+//~ STRUCT - secretGroupRepository --------------------------------------------
+
+// secretGroupRepository encrypts the entity.Group secrets.
+type secretGroupRepository struct {
+	GroupRepository
+	keyring *secret.Keyring
+}
+
+func newSecretGroupRepository(r GroupRepository, keyring *secret.Keyring) *GroupRepository {
+	var gr GroupRepository
+	gr = &secretGroupRepository{r, keyring}
+	return &gr
+}
+
+// Create implements repository.GroupRepository interface
+func (g *secretGroupRepository) Create(group entity.Group) error {
+	var err error
+	if group.Secrets, err = encryptSecrets(g.keyring, group.Secrets); err != nil {
+		return secretError("entity.Group", group.ID, err)
+	}
+	return g.GroupRepository.Create(group)
+}
+
+// Get implements repository.GroupRepository interface
+func (g *secretGroupRepository) Get(ID string) (entity.Group, error) {
+	return g.decrypt(g.GroupRepository.Get(ID))
+}
+
+// Update implements repository.GroupRepository interface
+func (g *secretGroupRepository) Update(group entity.Group) error {
+	stored, err := g.GroupRepository.Get(group.ID)
+	if err != nil && !errors.Is(err, errors.ERepositoryKeyNotFound) {
+		return err
+	}
+	if group.Secrets, err = updateSecrets(g.keyring, stored.Secrets, group.Secrets); err != nil {
+		return secretError("entity.Group", group.ID, err)
+	}
+	return g.GroupRepository.Update(group)
+}
+
+// List implements repository.GroupRepository interface
+func (g *secretGroupRepository) List(after string, limit int) ([]entity.Group, error) {
+	l, err := g.GroupRepository.List(after, limit)
+	if err != nil {
+		return nil, err
+	}
+	for i := range l {
+		if l[i], err = g.decrypt(l[i], nil); err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// decrypt decrypts the secrets of the group returned by the decorated repository.
+func (g *secretGroupRepository) decrypt(group entity.Group, err error) (entity.Group, error) {
+	if err != nil {
+		return group, err
+	}
+	if group.Secrets, err = decryptSecrets(g.keyring, group.Secrets); err != nil {
+		return entity.Group{}, secretError("entity.Group", group.ID, err)
+	}
+	return group, nil
+}
@@ -0,0 +1,202 @@
+package repository
+
+import (
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+	"github.com/pxecore/pxecore/pkg/secret"
+	"reflect"
+	"testing"
+)
+
+func TestSecretRepository(t *testing.T) {
+	key1, _ := secret.GenerateKey()
+	key2, _ := secret.GenerateKey()
+	key3, _ := secret.GenerateKey()
+	k1, _ := secret.NewKeyring(map[string]interface{}{"key": key1})
+	k3, _ := secret.NewKeyring(map[string]interface{}{"key": key3})
+	k2, _ := secret.NewKeyring(map[string]interface{}{"key": key2, "previous-keys": []interface{}{key1}})
+	inner := newMemoryRepositoryTest(t)
+	secrets := map[string]string{"root-password": "$6$hash", "bmc": "admin:admin"}
+	if err := NewSecretRepository(inner, k1).Write(func(s Session) error {
+		if err := s.Group().Create(entity.Group{ID: "sg", Secrets: secrets}); err != nil {
+			return err
+		}
+		return s.Host().Create(entity.Host{ID: "sh", HardwareAddr: []string{"00-00-00-00-00-e1"}, GroupID: "sg",
+			Secrets: secrets})
+	}); err != nil {
+		t.Fatal("TestSecretRepository - error creating ", err)
+	}
+	_ = inner.Write(func(s Session) error {
+		return s.Host().Create(entity.Host{ID: "plain", HardwareAddr: []string{"00-00-00-00-00-e2"},
+			Secrets: map[string]string{"token": "plain"}})
+	})
+
+	tests := []struct {
+		name       string
+		keyring    *secret.Keyring
+		rotate     bool
+		wantStored func(string) bool
+		wantErr    bool
+	}{
+		{"OK_ENCRYPTED_AT_REST", k1, false, k1.IsCurrent, false},
+		{"KO_UNKNOWN_KEY", k3, false, k1.IsCurrent, true},
+		{"OK_PREVIOUS_KEY", k2, false, k1.IsCurrent, false},
+		{"OK_ROTATED", k2, true, k2.IsCurrent, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.rotate {
+				if n, err := RotateSecrets(inner, tt.keyring); err != nil || n != 3 {
+					t.Fatalf("RotateSecrets() = %v, %v want 3", n, err)
+				}
+			}
+			_ = inner.Read(func(s Session) error {
+				h, _ := s.Host().Get("sh")
+				g, _ := s.Group().Get("sg")
+				for _, v := range []string{h.Secrets["bmc"], g.Secrets["bmc"]} {
+					if !tt.wantStored(v) {
+						t.Errorf("stored secret %v is not encrypted with the expected key", v)
+					}
+				}
+				return nil
+			})
+			err := NewSecretRepository(inner, tt.keyring).Read(func(s Session) error {
+				h, err := s.Host().FindByHardwareAddr("00-00-00-00-00-e1")
+				if err != nil {
+					return err
+				}
+				l, err := s.Group().List("", 0)
+				if err != nil {
+					return err
+				}
+				if !reflect.DeepEqual(h.Secrets, secrets) || !reflect.DeepEqual(l[0].Secrets, secrets) {
+					t.Errorf("decrypted secrets = %v, %v want %v", h.Secrets, l[0].Secrets, secrets)
+				}
+				p, err := s.Host().Get("plain")
+				if err != nil {
+					return err
+				}
+				if p.Secrets["token"] != "plain" {
+					t.Errorf("plain secret = %v, want plain", p.Secrets["token"])
+				}
+				return nil
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Read() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, errors.ESecretError) {
+				t.Errorf("Read() error = %v, want %v", err, errors.ESecretError)
+			}
+		})
+	}
+}
+
+func TestSecretRepository_NoKeyring(t *testing.T) {
+	r := NewSecretRepository(newMemoryRepositoryTest(t), nil)
+	err := r.Write(func(s Session) error {
+		return s.Host().Create(entity.Host{ID: "sh", HardwareAddr: []string{"00-00-00-00-00-e1"},
+			Secrets: map[string]string{"bmc": "admin:admin"}})
+	})
+	if !errors.Is(err, errors.ESecretError) {
+		t.Errorf("Create() error = %v, want %v", err, errors.ESecretError)
+	}
+	if err := r.Write(func(s Session) error {
+		return s.Host().Create(entity.Host{ID: "sh", HardwareAddr: []string{"00-00-00-00-00-e1"}})
+	}); err != nil {
+		t.Errorf("Create() error = %v", err)
+	}
+}
+
+func TestSecretRepository_Update(t *testing.T) {
+	key, _ := secret.GenerateKey()
+	k, _ := secret.NewKeyring(map[string]interface{}{"key": key})
+	tests := []struct {
+		name       string
+		keyring    *secret.Keyring
+		secrets    map[string]string
+		wantStored func(stored string) bool
+		wantErr    bool
+	}{
+		{"OK_NO_KEYRING_UNCHANGED", nil, map[string]string{"token": "plain"},
+			func(stored string) bool { return stored == "plain" }, false},
+		{"KO_NO_KEYRING_CHANGED", nil, map[string]string{"token": "changed"},
+			func(stored string) bool { return stored == "plain" }, true},
+		{"OK_KEYRING_UNCHANGED", k, map[string]string{"token": "plain"},
+			func(stored string) bool { return stored == "plain" }, false},
+		{"OK_KEYRING_CHANGED", k, map[string]string{"token": "changed"}, k.IsCurrent, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := newMemoryRepositoryTest(t)
+			_ = inner.Write(func(s Session) error {
+				return s.Host().Create(entity.Host{ID: "plain", HardwareAddr: []string{"00-00-00-00-00-e2"},
+					Secrets: map[string]string{"token": "plain"}})
+			})
+			err := NewSecretRepository(inner, tt.keyring).Write(func(s Session) error {
+				h, err := s.Host().Get("plain")
+				if err != nil {
+					return err
+				}
+				h.TrapTriggered = true
+				h.Secrets = tt.secrets
+				return s.Host().Update(h)
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Update() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, errors.ESecretError) {
+				t.Errorf("Update() error = %v, want %v", err, errors.ESecretError)
+			}
+			_ = inner.Read(func(s Session) error {
+				h, _ := s.Host().Get("plain")
+				if !tt.wantStored(h.Secrets["token"]) {
+					t.Errorf("stored secret = %v", h.Secrets["token"])
+				}
+				return nil
+			})
+		})
+	}
+}
+
+func TestSecretRepository_Drivers(t *testing.T) {
+	runDriverTest(t, runSecretsTest)
+}
+
+func runSecretsTest(t *testing.T, m Repository) {
+	if err := m.Write(func(s Session) error {
+		if err := s.Group().Create(entity.Group{ID: "sg", Secrets: map[string]string{"password": "v1:00000000:BBBB"}}); err != nil {
+			return err
+		}
+		return s.Host().Create(entity.Host{ID: "sh", HardwareAddr: []string{"00-00-00-00-00-f2"}, GroupID: "sg",
+			Secrets: map[string]string{"token": "v1:00000000:AAAA"}})
+	}); err != nil {
+		t.Fatal("runSecretsTest - error creating ", err)
+	}
+	if err := m.Read(func(s Session) error {
+		g, err := s.Group().Get("sg")
+		if err != nil {
+			return err
+		}
+		if g.Secrets["password"] != "v1:00000000:BBBB" {
+			t.Error("runSecretsTest - invalid stored group secrets ", g.Secrets)
+		}
+		h, err := s.Host().Get("sh")
+		if err != nil {
+			return err
+		}
+		if h.Secrets["token"] != "v1:00000000:AAAA" {
+			t.Error("runSecretsTest - invalid stored host secrets ", h.Secrets)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal("runSecretsTest - error reading ", err)
+	}
+	if err := m.Write(func(s Session) error {
+		if err := s.Host().Delete(entity.Host{ID: "sh"}); err != nil {
+			return err
+		}
+		return s.Group().Delete(entity.Group{ID: "sg"})
+	}); err != nil {
+		t.Fatal("runSecretsTest - error deleting ", err)
+	}
+}
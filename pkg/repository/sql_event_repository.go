@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"fmt"
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+)
+
+// sqlEventRepository defines the CRUD procedure for entity.BootEvent
+// Every event is stored as a JSON document.
+type sqlEventRepository struct {
+	session *SQLSession
+	config  SQLConfig
+}
+
+// newSQLEventRepository instantiates a new repository for entity.BootEvent
+func newSQLEventRepository(s *SQLSession, config SQLConfig) *EventRepository {
+	var er EventRepository
+	er = &sqlEventRepository{
+		s,
+		config,
+	}
+	return &er
+}
+
+// Create implements repository.EventRepository interface
+func (r *sqlEventRepository) Create(event entity.BootEvent) error {
+	if r.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	var latest int
+	if err := r.session.queryRow(`SELECT COALESCE(MAX(sequence), 0) FROM boot_events WHERE host_id = ?`,
+		event.HostID).Scan(&latest); err != nil {
+		return &errors.Error{Code: errors.EUnknown,
+			Msg: fmt.Sprintf("entity.BootEvent host %v can't be read", event.HostID), Err: err}
+	}
+	e := nextBootEvent(latest, event)
+	document, err := sqlEncodeVars(e)
+	if err != nil {
+		return err
+	}
+	if err := r.session.exec(`INSERT INTO boot_events (host_id, sequence, time, document) VALUES (?, ?, ?, ?)`,
+		e.HostID, e.Sequence, e.Time.UnixNano(), document); err != nil {
+		return err
+	}
+	return r.session.exec(`DELETE FROM boot_events WHERE host_id = ? AND sequence <= ?`,
+		e.HostID, e.Sequence-MaxHostEvents)
+}
+
+// List implements repository.EventRepository interface
+func (r *sqlEventRepository) List(hostID string) ([]entity.BootEvent, error) {
+	return r.query(`SELECT document FROM boot_events WHERE host_id = ? ORDER BY sequence DESC`, hostID)
+}
+
+// Recent implements repository.EventRepository interface
+func (r *sqlEventRepository) Recent(limit int) ([]entity.BootEvent, error) {
+	q := `SELECT document FROM boot_events ORDER BY time DESC, sequence DESC`
+	args := make([]interface{}, 0, 1)
+	if limit > 0 {
+		q += ` LIMIT ?`
+		args = append(args, limit)
+	}
+	return r.query(q, args...)
+}
+
+// Delete implements repository.EventRepository interface
+func (r *sqlEventRepository) Delete(hostID string) error {
+	if r.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	return r.session.exec(`DELETE FROM boot_events WHERE host_id = ?`, hostID)
+}
+
+// query returns the events decoded from the documents selected by the query.
+func (r *sqlEventRepository) query(q string, args ...interface{}) ([]entity.BootEvent, error) {
+	rows, err := r.session.query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	l := make([]entity.BootEvent, 0)
+	for rows.Next() {
+		var document string
+		if err := rows.Scan(&document); err != nil {
+			_ = rows.Close()
+			return nil, &errors.Error{Code: errors.EUnknown, Msg: "sql row can't be read", Err: err}
+		}
+		e := entity.BootEvent{}
+		if err := sqlDecodeVars(document, &e); err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		l = append(l, e)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, &errors.Error{Code: errors.EUnknown, Msg: "sql rows can't be closed", Err: err}
+	}
+	return l, nil
+}
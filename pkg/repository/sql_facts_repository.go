@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+)
+
+// sqlFactsRepository defines the CRUD procedure for entity.Facts
+// Every revision is stored as a JSON document.
+type sqlFactsRepository struct {
+	session *SQLSession
+	config  SQLConfig
+}
+
+// newSQLFactsRepository instantiates a new repository for entity.Facts
+func newSQLFactsRepository(s *SQLSession, config SQLConfig) *FactsRepository {
+	var fr FactsRepository
+	fr = &sqlFactsRepository{
+		s,
+		config,
+	}
+	return &fr
+}
+
+// Create implements repository.FactsRepository interface
+func (f *sqlFactsRepository) Create(facts entity.Facts) error {
+	if f.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	if facts.HostID == "" {
+		return &errors.Error{Code: errors.ERepositoryEmptyKey,
+			Msg: "entity.Facts host key is empty"}
+	}
+	if err := checkFactsHost(f.session, facts); err != nil {
+		return err
+	}
+	var latest int
+	if err := f.session.queryRow(`SELECT COALESCE(MAX(revision), 0) FROM host_facts WHERE host_id = ?`,
+		facts.HostID).Scan(&latest); err != nil {
+		return &errors.Error{Code: errors.EUnknown,
+			Msg: fmt.Sprintf("entity.Facts host %v can't be read", facts.HostID), Err: err}
+	}
+	e := nextFactsRevision(latest, facts)
+	document, err := sqlEncodeVars(e)
+	if err != nil {
+		return err
+	}
+	if err := f.session.exec(`INSERT INTO host_facts (host_id, revision, created, document) VALUES (?, ?, ?, ?)`,
+		e.HostID, e.Revision, e.Created.Unix(), document); err != nil {
+		return err
+	}
+	return f.session.exec(`DELETE FROM host_facts WHERE host_id = ? AND revision <= ?`,
+		e.HostID, e.Revision-MaxFactsRevisions)
+}
+
+// Get implements repository.FactsRepository interface
+func (f *sqlFactsRepository) Get(hostID string) (entity.Facts, error) {
+	var document string
+	err := f.session.queryRow(`SELECT document FROM host_facts WHERE host_id = ? ORDER BY revision DESC LIMIT 1`,
+		hostID).Scan(&document)
+	if err == sql.ErrNoRows {
+		return entity.Facts{}, factsNotFound(hostID)
+	}
+	if err != nil {
+		return entity.Facts{}, &errors.Error{Code: errors.EUnknown,
+			Msg: fmt.Sprintf("entity.Facts host %v can't be read", hostID), Err: err}
+	}
+	e := entity.Facts{}
+	return e, sqlDecodeVars(document, &e)
+}
+
+// GetRevision implements repository.FactsRepository interface
+func (f *sqlFactsRepository) GetRevision(hostID string, revision int) (entity.Facts, error) {
+	var document string
+	err := f.session.queryRow(`SELECT document FROM host_facts WHERE host_id = ? AND revision = ?`,
+		hostID, revision).Scan(&document)
+	if err == sql.ErrNoRows {
+		return entity.Facts{}, factsRevisionNotFound(hostID, revision)
+	}
+	if err != nil {
+		return entity.Facts{}, &errors.Error{Code: errors.EUnknown,
+			Msg: fmt.Sprintf("entity.Facts host %v revision %v can't be read", hostID, revision), Err: err}
+	}
+	e := entity.Facts{}
+	return e, sqlDecodeVars(document, &e)
+}
+
+// ListRevisions implements repository.FactsRepository interface
+func (f *sqlFactsRepository) ListRevisions(hostID string) ([]entity.Facts, error) {
+	rows, err := f.session.query(`SELECT document FROM host_facts WHERE host_id = ? ORDER BY revision`, hostID)
+	if err != nil {
+		return nil, err
+	}
+	l := make([]entity.Facts, 0)
+	for rows.Next() {
+		var document string
+		if err := rows.Scan(&document); err != nil {
+			_ = rows.Close()
+			return nil, &errors.Error{Code: errors.EUnknown, Msg: "sql row can't be read", Err: err}
+		}
+		e := entity.Facts{}
+		if err := sqlDecodeVars(document, &e); err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		l = append(l, e)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, &errors.Error{Code: errors.EUnknown, Msg: "sql rows can't be closed", Err: err}
+	}
+	if len(l) == 0 {
+		return nil, factsNotFound(hostID)
+	}
+	return l, nil
+}
+
+// Delete implements repository.FactsRepository interface
+func (f *sqlFactsRepository) Delete(hostID string) error {
+	if f.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	if _, err := f.Get(hostID); err != nil {
+		return err
+	}
+	return f.session.exec(`DELETE FROM host_facts WHERE host_id = ?`, hostID)
+}
@@ -0,0 +1,250 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+)
+
+// sqlGroupRepository defines the CRUD procedure for entity.Group
+type sqlGroupRepository struct {
+	session *SQLSession
+	config  SQLConfig
+}
+
+// newSQLGroupRepository instantiates a new repository for entity.Group
+func newSQLGroupRepository(s *SQLSession, config SQLConfig) *GroupRepository {
+	var gr GroupRepository
+	gr = &sqlGroupRepository{
+		s,
+		config,
+	}
+	return &gr
+}
+
+// Create implements repository.GroupRepository interface
+func (g *sqlGroupRepository) Create(group entity.Group) error {
+	if g.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	e := group
+	if e.ID == "" {
+		return &errors.Error{Code: errors.ERepositoryEmptyKey,
+			Msg: "entity.Group key is empty"}
+	}
+	if _, err := g.Get(e.ID); err == nil {
+		return &errors.Error{Code: errors.ERepositoryKeyExist,
+			Msg: fmt.Sprintf("entity.Group key %v already exists ", e.ID)}
+	} else if !errors.Is(err, errors.ERepositoryKeyNotFound) {
+		return err
+	}
+	if e.ParentID != "" {
+		parent, err := g.Get(e.ParentID)
+		if err != nil {
+			return &errors.Error{Code: errors.ERepositoryKeyNotFound,
+				Msg: fmt.Sprintf("repository.sqlGroupRepository parent group %v does't exist.", e.ParentID)}
+		}
+		parent.AddGroup(e.ID)
+		if err := g.saveLinks(parent); err != nil {
+			return err
+		}
+	}
+	if e.HostsIDs == nil {
+		e.HostsIDs = make([]string, 0)
+	}
+	if e.GroupIDs == nil {
+		e.GroupIDs = make([]string, 0)
+	}
+	vars, err := sqlEncodeVars(e.Vars)
+	if err != nil {
+		return err
+	}
+	artifacts, err := sqlEncodeVars(e.Artifacts)
+	if err != nil {
+		return err
+	}
+	secrets, err := sqlEncodeVars(e.Secrets)
+	if err != nil {
+		return err
+	}
+	if err := g.session.exec(`INSERT INTO "groups" (id, vars, parent_id, template_id, template_revision, artifacts,
+		secrets) VALUES (?, ?, ?, ?, ?, ?, ?)`, e.ID, vars, e.ParentID, e.TemplateID, e.TemplateRevision, artifacts,
+		secrets); err != nil {
+		return err
+	}
+	return g.saveLinks(e)
+}
+
+// Get implements repository.GroupRepository interface
+func (g *sqlGroupRepository) Get(ID string) (entity.Group, error) {
+	e := entity.Group{}
+	var vars, artifacts, secrets string
+	err := g.session.queryRow(`SELECT id, vars, parent_id, template_id, template_revision, artifacts, secrets
+		FROM "groups" WHERE id = ?`, ID).Scan(&e.ID, &vars, &e.ParentID, &e.TemplateID, &e.TemplateRevision,
+		&artifacts, &secrets)
+	if err == sql.ErrNoRows {
+		return entity.Group{}, &errors.Error{Code: errors.ERepositoryKeyNotFound,
+			Msg: fmt.Sprintf("entity.Group key %v not found", ID)}
+	}
+	if err != nil {
+		return entity.Group{}, &errors.Error{Code: errors.EUnknown,
+			Msg: fmt.Sprintf("entity.Group key %v can't be read", ID), Err: err}
+	}
+	if err = sqlDecodeVars(vars, &e.Vars); err != nil {
+		return entity.Group{}, err
+	}
+	if err = sqlDecodeVars(artifacts, &e.Artifacts); err != nil {
+		return entity.Group{}, err
+	}
+	if err = sqlDecodeVars(secrets, &e.Secrets); err != nil {
+		return entity.Group{}, err
+	}
+	if e.HostsIDs, err = g.session.queryStrings(`SELECT host_id FROM group_hosts
+		WHERE group_id = ? ORDER BY position`, ID); err != nil {
+		return entity.Group{}, err
+	}
+	if e.GroupIDs, err = g.session.queryStrings(`SELECT child_id FROM group_children
+		WHERE group_id = ? ORDER BY position`, ID); err != nil {
+		return entity.Group{}, err
+	}
+	return e, nil
+}
+
+// Update implements repository.GroupRepository interface
+func (g *sqlGroupRepository) Update(group entity.Group) error {
+	if g.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	e := group
+	if e.ID == "" {
+		return &errors.Error{Code: errors.ERepositoryEmptyKey,
+			Msg: "entity.Group key is empty"}
+	}
+	og, err := g.Get(e.ID)
+	if err != nil {
+		return err
+	}
+	if e.ParentID != og.ParentID {
+		if e.ParentID != "" {
+			np, err := g.Get(e.ParentID)
+			if err != nil {
+				return &errors.Error{Code: errors.ERepositoryKeyNotFound,
+					Msg: fmt.Sprintf("entity.Group key %v not found ", e.ParentID)}
+			}
+			np.AddGroup(e.ID)
+			if err := g.saveLinks(np); err != nil {
+				return err
+			}
+		}
+		if op, err := g.Get(og.ParentID); err == nil {
+			op.RemoveGroup(e.ID)
+			if err := g.saveLinks(op); err != nil {
+				return err
+			}
+		}
+	}
+	if e.HostsIDs == nil {
+		e.HostsIDs = og.HostsIDs
+	}
+	if e.GroupIDs == nil {
+		e.GroupIDs = og.GroupIDs
+	}
+	vars, err := sqlEncodeVars(e.Vars)
+	if err != nil {
+		return err
+	}
+	artifacts, err := sqlEncodeVars(e.Artifacts)
+	if err != nil {
+		return err
+	}
+	secrets, err := sqlEncodeVars(e.Secrets)
+	if err != nil {
+		return err
+	}
+	if err := g.session.exec(`UPDATE "groups" SET vars = ?, parent_id = ?, template_id = ?, template_revision = ?,
+		artifacts = ?, secrets = ? WHERE id = ?`, vars, e.ParentID, e.TemplateID, e.TemplateRevision, artifacts,
+		secrets, e.ID); err != nil {
+		return err
+	}
+	return g.saveLinks(e)
+}
+
+// Delete implements repository.GroupRepository interface
+func (g *sqlGroupRepository) Delete(group entity.Group) error {
+	if g.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	if group.ID == "" {
+		return &errors.Error{Code: errors.ERepositoryEmptyKey,
+			Msg: "entity.Group key is empty"}
+	}
+	oe, err := g.Get(group.ID)
+	if err != nil {
+		return err
+	}
+	if err := checkGroupDependencies(g.session, oe.ID); err != nil {
+		return err
+	}
+	if oe.ParentID != "" {
+		if op, err := g.Get(oe.ParentID); err == nil {
+			op.RemoveGroup(oe.ID)
+			if err := g.saveLinks(op); err != nil {
+				return err
+			}
+		}
+	}
+	if err := g.session.exec(`DELETE FROM group_hosts WHERE group_id = ?`, oe.ID); err != nil {
+		return err
+	}
+	if err := g.session.exec(`DELETE FROM group_children WHERE group_id = ?`, oe.ID); err != nil {
+		return err
+	}
+	return g.session.exec(`DELETE FROM "groups" WHERE id = ?`, oe.ID)
+}
+
+// saveLinks replaces the stored HostsIDs and GroupIDs of the group.
+func (g *sqlGroupRepository) saveLinks(e entity.Group) error {
+	if err := g.session.exec(`DELETE FROM group_hosts WHERE group_id = ?`, e.ID); err != nil {
+		return err
+	}
+	for i, id := range e.HostsIDs {
+		if err := g.session.exec(`INSERT INTO group_hosts (group_id, host_id, position) VALUES (?, ?, ?)`,
+			e.ID, id, i); err != nil {
+			return err
+		}
+	}
+	if err := g.session.exec(`DELETE FROM group_children WHERE group_id = ?`, e.ID); err != nil {
+		return err
+	}
+	for i, id := range e.GroupIDs {
+		if err := g.session.exec(`INSERT INTO group_children (group_id, child_id, position) VALUES (?, ?, ?)`,
+			e.ID, id, i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// List implements repository.GroupRepository interface
+func (g *sqlGroupRepository) List(after string, limit int) ([]entity.Group, error) {
+	q := `SELECT id FROM "groups" WHERE id > ? ORDER BY id`
+	args := []interface{}{after}
+	if limit > 0 {
+		q += ` LIMIT ?`
+		args = append(args, limit)
+	}
+	ids, err := g.session.queryStrings(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	l := make([]entity.Group, 0, len(ids))
+	for _, id := range ids {
+		e, err := g.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		l = append(l, e)
+	}
+	return l, nil
+}
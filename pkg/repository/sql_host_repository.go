@@ -0,0 +1,422 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+	"github.com/pxecore/pxecore/pkg/util"
+)
+
+// sqlHostRepository defines the CRUD procedure for entity.Host
+type sqlHostRepository struct {
+	session *SQLSession
+	config  SQLConfig
+}
+
+// newSQLHostRepository instantiates a new repository for entity.Host
+func newSQLHostRepository(s *SQLSession, config SQLConfig) *HostRepository {
+	var hr HostRepository
+	hr = &sqlHostRepository{
+		s,
+		config,
+	}
+	return &hr
+}
+
+// Create implements repository.HostRepository interface
+func (h *sqlHostRepository) Create(host entity.Host) error {
+	if h.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	e := host
+	if e.ID == "" {
+		return &errors.Error{Code: errors.ERepositoryEmptyKey,
+			Msg: "entity.Host key is empty"}
+	}
+	if _, err := h.Get(e.ID); err == nil {
+		return &errors.Error{Code: errors.ERepositoryKeyExist,
+			Msg: fmt.Sprintf("entity.Host key %v already exists ", e.ID)}
+	} else if !errors.Is(err, errors.ERepositoryKeyNotFound) {
+		return err
+	}
+	if err := h.checkHardwareAddr(e); err != nil {
+		return err
+	}
+	if err := h.checkIdentifiers(e); err != nil {
+		return err
+	}
+	if err := checkHostReservation(h.session, e); err != nil {
+		return err
+	}
+	if e.TemplateID != "" {
+		if _, err := h.session.Template().Get(e.TemplateID); err != nil {
+			return &errors.Error{Code: errors.ERepositoryKeyNotFound,
+				Msg: fmt.Sprintf("entity.Host TemplateID %v not found.", e.TemplateID)}
+		}
+	}
+	if err := checkHostGroups(h.session, e); err != nil {
+		return err
+	}
+	if err := updateHostGroups(h.session, e.ID, nil, e.Groups()); err != nil {
+		return err
+	}
+
+	vars, err := sqlEncodeVars(e.Vars)
+	if err != nil {
+		return err
+	}
+	artifacts, err := sqlEncodeVars(e.Artifacts)
+	if err != nil {
+		return err
+	}
+	secrets, err := sqlEncodeVars(e.Secrets)
+	if err != nil {
+		return err
+	}
+	labels, err := sqlEncodeVars(e.Labels)
+	if err != nil {
+		return err
+	}
+	history, err := sqlEncodeList(e.StateHistory)
+	if err != nil {
+		return err
+	}
+	callback, err := sqlEncodeVars(e.LastCallback)
+	if err != nil {
+		return err
+	}
+	if err := h.session.exec(`INSERT INTO hosts (id, trap_mode, trap_triggered, vars, group_id, template_id, ip, hostname,
+		template_revision, artifacts, secrets, labels, pending, uuid, serial, asset_tag, state, state_history,
+		callback_token, callback_token_expires, last_callback)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.ID, e.TrapMode, e.TrapTriggered, vars, e.GroupID, e.TemplateID, e.IP, e.Hostname, e.TemplateRevision,
+		artifacts, secrets, labels, e.Pending, e.UUID, e.Serial, e.AssetTag, e.State, history,
+		e.CallbackToken, sqlEncodeTime(e.CallbackTokenExpires), callback); err != nil {
+		return err
+	}
+	if err := h.saveGroupIDs(e); err != nil {
+		return err
+	}
+	if err := h.saveIdentifiers(e); err != nil {
+		return err
+	}
+	return h.saveHardwareAddr(e)
+}
+
+// Get implements repository.HostRepository interface
+func (h *sqlHostRepository) Get(ID string) (entity.Host, error) {
+	e := entity.Host{}
+	var vars, artifacts, secrets, labels, history, callback string
+	var expires int64
+	err := h.session.queryRow(`SELECT id, trap_mode, trap_triggered, vars, group_id, template_id, ip, hostname,
+		template_revision, artifacts, secrets, labels, pending, uuid, serial, asset_tag, state, state_history,
+		callback_token, callback_token_expires, last_callback FROM hosts WHERE id = ?`,
+		ID).Scan(&e.ID, &e.TrapMode, &e.TrapTriggered, &vars, &e.GroupID, &e.TemplateID, &e.IP, &e.Hostname,
+		&e.TemplateRevision, &artifacts, &secrets, &labels, &e.Pending, &e.UUID, &e.Serial, &e.AssetTag,
+		&e.State, &history, &e.CallbackToken, &expires, &callback)
+	if err == sql.ErrNoRows {
+		return entity.Host{}, &errors.Error{Code: errors.ERepositoryKeyNotFound,
+			Msg: fmt.Sprintf("entity.Host key %v not found", ID)}
+	}
+	if err != nil {
+		return entity.Host{}, &errors.Error{Code: errors.EUnknown,
+			Msg: fmt.Sprintf("entity.Host key %v can't be read", ID), Err: err}
+	}
+	if err = sqlDecodeVars(vars, &e.Vars); err != nil {
+		return entity.Host{}, err
+	}
+	if err = sqlDecodeVars(artifacts, &e.Artifacts); err != nil {
+		return entity.Host{}, err
+	}
+	if err = sqlDecodeVars(secrets, &e.Secrets); err != nil {
+		return entity.Host{}, err
+	}
+	if err = sqlDecodeVars(labels, &e.Labels); err != nil {
+		return entity.Host{}, err
+	}
+	if err = sqlDecodeList(history, &e.StateHistory); err != nil {
+		return entity.Host{}, err
+	}
+	if err = sqlDecodeVars(callback, &e.LastCallback); err != nil {
+		return entity.Host{}, err
+	}
+	e.CallbackTokenExpires = sqlDecodeTime(expires)
+	if e.HardwareAddr, err = h.session.queryStrings(`SELECT hardware_addr FROM host_hardware_addrs
+		WHERE host_id = ? ORDER BY position`, ID); err != nil {
+		return entity.Host{}, err
+	}
+	if e.GroupIDs, err = h.session.queryStrings(`SELECT group_id FROM host_groups
+		WHERE host_id = ? ORDER BY position`, ID); err != nil {
+		return entity.Host{}, err
+	}
+	return e, nil
+}
+
+// FindByHardwareAddr implements repository.HostRepository interface
+func (h *sqlHostRepository) FindByHardwareAddr(hardwareAddr string) (entity.Host, error) {
+	var id string
+	err := h.session.queryRow(`SELECT host_id FROM host_hardware_addrs WHERE hardware_addr = ?`, hardwareAddr).
+		Scan(&id)
+	if err == sql.ErrNoRows {
+		return entity.Host{}, &errors.Error{Code: errors.ERepositoryKeyNotFound,
+			Msg: fmt.Sprintf("entity.Host key %v not found", hardwareAddr)}
+	}
+	if err != nil {
+		return entity.Host{}, &errors.Error{Code: errors.EUnknown,
+			Msg: fmt.Sprintf("entity.Host HardwareAddr %v can't be read", hardwareAddr), Err: err}
+	}
+	return h.Get(id)
+}
+
+// FindByIdentifier implements repository.HostRepository interface
+func (h *sqlHostRepository) FindByIdentifier(kind string, value string) (entity.Host, error) {
+	var id string
+	err := h.session.queryRow(`SELECT host_id FROM host_identifiers WHERE identifier = ?`,
+		hostIdentifierKey(kind, value)).Scan(&id)
+	if err == sql.ErrNoRows {
+		return entity.Host{}, hostIdentifierNotFound(kind, value)
+	}
+	if err != nil {
+		return entity.Host{}, &errors.Error{Code: errors.EUnknown,
+			Msg: fmt.Sprintf("entity.Host %v %v can't be read", kind, value), Err: err}
+	}
+	return h.Get(id)
+}
+
+// FindByIP implements repository.HostRepository interface
+func (h *sqlHostRepository) FindByIP(ip string) (entity.Host, error) {
+	if ip == "" {
+		return entity.Host{}, hostIPNotFound(ip)
+	}
+	var id string
+	err := h.session.queryRow(`SELECT id FROM hosts WHERE ip = ?`, ip).Scan(&id)
+	if err == sql.ErrNoRows {
+		return entity.Host{}, hostIPNotFound(ip)
+	}
+	if err != nil {
+		return entity.Host{}, &errors.Error{Code: errors.EUnknown,
+			Msg: fmt.Sprintf("entity.Host IP %v can't be read", ip), Err: err}
+	}
+	return h.Get(id)
+}
+
+// Update implements repository.HostRepository interface
+func (h *sqlHostRepository) Update(host entity.Host) error {
+	if h.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	e := host
+	if e.ID == "" {
+		return &errors.Error{Code: errors.ERepositoryEmptyKey,
+			Msg: "entity.Host key is empty"}
+	}
+	oe, err := h.Get(e.ID)
+	if err != nil {
+		return err
+	}
+	if err := h.checkHardwareAddr(e); err != nil {
+		return err
+	}
+	if err := h.checkIdentifiers(e); err != nil {
+		return err
+	}
+	if err := checkHostReservation(h.session, e); err != nil {
+		return err
+	}
+	if e.TemplateID != "" {
+		if _, err := h.session.Template().Get(e.TemplateID); err != nil {
+			return &errors.Error{Code: errors.ERepositoryKeyNotFound,
+				Msg: fmt.Sprintf("entity.Host TemplateID %v not found.", e.TemplateID)}
+		}
+	}
+	if err := checkHostGroups(h.session, e); err != nil {
+		return err
+	}
+	if err := updateHostGroups(h.session, e.ID, oe.Groups(), e.Groups()); err != nil {
+		return err
+	}
+
+	vars, err := sqlEncodeVars(e.Vars)
+	if err != nil {
+		return err
+	}
+	artifacts, err := sqlEncodeVars(e.Artifacts)
+	if err != nil {
+		return err
+	}
+	secrets, err := sqlEncodeVars(e.Secrets)
+	if err != nil {
+		return err
+	}
+	labels, err := sqlEncodeVars(e.Labels)
+	if err != nil {
+		return err
+	}
+	history, err := sqlEncodeList(e.StateHistory)
+	if err != nil {
+		return err
+	}
+	callback, err := sqlEncodeVars(e.LastCallback)
+	if err != nil {
+		return err
+	}
+	if err := h.session.exec(`UPDATE hosts SET trap_mode = ?, trap_triggered = ?, vars = ?, group_id = ?,
+		template_id = ?, ip = ?, hostname = ?, template_revision = ?, artifacts = ?, secrets = ?, labels = ?,
+		pending = ?, uuid = ?, serial = ?, asset_tag = ?, state = ?, state_history = ?, callback_token = ?,
+		callback_token_expires = ?, last_callback = ? WHERE id = ?`,
+		e.TrapMode, e.TrapTriggered, vars, e.GroupID, e.TemplateID, e.IP, e.Hostname, e.TemplateRevision, artifacts,
+		secrets, labels, e.Pending, e.UUID, e.Serial, e.AssetTag, e.State, history, e.CallbackToken,
+		sqlEncodeTime(e.CallbackTokenExpires), callback, e.ID); err != nil {
+		return err
+	}
+	if err := h.saveIdentifiers(e); err != nil {
+		return err
+	}
+	if err := h.session.exec(`DELETE FROM host_hardware_addrs WHERE host_id = ?`, e.ID); err != nil {
+		return err
+	}
+	if err := h.saveGroupIDs(e); err != nil {
+		return err
+	}
+	return h.saveHardwareAddr(e)
+}
+
+// Delete implements repository.HostRepository interface
+func (h *sqlHostRepository) Delete(host entity.Host) error {
+	if h.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	if host.ID == "" {
+		return &errors.Error{Code: errors.ERepositoryEmptyKey,
+			Msg: "entity.Host key is empty"}
+	}
+	oe, err := h.Get(host.ID)
+	if err != nil {
+		return err
+	}
+	if err := updateHostGroups(h.session, oe.ID, oe.Groups(), nil); err != nil {
+		return err
+	}
+	if err := deleteHostFacts(h.session, oe.ID); err != nil {
+		return err
+	}
+	if err := deleteHostEvents(h.session, oe.ID); err != nil {
+		return err
+	}
+	if err := h.session.exec(`DELETE FROM host_hardware_addrs WHERE host_id = ?`, oe.ID); err != nil {
+		return err
+	}
+	if err := h.session.exec(`DELETE FROM host_identifiers WHERE host_id = ?`, oe.ID); err != nil {
+		return err
+	}
+	if err := h.session.exec(`DELETE FROM host_groups WHERE host_id = ?`, oe.ID); err != nil {
+		return err
+	}
+	return h.session.exec(`DELETE FROM hosts WHERE id = ?`, oe.ID)
+}
+
+// checkHardwareAddr returns errors.ERepositoryKeyExist if any HardwareAddr belongs to another host.
+func (h *sqlHostRepository) checkHardwareAddr(e entity.Host) error {
+	for _, m := range e.HardwareAddr {
+		var id string
+		err := h.session.queryRow(`SELECT host_id FROM host_hardware_addrs WHERE hardware_addr = ?`, m).Scan(&id)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return &errors.Error{Code: errors.EUnknown,
+				Msg: fmt.Sprintf("entity.Host HardwareAddr %v can't be read", m), Err: err}
+		}
+		if id != e.ID {
+			return &errors.Error{Code: errors.ERepositoryKeyExist,
+				Msg: fmt.Sprintf("entity.Host HardwareAddr %v already exists ", m)}
+		}
+	}
+	return nil
+}
+
+// saveHardwareAddr stores the HardwareAddr index of the host.
+func (h *sqlHostRepository) saveHardwareAddr(e entity.Host) error {
+	for i, m := range e.HardwareAddr {
+		if err := h.session.exec(`INSERT INTO host_hardware_addrs (hardware_addr, host_id, position)
+			VALUES (?, ?, ?)`, m, e.ID, i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkIdentifiers returns errors.ERepositoryKeyExist if any identifier belongs to another host.
+func (h *sqlHostRepository) checkIdentifiers(e entity.Host) error {
+	for _, k := range hostIdentifierKeys(e) {
+		var id string
+		err := h.session.queryRow(`SELECT host_id FROM host_identifiers WHERE identifier = ?`, k).Scan(&id)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return &errors.Error{Code: errors.EUnknown,
+				Msg: fmt.Sprintf("entity.Host identifier %v can't be read", k), Err: err}
+		}
+		if id != e.ID {
+			return hostIdentifierExist(k)
+		}
+	}
+	return nil
+}
+
+// saveIdentifiers replaces the identifier index of the host.
+func (h *sqlHostRepository) saveIdentifiers(e entity.Host) error {
+	if err := h.session.exec(`DELETE FROM host_identifiers WHERE host_id = ?`, e.ID); err != nil {
+		return err
+	}
+	for _, k := range hostIdentifierKeys(e) {
+		if err := h.session.exec(`INSERT INTO host_identifiers (identifier, host_id) VALUES (?, ?)`,
+			k, e.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveGroupIDs replaces the stored GroupIDs of the host, duplicates are stored once.
+func (h *sqlHostRepository) saveGroupIDs(e entity.Host) error {
+	if err := h.session.exec(`DELETE FROM host_groups WHERE host_id = ?`, e.ID); err != nil {
+		return err
+	}
+	groupIDs := make([]string, 0, len(e.GroupIDs))
+	for _, g := range e.GroupIDs {
+		groupIDs = util.AddUniqueStringToSlice(groupIDs, g)
+	}
+	for i, g := range groupIDs {
+		if err := h.session.exec(`INSERT INTO host_groups (host_id, group_id, position) VALUES (?, ?, ?)`,
+			e.ID, g, i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// List implements repository.HostRepository interface
+func (h *sqlHostRepository) List(after string, limit int) ([]entity.Host, error) {
+	q := `SELECT id FROM hosts WHERE id > ? ORDER BY id`
+	args := []interface{}{after}
+	if limit > 0 {
+		q += ` LIMIT ?`
+		args = append(args, limit)
+	}
+	ids, err := h.session.queryStrings(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	l := make([]entity.Host, 0, len(ids))
+	for _, id := range ids {
+		e, err := h.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		l = append(l, e)
+	}
+	return l, nil
+}
@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+	"time"
+)
+
+// sqlLeaseRepository defines the CRUD procedure for entity.Lease
+type sqlLeaseRepository struct {
+	session *SQLSession
+	config  SQLConfig
+}
+
+// newSQLLeaseRepository instantiates a new repository for entity.Lease
+func newSQLLeaseRepository(s *SQLSession, config SQLConfig) *LeaseRepository {
+	var lr LeaseRepository
+	lr = &sqlLeaseRepository{
+		s,
+		config,
+	}
+	return &lr
+}
+
+// Create implements repository.LeaseRepository interface
+func (l *sqlLeaseRepository) Create(lease entity.Lease) error {
+	if l.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	e := lease
+	if e.HardwareAddr == "" {
+		return &errors.Error{Code: errors.ERepositoryEmptyKey,
+			Msg: "entity.Lease key is empty"}
+	}
+	if _, err := l.Get(e.HardwareAddr); err == nil {
+		return &errors.Error{Code: errors.ERepositoryKeyExist,
+			Msg: fmt.Sprintf("entity.Lease key %v already exists ", e.HardwareAddr)}
+	} else if !errors.Is(err, errors.ERepositoryKeyNotFound) {
+		return err
+	}
+	if _, err := l.FindByIP(e.IP); err == nil {
+		return &errors.Error{Code: errors.ERepositoryKeyExist,
+			Msg: fmt.Sprintf("entity.Lease IP %v already exists ", e.IP)}
+	} else if !errors.Is(err, errors.ERepositoryKeyNotFound) {
+		return err
+	}
+	return l.session.exec(`INSERT INTO leases (hardware_addr, ip, hostname, host_id, expires) VALUES (?, ?, ?, ?, ?)`,
+		e.HardwareAddr, e.IP, e.Hostname, e.HostID, e.Expires.Unix())
+}
+
+// Get implements repository.LeaseRepository interface
+func (l *sqlLeaseRepository) Get(hardwareAddr string) (entity.Lease, error) {
+	return l.get(`SELECT hardware_addr, ip, hostname, host_id, expires FROM leases WHERE hardware_addr = ?`,
+		hardwareAddr, "key")
+}
+
+// FindByIP implements repository.LeaseRepository interface
+func (l *sqlLeaseRepository) FindByIP(ip string) (entity.Lease, error) {
+	return l.get(`SELECT hardware_addr, ip, hostname, host_id, expires FROM leases WHERE ip = ?`, ip, "IP")
+}
+
+// Update implements repository.LeaseRepository interface
+func (l *sqlLeaseRepository) Update(lease entity.Lease) error {
+	if l.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	e := lease
+	if e.HardwareAddr == "" {
+		return &errors.Error{Code: errors.ERepositoryEmptyKey,
+			Msg: "entity.Lease key is empty"}
+	}
+	if _, err := l.Get(e.HardwareAddr); err != nil {
+		return err
+	}
+	if oe, err := l.FindByIP(e.IP); err == nil && oe.HardwareAddr != e.HardwareAddr {
+		return &errors.Error{Code: errors.ERepositoryKeyExist,
+			Msg: fmt.Sprintf("entity.Lease IP %v already exists ", e.IP)}
+	} else if err != nil && !errors.Is(err, errors.ERepositoryKeyNotFound) {
+		return err
+	}
+	return l.session.exec(`UPDATE leases SET ip = ?, hostname = ?, host_id = ?, expires = ? WHERE hardware_addr = ?`,
+		e.IP, e.Hostname, e.HostID, e.Expires.Unix(), e.HardwareAddr)
+}
+
+// Delete implements repository.LeaseRepository interface
+func (l *sqlLeaseRepository) Delete(lease entity.Lease) error {
+	if l.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	if lease.HardwareAddr == "" {
+		return &errors.Error{Code: errors.ERepositoryEmptyKey,
+			Msg: "entity.Lease key is empty"}
+	}
+	if _, err := l.Get(lease.HardwareAddr); err != nil {
+		return err
+	}
+	return l.session.exec(`DELETE FROM leases WHERE hardware_addr = ?`, lease.HardwareAddr)
+}
+
+// List implements repository.LeaseRepository interface
+func (l *sqlLeaseRepository) List(after string, limit int) ([]entity.Lease, error) {
+	q := `SELECT hardware_addr FROM leases WHERE hardware_addr > ? ORDER BY hardware_addr`
+	args := []interface{}{after}
+	if limit > 0 {
+		q += ` LIMIT ?`
+		args = append(args, limit)
+	}
+	keys, err := l.session.queryStrings(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	r := make([]entity.Lease, 0, len(keys))
+	for _, k := range keys {
+		e, err := l.Get(k)
+		if err != nil {
+			return nil, err
+		}
+		r = append(r, e)
+	}
+	return r, nil
+}
+
+// get returns the single entity.Lease matching the query.
+func (l *sqlLeaseRepository) get(query string, arg string, field string) (entity.Lease, error) {
+	e := entity.Lease{}
+	var expires int64
+	err := l.session.queryRow(query, arg).Scan(&e.HardwareAddr, &e.IP, &e.Hostname, &e.HostID, &expires)
+	if err == sql.ErrNoRows {
+		return entity.Lease{}, &errors.Error{Code: errors.ERepositoryKeyNotFound,
+			Msg: fmt.Sprintf("entity.Lease %v %v not found", field, arg)}
+	}
+	if err != nil {
+		return entity.Lease{}, &errors.Error{Code: errors.EUnknown,
+			Msg: fmt.Sprintf("entity.Lease %v %v can't be read", field, arg), Err: err}
+	}
+	e.Expires = time.Unix(expires, 0)
+	return e, nil
+}
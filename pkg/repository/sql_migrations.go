@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/pxecore/pxecore/pkg/errors"
+)
+
+// sqlMigrations holds the versioned schema of the sql driver.
+// Version N is the statement list at index N-1. Released versions must never
+// be modified, new changes are appended as a new version.
+var sqlMigrations = [][]string{
+	{
+		`CREATE TABLE templates (
+			id VARCHAR(255) PRIMARY KEY,
+			template TEXT NOT NULL)`,
+		`CREATE TABLE "groups" (
+			id VARCHAR(255) PRIMARY KEY,
+			vars TEXT NOT NULL,
+			parent_id VARCHAR(255) NOT NULL,
+			template_id VARCHAR(255) NOT NULL)`,
+		`CREATE TABLE group_hosts (
+			group_id VARCHAR(255) NOT NULL,
+			host_id VARCHAR(255) NOT NULL,
+			position INTEGER NOT NULL,
+			PRIMARY KEY (group_id, host_id))`,
+		`CREATE TABLE group_children (
+			group_id VARCHAR(255) NOT NULL,
+			child_id VARCHAR(255) NOT NULL,
+			position INTEGER NOT NULL,
+			PRIMARY KEY (group_id, child_id))`,
+		`CREATE TABLE hosts (
+			id VARCHAR(255) PRIMARY KEY,
+			trap_mode BOOLEAN NOT NULL,
+			trap_triggered BOOLEAN NOT NULL,
+			vars TEXT NOT NULL,
+			group_id VARCHAR(255) NOT NULL,
+			template_id VARCHAR(255) NOT NULL)`,
+		`CREATE TABLE host_hardware_addrs (
+			hardware_addr VARCHAR(255) PRIMARY KEY,
+			host_id VARCHAR(255) NOT NULL,
+			position INTEGER NOT NULL)`,
+		`CREATE INDEX host_hardware_addrs_host_id ON host_hardware_addrs (host_id)`,
+	},
+	{
+		`ALTER TABLE hosts ADD COLUMN ip VARCHAR(64) NOT NULL DEFAULT ''`,
+		`ALTER TABLE hosts ADD COLUMN hostname VARCHAR(255) NOT NULL DEFAULT ''`,
+		`CREATE TABLE leases (
+			hardware_addr VARCHAR(255) PRIMARY KEY,
+			ip VARCHAR(64) NOT NULL UNIQUE,
+			hostname VARCHAR(255) NOT NULL,
+			host_id VARCHAR(255) NOT NULL,
+			expires BIGINT NOT NULL)`,
+	},
+	{
+		`ALTER TABLE templates ADD COLUMN revision INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE templates ADD COLUMN author VARCHAR(255) NOT NULL DEFAULT ''`,
+		`CREATE TABLE template_revisions (
+			template_id VARCHAR(255) NOT NULL,
+			revision INTEGER NOT NULL,
+			template TEXT NOT NULL,
+			author VARCHAR(255) NOT NULL,
+			created BIGINT NOT NULL,
+			hash VARCHAR(64) NOT NULL,
+			PRIMARY KEY (template_id, revision))`,
+		`ALTER TABLE hosts ADD COLUMN template_revision INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE "groups" ADD COLUMN template_revision INTEGER NOT NULL DEFAULT 0`,
+	},
+	{
+		`ALTER TABLE templates ADD COLUMN kind VARCHAR(64) NOT NULL DEFAULT ''`,
+		`ALTER TABLE hosts ADD COLUMN artifacts TEXT NOT NULL DEFAULT '{}'`,
+		`ALTER TABLE "groups" ADD COLUMN artifacts TEXT NOT NULL DEFAULT '{}'`,
+	},
+	{
+		`ALTER TABLE hosts ADD COLUMN secrets TEXT NOT NULL DEFAULT '{}'`,
+		`ALTER TABLE "groups" ADD COLUMN secrets TEXT NOT NULL DEFAULT '{}'`,
+	},
+	{
+		`CREATE TABLE host_groups (
+			host_id VARCHAR(255) NOT NULL,
+			group_id VARCHAR(255) NOT NULL,
+			position INTEGER NOT NULL,
+			PRIMARY KEY (host_id, group_id))`,
+	},
+	{
+		`CREATE TABLE rules (
+			id VARCHAR(255) PRIMARY KEY,
+			priority INTEGER NOT NULL,
+			selector TEXT NOT NULL,
+			template_id VARCHAR(255) NOT NULL)`,
+		`ALTER TABLE hosts ADD COLUMN labels TEXT NOT NULL DEFAULT '{}'`,
+	},
+	{
+		`ALTER TABLE hosts ADD COLUMN pending BOOLEAN NOT NULL DEFAULT FALSE`,
+	},
+	{
+		`CREATE TABLE host_facts (
+			host_id VARCHAR(255) NOT NULL,
+			revision INTEGER NOT NULL,
+			created BIGINT NOT NULL,
+			document TEXT NOT NULL,
+			PRIMARY KEY (host_id, revision))`,
+	},
+	{
+		`ALTER TABLE hosts ADD COLUMN uuid VARCHAR(255) NOT NULL DEFAULT ''`,
+		`ALTER TABLE hosts ADD COLUMN serial VARCHAR(255) NOT NULL DEFAULT ''`,
+		`ALTER TABLE hosts ADD COLUMN asset_tag VARCHAR(255) NOT NULL DEFAULT ''`,
+		`CREATE TABLE host_identifiers (
+			identifier VARCHAR(255) PRIMARY KEY,
+			host_id VARCHAR(255) NOT NULL)`,
+		`CREATE INDEX host_identifiers_host_id ON host_identifiers (host_id)`,
+	},
+	{
+		`CREATE TABLE boot_events (
+			host_id VARCHAR(255) NOT NULL,
+			sequence INTEGER NOT NULL,
+			time BIGINT NOT NULL,
+			document TEXT NOT NULL,
+			PRIMARY KEY (host_id, sequence))`,
+		`CREATE INDEX boot_events_time ON boot_events (time)`,
+	},
+	{
+		`ALTER TABLE hosts ADD COLUMN state VARCHAR(64) NOT NULL DEFAULT ''`,
+		`ALTER TABLE hosts ADD COLUMN state_history TEXT NOT NULL DEFAULT '[]'`,
+	},
+	{
+		`ALTER TABLE hosts ADD COLUMN callback_token VARCHAR(255) NOT NULL DEFAULT ''`,
+		`ALTER TABLE hosts ADD COLUMN callback_token_expires BIGINT NOT NULL DEFAULT 0`,
+		`ALTER TABLE hosts ADD COLUMN last_callback TEXT NOT NULL DEFAULT '{}'`,
+	},
+	{
+		`CREATE INDEX hosts_ip ON hosts (ip)`,
+	},
+}
+
+// migrateSQL applies all the pending sqlMigrations, each version in its own transaction.
+func migrateSQL(db *sql.DB, dialect string) error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY)`); err != nil {
+		return &errors.Error{Code: errors.EUnknown, Msg: "sql schema_migrations can't be created", Err: err}
+	}
+	var current int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return &errors.Error{Code: errors.EUnknown, Msg: "sql schema version can't be read", Err: err}
+	}
+	if current > len(sqlMigrations) {
+		return &errors.Error{Code: errors.EUnknown,
+			Msg: fmt.Sprintf("sql schema version %v is newer than the supported %v", current, len(sqlMigrations))}
+	}
+	for v := current + 1; v <= len(sqlMigrations); v++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return &errors.Error{Code: errors.EUnknown, Msg: "sql migration transaction can't be started", Err: err}
+		}
+		for _, stmt := range sqlMigrations[v-1] {
+			if _, err := tx.Exec(stmt); err != nil {
+				_ = tx.Rollback()
+				return &errors.Error{Code: errors.EUnknown,
+					Msg: fmt.Sprintf("sql migration %v failed", v), Err: err}
+			}
+		}
+		if _, err := tx.Exec(sqlRebind(dialect, `INSERT INTO schema_migrations (version) VALUES (?)`), v); err != nil {
+			_ = tx.Rollback()
+			return &errors.Error{Code: errors.EUnknown,
+				Msg: fmt.Sprintf("sql migration %v can't be recorded", v), Err: err}
+		}
+		if err := tx.Commit(); err != nil {
+			return &errors.Error{Code: errors.EUnknown,
+				Msg: fmt.Sprintf("sql migration %v can't be committed", v), Err: err}
+		}
+	}
+	return nil
+}
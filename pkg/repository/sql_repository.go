@@ -0,0 +1,378 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/pxecore/pxecore/pkg/errors"
+	"github.com/pxecore/pxecore/pkg/util"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	// Supported database/sql drivers.
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const (
+	// SQLDialectSQLite selects the embedded SQLite database.
+	SQLDialectSQLite string = "sqlite3"
+	// SQLDialectPostgres selects a PostgreSQL server.
+	SQLDialectPostgres string = "postgres"
+)
+
+//~ STRUCT - sqlRepository ----------------------------------------------------
+
+// sqlRepository stores all entities in a relational database.
+type sqlRepository struct {
+	lock   *sync.RWMutex
+	config SQLConfig
+	db     *sql.DB
+}
+
+func (r *sqlRepository) Open(write bool) (Session, error) {
+	if write {
+		r.lock.Lock()
+	} else {
+		r.lock.RLock()
+	}
+	tx, err := r.db.Begin()
+	if err != nil {
+		if write {
+			r.lock.Unlock()
+		} else {
+			r.lock.RUnlock()
+		}
+		return nil, &errors.Error{Code: errors.EUnknown, Msg: "sql transaction can't be started", Err: err}
+	}
+	return newSQLSession(r, r.config, tx, !write), nil
+}
+
+func (r *sqlRepository) Read(f func(session Session) error) error {
+	s, err := r.Open(false)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	return f(s)
+}
+
+func (r *sqlRepository) Write(f func(session Session) error) error {
+	s, err := r.Open(true)
+	if err != nil {
+		return err
+	}
+	ss := s.(*SQLSession)
+	if err := f(ss); err != nil {
+		_ = ss.rollback()
+		return err
+	}
+	return ss.Close()
+}
+
+// newSQLRepository creates a new repository for the driver sql.
+func newSQLRepository(config map[string]interface{}) (Repository, error) {
+	r := new(sqlRepository)
+	var ri Repository = r
+
+	c, err := NewSQLConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	r.config = c
+	r.lock = new(sync.RWMutex)
+	r.db, err = sql.Open(c.dialect, c.dsn)
+	if err != nil {
+		return nil, &errors.Error{Code: errors.EUnknown, Msg: "sql database can't be opened", Err: err}
+	}
+	if err := migrateSQL(r.db, c.dialect); err != nil {
+		_ = r.db.Close()
+		return nil, err
+	}
+	return ri, nil
+}
+
+//~ STRUCT - SQLSession -------------------------------------------------------
+
+// SQLSession holds a single sql transaction.
+type SQLSession struct {
+	open               bool
+	readOnly           bool
+	repository         *sqlRepository
+	config             SQLConfig
+	tx                 *sql.Tx
+	hostRepository     *HostRepository
+	templateRepository *TemplateRepository
+	groupRepository    *GroupRepository
+	leaseRepository    *LeaseRepository
+	ruleRepository     *RuleRepository
+	factsRepository    *FactsRepository
+	eventRepository    *EventRepository
+}
+
+// Close terminates the session committing the write transactions.
+func (s *SQLSession) Close() error {
+	if !s.open {
+		return &errors.Error{Code: errors.EUnknown, Msg: "repository already closed"}
+	}
+	var err error
+	if s.readOnly {
+		err = s.tx.Rollback()
+		s.repository.lock.RUnlock()
+	} else {
+		err = s.tx.Commit()
+		s.repository.lock.Unlock()
+	}
+	s.open = false
+	if err != nil {
+		return &errors.Error{Code: errors.EUnknown, Msg: "sql transaction can't be closed", Err: err}
+	}
+	return nil
+}
+
+// rollback terminates the session discarding all changes.
+func (s *SQLSession) rollback() error {
+	if !s.open {
+		return &errors.Error{Code: errors.EUnknown, Msg: "repository already closed"}
+	}
+	err := s.tx.Rollback()
+	if s.readOnly {
+		s.repository.lock.RUnlock()
+	} else {
+		s.repository.lock.Unlock()
+	}
+	s.open = false
+	return err
+}
+
+// Host returns HostRepository
+func (s *SQLSession) Host() HostRepository {
+	if s.hostRepository == nil {
+		s.hostRepository = newSQLHostRepository(s, s.config)
+	}
+	return *s.hostRepository
+}
+
+// Template returns TemplateRepository
+func (s *SQLSession) Template() TemplateRepository {
+	if s.templateRepository == nil {
+		s.templateRepository = newSQLTemplateRepository(s, s.config)
+	}
+	return *s.templateRepository
+}
+
+// Group returns GroupRepository
+func (s *SQLSession) Group() GroupRepository {
+	if s.groupRepository == nil {
+		s.groupRepository = newSQLGroupRepository(s, s.config)
+	}
+	return *s.groupRepository
+}
+
+// Lease returns LeaseRepository
+func (s *SQLSession) Lease() LeaseRepository {
+	if s.leaseRepository == nil {
+		s.leaseRepository = newSQLLeaseRepository(s, s.config)
+	}
+	return *s.leaseRepository
+}
+
+// Rule returns RuleRepository
+func (s *SQLSession) Rule() RuleRepository {
+	if s.ruleRepository == nil {
+		s.ruleRepository = newSQLRuleRepository(s, s.config)
+	}
+	return *s.ruleRepository
+}
+
+// Facts returns FactsRepository
+func (s *SQLSession) Facts() FactsRepository {
+	if s.factsRepository == nil {
+		s.factsRepository = newSQLFactsRepository(s, s.config)
+	}
+	return *s.factsRepository
+}
+
+// Event returns EventRepository
+func (s *SQLSession) Event() EventRepository {
+	if s.eventRepository == nil {
+		s.eventRepository = newSQLEventRepository(s, s.config)
+	}
+	return *s.eventRepository
+}
+
+// IsReadOnly returns true is the session is for read only.
+func (s *SQLSession) IsReadOnly() bool {
+	return s.readOnly
+}
+
+// IsOpen returns true is the session is open for transactions.
+func (s *SQLSession) IsOpen() bool {
+	return s.open
+}
+
+// exec runs a statement written with "?" placeholders inside the session transaction.
+func (s *SQLSession) exec(query string, args ...interface{}) error {
+	if _, err := s.tx.Exec(sqlRebind(s.config.dialect, query), args...); err != nil {
+		return &errors.Error{Code: errors.EUnknown, Msg: "sql statement failed", Err: err}
+	}
+	return nil
+}
+
+// query runs a query written with "?" placeholders inside the session transaction.
+func (s *SQLSession) query(query string, args ...interface{}) (*sql.Rows, error) {
+	rows, err := s.tx.Query(sqlRebind(s.config.dialect, query), args...)
+	if err != nil {
+		return nil, &errors.Error{Code: errors.EUnknown, Msg: "sql query failed", Err: err}
+	}
+	return rows, nil
+}
+
+// queryRow runs a single row query written with "?" placeholders inside the session transaction.
+func (s *SQLSession) queryRow(query string, args ...interface{}) *sql.Row {
+	return s.tx.QueryRow(sqlRebind(s.config.dialect, query), args...)
+}
+
+// queryStrings returns the first column of all the rows of a query.
+func (s *SQLSession) queryStrings(query string, args ...interface{}) ([]string, error) {
+	rows, err := s.query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	r := make([]string, 0)
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return nil, &errors.Error{Code: errors.EUnknown, Msg: "sql row can't be read", Err: err}
+		}
+		r = append(r, v)
+	}
+	return r, rows.Err()
+}
+
+func newSQLSession(r *sqlRepository, config SQLConfig, tx *sql.Tx, readOnly bool) Session {
+	s := SQLSession{
+		repository: r,
+		open:       true,
+		readOnly:   readOnly,
+		config:     config,
+		tx:         tx,
+	}
+	return &s
+}
+
+// sqlRebind converts "?" placeholders into the dialect placeholders.
+func sqlRebind(dialect string, query string) string {
+	if dialect != SQLDialectPostgres {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			b.WriteString("$")
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// sqlEncodeVars converts a map of vars or artifacts into its stored JSON representation.
+func sqlEncodeVars(vars interface{}) (string, error) {
+	j, err := json.Marshal(vars)
+	if err != nil {
+		return "", &errors.Error{Code: errors.EInvalidType, Msg: "sql vars can't be encoded", Err: err}
+	}
+	if string(j) == "null" {
+		return "{}", nil
+	}
+	return string(j), nil
+}
+
+// sqlDecodeVars converts the stored JSON representation into the map pointed by vars.
+func sqlDecodeVars(j string, vars interface{}) error {
+	if err := json.Unmarshal([]byte(j), vars); err != nil {
+		return &errors.Error{Code: errors.EInvalidType, Msg: "sql vars can't be decoded", Err: err}
+	}
+	return nil
+}
+
+// sqlEncodeList converts a list into its stored JSON representation, nil lists are stored empty.
+func sqlEncodeList(l interface{}) (string, error) {
+	j, err := json.Marshal(l)
+	if err != nil {
+		return "", &errors.Error{Code: errors.EInvalidType, Msg: "sql list can't be encoded", Err: err}
+	}
+	if string(j) == "null" {
+		return "[]", nil
+	}
+	return string(j), nil
+}
+
+// sqlDecodeList converts the stored JSON representation into the list pointed by l.
+// Empty lists are left nil.
+func sqlDecodeList(j string, l interface{}) error {
+	if j == "[]" {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(j), l); err != nil {
+		return &errors.Error{Code: errors.EInvalidType, Msg: "sql list can't be decoded", Err: err}
+	}
+	return nil
+}
+
+// sqlEncodeTime converts a time into its stored Unix seconds, the zero time is stored as 0.
+func sqlEncodeTime(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+// sqlDecodeTime converts the stored Unix seconds into a time, 0 is the zero time.
+func sqlDecodeTime(s int64) time.Time {
+	if s == 0 {
+		return time.Time{}
+	}
+	return time.Unix(s, 0)
+}
+
+//~ STRUCT - SQLConfig --------------------------------------------------------
+
+// SQLConfig stores sql driver config for all repositories.
+type SQLConfig struct {
+	dialect string
+	dsn     string
+}
+
+// NewSQLConfig creates a new SQLConfig extracting and checking type of the required fields.
+func NewSQLConfig(config map[string]interface{}) (SQLConfig, error) {
+	c := SQLConfig{}
+
+	s, err := util.StringFromMap(config, "dialect", SQLDialectSQLite)
+	if err != nil {
+		return c, &errors.Error{Code: errors.EInvalidType, Msg: "config invalid type for key dialect"}
+	}
+	switch strings.ToLower(s) {
+	case SQLDialectSQLite, "sqlite":
+		c.dialect = SQLDialectSQLite
+	case SQLDialectPostgres, "postgresql":
+		c.dialect = SQLDialectPostgres
+	default:
+		return c, &errors.Error{Code: errors.EInvalidType, Msg: fmt.Sprintf("config unsupported sql dialect %v", s)}
+	}
+
+	s, err = util.StringFromMap(config, "dsn", "pxecore.sqlite")
+	if err != nil {
+		return c, &errors.Error{Code: errors.EInvalidType, Msg: "config invalid type for key dsn"}
+	}
+	c.dsn = s
+
+	return c, nil
+}
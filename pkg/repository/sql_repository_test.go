@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestSQLRepository_Atomicity(t *testing.T) {
+	d, err := ioutil.TempDir(testDir, "atomicity")
+	if err != nil {
+		t.Fatal("Error creating test directory - ", err)
+	}
+	c := map[string]interface{}{"dialect": "sqlite3", "dsn": filepath.Join(d, "atomicity.sqlite")}
+	r, err := newSQLRepository(c)
+	if err != nil {
+		t.Fatal("Error opening sql repository - ", err)
+	}
+	if err := r.Write(func(s Session) error {
+		if err := s.Group().Create(entity.Group{ID: "group"}); err != nil {
+			return err
+		}
+		return s.Host().Create(entity.Host{ID: "host", HardwareAddr: []string{"88-99-aa-bb-cc-dd"},
+			GroupID: "group"})
+	}); err != nil {
+		t.Fatal("Error writing sql repository - ", err)
+	}
+	if err := r.Write(func(s Session) error {
+		if err := s.Host().Delete(entity.Host{ID: "host"}); err != nil {
+			return err
+		}
+		return &errors.Error{Code: errors.EUnknown, Msg: "forced rollback"}
+	}); err == nil {
+		t.Fatal("Forced rollback returned no error")
+	}
+	_ = r.(*sqlRepository).db.Close()
+
+	r, err = newSQLRepository(c)
+	if err != nil {
+		t.Fatal("Error migrating an existing sql repository - ", err)
+	}
+	defer r.(*sqlRepository).db.Close()
+	if err := r.Read(func(s Session) error {
+		h, err := s.Host().FindByHardwareAddr("88-99-aa-bb-cc-dd")
+		if err != nil {
+			return err
+		}
+		if h.ID != "host" || h.GroupID != "group" {
+			t.Error("Invalid stored data - ", h)
+		}
+		g, err := s.Group().Get("group")
+		if err != nil {
+			return err
+		}
+		if len(g.HostsIDs) != 1 || g.HostsIDs[0] != "host" {
+			t.Error("Invalid group membership - ", g)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal("Error reading sql repository - ", err)
+	}
+}
+
+func Test_sqlRebind(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect string
+		query   string
+		want    string
+	}{
+		{"SQLITE", SQLDialectSQLite, "SELECT a FROM b WHERE c = ? AND d = ?", "SELECT a FROM b WHERE c = ? AND d = ?"},
+		{"POSTGRES", SQLDialectPostgres, "SELECT a FROM b WHERE c = ? AND d = ?", "SELECT a FROM b WHERE c = $1 AND d = $2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sqlRebind(tt.dialect, tt.query); got != tt.want {
+				t.Errorf("sqlRebind() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+)
+
+// sqlRuleRepository defines the CRUD procedure for entity.Rule
+type sqlRuleRepository struct {
+	session *SQLSession
+	config  SQLConfig
+}
+
+// newSQLRuleRepository instantiates a new repository for entity.Rule
+func newSQLRuleRepository(s *SQLSession, config SQLConfig) *RuleRepository {
+	var rr RuleRepository
+	rr = &sqlRuleRepository{
+		s,
+		config,
+	}
+	return &rr
+}
+
+// Create implements repository.RuleRepository interface
+func (r *sqlRuleRepository) Create(rule entity.Rule) error {
+	if r.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	e := rule
+	if e.ID == "" {
+		return &errors.Error{Code: errors.ERepositoryEmptyKey,
+			Msg: "entity.Rule key is empty"}
+	}
+	if _, err := r.Get(e.ID); err == nil {
+		return &errors.Error{Code: errors.ERepositoryKeyExist,
+			Msg: fmt.Sprintf("entity.Rule key %v already exists ", e.ID)}
+	} else if !errors.Is(err, errors.ERepositoryKeyNotFound) {
+		return err
+	}
+	if err := checkRuleTemplate(r.session, e); err != nil {
+		return err
+	}
+	selector, err := sqlEncodeVars(e.Selector)
+	if err != nil {
+		return err
+	}
+	return r.session.exec(`INSERT INTO rules (id, priority, selector, template_id) VALUES (?, ?, ?, ?)`,
+		e.ID, e.Priority, selector, e.TemplateID)
+}
+
+// Get implements repository.RuleRepository interface
+func (r *sqlRuleRepository) Get(ID string) (entity.Rule, error) {
+	e := entity.Rule{}
+	var selector string
+	err := r.session.queryRow(`SELECT id, priority, selector, template_id FROM rules WHERE id = ?`, ID).
+		Scan(&e.ID, &e.Priority, &selector, &e.TemplateID)
+	if err == sql.ErrNoRows {
+		return entity.Rule{}, &errors.Error{Code: errors.ERepositoryKeyNotFound,
+			Msg: fmt.Sprintf("entity.Rule key %v not found", ID)}
+	}
+	if err != nil {
+		return entity.Rule{}, &errors.Error{Code: errors.EUnknown,
+			Msg: fmt.Sprintf("entity.Rule key %v can't be read", ID), Err: err}
+	}
+	if err = sqlDecodeVars(selector, &e.Selector); err != nil {
+		return entity.Rule{}, err
+	}
+	return e, nil
+}
+
+// Update implements repository.RuleRepository interface
+func (r *sqlRuleRepository) Update(rule entity.Rule) error {
+	if r.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	e := rule
+	if e.ID == "" {
+		return &errors.Error{Code: errors.ERepositoryEmptyKey,
+			Msg: "entity.Rule key is empty"}
+	}
+	if _, err := r.Get(e.ID); err != nil {
+		return err
+	}
+	if err := checkRuleTemplate(r.session, e); err != nil {
+		return err
+	}
+	selector, err := sqlEncodeVars(e.Selector)
+	if err != nil {
+		return err
+	}
+	return r.session.exec(`UPDATE rules SET priority = ?, selector = ?, template_id = ? WHERE id = ?`,
+		e.Priority, selector, e.TemplateID, e.ID)
+}
+
+// Delete implements repository.RuleRepository interface
+func (r *sqlRuleRepository) Delete(rule entity.Rule) error {
+	if r.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	if rule.ID == "" {
+		return &errors.Error{Code: errors.ERepositoryEmptyKey,
+			Msg: "entity.Rule key is empty"}
+	}
+	oe, err := r.Get(rule.ID)
+	if err != nil {
+		return err
+	}
+	return r.session.exec(`DELETE FROM rules WHERE id = ?`, oe.ID)
+}
+
+// List implements repository.RuleRepository interface
+func (r *sqlRuleRepository) List(after string, limit int) ([]entity.Rule, error) {
+	q := `SELECT id FROM rules WHERE id > ? ORDER BY id`
+	args := []interface{}{after}
+	if limit > 0 {
+		q += ` LIMIT ?`
+		args = append(args, limit)
+	}
+	ids, err := r.session.queryStrings(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	l := make([]entity.Rule, 0, len(ids))
+	for _, id := range ids {
+		e, err := r.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		l = append(l, e)
+	}
+	return l, nil
+}
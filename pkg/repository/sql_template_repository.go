@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+	"time"
+)
+
+// sqlTemplateRepository defines the CRUD procedure for entity.Template
+type sqlTemplateRepository struct {
+	session *SQLSession
+	config  SQLConfig
+}
+
+// newSQLTemplateRepository instantiates a new repository for entity.Template
+func newSQLTemplateRepository(s *SQLSession, config SQLConfig) *TemplateRepository {
+	var tr TemplateRepository
+	tr = &sqlTemplateRepository{
+		s,
+		config,
+	}
+	return &tr
+}
+
+// Create implements repository.TemplateRepository interface
+func (t *sqlTemplateRepository) Create(template entity.Template) error {
+	if t.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	e := template
+	if e.ID == "" {
+		return &errors.Error{Code: errors.ERepositoryEmptyKey,
+			Msg: "entity.Template key is empty"}
+	}
+	if _, err := t.Get(e.ID); err == nil {
+		return &errors.Error{Code: errors.ERepositoryKeyExist,
+			Msg: fmt.Sprintf("entity.Template key %v already exists ", e.ID)}
+	} else if !errors.Is(err, errors.ERepositoryKeyNotFound) {
+		return err
+	}
+	e, r := firstTemplateRevision(e)
+	if err := t.insertRevision(r); err != nil {
+		return err
+	}
+	return t.session.exec(`INSERT INTO templates (id, template, revision, author, kind) VALUES (?, ?, ?, ?, ?)`,
+		e.ID, e.Template, e.Revision, e.Author, e.Kind)
+}
+
+// Get implements repository.TemplateRepository interface
+func (t *sqlTemplateRepository) Get(ID string) (entity.Template, error) {
+	e := entity.Template{}
+	err := t.session.queryRow(`SELECT id, template, revision, author, kind FROM templates WHERE id = ?`, ID).
+		Scan(&e.ID, &e.Template, &e.Revision, &e.Author, &e.Kind)
+	if err == sql.ErrNoRows {
+		return entity.Template{}, &errors.Error{Code: errors.ERepositoryKeyNotFound,
+			Msg: fmt.Sprintf("entity.Template key %v not found", ID)}
+	}
+	if err != nil {
+		return entity.Template{}, &errors.Error{Code: errors.EUnknown,
+			Msg: fmt.Sprintf("entity.Template key %v can't be read", ID), Err: err}
+	}
+	return e, nil
+}
+
+// Update implements repository.TemplateRepository interface
+func (t *sqlTemplateRepository) Update(template entity.Template) error {
+	if t.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	e := template
+	if e.ID == "" {
+		return &errors.Error{Code: errors.ERepositoryEmptyKey,
+			Msg: "entity.Template key is empty"}
+	}
+	current, err := t.Get(e.ID)
+	if err != nil {
+		return err
+	}
+	e, r := nextTemplateRevision(current, e)
+	if r != nil {
+		if err := t.insertRevision(*r); err != nil {
+			return err
+		}
+	}
+	return t.session.exec(`UPDATE templates SET template = ?, revision = ?, author = ?, kind = ? WHERE id = ?`,
+		e.Template, e.Revision, e.Author, e.Kind, e.ID)
+}
+
+// Delete implements repository.TemplateRepository interface
+func (t *sqlTemplateRepository) Delete(template entity.Template) error {
+	if t.session.IsReadOnly() {
+		return &errors.Error{Code: errors.ERepositoryReadOnly, Msg: "read-only mode"}
+	}
+	if template.ID == "" {
+		return &errors.Error{Code: errors.ERepositoryEmptyKey,
+			Msg: "entity.Template key is empty"}
+	}
+	if _, err := t.Get(template.ID); err != nil {
+		return err
+	}
+	if err := checkTemplateDependencies(t.session, template.ID); err != nil {
+		return err
+	}
+	if err := t.session.exec(`DELETE FROM template_revisions WHERE template_id = ?`, template.ID); err != nil {
+		return err
+	}
+	return t.session.exec(`DELETE FROM templates WHERE id = ?`, template.ID)
+}
+
+// List implements repository.TemplateRepository interface
+func (t *sqlTemplateRepository) List(after string, limit int) ([]entity.Template, error) {
+	q := `SELECT id FROM templates WHERE id > ? ORDER BY id`
+	args := []interface{}{after}
+	if limit > 0 {
+		q += ` LIMIT ?`
+		args = append(args, limit)
+	}
+	ids, err := t.session.queryStrings(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	l := make([]entity.Template, 0, len(ids))
+	for _, id := range ids {
+		e, err := t.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		l = append(l, e)
+	}
+	return l, nil
+}
+
+// GetRevision implements repository.TemplateRepository interface
+func (t *sqlTemplateRepository) GetRevision(ID string, revision int) (entity.TemplateRevision, error) {
+	e := entity.TemplateRevision{}
+	var created int64
+	err := t.session.queryRow(`SELECT template_id, revision, template, author, created, hash
+		FROM template_revisions WHERE template_id = ? AND revision = ?`, ID, revision).
+		Scan(&e.TemplateID, &e.Revision, &e.Template, &e.Author, &created, &e.Hash)
+	if err == sql.ErrNoRows {
+		return entity.TemplateRevision{}, templateRevisionNotFound(ID, revision)
+	}
+	if err != nil {
+		return entity.TemplateRevision{}, &errors.Error{Code: errors.EUnknown,
+			Msg: fmt.Sprintf("entity.TemplateRevision key %v revision %v can't be read", ID, revision), Err: err}
+	}
+	e.Created = time.Unix(created, 0)
+	return e, nil
+}
+
+// ListRevisions implements repository.TemplateRepository interface
+func (t *sqlTemplateRepository) ListRevisions(ID string) ([]entity.TemplateRevision, error) {
+	if _, err := t.Get(ID); err != nil {
+		return nil, err
+	}
+	rows, err := t.session.query(`SELECT revision FROM template_revisions WHERE template_id = ? ORDER BY revision`, ID)
+	if err != nil {
+		return nil, err
+	}
+	revisions := make([]int, 0)
+	for rows.Next() {
+		var r int
+		if err := rows.Scan(&r); err != nil {
+			_ = rows.Close()
+			return nil, &errors.Error{Code: errors.EUnknown, Msg: "sql row can't be read", Err: err}
+		}
+		revisions = append(revisions, r)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, &errors.Error{Code: errors.EUnknown, Msg: "sql rows can't be closed", Err: err}
+	}
+	l := make([]entity.TemplateRevision, 0, len(revisions))
+	for _, r := range revisions {
+		e, err := t.GetRevision(ID, r)
+		if err != nil {
+			return nil, err
+		}
+		l = append(l, e)
+	}
+	return l, nil
+}
+
+// insertRevision stores a new entity.TemplateRevision.
+func (t *sqlTemplateRepository) insertRevision(r entity.TemplateRevision) error {
+	return t.session.exec(`INSERT INTO template_revisions (template_id, revision, template, author, created, hash)
+		VALUES (?, ?, ?, ?, ?, ?)`, r.TemplateID, r.Revision, r.Template, r.Author, r.Created.Unix(), r.Hash)
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+	"reflect"
+	"testing"
+)
+
+func TestTemplateRepository_Artifacts(t *testing.T) {
+	runDriverTest(t, runTemplateArtifactTest)
+}
+
+func runTemplateArtifactTest(t *testing.T, m Repository) {
+	if err := m.Write(func(s Session) error {
+		if err := s.Template().Create(entity.Template{ID: "ks", Template: "ks", Kind: entity.TemplateKindKickstart}); err != nil {
+			return err
+		}
+		if err := s.Group().Create(entity.Group{ID: "ag", Artifacts: map[string]string{"kickstart": "ks"}}); err != nil {
+			return err
+		}
+		return s.Host().Create(entity.Host{ID: "ah", HardwareAddr: []string{"00-00-00-00-00-a1"},
+			Artifacts: map[string]string{"user-data": "ks"}})
+	}); err != nil {
+		t.Fatal("runTemplateArtifactTest - error creating ", err)
+	}
+	if err := m.Write(func(s Session) error {
+		return s.Template().Update(entity.Template{ID: "ks", Template: "ks2", Kind: entity.TemplateKindCloudInit})
+	}); err != nil {
+		t.Fatal("runTemplateArtifactTest - error updating ", err)
+	}
+	if err := m.Read(func(s Session) error {
+		tp, err := s.Template().Get("ks")
+		if err != nil {
+			return err
+		}
+		if tp.Kind != entity.TemplateKindCloudInit || tp.Template != "ks2" {
+			t.Error("runTemplateArtifactTest - invalid stored template ", tp)
+		}
+		g, err := s.Group().Get("ag")
+		if err != nil {
+			return err
+		}
+		if !reflect.DeepEqual(g.Artifacts, map[string]string{"kickstart": "ks"}) {
+			t.Error("runTemplateArtifactTest - invalid stored group artifacts ", g.Artifacts)
+		}
+		h, err := s.Host().Get("ah")
+		if err != nil {
+			return err
+		}
+		if !reflect.DeepEqual(h.Artifacts, map[string]string{"user-data": "ks"}) {
+			t.Error("runTemplateArtifactTest - invalid stored host artifacts ", h.Artifacts)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal("runTemplateArtifactTest - error reading ", err)
+	}
+	tests := []struct {
+		name     string
+		delete   func(s Session) error
+		wantCode string
+	}{
+		{"KO_TEMPLATE_USED", func(s Session) error { return s.Template().Delete(entity.Template{ID: "ks"}) },
+			errors.ERepositoryDependency},
+		{"OK_HOST", func(s Session) error { return s.Host().Delete(entity.Host{ID: "ah"}) }, ""},
+		{"KO_TEMPLATE_USED_BY_GROUP", func(s Session) error { return s.Template().Delete(entity.Template{ID: "ks"}) },
+			errors.ERepositoryDependency},
+		{"OK_GROUP", func(s Session) error { return s.Group().Delete(entity.Group{ID: "ag"}) }, ""},
+		{"OK_TEMPLATE", func(s Session) error { return s.Template().Delete(entity.Template{ID: "ks"}) }, ""},
+	}
+	for _, tt := range tests {
+		err := m.Write(tt.delete)
+		if tt.wantCode == "" && err != nil {
+			t.Errorf("runTemplateArtifactTest %v - unexpected error %v", tt.name, err)
+		}
+		if tt.wantCode != "" && !errors.Is(err, tt.wantCode) {
+			t.Errorf("runTemplateArtifactTest %v - got %v want %v", tt.name, err, tt.wantCode)
+		}
+	}
+}
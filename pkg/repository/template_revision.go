@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"fmt"
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+	"time"
+)
+
+// firstTemplateRevision returns the template to store on creation and its revision.
+func firstTemplateRevision(e entity.Template) (entity.Template, entity.TemplateRevision) {
+	e.Revision = 1
+	return e, entity.NewTemplateRevision(e, time.Now())
+}
+
+// nextTemplateRevision returns the template to store on update and the revision to record,
+// or nil if the body did not change and the current revision is kept.
+func nextTemplateRevision(current entity.Template, e entity.Template) (entity.Template, *entity.TemplateRevision) {
+	if current.Template == e.Template {
+		e.Revision = current.Revision
+		e.Author = current.Author
+		return e, nil
+	}
+	e.Revision = current.Revision + 1
+	r := entity.NewTemplateRevision(e, time.Now())
+	return e, &r
+}
+
+// templateRevisionNotFound builds the error returned when a revision is missing.
+func templateRevisionNotFound(ID string, revision int) error {
+	return &errors.Error{Code: errors.ERepositoryKeyNotFound,
+		Msg: fmt.Sprintf("entity.TemplateRevision key %v revision %v not found", ID, revision)}
+}
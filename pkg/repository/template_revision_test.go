@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+	"testing"
+)
+
+func TestTemplateRevision(t *testing.T) {
+	runDriverTest(t, runTemplateRevisionTest)
+}
+
+func runTemplateRevisionTest(t *testing.T, m Repository) {
+	if err := m.Write(func(s Session) error {
+		if err := s.Template().Create(entity.Template{ID: "rev", Template: "v1", Author: "alice"}); err != nil {
+			return err
+		}
+		for _, e := range []entity.Template{{ID: "rev", Template: "v1", Author: "bob"},
+			{ID: "rev", Template: "v2", Author: "bob"}, {ID: "rev", Template: "v3"}} {
+			if err := s.Template().Update(e); err != nil {
+				return err
+			}
+		}
+		return s.Host().Create(entity.Host{ID: "pinned", HardwareAddr: []string{"88-99-aa-bb-cc-ef"},
+			TemplateID: "rev", TemplateRevision: 2})
+	}); err != nil {
+		t.Fatal("runTemplateRevisionTest - error creating ", err)
+	}
+	if err := m.Read(func(s Session) error {
+		e, err := s.Template().Get("rev")
+		if err != nil {
+			return err
+		}
+		if e.Revision != 3 || e.Author != "" {
+			t.Error("Invalid current revision - ", e)
+		}
+		l, err := s.Template().ListRevisions("rev")
+		if err != nil {
+			return err
+		}
+		if len(l) != 3 {
+			t.Fatal("Invalid revision history - ", l)
+		}
+		for i, want := range []string{"v1", "v2", "v3"} {
+			if l[i].Revision != i+1 || l[i].Template != want || l[i].TemplateID != "rev" || l[i].Created.IsZero() {
+				t.Error("Invalid revision - ", l[i])
+			}
+		}
+		r, err := s.Template().GetRevision("rev", 1)
+		if err != nil {
+			return err
+		}
+		if r.Author != "alice" || r.Hash != "3bfc269594ef649228e9a74bab00f042efc91d5acc6fbee31a382e80d42388fe" {
+			t.Error("Invalid revision 1 - ", r)
+		}
+		if _, err := s.Template().GetRevision("rev", 4); !errors.Is(err, errors.ERepositoryKeyNotFound) {
+			t.Error("Missing revision should not be found - ", err)
+		}
+		if _, err := s.Template().ListRevisions("unknown"); !errors.Is(err, errors.ERepositoryKeyNotFound) {
+			t.Error("Missing template should not be found - ", err)
+		}
+		h, err := s.Host().Get("pinned")
+		if err != nil {
+			return err
+		}
+		if h.TemplateRevision != 2 {
+			t.Error("Invalid pinned revision - ", h)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal("runTemplateRevisionTest - error reading ", err)
+	}
+	if err := m.Write(func(s Session) error {
+		if err := s.Host().Delete(entity.Host{ID: "pinned"}); err != nil {
+			return err
+		}
+		return s.Template().Delete(entity.Template{ID: "rev"})
+	}); err != nil {
+		t.Fatal("runTemplateRevisionTest - error deleting ", err)
+	}
+	if err := m.Read(func(s Session) error {
+		if _, err := s.Template().GetRevision("rev", 1); !errors.Is(err, errors.ERepositoryKeyNotFound) {
+			t.Error("Revisions should be deleted with the template - ", err)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal("runTemplateRevisionTest - error reading ", err)
+	}
+}
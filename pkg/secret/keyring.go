@@ -0,0 +1,144 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"github.com/pxecore/pxecore/pkg/errors"
+	"github.com/pxecore/pxecore/pkg/util"
+	"strings"
+)
+
+const (
+	// version prefixes the encrypted values.
+	version = "v1"
+	// keySize is the size in bytes of the generated keys, AES-256.
+	keySize = 32
+)
+
+//~ STRUCT - Keyring ----------------------------------------------------------
+
+// Keyring encrypts values with AES-GCM using the current key and decrypts
+// values encrypted with the current or any of the previous keys.
+//
+// Encrypted values have the format "v1:<key id>:<base64 nonce and ciphertext>",
+// the key id being the first 4 bytes of the key SHA-256 in hex.
+type Keyring struct {
+	current string
+	keys    map[string]cipher.AEAD
+}
+
+// NewKeyring creates a Keyring from the config keys:
+//
+//	key: base64 encoded 16, 24 or 32 bytes key used to encrypt.
+//	previous-keys: base64 encoded keys only used to decrypt, to rotate the key.
+func NewKeyring(config map[string]interface{}) (*Keyring, error) {
+	key, err := util.StringFromMap(config, "key", "")
+	if err != nil {
+		return nil, &errors.Error{Code: errors.Code(err), Msg: "secret keyring configuration failed.", Err: err}
+	}
+	previous, err := util.StringSliceFromMap(config, "previous-keys", []string{})
+	if err != nil {
+		return nil, &errors.Error{Code: errors.Code(err), Msg: "secret keyring configuration failed.", Err: err}
+	}
+	k := &Keyring{keys: make(map[string]cipher.AEAD)}
+	if k.current, err = k.addKey(key); err != nil {
+		return nil, err
+	}
+	for _, p := range previous {
+		if _, err := k.addKey(p); err != nil {
+			return nil, err
+		}
+	}
+	return k, nil
+}
+
+// GenerateKey returns a new random base64 encoded key.
+func GenerateKey() (string, error) {
+	b := make([]byte, keySize)
+	if _, err := rand.Read(b); err != nil {
+		return "", &errors.Error{Code: errors.ESecretError, Msg: "secret key can't be generated.", Err: err}
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// addKey decodes a base64 key and returns its id.
+func (k *Keyring) addKey(key string) (string, error) {
+	b, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return "", &errors.Error{Code: errors.EInvalidType, Msg: "secret key should be base64 encoded.", Err: err}
+	}
+	block, err := aes.NewCipher(b)
+	if err != nil {
+		return "", &errors.Error{Code: errors.EInvalidType, Msg: "secret key should be 16, 24 or 32 bytes long.",
+			Err: err}
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", &errors.Error{Code: errors.ESecretError, Msg: "secret cipher can't be created.", Err: err}
+	}
+	sum := sha256.Sum256(b)
+	id := hex.EncodeToString(sum[:4])
+	k.keys[id] = aead
+	return id, nil
+}
+
+// Encrypt encrypts the value with the current key.
+func (k *Keyring) Encrypt(value string) (string, error) {
+	aead := k.keys[k.current]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", &errors.Error{Code: errors.ESecretError, Msg: "secret nonce can't be generated.", Err: err}
+	}
+	b := aead.Seal(nonce, nonce, []byte(value), []byte(k.current))
+	return fmt.Sprintf("%v:%v:%v", version, k.current, base64.StdEncoding.EncodeToString(b)), nil
+}
+
+// Decrypt decrypts a value encrypted with the current or a previous key.
+func (k *Keyring) Decrypt(value string) (string, error) {
+	id, b, err := parse(value)
+	if err != nil {
+		return "", err
+	}
+	aead, ok := k.keys[id]
+	if !ok {
+		return "", &errors.Error{Code: errors.ESecretError, Msg: fmt.Sprintf("secret key %v is unknown.", id)}
+	}
+	if len(b) < aead.NonceSize() {
+		return "", &errors.Error{Code: errors.ESecretError, Msg: "secret value is truncated."}
+	}
+	plain, err := aead.Open(nil, b[:aead.NonceSize()], b[aead.NonceSize():], []byte(id))
+	if err != nil {
+		return "", &errors.Error{Code: errors.ESecretError, Msg: "secret value can't be decrypted.", Err: err}
+	}
+	return string(plain), nil
+}
+
+// IsCurrent returns true if the value is encrypted with the current key.
+func (k *Keyring) IsCurrent(value string) bool {
+	id, _, err := parse(value)
+	return err == nil && id == k.current
+}
+
+// IsEncrypted returns true if the value has the format of the encrypted values.
+func IsEncrypted(value string) bool {
+	_, _, err := parse(value)
+	return err == nil
+}
+
+// parse splits an encrypted value into its key id and its nonce and ciphertext.
+func parse(value string) (string, []byte, error) {
+	p := strings.SplitN(value, ":", 3)
+	if len(p) != 3 || p[0] != version {
+		return "", nil, &errors.Error{Code: errors.ESecretError, Msg: "secret value is not encrypted."}
+	}
+	b, err := base64.StdEncoding.DecodeString(p[2])
+	if err != nil {
+		return "", nil, &errors.Error{Code: errors.ESecretError, Msg: "secret value is not encrypted.", Err: err}
+	}
+	return p[1], b, nil
+}
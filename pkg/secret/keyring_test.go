@@ -0,0 +1,74 @@
+package secret
+
+import (
+	"github.com/pxecore/pxecore/pkg/errors"
+	"testing"
+)
+
+func TestKeyring(t *testing.T) {
+	old, _ := GenerateKey()
+	key, _ := GenerateKey()
+	other, _ := GenerateKey()
+	previous, _ := NewKeyring(map[string]interface{}{"key": old})
+	encryptedOld, _ := previous.Encrypt("old")
+	k, err := NewKeyring(map[string]interface{}{"key": key, "previous-keys": []interface{}{old}})
+	if err != nil {
+		t.Fatal("NewKeyring() error ", err)
+	}
+	encrypted, _ := k.Encrypt("value")
+	o, _ := NewKeyring(map[string]interface{}{"key": other})
+	encryptedOther, _ := o.Encrypt("other")
+	tests := []struct {
+		name        string
+		value       string
+		want        string
+		wantCurrent bool
+		wantErr     bool
+	}{
+		{"OK_CURRENT_KEY", encrypted, "value", true, false},
+		{"OK_PREVIOUS_KEY", encryptedOld, "old", false, false},
+		{"KO_UNKNOWN_KEY", encryptedOther, "", false, true},
+		{"KO_TAMPERED", encrypted[:len(encrypted)-4] + "AAA=", "", true, true},
+		{"KO_NOT_ENCRYPTED", "value", "", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := k.Decrypt(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Decrypt() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, errors.ESecretError) {
+				t.Errorf("Decrypt() error = %v, want %v", err, errors.ESecretError)
+			}
+			if got != tt.want {
+				t.Errorf("Decrypt() = %v, want %v", got, tt.want)
+			}
+			if c := k.IsCurrent(tt.value); c != tt.wantCurrent {
+				t.Errorf("IsCurrent() = %v, want %v", c, tt.wantCurrent)
+			}
+		})
+	}
+}
+
+func TestNewKeyring(t *testing.T) {
+	key, _ := GenerateKey()
+	tests := []struct {
+		name    string
+		config  map[string]interface{}
+		wantErr bool
+	}{
+		{"OK", map[string]interface{}{"key": key}, false},
+		{"OK_AES_128", map[string]interface{}{"key": "MDEyMzQ1Njc4OWFiY2RlZg=="}, false},
+		{"KO_MISSING_KEY", map[string]interface{}{}, true},
+		{"KO_NOT_BASE64", map[string]interface{}{"key": "not base64"}, true},
+		{"KO_KEY_SIZE", map[string]interface{}{"key": "c2hvcnQ="}, true},
+		{"KO_PREVIOUS_KEY", map[string]interface{}{"key": key, "previous-keys": []interface{}{"c2hvcnQ="}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewKeyring(tt.config); (err != nil) != tt.wantErr {
+				t.Errorf("NewKeyring() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
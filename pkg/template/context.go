@@ -0,0 +1,64 @@
+package template
+
+import (
+	"github.com/pxecore/pxecore/pkg/entity"
+	"net/url"
+	"strings"
+)
+
+// Firmwares of the booting clients.
+const (
+	FirmwareBIOS = "bios"
+	FirmwareUEFI = "uefi"
+)
+
+// Context holds the information of the request that triggered a template.
+// Fields are empty when the information is not known.
+type Context struct {
+	// ClientIP is the address of the booting client.
+	ClientIP string
+	// Transport is entity.BootTransportTFTP or entity.BootTransportHTTP.
+	Transport string
+	// Path requested by the client.
+	Path string
+	// Firmware is FirmwareBIOS or FirmwareUEFI.
+	Firmware string
+	// Arch is the client architecture reported by iPXE. Example: "x86_64".
+	Arch string
+	// HardwareAddr used to find the host. Example: "88-99-aa-bb-cc-dd".
+	HardwareAddr string
+	// ServerAddr is the pxecore address advertised to the clients.
+	ServerAddr string
+	// BaseURL is the pxecore HTTP base URL. Example: "http://10.0.0.1:80".
+	BaseURL string
+	// CallbackToken is the callback token presented by the client, see the artifact func.
+	CallbackToken string
+	// event is filled with the served host and template, see WithEvent.
+	event *entity.BootEvent
+}
+
+// WithEvent returns a copy of the context that fills e with the host and template served by Boot and Discover.
+func (c Context) WithEvent(e *entity.BootEvent) Context {
+	c.event = e
+	return c
+}
+
+// serve fills the event of the context, if any, with the host and template of the helper.
+func (c Context) serve(h *Helper) {
+	if c.event != nil {
+		c.event.HostID, c.event.TemplateID, c.event.TemplateRevision = h.HostID, h.TemplateID, h.TemplateRevision
+	}
+}
+
+// LoadQuery reads the firmware from the "platform" and "buildarch" query params
+// added by the embedded iPXE script to the chained URLs and the callback token from the "token" one.
+func (c *Context) LoadQuery(q url.Values) {
+	switch strings.ToLower(q.Get("platform")) {
+	case "pcbios":
+		c.Firmware = FirmwareBIOS
+	case "efi":
+		c.Firmware = FirmwareUEFI
+	}
+	c.Arch = q.Get("buildarch")
+	c.CallbackToken = q.Get("token")
+}
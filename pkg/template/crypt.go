@@ -0,0 +1,157 @@
+package template
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"hash"
+	"math/big"
+	"strings"
+)
+
+const (
+	// cryptAlphabet is the base64 alphabet used by crypt(3).
+	cryptAlphabet = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	// cryptRounds is the default number of rounds of SHA-crypt.
+	cryptRounds = 5000
+	// cryptSaltLength is the maximum salt length of SHA-crypt.
+	cryptSaltLength = 16
+)
+
+// sha256CryptOrder and sha512CryptOrder are the byte permutations of the final digest.
+var (
+	sha256CryptOrder = [][3]int{{0, 10, 20}, {21, 1, 11}, {12, 22, 2}, {3, 13, 23}, {24, 4, 14},
+		{15, 25, 5}, {6, 16, 26}, {27, 7, 17}, {18, 28, 8}, {9, 19, 29}}
+	sha512CryptOrder = [][3]int{{0, 21, 42}, {22, 43, 1}, {44, 2, 23}, {3, 24, 45}, {25, 46, 4},
+		{47, 5, 26}, {6, 27, 48}, {28, 49, 7}, {50, 8, 29}, {9, 30, 51}, {31, 52, 10},
+		{53, 11, 32}, {12, 33, 54}, {34, 55, 13}, {56, 14, 35}, {15, 36, 57}, {37, 58, 16},
+		{59, 17, 38}, {18, 39, 60}, {40, 61, 19}, {62, 20, 41}}
+)
+
+// SHA256Crypt hashes the password with the SHA-256 based crypt(3) "$5$" scheme.
+// A random salt is generated if the salt is empty.
+func SHA256Crypt(password string, salt string) (string, error) {
+	salt, err := cryptSalt(salt)
+	if err != nil {
+		return "", err
+	}
+	d := shaCrypt(sha256.New, []byte(password), []byte(salt))
+	b := new(strings.Builder)
+	for _, o := range sha256CryptOrder {
+		cryptEncode(b, d[o[0]], d[o[1]], d[o[2]], 4)
+	}
+	cryptEncode(b, 0, d[31], d[30], 3)
+	return "$5$" + salt + "$" + b.String(), nil
+}
+
+// SHA512Crypt hashes the password with the SHA-512 based crypt(3) "$6$" scheme.
+// A random salt is generated if the salt is empty.
+func SHA512Crypt(password string, salt string) (string, error) {
+	salt, err := cryptSalt(salt)
+	if err != nil {
+		return "", err
+	}
+	d := shaCrypt(sha512.New, []byte(password), []byte(salt))
+	b := new(strings.Builder)
+	for _, o := range sha512CryptOrder {
+		cryptEncode(b, d[o[0]], d[o[1]], d[o[2]], 4)
+	}
+	cryptEncode(b, 0, 0, d[63], 2)
+	return "$6$" + salt + "$" + b.String(), nil
+}
+
+// shaCrypt computes the SHA-crypt digest with the default rounds.
+// See: https://www.akkadia.org/drepper/SHA-crypt.txt
+func shaCrypt(newHash func() hash.Hash, key []byte, salt []byte) []byte {
+	b := newHash()
+	b.Write(key)
+	b.Write(salt)
+	b.Write(key)
+	db := b.Sum(nil)
+
+	a := newHash()
+	a.Write(key)
+	a.Write(salt)
+	a.Write(repeatTo(db, len(key)))
+	for i := len(key); i > 0; i >>= 1 {
+		if i&1 != 0 {
+			a.Write(db)
+		} else {
+			a.Write(key)
+		}
+	}
+	da := a.Sum(nil)
+
+	dp := newHash()
+	for i := 0; i < len(key); i++ {
+		dp.Write(key)
+	}
+	p := repeatTo(dp.Sum(nil), len(key))
+
+	ds := newHash()
+	for i := 0; i < 16+int(da[0]); i++ {
+		ds.Write(salt)
+	}
+	s := repeatTo(ds.Sum(nil), len(salt))
+
+	c := da
+	for i := 0; i < cryptRounds; i++ {
+		h := newHash()
+		if i&1 != 0 {
+			h.Write(p)
+		} else {
+			h.Write(c)
+		}
+		if i%3 != 0 {
+			h.Write(s)
+		}
+		if i%7 != 0 {
+			h.Write(p)
+		}
+		if i&1 != 0 {
+			h.Write(c)
+		} else {
+			h.Write(p)
+		}
+		c = h.Sum(nil)
+	}
+	return c
+}
+
+// repeatTo repeats the bytes up to the length n.
+func repeatTo(b []byte, n int) []byte {
+	return bytes.Repeat(b, n/len(b)+1)[:n]
+}
+
+// cryptEncode writes n characters of the 24 bits formed by the three bytes.
+func cryptEncode(b *strings.Builder, b2, b1, b0 byte, n int) {
+	w := uint(b2)<<16 | uint(b1)<<8 | uint(b0)
+	for ; n > 0; n-- {
+		b.WriteByte(cryptAlphabet[w&0x3f])
+		w >>= 6
+	}
+}
+
+// cryptSalt truncates the salt at the first "$" and to cryptSaltLength
+// or generates a random one if empty.
+func cryptSalt(salt string) (string, error) {
+	if i := strings.IndexByte(salt, '$'); i >= 0 {
+		salt = salt[:i]
+	}
+	if salt != "" {
+		if len(salt) > cryptSaltLength {
+			salt = salt[:cryptSaltLength]
+		}
+		return salt, nil
+	}
+	b := make([]byte, cryptSaltLength)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(cryptAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		b[i] = cryptAlphabet[n.Int64()]
+	}
+	return string(b), nil
+}
@@ -0,0 +1,85 @@
+package template
+
+import (
+	rep "github.com/pxecore/pxecore/pkg/repository"
+)
+
+const (
+	// hostSource is the source of the values set by the host itself.
+	hostSource = "host"
+	// trapSource is the source of the local boot template served once the host trap is triggered.
+	trapSource = "trap"
+	// discoverySource is the source of the discovery template served to the pending hosts.
+	discoverySource = "discovery"
+)
+
+// VarSource is a value provided by a group, "group:<id>", or by the host, "host".
+type VarSource struct {
+	Source string      `json:"source"`
+	Value  interface{} `json:"value"`
+}
+
+// VarProvenance tells which group or host provided the winning value of a var
+// and the values it overrode, in merge order.
+type VarProvenance struct {
+	Source    string      `json:"source"`
+	Overrides []VarSource `json:"overrides,omitempty"`
+}
+
+// EffectiveConfig is the configuration resolved for a host, as served when it boots.
+// Secrets are not included.
+type EffectiveConfig struct {
+	HostID           string `json:"host-id"`
+	TemplateID       string `json:"template-id"`
+	TemplateRevision int    `json:"template-revision,omitempty"`
+	TemplateSource   string `json:"template-source"`
+	// Rule is the ID of the rule that assigned the template when neither the host nor its groups set one.
+	Rule        string                   `json:"rule,omitempty"`
+	TrapPending bool                     `json:"trap-pending"`
+	State       string                   `json:"state"`
+	Groups      []string                 `json:"groups"`
+	Vars        map[string]interface{}   `json:"vars"`
+	Provenance  map[string]VarProvenance `json:"provenance"`
+}
+
+// Effective resolves the configuration of a host with the same logic as Boot, see Helper.Init.
+// Missing hosts return errors.ERepositoryKeyNotFound.
+func Effective(repository rep.Repository, hostID string, ctx Context) (EffectiveConfig, error) {
+	if err := repository.Read(func(session rep.Session) error {
+		_, err := session.Host().Get(hostID)
+		return err
+	}); err != nil {
+		return EffectiveConfig{}, err
+	}
+	h := NewHelper(repository, hostID, "", ctx)
+	if err := h.Init(); err != nil {
+		return EffectiveConfig{}, err
+	}
+	return EffectiveConfig{
+		HostID:           h.HostID,
+		TemplateID:       h.TemplateID,
+		TemplateRevision: h.TemplateRevision,
+		TemplateSource:   h.templateSource,
+		Rule:             h.rule,
+		TrapPending:      h.TrapPending,
+		State:            h.state,
+		Groups:           h.groups,
+		Vars:             h.Vars,
+		Provenance:       h.varSources,
+	}, nil
+}
+
+// stateSource is the source of the local boot template served to the hosts in a lifecycle state.
+func stateSource(state string) string {
+	return "state:" + state
+}
+
+// ruleSource is the source of the template assigned by a rule.
+func ruleSource(ID string) string {
+	return "rule:" + ID
+}
+
+// groupSource is the source of the values set by a group.
+func groupSource(ID string) string {
+	return "group:" + ID
+}
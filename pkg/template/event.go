@@ -0,0 +1,34 @@
+package template
+
+import (
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+	rep "github.com/pxecore/pxecore/pkg/repository"
+	"time"
+)
+
+// NewEvent returns the boot event of the request, to be filled by Boot and Discover through WithEvent.
+func (c Context) NewEvent() entity.BootEvent {
+	return entity.BootEvent{
+		Time:         time.Now(),
+		HardwareAddr: c.HardwareAddr,
+		ClientIP:     c.ClientIP,
+		Transport:    c.Transport,
+		Path:         c.Path,
+	}
+}
+
+// RecordEvent stores the boot event with the outcome of serving n bytes or failing with err.
+// Missing hosts or templates are recorded as entity.BootOutcomeNotFound.
+func RecordEvent(repository rep.Repository, e entity.BootEvent, n int64, err error) error {
+	e.Bytes = n
+	switch {
+	case err == nil:
+		e.Outcome = entity.BootOutcomeServed
+	case errors.Is(err, errors.ERepositoryKeyNotFound) || errors.Is(err, errors.ENotFound):
+		e.Outcome, e.Error = entity.BootOutcomeNotFound, err.Error()
+	default:
+		e.Outcome, e.Error = entity.BootOutcomeFailed, err.Error()
+	}
+	return rep.RecordBootEvent(repository, e)
+}
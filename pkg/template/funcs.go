@@ -0,0 +1,213 @@
+package template
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+	"github.com/pxecore/pxecore/pkg/repository"
+	"gopkg.in/yaml.v2"
+	"net/url"
+	"path"
+	"reflect"
+	"strings"
+	"text/template"
+)
+
+// funcMap returns the functions available to the templates compiled by the helper.
+// The subject of the string functions is the last argument so they can be pipelined.
+//
+// Strings: lower, upper, title, trim, trimPrefix, trimSuffix, replace, contains,
+// hasPrefix, hasSuffix, split, join, quote, indent.
+//
+// Values: default returns the first argument if the second is empty,
+// required returns the named var or an errors.ETemplateError if it's missing.
+//
+// Encoding: b64enc, b64dec, toJSON, toYAML, sha256crypt and sha512crypt
+// which take the password and an optional salt.
+//
+// Server: url joins the paths to the pxecore HTTP base URL,
+// callback returns the URL the installer calls to report the "installed" or "failed" status with the callback token,
+// host and group return the entity.Host and entity.Group with the provided ID.
+//
+// Composition: include renders a stored template or a "define" with the provided data.
+func funcMap(h *Helper) template.FuncMap {
+	return template.FuncMap{
+		"lower":      strings.ToLower,
+		"upper":      strings.ToUpper,
+		"title":      strings.Title,
+		"trim":       strings.TrimSpace,
+		"trimPrefix": func(p string, s string) string { return strings.TrimPrefix(s, p) },
+		"trimSuffix": func(p string, s string) string { return strings.TrimSuffix(s, p) },
+		"replace":    func(o string, n string, s string) string { return strings.Replace(s, o, n, -1) },
+		"contains":   func(sub string, s string) bool { return strings.Contains(s, sub) },
+		"hasPrefix":  func(p string, s string) bool { return strings.HasPrefix(s, p) },
+		"hasSuffix":  func(p string, s string) bool { return strings.HasSuffix(s, p) },
+		"split":      func(sep string, s string) []string { return strings.Split(s, sep) },
+		"join":       join,
+		"quote":      func(s string) string { return fmt.Sprintf("%q", s) },
+		"indent":     indent,
+		"default":    defaultValue,
+		"required":   h.required,
+		"b64enc":     func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) },
+		"b64dec":     b64dec,
+		"toJSON":     toJSON,
+		"toYAML":     toYAML,
+		"sha256crypt": func(p string, salt ...string) (string, error) {
+			return SHA256Crypt(p, strings.Join(salt, ""))
+		},
+		"sha512crypt": func(p string, salt ...string) (string, error) {
+			return SHA512Crypt(p, strings.Join(salt, ""))
+		},
+		"url":      h.url,
+		"callback": h.callback,
+		"artifact": h.artifact,
+		"host":     h.host,
+		"group":    h.group,
+		"include":  h.include,
+	}
+}
+
+// include executes the named template, loaded as a partial or defined in the body.
+func (h *Helper) include(name string, data interface{}) (string, error) {
+	if h.tmpl == nil || h.tmpl.Lookup(name) == nil {
+		return "", &errors.Error{Code: errors.ETemplateError,
+			Msg: fmt.Sprintf("template.Helper template %v not found.", name)}
+	}
+	buf := new(strings.Builder)
+	if err := h.tmpl.ExecuteTemplate(buf, name, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// required returns the var or an errors.ETemplateError naming the missing var.
+func (h *Helper) required(key string) (interface{}, error) {
+	if v, ok := h.Vars[key]; ok && v != "" {
+		return v, nil
+	}
+	return nil, &errors.Error{Code: errors.ETemplateError,
+		Msg: fmt.Sprintf("template.Helper required var %v is missing", key)}
+}
+
+// url joins the paths to the base URL of the context.
+func (h *Helper) url(paths ...string) (string, error) {
+	if h.Context.BaseURL == "" {
+		return "", &errors.Error{Code: errors.ETemplateError, Msg: "template.Helper server base URL is unknown"}
+	}
+	return strings.TrimSuffix(h.Context.BaseURL, "/") + path.Join(append([]string{"/"}, paths...)...), nil
+}
+
+// callback returns the installer callback URL of the host for the status, including the callback token.
+func (h *Helper) callback(status string) (string, error) {
+	if status != entity.HostStateInstalled && status != entity.HostStateFailed {
+		return "", &errors.Error{Code: errors.ETemplateError,
+			Msg: fmt.Sprintf("template.Helper callback status %v should be installed or failed.", status)}
+	}
+	u, err := h.url("host", h.HostID, "callback", status)
+	if err != nil {
+		return "", err
+	}
+	return u + "?token=" + url.QueryEscape(h.CallbackToken), nil
+}
+
+// artifact returns the URL of the host artifact by name, including the callback token,
+// so the installer fetching it is served the callback URLs. See Artifact.
+func (h *Helper) artifact(name string) (string, error) {
+	u, err := h.url("host", h.HostID, "artifact", name)
+	if err != nil {
+		return "", err
+	}
+	return u + "?token=" + url.QueryEscape(h.CallbackToken), nil
+}
+
+// host returns the entity.Host by ID. The secrets and the callback token of the host are not included,
+// the secrets of the rendered host are only available through Helper.Secrets.
+func (h *Helper) host(ID string) (entity.Host, error) {
+	var e entity.Host
+	err := h.repository.Read(func(session repository.Session) error {
+		var err error
+		e, err = session.Host().Get(ID)
+		return err
+	})
+	if err != nil {
+		return e, &errors.Error{Code: errors.ETemplateError,
+			Msg: fmt.Sprintf("template.Helper host %v not found.", ID), Err: err}
+	}
+	e.Secrets, e.CallbackToken = nil, ""
+	return e, nil
+}
+
+// group returns the entity.Group by ID. The secrets of the group are not included.
+func (h *Helper) group(ID string) (entity.Group, error) {
+	var e entity.Group
+	err := h.repository.Read(func(session repository.Session) error {
+		var err error
+		e, err = session.Group().Get(ID)
+		return err
+	})
+	if err != nil {
+		return e, &errors.Error{Code: errors.ETemplateError,
+			Msg: fmt.Sprintf("template.Helper group %v not found.", ID), Err: err}
+	}
+	e.Secrets = nil
+	return e, nil
+}
+
+// defaultValue returns d if v is empty.
+func defaultValue(d interface{}, v interface{}) interface{} {
+	if v == nil {
+		return d
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		if rv.Len() == 0 {
+			return d
+		}
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return d
+		}
+	default:
+		if rv.IsZero() {
+			return d
+		}
+	}
+	return v
+}
+
+// join concatenates the elements of a list of any type, like the list vars, with sep.
+func join(sep string, l interface{}) (string, error) {
+	rv := reflect.ValueOf(l)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return "", &errors.Error{Code: errors.ETemplateError, Msg: fmt.Sprintf("template.Helper join of %T", l)}
+	}
+	s := make([]string, rv.Len())
+	for i := range s {
+		s[i] = fmt.Sprint(rv.Index(i).Interface())
+	}
+	return strings.Join(s, sep), nil
+}
+
+// indent adds n spaces at the start of every line.
+func indent(n int, s string) string {
+	p := strings.Repeat(" ", n)
+	return p + strings.Replace(s, "\n", "\n"+p, -1)
+}
+
+func b64dec(s string) (string, error) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	return string(b), err
+}
+
+func toJSON(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+func toYAML(v interface{}) (string, error) {
+	b, err := yaml.Marshal(v)
+	return strings.TrimSuffix(string(b), "\n"), err
+}
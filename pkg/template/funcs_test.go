@@ -0,0 +1,110 @@
+package template
+
+import (
+	"bytes"
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+	"github.com/pxecore/pxecore/pkg/repository"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSHACrypt(t *testing.T) {
+	tests := []struct {
+		name     string
+		f        func(string, string) (string, error)
+		password string
+		salt     string
+		want     string
+	}{
+		{"OK_SHA256", SHA256Crypt, "Hello world!", "saltstring",
+			"$5$saltstring$5B8vYYiY.CVt1RlTTf8KbXBH3hsxY/GNooZaBBGWEc5"},
+		{"OK_SHA512", SHA512Crypt, "Hello world!", "saltstring",
+			"$6$saltstring$svn8UoSVapNtMuq1ukKS4tPQd8iKwSMHWjl/O817G3uBnIFNjnQJuesI68u4OTLiBFdcbYEdFCoEOfaS35inz1"},
+		{"OK_SHA512_LONG_SALT", SHA512Crypt, "Hello world!", "saltstringsaltstring",
+			"$6$saltstringsaltst$"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.f(tt.password, tt.salt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(got, tt.want) {
+				t.Errorf("crypt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	a, _ := SHA512Crypt("password", "")
+	b, _ := SHA512Crypt("password", "")
+	if a == b || len(a) != 3+16+1+86 {
+		t.Errorf("crypt() random salt = %v, %v", a, b)
+	}
+}
+
+func TestCompile_Funcs(t *testing.T) {
+	r, _ := repository.NewRepository(map[string]interface{}{"driver": "memory"})
+	_ = r.Write(func(s repository.Session) error {
+		_ = s.Group().Create(entity.Group{ID: "group1", Vars: map[string]interface{}{"role": "web"},
+			Secrets: map[string]string{"key": "k3y"}})
+		_ = s.Template().Create(entity.Template{ID: "template1"})
+		return s.Host().Create(entity.Host{ID: "host1", HardwareAddr: []string{"88-99-aa-bb-cc-dd"},
+			GroupID: "group1", TemplateID: "template1",
+			Vars:          map[string]interface{}{"name": " Node1 ", "empty": ""},
+			Secrets:       map[string]string{"password": "s3cret"},
+			CallbackToken: "0a1b", CallbackTokenExpires: time.Now().Add(time.Hour)})
+	})
+	ctx := Context{BaseURL: "http://10.0.0.1:8080/"}
+	tests := []struct {
+		name     string
+		template string
+		want     string
+		wantCode string
+	}{
+		{"OK_STRINGS", `{{ .Vars.name | trim | lower }} {{ "a-b" | replace "-" "_" | upper }} {{ "x" | quote }}`,
+			`node1 A_B "x"`, ""},
+		{"OK_SPLIT_JOIN", `{{ "a,b" | split "," | join " " }} {{ hasPrefix "a" "ab" }}`, "a b true", ""},
+		{"OK_DEFAULT", `{{ default "d" .Vars.empty }} {{ default "d" .Vars.role }}`, "d web", ""},
+		{"OK_REQUIRED", `{{ required "role" }}`, "web", ""},
+		{"KO_REQUIRED", `{{ required "missing" }}`, "required var missing", errors.ETemplateError},
+		{"OK_ENCODING", `{{ b64enc "pxe" }} {{ toJSON .Vars.role }} {{ toYAML .Vars.role }}`,
+			`cHhl "web" web`, ""},
+		{"OK_CRYPT", `{{ sha256crypt "Hello world!" "saltstring" }}`,
+			"$5$saltstring$5B8vYYiY.CVt1RlTTf8KbXBH3hsxY/GNooZaBBGWEc5", ""},
+		{"OK_URL", `{{ url "static" "vmlinuz" }}`, "http://10.0.0.1:8080/static/vmlinuz", ""},
+		{"OK_CALLBACK", `{{ callback "installed" }} {{ .CallbackToken }}`,
+			"http://10.0.0.1:8080/host/host1/callback/installed?token=callback-token-placeholder " +
+				"callback-token-placeholder", ""},
+		{"OK_ARTIFACT", `{{ artifact "kickstart" }}`,
+			"http://10.0.0.1:8080/host/host1/artifact/kickstart?token=callback-token-placeholder", ""},
+		{"KO_CALLBACK_STATUS", `{{ callback "installing" }}`, "callback status", errors.ETemplateError},
+		{"OK_LOOKUP", `{{ (host "host1").GroupID }} {{ index (group "group1").Vars "role" }}`, "group1 web", ""},
+		{"OK_LOOKUP_NO_TOKEN", `[{{ (host "host1").CallbackToken }}]`, "[]", ""},
+		{"OK_LOOKUP_NO_SECRETS", `{{ (host "host1").Secrets }} {{ (group "group1").Secrets }} {{ .Secrets.password }} ` +
+			`{{ .Secrets.key }}`, "map[] map[] s3cret k3y", ""},
+		{"KO_LOOKUP", `{{ (host "missing").ID }}`, "", errors.ETemplateError},
+		{"KO_PARSE", `{{ unknown }}`, "", errors.ETemplateError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_ = r.Write(func(s repository.Session) error {
+				return s.Template().Update(entity.Template{ID: "template1", Template: tt.template})
+			})
+			buf := new(bytes.Buffer)
+			err := Compile(buf, r, "host1", "", ctx)
+			if tt.wantCode != "" {
+				if !errors.Is(err, tt.wantCode) || !strings.Contains(err.Error(), tt.want) {
+					t.Errorf("Compile() error = %v, want %v %v", err, tt.wantCode, tt.want)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("Compile() = %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
@@ -1,36 +1,105 @@
 package template
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/pxecore/pxecore/pkg/entity"
 	"github.com/pxecore/pxecore/pkg/errors"
 	"github.com/pxecore/pxecore/pkg/repository"
+	"github.com/pxecore/pxecore/pkg/util"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+)
+
+const (
+	// LocalBootTemplateID is the template served to hosts whose trap has been triggered.
+	LocalBootTemplateID = "local-boot"
+	// DefaultLocalBootTemplate is served when no LocalBootTemplateID template is stored.
+	DefaultLocalBootTemplate = "#!ipxe\nexit\n"
+	// CallbackTokenTTL is the time the callback token issued on boot is valid.
+	CallbackTokenTTL = 6 * time.Hour
+	// CallbackTokenPlaceholder replaces the callback token in the templates not served to the installer.
+	CallbackTokenPlaceholder = "callback-token-placeholder"
+	// DiscoveryTemplateID is the template served to unknown hardware addresses and pending hosts.
+	DiscoveryTemplateID = "discovery"
+	// DefaultDiscoveryTemplate is served when no DiscoveryTemplateID template is stored.
+	// It posts the hardware facts known by iPXE to the discovery endpoint.
+	DefaultDiscoveryTemplate = `#!ipxe
+params
+param mac {{ .Context.HardwareAddr | default "${mac:hexhyp}" }}
+param manufacturer ${manufacturer}
+param product ${product}
+param serial ${serial}
+param uuid ${uuid}
+param asset ${asset}
+param platform ${platform}
+param buildarch ${buildarch}
+chain {{ url "discovery" }}##params || goto failed
+:failed
+echo Discovery failed, rebooting in 30 seconds.
+sleep 30
+reboot
+`
 )
 
 // Helper assist template creation providing data and related functionality.
 type Helper struct {
 	HostID       string
 	TemplateID   string
-	Vars         map[string]string
+	Vars         map[string]interface{}
 	TemplateBody string
-	repository   repository.Repository
+	// TrapPending is true when the host is in trap mode and the trap was not triggered yet.
+	TrapPending bool
+	// Context of the request that triggered the template.
+	Context Context
+	// TemplateRevision is the pinned revision of TemplateID, 0 is the latest one.
+	TemplateRevision int
+	// Secrets are the decrypted secret vars of the host and its groups, children override their parents.
+	Secrets map[string]string
+	// Facts are the latest hardware facts reported for the host, empty if there are none.
+	Facts entity.Facts
+	// CallbackToken authenticates the installer callbacks of the current boot, see the callback func.
+	// It is issued by Boot and exposed to the requests presenting it, otherwise it is CallbackTokenPlaceholder.
+	CallbackToken string
+	// Partials are the stored templates referenced by the template body, dependencies first.
+	Partials   []entity.Template
+	repository repository.Repository
+	tmpl       *template.Template
+	// groups are the IDs of the host groups in merge order.
+	groups []string
+	// templateSource and varSources tell which group or host provided the template and the vars.
+	templateSource string
+	varSources     map[string]VarProvenance
+	// rule is the ID of the rule that assigned the template, if any.
+	rule string
+	// state is the lifecycle state of the host, see entity.Host.Lifecycle.
+	state string
 }
 
 // NewHelper construct new Helper
-func NewHelper(repository repository.Repository, hostID string, templateID string) *Helper {
+func NewHelper(repository repository.Repository, hostID string, templateID string, ctx Context) *Helper {
 	h := new(Helper)
 	h.repository = repository
 	h.HostID = hostID
 	h.TemplateID = templateID
+	h.Context = ctx
+	h.CallbackToken = CallbackTokenPlaceholder
 	return h
 }
 
 // GetVar retrieves a particular key from the vars or the default string is returned.
+// Numbers and booleans are formatted, lists and maps return the default string.
 func (h *Helper) GetVar(key string, def string) string {
-	if h.Vars == nil {
-		return def
-	}
-	s, ok := h.Vars[key]
-	if ok {
-		return s
+	switch v := h.Vars[key].(type) {
+	case string:
+		return v
+	case float64, bool:
+		return fmt.Sprint(v)
 	}
 	return def
 }
@@ -42,46 +111,537 @@ func (h *Helper) Init() error {
 		if err != nil {
 			return &errors.Error{Code: errors.ETemplateError, Msg: "template.Helper host not found.", Err: err}
 		}
-		h.Vars, h.TemplateID, err = recursiveGroupMerge(session, make([]string, 0), host.GroupID)
+		groups, err := h.initHost(session, host)
 		if err != nil {
 			return err
 		}
-		h.Vars = mergeMaps(h.Vars, host.Vars)
+		h.TemplateID, h.TemplateRevision, h.templateSource = groupTemplate(groups)
 		if host.TemplateID != "" {
-			h.TemplateID = host.TemplateID
+			h.TemplateID, h.TemplateRevision, h.templateSource = host.TemplateID, host.TemplateRevision, hostSource
+		}
+		if h.TemplateID == "" {
+			r, ok, err := matchRule(session, host.Labels)
+			if err != nil {
+				return &errors.Error{Code: errors.ETemplateError, Msg: "template.Helper rules can't be read.", Err: err}
+			}
+			if ok {
+				h.TemplateID, h.templateSource, h.rule = r.TemplateID, ruleSource(r.ID), r.ID
+			}
+		}
+		if host.Pending {
+			h.TrapPending = false
+			if err := h.initDiscovery(session); err != nil {
+				return err
+			}
+		} else if h.state == entity.HostStateInstalled || h.state == entity.HostStateDecommissioned {
+			h.TrapPending = false
+			err := h.initBuiltin(session, LocalBootTemplateID, DefaultLocalBootTemplate, stateSource(h.state))
+			if err != nil {
+				return err
+			}
+		} else if host.TrapMode && host.TrapTriggered {
+			if err := h.initLocalBoot(session); err != nil {
+				return err
+			}
+		} else if h.TemplateRevision > 0 {
+			r, err := session.Template().GetRevision(h.TemplateID, h.TemplateRevision)
+			if err != nil {
+				return &errors.Error{Code: errors.ETemplateError, Msg: "template.Helper template revision not found.", Err: err}
+			}
+			h.TemplateBody = r.Template
+		} else {
+			template, err := session.Template().Get(h.TemplateID)
+			if err != nil {
+				return &errors.Error{Code: errors.ETemplateError, Msg: "template.Helper template not found.", Err: err}
+			}
+			h.TemplateBody = template.Template
+		}
+		h.Partials, err = recursiveTemplateResolve(session, h, []string{h.TemplateID}, make(map[string]bool),
+			entity.Template{ID: h.TemplateID, Template: h.TemplateBody})
+		return err
+	})
+}
+
+// initRender initializes the helper to render TemplateID as a dry run.
+// The host trap is ignored and missing hosts or templates return the repository error.
+func (h *Helper) initRender(vars map[string]interface{}) error {
+	return h.repository.Read(func(session repository.Session) error {
+		h.Vars = make(map[string]interface{})
+		if h.HostID != "" {
+			host, err := session.Host().Get(h.HostID)
+			if err != nil {
+				return err
+			}
+			if _, err = h.initHost(session, host); err != nil {
+				return err
+			}
 		}
+		h.Vars = mergeVars(h.Vars, vars)
 		template, err := session.Template().Get(h.TemplateID)
 		if err != nil {
-			return &errors.Error{Code: errors.ETemplateError, Msg: "template.Helper template not found.", Err: err}
+			return err
 		}
 		h.TemplateBody = template.Template
+		h.Partials, err = recursiveTemplateResolve(session, h, []string{h.TemplateID}, make(map[string]bool),
+			entity.Template{ID: h.TemplateID, Template: h.TemplateBody})
+		return err
+	})
+}
+
+// initArtifact initializes the helper with the template mapped to the artifact name of the host.
+// Missing hosts, artifacts or templates return the repository error.
+func (h *Helper) initArtifact(name string) (entity.Template, error) {
+	var t entity.Template
+	err := h.repository.Read(func(session repository.Session) error {
+		host, err := session.Host().Get(h.HostID)
+		if err != nil {
+			return err
+		}
+		groups, err := h.initHost(session, host)
+		if err != nil {
+			return err
+		}
+		artifacts := groupMaps(groups, func(g entity.Group) map[string]string { return g.Artifacts })
+		ID, ok := mergeMaps(artifacts, host.Artifacts)[name]
+		if !ok {
+			return &errors.Error{Code: errors.ERepositoryKeyNotFound, Msg: "template.Helper artifact not found."}
+		}
+		if t, err = session.Template().Get(ID); err != nil {
+			return err
+		}
+		h.TemplateID, h.TemplateBody = t.ID, t.Template
+		h.Partials, err = recursiveTemplateResolve(session, h, []string{h.TemplateID}, make(map[string]bool), t)
+		return err
+	})
+	return t, err
+}
+
+// initHost loads the vars and secrets of the host merged over the ones of its groups and the host facts.
+// The groups are returned in merge order, see hostGroups.
+func (h *Helper) initHost(session repository.Session, host entity.Host) ([]entity.Group, error) {
+	groups, err := hostGroups(session, host)
+	if err != nil {
+		return nil, err
+	}
+	h.groups = make([]string, 0, len(groups))
+	for _, g := range groups {
+		h.groups = append(h.groups, g.ID)
+	}
+	h.Vars, h.varSources = sourcedVars(groups, host)
+	h.Secrets = mergeMaps(groupMaps(groups, func(g entity.Group) map[string]string { return g.Secrets }),
+		host.Secrets)
+	h.TrapPending = host.TrapMode && !host.TrapTriggered
+	h.state = host.Lifecycle()
+	if h.Context.CallbackToken != "" && host.ValidCallbackToken(h.Context.CallbackToken, time.Now()) {
+		h.CallbackToken = host.CallbackToken
+	}
+	if h.Facts, err = session.Facts().Get(host.ID); err != nil && !errors.Is(err, errors.ERepositoryKeyNotFound) {
+		return nil, &errors.Error{Code: errors.ETemplateError, Msg: "template.Helper host facts can't be read.", Err: err}
+	}
+	return groups, nil
+}
+
+// initLocalBoot loads the local boot template replacing the host one.
+func (h *Helper) initLocalBoot(session repository.Session) error {
+	return h.initBuiltin(session, LocalBootTemplateID, DefaultLocalBootTemplate, trapSource)
+}
+
+// initDiscovery initializes the helper to serve the discovery template.
+func (h *Helper) initDiscovery(session repository.Session) error {
+	return h.initBuiltin(session, DiscoveryTemplateID, DefaultDiscoveryTemplate, discoverySource)
+}
+
+// initBuiltin initializes the helper with the stored template ID or with the default body if it's not stored.
+func (h *Helper) initBuiltin(session repository.Session, ID string, def string, source string) error {
+	h.TemplateID, h.TemplateRevision, h.templateSource = ID, 0, source
+	template, err := session.Template().Get(ID)
+	if err != nil {
+		if !errors.Is(err, errors.ERepositoryKeyNotFound) {
+			return &errors.Error{Code: errors.ETemplateError, Msg: fmt.Sprintf("template.Helper %v template error.", ID),
+				Err: err}
+		}
+		h.TemplateBody = def
 		return nil
+	}
+	h.TemplateBody = template.Template
+	return nil
+}
+
+// TriggerTrap persists the host trap as triggered, so the following boots
+// are served the local boot template.
+func (h *Helper) TriggerTrap() error {
+	return h.repository.Write(func(session repository.Session) error {
+		host, err := session.Host().Get(h.HostID)
+		if err != nil {
+			return &errors.Error{Code: errors.ETemplateError, Msg: "template.Helper host not found.", Err: err}
+		}
+		if !host.TrapMode || host.TrapTriggered {
+			return nil
+		}
+		host.TrapTriggered = true
+		return session.Host().Update(host)
+	})
+}
+
+// servesHostTemplate returns true when the host is served its own template, not a builtin one.
+func (h *Helper) servesHostTemplate() bool {
+	return h.templateSource != trapSource && h.templateSource != discoverySource &&
+		h.templateSource != stateSource(h.state)
+}
+
+// installPending returns true when serving the host template starts its installation,
+// that is the host is entity.HostStateReady or entity.HostStateFailed and it's served its own template.
+func (h *Helper) installPending() bool {
+	return (h.state == entity.HostStateReady || h.state == entity.HostStateFailed) && h.servesHostTemplate()
+}
+
+// IssueCallbackToken stores a new callback token for the host, valid for CallbackTokenTTL,
+// replacing the one of the previous boot.
+func (h *Helper) IssueCallbackToken() error {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return &errors.Error{Code: errors.ETemplateError, Msg: "template.Helper callback token error.", Err: err}
+	}
+	token := hex.EncodeToString(b)
+	if err := h.repository.Write(func(session repository.Session) error {
+		host, err := session.Host().Get(h.HostID)
+		if err != nil {
+			return &errors.Error{Code: errors.ETemplateError, Msg: "template.Helper host not found.", Err: err}
+		}
+		host.CallbackToken, host.CallbackTokenExpires = token, time.Now().Add(CallbackTokenTTL)
+		return session.Host().Update(host)
+	}); err != nil {
+		return err
+	}
+	h.CallbackToken = token
+	return nil
+}
+
+// TriggerInstall moves the host to entity.HostStateInstalling once its template is served.
+// Hosts whose state changed meanwhile are left as they are.
+func (h *Helper) TriggerInstall() error {
+	return h.repository.Write(func(session repository.Session) error {
+		_, err := repository.TransitionHost(session, h.HostID, h.state, entity.HostStateInstalling,
+			entity.TransitionTriggerBoot, fmt.Sprintf("template %v served", h.TemplateID))
+		if errors.Is(err, errors.ERepositoryConflict) {
+			return nil
+		}
+		return err
 	})
 }
 
-// recursiveGroupVarsMerge retrieves the groups and merges the results.
-func recursiveGroupMerge(session repository.Session, groups []string, groupID string) (map[string]string, string, error) {
-	if groupID == "" {
-		return make(map[string]string), "", nil
+// hostGroups retrieves the groups of the host in merge order. The groups of entity.Host.Groups
+// are resolved in order, each one preceded by its ancestors, and every group is returned once:
+// later groups override the earlier ones and children override their parents.
+// A group that is its own ancestor returns errors.ETemplateError.
+func hostGroups(session repository.Session, host entity.Host) ([]entity.Group, error) {
+	groups := make([]entity.Group, 0)
+	resolved := make(map[string]bool)
+	for _, ID := range host.Groups() {
+		if err := recursiveGroupResolve(session, ID, resolved, &groups); err != nil {
+			return nil, err
+		}
+	}
+	return groups, nil
+}
+
+// recursiveGroupResolve appends the ancestors of the group and then the group to groups.
+// resolved holds false for the groups being resolved and true for the ones appended.
+func recursiveGroupResolve(session repository.Session, ID string, resolved map[string]bool,
+	groups *[]entity.Group) error {
+	if done, ok := resolved[ID]; ok {
+		if !done {
+			return &errors.Error{Code: errors.ETemplateError,
+				Msg: fmt.Sprintf("template.Helper recursive group %v error.", ID)}
+		}
+		return nil
+	}
+	resolved[ID] = false
+	group, err := session.Group().Get(ID)
+	if err != nil {
+		return &errors.Error{Code: errors.ETemplateError, Msg: "template.Helper group not found.", Err: err}
+	}
+	if group.ParentID != "" {
+		if err := recursiveGroupResolve(session, group.ParentID, resolved, groups); err != nil {
+			return err
+		}
+	}
+	resolved[ID] = true
+	*groups = append(*groups, group)
+	return nil
+}
+
+// sourcedVars deep merges the vars of the groups and then the ones of the host, see mergeVars.
+// It also returns, for every var, the group or host that provided the value and the values it overrode.
+func sourcedVars(groups []entity.Group, host entity.Host) (map[string]interface{}, map[string]VarProvenance) {
+	vars := make(map[string]interface{})
+	sources := make(map[string]VarProvenance)
+	merge := func(source string, v map[string]interface{}) {
+		previous := make(map[string]interface{}, len(v))
+		for k := range v {
+			if strings.HasSuffix(k, "+") && len(k) > 1 {
+				k = strings.TrimSuffix(k, "+")
+			}
+			previous[k] = vars[k]
+		}
+		vars = mergeVars(vars, v)
+		for k, pv := range previous {
+			if _, ok := vars[k]; !ok {
+				delete(sources, k)
+				continue
+			}
+			p, ok := sources[k]
+			if ok {
+				p.Overrides = append(p.Overrides, VarSource{Source: p.Source, Value: pv})
+			}
+			p.Source = source
+			sources[k] = p
+		}
 	}
 	for _, g := range groups {
-		if g == groupID {
-			return nil, "", &errors.Error{Code: errors.ETemplateError, Msg: "template.Helper recursive group error."}
+		merge(groupSource(g.ID), g.Vars)
+	}
+	merge(hostSource, host.Vars)
+	return vars, sources
+}
+
+// groupTemplate returns the template of the last group that sets one together with its pinned revision and source.
+func groupTemplate(groups []entity.Group) (string, int, string) {
+	for i := len(groups) - 1; i >= 0; i-- {
+		if groups[i].TemplateID != "" {
+			return groups[i].TemplateID, groups[i].TemplateRevision, groupSource(groups[i].ID)
 		}
 	}
-	group, err := session.Group().Get(groupID)
+	return "", 0, ""
+}
+
+// matchRule returns the first rule, in ascending priority and ID order, whose selector matches the labels.
+func matchRule(session repository.Session, labels map[string]string) (entity.Rule, bool, error) {
+	rules, err := session.Rule().List("", 0)
 	if err != nil {
-		return nil, "", &errors.Error{Code: errors.ETemplateError, Msg: "template.Helper group not found.", Err: err}
+		return entity.Rule{}, false, err
 	}
-	groups = append(groups, group.ID)
-	m1, tID, err := recursiveGroupMerge(session, groups, group.ParentID)
+	sort.SliceStable(rules, func(i, j int) bool {
+		if rules[i].Priority != rules[j].Priority {
+			return rules[i].Priority < rules[j].Priority
+		}
+		return rules[i].ID < rules[j].ID
+	})
+	for _, r := range rules {
+		if r.Matches(labels) {
+			return r, true, nil
+		}
+	}
+	return entity.Rule{}, false, nil
+}
+
+// groupMaps merges the map returned by field for each of the groups.
+func groupMaps(groups []entity.Group, field func(entity.Group) map[string]string) map[string]string {
+	m := make(map[string]string)
+	for _, g := range groups {
+		m = mergeMaps(m, field(g))
+	}
+	return m
+}
+
+// recursiveTemplateResolve retrieves the stored templates referenced by
+// "template" actions and "include" calls. Dependencies are returned first so
+// the "define" overrides of a template take precedence over the "block" of
+// the templates it references.
+func recursiveTemplateResolve(session repository.Session, h *Helper, templates []string, loaded map[string]bool,
+	t entity.Template) ([]entity.Template, error) {
+	tmpl, err := template.New(t.ID).Funcs(funcMap(h)).Parse(t.Template)
 	if err != nil {
-		return nil, "", err
+		return nil, parseError(t.ID, t.Template, err)
+	}
+	refs := make([]string, 0)
+	for _, d := range tmpl.Templates() {
+		if d.Tree != nil {
+			templateRefs(d.Tree.Root, &refs)
+		}
+	}
+	r := make([]entity.Template, 0)
+	for _, ref := range refs {
+		if loaded[ref] || (tmpl.Lookup(ref) != nil && ref != t.ID) {
+			continue
+		}
+		for _, id := range templates {
+			if id == ref {
+				return nil, &errors.Error{Code: errors.ETemplateError, Msg: "template.Helper recursive template error."}
+			}
+		}
+		p, err := session.Template().Get(ref)
+		if errors.Is(err, errors.ERepositoryKeyNotFound) {
+			continue
+		} else if err != nil {
+			return nil, &errors.Error{Code: errors.ETemplateError, Msg: "template.Helper template error.", Err: err}
+		}
+		deps, err := recursiveTemplateResolve(session, h, append(templates, ref), loaded, p)
+		if err != nil {
+			return nil, err
+		}
+		loaded[ref] = true
+		r = append(append(r, deps...), p)
 	}
-	if group.TemplateID != "" {
-		return mergeMaps(m1, group.Vars), group.TemplateID, nil
+	return r, nil
+}
+
+// templateRefs appends the names referenced by "template" actions and "include" calls.
+func templateRefs(n parse.Node, refs *[]string) {
+	walkNodes(n, func(n parse.Node) {
+		switch n := n.(type) {
+		case *parse.CommandNode:
+			if len(n.Args) > 1 && isIdentifier(n.Args[0], "include") {
+				if s, ok := n.Args[1].(*parse.StringNode); ok {
+					*refs = append(*refs, s.Text)
+				}
+			}
+		case *parse.TemplateNode:
+			*refs = append(*refs, n.Name)
+		}
+	})
+}
+
+// varRefs appends the vars referenced by ".Vars.key", "index .Vars "key"",
+// ".GetVar "key"" and "required "key"".
+func varRefs(n parse.Node, refs *[]string) {
+	walkNodes(n, func(n parse.Node) {
+		switch n := n.(type) {
+		case *parse.FieldNode:
+			if len(n.Ident) > 1 && n.Ident[0] == "Vars" {
+				*refs = append(*refs, n.Ident[1])
+			}
+		case *parse.VariableNode:
+			if len(n.Ident) > 2 && n.Ident[0] == "$" && n.Ident[1] == "Vars" {
+				*refs = append(*refs, n.Ident[2])
+			}
+		case *parse.CommandNode:
+			var key parse.Node
+			if len(n.Args) > 2 && isIdentifier(n.Args[0], "index") && isField(n.Args[1], "Vars") {
+				key = n.Args[2]
+			} else if len(n.Args) > 1 && (isField(n.Args[0], "GetVar") || isIdentifier(n.Args[0], "required")) {
+				key = n.Args[1]
+			}
+			if s, ok := key.(*parse.StringNode); ok {
+				*refs = append(*refs, s.Text)
+			}
+		}
+	})
+}
+
+// walkNodes calls visit for n and all its descendants.
+func walkNodes(n parse.Node, visit func(parse.Node)) {
+	if n == nil || reflect.ValueOf(n).IsNil() {
+		return
+	}
+	visit(n)
+	switch n := n.(type) {
+	case *parse.ListNode:
+		for _, c := range n.Nodes {
+			walkNodes(c, visit)
+		}
+	case *parse.ActionNode:
+		walkNodes(n.Pipe, visit)
+	case *parse.PipeNode:
+		for _, c := range n.Cmds {
+			walkNodes(c, visit)
+		}
+	case *parse.CommandNode:
+		for _, a := range n.Args {
+			walkNodes(a, visit)
+		}
+	case *parse.ChainNode:
+		walkNodes(n.Node, visit)
+	case *parse.TemplateNode:
+		walkNodes(n.Pipe, visit)
+	case *parse.IfNode:
+		walkNodes(&n.BranchNode, visit)
+	case *parse.RangeNode:
+		walkNodes(&n.BranchNode, visit)
+	case *parse.WithNode:
+		walkNodes(&n.BranchNode, visit)
+	case *parse.BranchNode:
+		walkNodes(n.Pipe, visit)
+		walkNodes(n.List, visit)
+		walkNodes(n.ElseList, visit)
+	}
+}
+
+// isIdentifier returns true if n is the function name.
+func isIdentifier(n parse.Node, name string) bool {
+	i, ok := n.(*parse.IdentifierNode)
+	return ok && i.Ident == name
+}
+
+// isField returns true if n is the field name of the root data, ".name" or "$.name".
+func isField(n parse.Node, name string) bool {
+	switch n := n.(type) {
+	case *parse.FieldNode:
+		return len(n.Ident) == 1 && n.Ident[0] == name
+	case *parse.VariableNode:
+		return len(n.Ident) == 2 && n.Ident[0] == "$" && n.Ident[1] == name
+	}
+	return false
+}
+
+// undefinedVars returns the vars referenced by the template body and partials that are not defined, sorted.
+func (h *Helper) undefinedVars() []string {
+	refs := make([]string, 0)
+	for _, t := range append([]entity.Template{{ID: h.TemplateID, Template: h.TemplateBody}}, h.Partials...) {
+		tmpl, err := template.New(t.ID).Funcs(funcMap(h)).Parse(t.Template)
+		if err != nil {
+			continue
+		}
+		for _, d := range tmpl.Templates() {
+			if d.Tree != nil {
+				varRefs(d.Tree.Root, &refs)
+			}
+		}
+	}
+	undefined := make([]string, 0)
+	for _, r := range refs {
+		if _, ok := h.Vars[r]; !ok {
+			undefined = util.AddUniqueStringToSlice(undefined, r)
+		}
+	}
+	sort.Strings(undefined)
+	return undefined
+}
+
+// mergeVars deep merges the vars of v2 into v1 and returns v1. Maps are merged recursively
+// and the other values of v2 replace the ones of v1, so lists are replaced by default.
+// A "key+" list is appended to the "key" list and a null value removes "key".
+// The maps of v2 are copied, so v1 can be modified without changing v2.
+func mergeVars(v1 map[string]interface{}, v2 map[string]interface{}) map[string]interface{} {
+	keys := make([]string, 0, len(v2))
+	for k := range v2 {
+		keys = append(keys, k)
+	}
+	// "key" is merged before "key+".
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := v2[k]
+		if v == nil {
+			delete(v1, k)
+			continue
+		}
+		if strings.HasSuffix(k, "+") && len(k) > 1 {
+			k = strings.TrimSuffix(k, "+")
+			l1, ok1 := v1[k].([]interface{})
+			l2, ok2 := v.([]interface{})
+			if ok1 && ok2 {
+				v1[k] = append(append(make([]interface{}, 0, len(l1)+len(l2)), l1...), l2...)
+				continue
+			}
+		}
+		if m2, ok := v.(map[string]interface{}); ok {
+			m1, _ := v1[k].(map[string]interface{})
+			v1[k] = mergeVars(mergeVars(make(map[string]interface{}), m1), m2)
+			continue
+		}
+		v1[k] = v
 	}
-	return mergeMaps(m1, group.Vars), tID, nil
+	return v1
 }
 
 // mergeMaps merges maps. m2 overrides m1.
@@ -3,7 +3,7 @@ package template
 import (
 	"github.com/golang/mock/gomock"
 	"github.com/pxecore/pxecore/pkg/entity"
-	"github.com/pxecore/pxecore/pkg/repository"
+	"github.com/pxecore/pxecore/pkg/errors"
 	mock_repository "github.com/pxecore/pxecore/pkg/repository/mock"
 	"reflect"
 	"testing"
@@ -33,58 +33,159 @@ func Test_mergeMaps(t *testing.T) {
 	}
 }
 
-func Test_recursiveGroupMerge(t *testing.T) {
+func Test_mergeVars(t *testing.T) {
+	type m = map[string]interface{}
+	type l = []interface{}
+	tests := []struct {
+		name string
+		v1   m
+		v2   m
+		want m
+	}{
+		{"OK_OVERRIDE", m{"a": "v1", "b": "v1"}, m{"a": "v2", "c": float64(2)},
+			m{"a": "v2", "b": "v1", "c": float64(2)}},
+		{"OK_DEEP_MERGE", m{"bond": m{"mode": "1", "slaves": l{"eth0"}}}, m{"bond": m{"mode": "4"}},
+			m{"bond": m{"mode": "4", "slaves": l{"eth0"}}}},
+		{"OK_LIST_REPLACE", m{"disks": l{"sda"}}, m{"disks": l{"sdb"}}, m{"disks": l{"sdb"}}},
+		{"OK_LIST_APPEND", m{"disks": l{"sda"}}, m{"disks+": l{"sdb"}}, m{"disks": l{"sda", "sdb"}}},
+		{"OK_LIST_APPEND_NESTED", m{"net": m{"dns": l{"a"}}}, m{"net": m{"dns+": l{"b"}}},
+			m{"net": m{"dns": l{"a", "b"}}}},
+		{"OK_LIST_APPEND_MISSING", m{}, m{"disks+": l{"sdb"}}, m{"disks": l{"sdb"}}},
+		{"OK_LIST_REPLACE_THEN_APPEND", m{"disks": l{"sda"}}, m{"disks": l{"sdb"}, "disks+": l{"sdc"}},
+			m{"disks": l{"sdb", "sdc"}}},
+		{"OK_TYPE_CHANGE", m{"disks": l{"sda"}}, m{"disks": "sda,sdb"}, m{"disks": "sda,sdb"}},
+		{"OK_REMOVE", m{"a": "v1", "b": "v1"}, m{"a": nil}, m{"b": "v1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeVars(tt.v1, tt.v2); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeVars() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHelper_GetVar(t *testing.T) {
+	h := &Helper{Vars: map[string]interface{}{"s": "value", "n": float64(9000), "b": true,
+		"l": []interface{}{"a"}, "m": map[string]interface{}{}}}
+	tests := []struct {
+		name string
+		key  string
+		want string
+	}{
+		{"OK_STRING", "s", "value"},
+		{"OK_NUMBER", "n", "9000"},
+		{"OK_BOOL", "b", "true"},
+		{"OK_LIST", "l", "def"},
+		{"OK_MAP", "m", "def"},
+		{"OK_MISSING", "missing", "def"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := h.GetVar(tt.key, "def"); got != tt.want {
+				t.Errorf("GetVar() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_sourcedVars(t *testing.T) {
+	type m = map[string]interface{}
+	type l = []interface{}
+	groups := []entity.Group{
+		{ID: "parent", Vars: m{"a": "parent", "b": "parent", "l": l{"sda"}}},
+		{ID: "child", Vars: m{"a": "child", "b": nil, "l+": l{"sdb"}, "c": "child"}},
+	}
+	vars, sources := sourcedVars(groups, entity.Host{Vars: m{"a": "host"}})
+	if want := (m{"a": "host", "l": l{"sda", "sdb"}, "c": "child"}); !reflect.DeepEqual(vars, want) {
+		t.Errorf("sourcedVars() vars = %v, want %v", vars, want)
+	}
+	want := map[string]VarProvenance{
+		"a": {Source: "host", Overrides: []VarSource{{"group:parent", "parent"}, {"group:child", "child"}}},
+		"l": {Source: "group:child", Overrides: []VarSource{{"group:parent", l{"sda"}}}},
+		"c": {Source: "group:child"},
+	}
+	if !reflect.DeepEqual(sources, want) {
+		t.Errorf("sourcedVars() sources = %v, want %v", sources, want)
+	}
+}
+
+func Test_hostGroups(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 	gr := mock_repository.NewMockGroupRepository(ctrl)
-	gr.EXPECT().Get("recursive").Return(entity.Group{
-		ID: "recursive", Vars: map[string]string{},
-		ParentID: "recursive", TemplateID: "template1",
-	}, nil).AnyTimes()
-	gr.EXPECT().Get("children").Return(entity.Group{
-		ID: "children", Vars: map[string]string{"a": "children"},
-		ParentID: "parent", TemplateID: "children",
-	}, nil).AnyTimes()
-	gr.EXPECT().Get("parent").Return(entity.Group{
-		ID: "parent", Vars: map[string]string{"a": "parent"},
-		ParentID: "", TemplateID: "parent",
-	}, nil).AnyTimes()
+	for _, g := range []entity.Group{
+		{ID: "recursive", ParentID: "recursive", TemplateID: "template1"},
+		{ID: "cycle1", ParentID: "cycle2"},
+		{ID: "cycle2", ParentID: "cycle1"},
+		{ID: "children", Vars: map[string]interface{}{"a": "children"}, ParentID: "parent", TemplateID: "children"},
+		{ID: "parent", Vars: map[string]interface{}{"a": "parent", "b": "parent"}, TemplateID: "parent"},
+		{ID: "gpu", Vars: map[string]interface{}{"b": "gpu"}, ParentID: "parent"},
+		{ID: "rack", Vars: map[string]interface{}{"a": "rack", "c": "rack"}},
+	} {
+		gr.EXPECT().Get(g.ID).Return(g, nil).AnyTimes()
+	}
+	gr.EXPECT().Get(gomock.Any()).Return(entity.Group{}, &errors.Error{Code: errors.ERepositoryKeyNotFound}).
+		AnyTimes()
 	ms := mock_repository.NewMockSession(ctrl)
 	ms.EXPECT().Group().Return(gr).AnyTimes()
 
-	type args struct {
-		session repository.Session
-		groups  []string
-		groupID string
-	}
 	tests := []struct {
-		name    string
-		args    args
-		want    map[string]string
-		want1   string
-		wantErr bool
+		name         string
+		host         entity.Host
+		want         []string
+		wantVars     map[string]interface{}
+		wantTemplate string
+		wantErr      bool
 	}{
 		{"OK",
-			args{ms, []string{}, "children"},
-			map[string]string{"a": "children"}, "children", false},
+			entity.Host{GroupID: "children"},
+			[]string{"parent", "children"}, map[string]interface{}{"a": "children", "b": "parent"}, "children", false},
+		{"OK_NO_GROUP",
+			entity.Host{},
+			[]string{}, map[string]interface{}{}, "", false},
+		{"OK_ORDERED_GROUPS",
+			entity.Host{GroupID: "children", GroupIDs: []string{"gpu", "rack"}},
+			[]string{"parent", "children", "gpu", "rack"},
+			map[string]interface{}{"a": "rack", "b": "gpu", "c": "rack"}, "children", false},
+		{"OK_DUPLICATED_GROUPS",
+			entity.Host{GroupIDs: []string{"rack", "gpu", "rack"}},
+			[]string{"rack", "parent", "gpu"},
+			map[string]interface{}{"a": "parent", "b": "gpu", "c": "rack"}, "parent", false},
 		{"KO_RECURSIVE",
-			args{ms, []string{}, "recursive"},
-			nil, "", true},
+			entity.Host{GroupID: "recursive"},
+			nil, nil, "", true},
+		{"KO_CYCLE",
+			entity.Host{GroupIDs: []string{"rack", "cycle1"}},
+			nil, nil, "", true},
+		{"KO_NOT_FOUND",
+			entity.Host{GroupIDs: []string{"rack", "missing"}},
+			nil, nil, "", true},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, got1, err := recursiveGroupMerge(tt.args.session, tt.args.groups, tt.args.groupID)
+			groups, err := hostGroups(ms, tt.host)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("recursiveGroupMerge() error = %v, wantErr %v", err, tt.wantErr)
-				return
+				t.Fatalf("hostGroups() error = %v, wantErr %v", err, tt.wantErr)
 			}
-			if err == nil {
-				if !reflect.DeepEqual(got, tt.want) {
-					t.Errorf("recursiveGroupMerge() got = %v, want %v", got, tt.want)
-				}
-				if got1 != tt.want1 {
-					t.Errorf("recursiveGroupMerge() got1 = %v, want %v", got1, tt.want1)
+			if err != nil {
+				if !errors.Is(err, errors.ETemplateError) {
+					t.Errorf("hostGroups() error = %v, want %v", err, errors.ETemplateError)
 				}
+				return
+			}
+			got := make([]string, 0)
+			for _, g := range groups {
+				got = append(got, g.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("hostGroups() = %v, want %v", got, tt.want)
+			}
+			if vars, _ := sourcedVars(groups, entity.Host{}); !reflect.DeepEqual(vars, tt.wantVars) {
+				t.Errorf("sourcedVars() = %v, want %v", vars, tt.wantVars)
+			}
+			if template, _, _ := groupTemplate(groups); template != tt.wantTemplate {
+				t.Errorf("groupTemplate() = %v, want %v", template, tt.wantTemplate)
 			}
 		})
 	}
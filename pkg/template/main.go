@@ -1,27 +1,132 @@
 package template
 
 import (
+	"bytes"
+	stderrors "errors"
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
 	rep "github.com/pxecore/pxecore/pkg/repository"
+	log "github.com/sirupsen/logrus"
 	"io"
 	"text/template"
 )
 
 // Compile executes the template body and returns the compiled body.
-func Compile(w io.Writer, repository rep.Repository, hostID string, templateID string) error {
-	h := NewHelper(repository, hostID, templateID)
+// It doesn't trigger the host trap, so it's safe to use it to preview templates.
+// The callback token is replaced by CallbackTokenPlaceholder.
+func Compile(w io.Writer, repository rep.Repository, hostID string, templateID string, ctx Context) error {
+	h := NewHelper(repository, hostID, templateID, ctx)
 	if err := h.Init(); err != nil {
 		return err
 	}
-	tmpl, err := template.New(h.TemplateID).Parse(h.TemplateBody)
+	return execute(w, h)
+}
+
+// Boot executes the template body served to a booting host and returns the compiled body.
+// If the host trap is pending it will be triggered once the template is compiled,
+// the boot fails if it can't be stored so the host isn't reinstalled on every boot.
+// Hosts served their own template get a new callback token and ready and failed hosts
+// move to entity.HostStateInstalling, failing to store them is logged and doesn't fail the boot.
+func Boot(w io.Writer, repository rep.Repository, hostID string, templateID string, ctx Context) error {
+	h := NewHelper(repository, hostID, templateID, ctx)
+	err := h.Init()
+	ctx.serve(h)
 	if err != nil {
 		return err
 	}
-	err = tmpl.Execute(w, h)
+	if h.servesHostTemplate() {
+		if err := h.IssueCallbackToken(); err != nil {
+			log.WithError(err).WithField("host", h.HostID).Warn("Error issuing the callback token.")
+		}
+	}
+	install := h.installPending()
+	if !h.TrapPending && !install {
+		return execute(w, h)
+	}
+	buf := new(bytes.Buffer)
+	if err := execute(buf, h); err != nil {
+		return err
+	}
+	if h.TrapPending {
+		if err := h.TriggerTrap(); err != nil {
+			return err
+		}
+	}
+	if install {
+		if err := h.TriggerInstall(); err != nil {
+			log.WithError(err).WithField("host", h.HostID).Warn("Error moving the host to installing.")
+		}
+	}
+	_, err = buf.WriteTo(w)
 	return err
 }
 
-// CompileWithHardwareAddr executes the template body and returns the compiled body.
-func CompileWithHardwareAddr(w io.Writer, repository rep.Repository, HardwareAddr string, templateID string) error {
+// execute parses and executes the helper template body.
+// The partials are parsed first, so the body "define" actions override their "block" actions.
+// Errors are returned as errors.ETemplateError.
+func execute(w io.Writer, h *Helper) error {
+	tmpl := template.New(h.TemplateID).Funcs(funcMap(h))
+	for _, p := range h.Partials {
+		if _, err := tmpl.New(p.ID).Parse(p.Template); err != nil {
+			return parseError(p.ID, p.Template, err)
+		}
+	}
+	if _, err := tmpl.Parse(h.TemplateBody); err != nil {
+		return parseError(h.TemplateID, h.TemplateBody, err)
+	}
+	h.tmpl = tmpl
+	if err := tmpl.Execute(w, h); err != nil {
+		var e *errors.Error
+		if stderrors.As(err, &e) {
+			return e
+		}
+		return &errors.Error{Code: errors.ETemplateError, Msg: "template.Helper execution error.", Err: err}
+	}
+	return nil
+}
+
+// Render executes a stored template as a dry run, nothing is written to the repository.
+// If hostID is provided the vars of the host and its groups are loaded and vars override them.
+// It returns the vars referenced by the templates that are not defined, also when the execution fails.
+// The callback token is replaced by CallbackTokenPlaceholder.
+func Render(w io.Writer, repository rep.Repository, templateID string, hostID string, vars map[string]interface{},
+	ctx Context) ([]string, error) {
+	h := NewHelper(repository, hostID, templateID, ctx)
+	if err := h.initRender(vars); err != nil {
+		return nil, err
+	}
+	return h.undefinedVars(), execute(w, h)
+}
+
+// Artifact executes the template mapped to the artifact name of the host and returns its content type.
+// The artifacts of the groups are merged, children override their parents and the host overrides them all.
+// It doesn't trigger the host trap. Missing hosts or artifacts return errors.ERepositoryKeyNotFound.
+// The callback token is only exposed to the installer, that is a request presenting it, see the artifact func.
+func Artifact(w io.Writer, repository rep.Repository, hostID string, name string, ctx Context) (string, error) {
+	h := NewHelper(repository, hostID, "", ctx)
+	t, err := h.initArtifact(name)
+	if err != nil {
+		return "", err
+	}
+	if t.Kind != entity.TemplateKindIgnition {
+		return t.ContentType(), execute(w, h)
+	}
+	buf := new(bytes.Buffer)
+	if err := execute(buf, h); err != nil {
+		return "", err
+	}
+	if err := validJSON(buf.Bytes()); err != nil {
+		return "", &errors.Error{Code: errors.ETemplateError, Msg: "template.Helper ignition artifact is not valid JSON.",
+			Err: err}
+	}
+	_, err = buf.WriteTo(w)
+	return t.ContentType(), err
+}
+
+// CompileWithHardwareAddr executes the template body served to a booting host and returns the compiled body.
+// The hardware address is stored in the context. See Boot.
+func CompileWithHardwareAddr(w io.Writer, repository rep.Repository, HardwareAddr string, templateID string,
+	ctx Context) error {
 	h := ""
 	if err := repository.Read(func(session rep.Session) error {
 		host, err := session.Host().FindByHardwareAddr(HardwareAddr)
@@ -33,5 +138,44 @@ func CompileWithHardwareAddr(w io.Writer, repository rep.Repository, HardwareAdd
 	}); err != nil {
 		return err
 	}
-	return Compile(w, repository, h, templateID)
+	ctx.HardwareAddr = HardwareAddr
+	return Boot(w, repository, h, templateID, ctx)
+}
+
+// CompileWithIdentifier executes the template body served to a booting host matched by
+// the SMBIOS identifier of a kind, see entity.Host.Identifiers. See Boot.
+func CompileWithIdentifier(w io.Writer, repository rep.Repository, kind string, value string, templateID string,
+	ctx Context) error {
+	h := ""
+	if err := repository.Read(func(session rep.Session) error {
+		host, err := session.Host().FindByIdentifier(kind, value)
+		if err != nil {
+			return err
+		}
+		h = host.ID
+		return nil
+	}); err != nil {
+		return err
+	}
+	return Boot(w, repository, h, templateID, ctx)
+}
+
+// Discover executes the discovery template served to an unknown hardware address.
+// The hardware address is stored in the context.
+func Discover(w io.Writer, repository rep.Repository, HardwareAddr string, ctx Context) error {
+	ctx.HardwareAddr = HardwareAddr
+	h := NewHelper(repository, "", "", ctx)
+	if err := repository.Read(func(session rep.Session) error {
+		if err := h.initDiscovery(session); err != nil {
+			return err
+		}
+		var err error
+		h.Partials, err = recursiveTemplateResolve(session, h, []string{h.TemplateID}, make(map[string]bool),
+			entity.Template{ID: h.TemplateID, Template: h.TemplateBody})
+		return err
+	}); err != nil {
+		return err
+	}
+	ctx.serve(h)
+	return execute(w, h)
 }
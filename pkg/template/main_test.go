@@ -0,0 +1,438 @@
+package template
+
+import (
+	"bytes"
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+	"github.com/pxecore/pxecore/pkg/repository"
+	"github.com/pxecore/pxecore/pkg/secret"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompile_Composition(t *testing.T) {
+	r, _ := repository.NewRepository(map[string]interface{}{"driver": "memory"})
+	_ = r.Write(func(s repository.Session) error {
+		for id, body := range map[string]string{
+			"console":  "console=ttyS0",
+			"base":     `kernel {{ block "args" . }}quiet{{ end }} {{ include "console" . }}`,
+			"child":    `{{ define "args" }}debug{{ end }}{{ template "base" . }}`,
+			"grand":    `{{ define "args" }}{{ .HostID }}{{ end }}{{ template "child" . }}`,
+			"indented": "menu\n{{ include \"console\" . | indent 2 }}",
+			"local":    `{{ define "part" }}{{ .HostID }}{{ end }}{{ include "part" . | upper }}`,
+			"loop-a":   `{{ include "loop-b" . }}`,
+			"loop-b":   `{{ if true }}{{ template "loop-a" . }}{{ end }}`,
+			"self":     `{{ include "self" . }}`,
+			"missing":  `{{ include "unknown" . }}`,
+		} {
+			if err := s.Template().Create(entity.Template{ID: id, Template: body}); err != nil {
+				return err
+			}
+		}
+		return s.Host().Create(entity.Host{ID: "host1", HardwareAddr: []string{"88-99-aa-bb-cc-dd"}})
+	})
+	tests := []struct {
+		name       string
+		templateID string
+		want       string
+		wantErr    bool
+	}{
+		{"OK_INCLUDE_BLOCK", "base", "kernel quiet console=ttyS0", false},
+		{"OK_OVERRIDE_BLOCK", "child", "kernel debug console=ttyS0", false},
+		{"OK_OVERRIDE_CHAIN", "grand", "kernel host1 console=ttyS0", false},
+		{"OK_INCLUDE_PIPELINE", "indented", "menu\n  console=ttyS0", false},
+		{"OK_INCLUDE_DEFINE", "local", "HOST1", false},
+		{"KO_RECURSIVE", "loop-a", "", true},
+		{"KO_SELF", "self", "", true},
+		{"KO_MISSING", "missing", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_ = r.Write(func(s repository.Session) error {
+				return s.Host().Update(entity.Host{ID: "host1", HardwareAddr: []string{"88-99-aa-bb-cc-dd"},
+					TemplateID: tt.templateID})
+			})
+			buf := new(bytes.Buffer)
+			err := Compile(buf, r, "host1", "", Context{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Compile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if !errors.Is(err, errors.ETemplateError) {
+					t.Errorf("Compile() error = %v, want %v", err, errors.ETemplateError)
+				}
+				return
+			}
+			if buf.String() != tt.want {
+				t.Errorf("Compile() = %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestCompile_TemplateRevision(t *testing.T) {
+	r, _ := repository.NewRepository(map[string]interface{}{"driver": "memory"})
+	_ = r.Write(func(s repository.Session) error {
+		_ = s.Template().Create(entity.Template{ID: "install", Template: "v1"})
+		_ = s.Template().Update(entity.Template{ID: "install", Template: "v2"})
+		_ = s.Template().Update(entity.Template{ID: "install", Template: "v3"})
+		return s.Group().Create(entity.Group{ID: "group1", TemplateID: "install", TemplateRevision: 2})
+	})
+	tests := []struct {
+		name    string
+		host    entity.Host
+		want    string
+		wantErr bool
+	}{
+		{"OK_LATEST", entity.Host{TemplateID: "install"}, "v3", false},
+		{"OK_HOST_PIN", entity.Host{TemplateID: "install", TemplateRevision: 1}, "v1", false},
+		{"OK_GROUP_PIN", entity.Host{GroupID: "group1"}, "v2", false},
+		{"OK_HOST_OVERRIDES_GROUP_PIN", entity.Host{GroupID: "group1", TemplateID: "install"}, "v3", false},
+		{"KO_MISSING_REVISION", entity.Host{TemplateID: "install", TemplateRevision: 9}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_ = r.Write(func(s repository.Session) error {
+				_ = s.Host().Delete(entity.Host{ID: "host1"})
+				tt.host.ID = "host1"
+				tt.host.HardwareAddr = []string{"88-99-aa-bb-cc-dd"}
+				return s.Host().Create(tt.host)
+			})
+			buf := new(bytes.Buffer)
+			err := Compile(buf, r, "host1", "", Context{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Compile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && buf.String() != tt.want {
+				t.Errorf("Compile() = %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestRender_UndefinedVars(t *testing.T) {
+	r, _ := repository.NewRepository(map[string]interface{}{"driver": "memory"})
+	_ = r.Write(func(s repository.Session) error {
+		_ = s.Template().Create(entity.Template{ID: "part", Template: `{{ define "args" }}{{ $.Vars.console }}{{ end }}`})
+		_ = s.Template().Create(entity.Template{ID: "install", Template: `{{ template "part" . }}` +
+			`{{ index .Vars "os" }} {{ with .Vars.disk }}{{ . }}{{ end }}{{ include "args" . }}` +
+			`{{ .GetVar "release" "stable" }}{{ if false }}{{ required "hidden" }}{{ end }}`})
+		return nil
+	})
+	buf := new(bytes.Buffer)
+	got, err := Render(buf, r, "install", "", map[string]interface{}{"os": "debian", "release": "sid"}, Context{})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if want := []string{"console", "disk", "hidden"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Render() undefined = %v, want %v", got, want)
+	}
+	if want := "debian <no value>sid"; buf.String() != want {
+		t.Errorf("Render() = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestCompile_StructuredVars(t *testing.T) {
+	r, _ := repository.NewRepository(map[string]interface{}{"driver": "memory"})
+	_ = r.Write(func(s repository.Session) error {
+		_ = s.Template().Create(entity.Template{ID: "install", Template: `{{ range .Vars.disks }}{{ . }} {{ end }}` +
+			`{{ .Vars.bond.mode }} {{ join "," .Vars.bond.slaves }} {{ .GetVar "mtu" "1500" }} {{ toJSON .Vars.ssh }}`})
+		_ = s.Group().Create(entity.Group{ID: "parent", TemplateID: "install", Vars: map[string]interface{}{
+			"disks": []interface{}{"sda"},
+			"bond":  map[string]interface{}{"mode": "active-backup", "slaves": []interface{}{"eth0", "eth1"}},
+			"ssh":   []interface{}{"key1"},
+		}})
+		_ = s.Group().Create(entity.Group{ID: "child", ParentID: "parent", Vars: map[string]interface{}{
+			"disks+": []interface{}{"sdb"},
+			"bond":   map[string]interface{}{"mode": "802.3ad"},
+		}})
+		return s.Host().Create(entity.Host{ID: "host1", HardwareAddr: []string{"88-99-aa-bb-cc-dd"}, GroupID: "child",
+			Vars: map[string]interface{}{"mtu": float64(9000), "ssh": []interface{}{"key2"}}})
+	})
+	buf := new(bytes.Buffer)
+	if err := Compile(buf, r, "host1", "", Context{}); err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	if want := `sda sdb 802.3ad eth0,eth1 9000 ["key2"]`; buf.String() != want {
+		t.Errorf("Compile() = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestCompile_Secrets(t *testing.T) {
+	m, _ := repository.NewRepository(map[string]interface{}{"driver": "memory"})
+	key, _ := secret.GenerateKey()
+	k, _ := secret.NewKeyring(map[string]interface{}{"key": key})
+	r := repository.NewSecretRepository(m, k)
+	_ = r.Write(func(s repository.Session) error {
+		_ = s.Template().Create(entity.Template{ID: "install",
+			Template: `{{ .Secrets.root }} {{ .Secrets.token }}`})
+		_ = s.Group().Create(entity.Group{ID: "group1", TemplateID: "install",
+			Secrets: map[string]string{"root": "group-root", "token": "group-token"}})
+		return s.Host().Create(entity.Host{ID: "host1", HardwareAddr: []string{"88-99-aa-bb-cc-dd"}, GroupID: "group1",
+			Secrets: map[string]string{"root": "host-root"}})
+	})
+	buf := new(bytes.Buffer)
+	if err := Compile(buf, r, "host1", "", Context{}); err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	if want := `host-root group-token`; buf.String() != want {
+		t.Errorf("Compile() = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestCompile_GroupPrecedence(t *testing.T) {
+	r, _ := repository.NewRepository(map[string]interface{}{"driver": "memory"})
+	_ = r.Write(func(s repository.Session) error {
+		_ = s.Template().Create(entity.Template{ID: "ubuntu", Template: `{{ .Vars.os }} {{ .Vars.rack }} {{ .Vars.gpu }}`})
+		_ = s.Group().Create(entity.Group{ID: "base", Vars: map[string]interface{}{"os": "base", "gpu": "none"}})
+		_ = s.Group().Create(entity.Group{ID: "rack-12", ParentID: "base", Vars: map[string]interface{}{"rack": "12"}})
+		_ = s.Group().Create(entity.Group{ID: "gpu-nodes", ParentID: "base", Vars: map[string]interface{}{"gpu": "a100"}})
+		_ = s.Group().Create(entity.Group{ID: "ubuntu-22", TemplateID: "ubuntu",
+			Vars: map[string]interface{}{"os": "ubuntu-22"}})
+		_ = s.Host().Create(entity.Host{ID: "host1", HardwareAddr: []string{"88-99-aa-bb-cc-dd"}, GroupID: "rack-12",
+			GroupIDs: []string{"gpu-nodes", "ubuntu-22"}})
+		return s.Host().Create(entity.Host{ID: "host2", HardwareAddr: []string{"88-99-aa-bb-cc-de"},
+			GroupIDs: []string{"ubuntu-22", "gpu-nodes"}})
+	})
+	tests := []struct {
+		name string
+		host string
+		want string
+	}{
+		{"OK_LATER_GROUPS_OVERRIDE", "host1", "ubuntu-22 12 a100"},
+		{"OK_ANCESTORS_BEFORE_GROUP", "host2", "base <no value> a100"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			if err := Compile(buf, r, tt.host, "", Context{}); err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("Compile() = %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+
+	_ = r.Write(func(s repository.Session) error {
+		return s.Group().Update(entity.Group{ID: "base", ParentID: "gpu-nodes"})
+	})
+	if err := Compile(new(bytes.Buffer), r, "host2", "", Context{}); !errors.Is(err, errors.ETemplateError) {
+		t.Errorf("Compile() error = %v, want %v", err, errors.ETemplateError)
+	}
+}
+
+func TestCompile_Rules(t *testing.T) {
+	r, _ := repository.NewRepository(map[string]interface{}{"driver": "memory"})
+	_ = r.Write(func(s repository.Session) error {
+		_ = s.Template().Create(entity.Template{ID: "db", Template: "db"})
+		_ = s.Template().Create(entity.Template{ID: "rack", Template: "rack"})
+		_ = s.Template().Create(entity.Template{ID: "other", Template: "other"})
+		_ = s.Template().Create(entity.Template{ID: "group", Template: "group"})
+		_ = s.Group().Create(entity.Group{ID: "g1", TemplateID: "group"})
+		_ = s.Rule().Create(entity.Rule{ID: "a-rack", Priority: 20, Selector: map[string]string{"rack": "a1"},
+			TemplateID: "rack"})
+		_ = s.Rule().Create(entity.Rule{ID: "b-db", Priority: 10, Selector: map[string]string{"role": "db"},
+			TemplateID: "db"})
+		_ = s.Rule().Create(entity.Rule{ID: "a-db", Priority: 10, Selector: map[string]string{"role": "db", "rack": "a1"},
+			TemplateID: "other"})
+		_ = s.Host().Create(entity.Host{ID: "host1", HardwareAddr: []string{"88-99-aa-bb-cc-d1"},
+			Labels: map[string]string{"role": "db", "rack": "b1"}})
+		_ = s.Host().Create(entity.Host{ID: "host2", HardwareAddr: []string{"88-99-aa-bb-cc-d2"},
+			Labels: map[string]string{"role": "db", "rack": "a1"}})
+		_ = s.Host().Create(entity.Host{ID: "host3", HardwareAddr: []string{"88-99-aa-bb-cc-d3"},
+			Labels: map[string]string{"rack": "a1"}})
+		_ = s.Host().Create(entity.Host{ID: "host4", HardwareAddr: []string{"88-99-aa-bb-cc-d4"}, GroupID: "g1",
+			Labels: map[string]string{"role": "db"}})
+		return s.Host().Create(entity.Host{ID: "host5", HardwareAddr: []string{"88-99-aa-bb-cc-d5"}})
+	})
+	tests := []struct {
+		name    string
+		host    string
+		want    string
+		wantErr bool
+	}{
+		{"OK_PRIORITY", "host1", "db", false},
+		{"OK_SAME_PRIORITY_BY_ID", "host2", "other", false},
+		{"OK_LOWER_PRIORITY", "host3", "rack", false},
+		{"OK_GROUP_TEMPLATE_FIRST", "host4", "group", false},
+		{"KO_NO_MATCH", "host5", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			err := Compile(buf, r, tt.host, "", Context{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Compile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("Compile() = %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	r, _ := repository.NewRepository(map[string]interface{}{"driver": "memory"})
+	ctx := Context{BaseURL: "http://10.0.0.1"}
+	buf := new(bytes.Buffer)
+	if err := Discover(buf, r, "88-99-aa-bb-cc-dd", ctx); err != nil {
+		t.Fatalf("Discover() error = %v", err)
+	}
+	for _, want := range []string{"param mac 88-99-aa-bb-cc-dd\n", "param serial ${serial}\n",
+		"chain http://10.0.0.1/discovery##params"} {
+		if !bytes.Contains(buf.Bytes(), []byte(want)) {
+			t.Errorf("Discover() = %q, want it to contain %q", buf.String(), want)
+		}
+	}
+	if err := Discover(new(bytes.Buffer), r, "88-99-aa-bb-cc-dd", Context{}); !errors.Is(err, errors.ETemplateError) {
+		t.Errorf("Discover() without base URL error = %v, want %v", err, errors.ETemplateError)
+	}
+
+	_ = r.Write(func(s repository.Session) error {
+		return s.Template().Create(entity.Template{ID: DiscoveryTemplateID, Template: "stored {{ .Context.HardwareAddr }}"})
+	})
+	buf.Reset()
+	if err := Discover(buf, r, "88-99-aa-bb-cc-dd", ctx); err != nil {
+		t.Fatalf("Discover() error = %v", err)
+	}
+	if buf.String() != "stored 88-99-aa-bb-cc-dd" {
+		t.Errorf("Discover() = %q, want %q", buf.String(), "stored 88-99-aa-bb-cc-dd")
+	}
+}
+
+func TestCompile_Facts(t *testing.T) {
+	r, _ := repository.NewRepository(map[string]interface{}{"driver": "memory"})
+	_ = r.Write(func(s repository.Session) error {
+		_ = s.Template().Create(entity.Template{ID: "t1",
+			Template: "{{ with .Facts.SmallestDisk.Name }}disk={{ . }}{{ else }}disk=auto{{ end }} serial={{ .Facts.Serial }}"})
+		_ = s.Host().Create(entity.Host{ID: "host1", HardwareAddr: []string{"88-99-aa-bb-cc-d1"}, TemplateID: "t1"})
+		_ = s.Host().Create(entity.Host{ID: "host2", HardwareAddr: []string{"88-99-aa-bb-cc-d2"}, TemplateID: "t1"})
+		_ = s.Facts().Create(entity.Facts{HostID: "host1", Serial: "S0"})
+		return s.Facts().Create(entity.Facts{HostID: "host1", Serial: "S1", Disks: []entity.FactsDisk{
+			{Name: "sda", Size: 2000}, {Name: "sdb", Size: 500, Removable: true}, {Name: "nvme0n1", Size: 1000}}})
+	})
+	tests := []struct {
+		name string
+		host string
+		want string
+	}{
+		{"OK_LATEST_FACTS", "host1", "disk=nvme0n1 serial=S1"},
+		{"OK_NO_FACTS", "host2", "disk=auto serial="},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			if err := Compile(buf, r, tt.host, "", Context{}); err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("Compile() = %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestCallbackToken(t *testing.T) {
+	r, _ := repository.NewRepository(map[string]interface{}{"driver": "memory"})
+	_ = r.Write(func(s repository.Session) error {
+		_ = s.Template().Create(entity.Template{ID: "install", Template: `{{ artifact "kickstart" }}`})
+		_ = s.Template().Create(entity.Template{ID: "kickstart", Template: `{{ .CallbackToken }}`})
+		return s.Host().Create(entity.Host{ID: "host1", HardwareAddr: []string{"88-99-aa-bb-cc-dd"},
+			TemplateID: "install", Artifacts: map[string]string{"kickstart": "kickstart"}})
+	})
+	ctx := Context{BaseURL: "http://10.0.0.1"}
+	preview := func(ctx Context) (string, error) {
+		buf := new(bytes.Buffer)
+		err := Compile(buf, r, "host1", "", ctx)
+		return buf.String(), err
+	}
+	render := func(ctx Context) (string, error) {
+		buf := new(bytes.Buffer)
+		_, err := Render(buf, r, "kickstart", "host1", nil, ctx)
+		return buf.String(), err
+	}
+	artifact := func(ctx Context) (string, error) {
+		buf := new(bytes.Buffer)
+		_, err := Artifact(buf, r, "host1", "kickstart", ctx)
+		return buf.String(), err
+	}
+	buf := new(bytes.Buffer)
+	if err := Boot(buf, r, "host1", "", ctx); err != nil {
+		t.Fatalf("Boot() error = %v", err)
+	}
+	prefix := "http://10.0.0.1/host/host1/artifact/kickstart?token="
+	token := strings.TrimPrefix(buf.String(), prefix)
+	if token == buf.String() || token == "" || token == CallbackTokenPlaceholder {
+		t.Fatalf("Boot() = %q, want %v<token>", buf.String(), prefix)
+	}
+	installer := ctx
+	installer.CallbackToken = token
+	wrong := ctx
+	wrong.CallbackToken = "0a1b"
+	tests := []struct {
+		name string
+		run  func(Context) (string, error)
+		ctx  Context
+		want string
+	}{
+		{"OK_COMPILE_PLACEHOLDER", preview, ctx, prefix + CallbackTokenPlaceholder},
+		{"OK_RENDER_PLACEHOLDER", render, ctx, CallbackTokenPlaceholder},
+		{"OK_ARTIFACT_PLACEHOLDER", artifact, ctx, CallbackTokenPlaceholder},
+		{"OK_ARTIFACT_WRONG_TOKEN", artifact, wrong, CallbackTokenPlaceholder},
+		{"OK_ARTIFACT_INSTALLER", artifact, installer, token},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.run(tt.ctx)
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBoot_ReadOnlyTrap(t *testing.T) {
+	d, err := ioutil.TempDir("", "tree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(d)
+	for name, content := range map[string]string{
+		"templates/install.ipxe": "install",
+		"hosts/host1.yaml":       "hardware-addr: [88-99-aa-bb-cc-dd]\ntemplate-id: install\ntrap-mode: true\n",
+	} {
+		_ = os.MkdirAll(filepath.Join(d, filepath.Dir(name)), 0755)
+		if err := ioutil.WriteFile(filepath.Join(d, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r, err := repository.NewRepository(map[string]interface{}{"driver": "directory", "path": d, "watch": false})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []string{"install", DefaultLocalBootTemplate} {
+		buf := new(bytes.Buffer)
+		if err := Boot(buf, r, "host1", "", Context{}); err != nil {
+			t.Fatalf("Boot() error = %v", err)
+		}
+		if buf.String() != want {
+			t.Errorf("Boot() %v = %q, want %q", i, buf.String(), want)
+		}
+	}
+	r, err = repository.NewRepository(map[string]interface{}{"driver": "directory", "path": d, "watch": false})
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := new(bytes.Buffer)
+	if err := Boot(buf, r, "host1", "", Context{}); err != nil || buf.String() != DefaultLocalBootTemplate {
+		t.Errorf("Boot() after restart = %q, %v want %q", buf.String(), err, DefaultLocalBootTemplate)
+	}
+}
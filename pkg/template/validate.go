@@ -0,0 +1,123 @@
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+var (
+	parseErrorRegex, _  = regexp.Compile(`^template: (.*?):(\d+): (.*)$`)
+	quotedTokenRegex, _ = regexp.Compile(`"((?:[^"\\]|\\.)*)"`)
+)
+
+// SyntaxError locates a template parse error.
+// Line and Column are 1-based, 0 when unknown. The parser only reports the line,
+// the column is the offending token when the message names it or the first action of the line.
+type SyntaxError struct {
+	Template string `json:"template"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Message  string `json:"message"`
+}
+
+// Error implements golang's error interface.
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%v:%v:%v: %v", e.Template, e.Line, e.Column, e.Message)
+}
+
+// Validate parses a template body with the template functions available at boot time.
+// Syntax errors are returned as errors.ETemplateError wrapping a SyntaxError.
+// Referenced stored templates are not checked as they may be stored afterwards.
+// Ignition templates must also be JSON documents, see jsonSkeleton.
+func Validate(t entity.Template) error {
+	tmpl, err := template.New(t.ID).Funcs(funcMap(new(Helper))).Parse(t.Template)
+	if err != nil {
+		return parseError(t.ID, t.Template, err)
+	}
+	if t.Kind != entity.TemplateKindIgnition {
+		return nil
+	}
+	b := new(strings.Builder)
+	if tmpl.Tree != nil {
+		jsonSkeleton(tmpl.Tree.Root, b)
+	}
+	if err := validJSON([]byte(b.String())); err != nil {
+		return &errors.Error{Code: errors.ETemplateError, Msg: "template.Helper ignition template is not valid JSON.",
+			Err: err}
+	}
+	return nil
+}
+
+// jsonSkeleton writes the text of the template replacing the actions by the placeholder 0.
+// Only one branch of each control structure is kept: the else branch of "if" and "with"
+// and the body of "range" once, so documents built with loops or optional members
+// may not be checked accurately.
+func jsonSkeleton(n parse.Node, b *strings.Builder) {
+	switch n := n.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Nodes {
+			jsonSkeleton(c, b)
+		}
+	case *parse.TextNode:
+		b.Write(n.Text)
+	case *parse.ActionNode:
+		if len(n.Pipe.Decl) == 0 {
+			b.WriteString("0")
+		}
+	case *parse.TemplateNode:
+		b.WriteString("0")
+	case *parse.IfNode:
+		jsonSkeleton(n.ElseList, b)
+	case *parse.WithNode:
+		jsonSkeleton(n.ElseList, b)
+	case *parse.RangeNode:
+		jsonSkeleton(n.List, b)
+	}
+}
+
+// validJSON returns the error decoding b as a JSON document.
+func validJSON(b []byte) error {
+	var v interface{}
+	return json.Unmarshal(b, &v)
+}
+
+// parseError converts the error of parsing body into an errors.ETemplateError wrapping a SyntaxError.
+func parseError(ID string, body string, err error) error {
+	se := &SyntaxError{Template: ID, Message: err.Error()}
+	if m := parseErrorRegex.FindStringSubmatch(err.Error()); m != nil {
+		se.Template, se.Message = m[1], m[3]
+		se.Line, _ = strconv.Atoi(m[2])
+		lines := strings.Split(body, "\n")
+		if se.Line > 0 && se.Line <= len(lines) {
+			se.Column = errorColumn(lines[se.Line-1], se.Message)
+		}
+	}
+	return &errors.Error{Code: errors.ETemplateError, Msg: "template.Helper parse error.", Err: se}
+}
+
+// errorColumn guesses the column of the error in the line.
+func errorColumn(line string, msg string) int {
+	if m := quotedTokenRegex.FindStringSubmatch(msg); m != nil {
+		token, err := strconv.Unquote(`"` + m[1] + `"`)
+		if err != nil {
+			token = m[1]
+		}
+		if i := strings.Index(line, token); token != "" && i >= 0 {
+			return i + 1
+		}
+	}
+	if i := strings.Index(line, "{{"); i >= 0 {
+		return i + 1
+	}
+	return 0
+}
@@ -0,0 +1,69 @@
+package template
+
+import (
+	stderrors "errors"
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want *SyntaxError
+	}{
+		{"OK", "kernel {{ .Vars.os | upper }}\n{{ include \"unknown\" . }}", nil},
+		{"KO_UNCLOSED", "#!ipxe\nkernel {{ .Vars.os ", &SyntaxError{"id", 2, 8, "unclosed action"}},
+		{"KO_FUNCTION", "#!ipxe\n  kernel {{ foo }}", &SyntaxError{"id", 2, 13, "function \"foo\" not defined"}},
+		{"KO_END", "#!ipxe\n  {{ end }}", &SyntaxError{"id", 2, 3, "unexpected {{end}}"}},
+		{"KO_DEFINE", "{{ define \"part\" }}\n{{ if }}{{ end }}", &SyntaxError{"id", 2, 1, "missing value for if"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(entity.Template{ID: "id", Template: tt.body})
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, errors.ETemplateError) {
+				t.Fatalf("Validate() error = %v, want %v", err, errors.ETemplateError)
+			}
+			var got *SyntaxError
+			if !stderrors.As(err.(*errors.Error).Err, &got) || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate_Ignition(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr bool
+	}{
+		{"OK", `{"ignition": {"version": "3.0.0"}, "passwd": {"users": [{"name": "{{ .Vars.user }}"}]}}`, false},
+		{"OK_ACTION_VALUE", `{"ignition": {{ toJSON .Vars }}, "count": {{ .Vars.count }}}`, false},
+		{"OK_DECLARATION", `{{ $u := .Vars.user }}{"user": "{{ $u }}"}`, false},
+		{"OK_OPTIONAL_MEMBER", `{"a": 1{{ if .Vars.b }}, "b": 2{{ end }}}`, false},
+		{"OK_RANGE", `[{{ range .Vars }}"{{ . }}"{{ end }}]`, false},
+		{"KO_TRAILING_COMMA", `{"ignition": {"version": "3.0.0"},}`, true},
+		{"KO_NOT_JSON", `#!ipxe\nkernel {{ .Vars.kernel }}`, true},
+		{"KO_EMPTY", ``, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(entity.Template{ID: "id", Template: tt.body, Kind: entity.TemplateKindIgnition})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, errors.ETemplateError) {
+				t.Errorf("Validate() error = %v, want %v", err, errors.ETemplateError)
+			}
+		})
+	}
+}
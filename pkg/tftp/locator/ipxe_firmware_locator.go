@@ -5,6 +5,7 @@ import (
 	"github.com/pxecore/pxecore/pkg/errors"
 	"github.com/pxecore/pxecore/pkg/ipxe"
 	"io"
+	"net"
 )
 
 const (
@@ -25,7 +26,7 @@ func NewIPXEFirmware() *IPXEFirmware {
 
 // Lookup returns the locator for the provided path.
 // See gitlab.com/pliego/pxecore/pkg/tftp/FileLocator
-func (s IPXEFirmware) Lookup(path string) (io.Reader, error) {
+func (s IPXEFirmware) Lookup(path string, client net.IP) (io.Reader, error) {
 	switch path {
 	case IPXEBiosFilename:
 		return bytes.NewReader(ipxe.GetIPXEBiosFile()), nil
@@ -2,33 +2,56 @@ package locator
 
 import (
 	"bytes"
+	"github.com/pxecore/pxecore/pkg/entity"
 	"github.com/pxecore/pxecore/pkg/errors"
 	"github.com/pxecore/pxecore/pkg/repository"
 	"github.com/pxecore/pxecore/pkg/template"
 	"io"
+	"net"
+	"net/url"
 	"regexp"
 	"strings"
 )
 
 // RepositoryIPXEScript searches the for the mac address in the configured repository.
 type RepositoryIPXEScript struct {
+	// Discovery serves the discovery template to the unknown hardware addresses instead of failing.
+	Discovery           bool
 	repository          repository.Repository
+	context             template.Context
 	ipxePathPattern     *regexp.Regexp
 	pxelinuxPathPattern *regexp.Regexp
 }
 
-// NewRepositoryIPXEScript construct RepositoryIPXEScript
-func NewRepositoryIPXEScript(repository repository.Repository) *RepositoryIPXEScript {
+// NewRepositoryIPXEScript construct RepositoryIPXEScript.
+// ctx holds the server fields of the context exposed to the templates, ServerAddr and BaseURL.
+func NewRepositoryIPXEScript(repository repository.Repository, ctx template.Context) *RepositoryIPXEScript {
 	s := new(RepositoryIPXEScript)
 	s.repository = repository
+	s.context = ctx
 	s.ipxePathPattern, _ = regexp.Compile("^mac-(([0-9a-f]{2}[-]){5}([0-9a-f]{2}))\\.ipxe$")
 	s.pxelinuxPathPattern, _ = regexp.Compile("^pxelinux\\.cfg/[0-9a-f]{2}-(([0-9a-f]{2}[-]){5}([0-9a-f]{2}))$")
 	return s
 }
 
 // Lookup returns the locator for the provided mac address.
+// The firmware is read from the optional "platform" and "buildarch" query params of the path.
+// The host is matched by the optional "uuid" query param first, unset or unknown UUIDs fall back to the mac address.
+// When the script can't be compiled it is returned along with the error so its boot event is recorded.
 // See github.com/pxecore/pxecore/pkg/tftp/FileLocator
-func (s RepositoryIPXEScript) Lookup(path string) (io.Reader, error) {
+func (s RepositoryIPXEScript) Lookup(path string, client net.IP) (io.Reader, error) {
+	ctx := template.Context{Transport: entity.BootTransportTFTP, Path: path,
+		ServerAddr: s.context.ServerAddr, BaseURL: s.context.BaseURL}
+	if client != nil {
+		ctx.ClientIP = client.String()
+	}
+	uuid := ""
+	if i := strings.Index(path, "?"); i >= 0 {
+		q, _ := url.ParseQuery(path[i+1:])
+		ctx.LoadQuery(q)
+		uuid = q.Get("uuid")
+		path = path[:i]
+	}
 	fn := strings.ToLower(path)
 	var ha string
 	var ok bool
@@ -37,11 +60,36 @@ func (s RepositoryIPXEScript) Lookup(path string) (io.Reader, error) {
 			return nil, &errors.Error{Code: errors.ENotFound, Msg: "[tftp.locator] Path is not an IPXE script"}
 		}
 	}
-	buf := new(bytes.Buffer)
-	if err := template.CompileWithHardwareAddr(buf, s.repository, ha, ""); err != nil {
-		return nil, err
+	ctx.HardwareAddr = ha
+	script := &Script{Buffer: new(bytes.Buffer), event: ctx.NewEvent()}
+	ctx = ctx.WithEvent(&script.event)
+	var err error = &errors.Error{Code: errors.ERepositoryKeyNotFound, Msg: "[tftp.locator] uuid is not set"}
+	if uuid != "" && !entity.PlaceholderUUID(uuid) {
+		err = template.CompileWithIdentifier(script, s.repository, entity.IdentifierUUID, uuid, "", ctx)
+	}
+	if errors.Is(err, errors.ERepositoryKeyNotFound) {
+		script.Reset()
+		err = template.CompileWithHardwareAddr(script, s.repository, ha, "", ctx)
+	}
+	if s.Discovery && errors.Is(err, errors.ERepositoryKeyNotFound) {
+		script.Reset()
+		err = template.Discover(script, s.repository, ha, ctx)
+	}
+	if err != nil {
+		return script, err
 	}
-	return buf, nil
+	return script, nil
+}
+
+// Script is an iPXE script compiled for a booting host.
+type Script struct {
+	*bytes.Buffer
+	event entity.BootEvent
+}
+
+// BootEvent implements tftp.EventReader interface.
+func (s Script) BootEvent() entity.BootEvent {
+	return s.event
 }
 
 // MatchIPXEPath searches the hardware address in the IPXE defined path.
@@ -3,6 +3,9 @@ package locator
 import (
 	"github.com/pxecore/pxecore/pkg/entity"
 	"github.com/pxecore/pxecore/pkg/repository"
+	"github.com/pxecore/pxecore/pkg/template"
+	"io/ioutil"
+	"net"
 	"reflect"
 	"testing"
 )
@@ -12,29 +15,33 @@ func TestRepositoryIPXEScript_Lookup(t *testing.T) {
 	s, _ := r.Open(true)
 	_ = s.Template().Create(entity.Template{ID: "template", Template: "A"})
 	_ = s.Host().Create(entity.Host{ID: "host", HardwareAddr: []string{"88-99-aa-bb-cc-dd"},
-		Vars: map[string]string{}, TemplateID: "template"})
+		Vars: map[string]interface{}{}, TemplateID: "template"})
 	_ = s.Close()
 	tests := []struct {
-		name    string
-		path    string
-		want    []byte
-		wantErr bool
+		name       string
+		path       string
+		want       []byte
+		wantErr    bool
+		wantScript bool
 	}{
-		{"OK_1", "mac-88-99-aa-bb-cc-dd.ipxe", []byte{65}, false},
-		{"OK_2", "mac-88-99-AA-BB-CC-DD.ipxe", []byte{65}, false},
-		{"OK_3", "pxelinux.cfg/01-88-99-AA-BB-CC-DD", []byte{65}, false},
-		{"OK_4", "pxelinux.cfg/01-88-99-AA-BB-CC-DD", []byte{65}, false},
-		{"KO_1", "pxelinux.cfg/01-88-99-AA-BB-CC-EE", nil, true},
-		{"KO_2", "none", nil, true},
+		{"OK_1", "mac-88-99-aa-bb-cc-dd.ipxe", []byte{65}, false, true},
+		{"OK_2", "mac-88-99-AA-BB-CC-DD.ipxe", []byte{65}, false, true},
+		{"OK_3", "pxelinux.cfg/01-88-99-AA-BB-CC-DD", []byte{65}, false, true},
+		{"OK_4", "pxelinux.cfg/01-88-99-AA-BB-CC-DD", []byte{65}, false, true},
+		{"KO_1", "pxelinux.cfg/01-88-99-AA-BB-CC-EE", nil, true, true},
+		{"KO_2", "none", nil, true, false},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			s := NewRepositoryIPXEScript(r)
-			g, err := s.Lookup(tt.path)
+			s := NewRepositoryIPXEScript(r, template.Context{})
+			g, err := s.Lookup(tt.path, nil)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Lookup() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
+			if sc, ok := g.(*Script); ok != tt.wantScript || (ok && sc.BootEvent().HardwareAddr == "") {
+				t.Errorf("Lookup() got = %#v, want script %v", g, tt.wantScript)
+			}
 			if err == nil {
 				got := make([]byte, 1)
 				g.Read(got)
@@ -46,6 +53,135 @@ func TestRepositoryIPXEScript_Lookup(t *testing.T) {
 	}
 }
 
+func TestRepositoryIPXEScript_LookupContext(t *testing.T) {
+	r, _ := repository.NewRepository(map[string]interface{}{"driver": "memory"})
+	s, _ := r.Open(true)
+	_ = s.Template().Create(entity.Template{ID: "context", Template: "{{ .Context.Transport }} {{ .Context.ClientIP }} " +
+		"{{ .Context.ServerAddr }} {{ .Context.HardwareAddr }} {{ .Context.Firmware }} {{ .Context.Arch }}"})
+	_ = s.Host().Create(entity.Host{ID: "host", HardwareAddr: []string{"88-99-aa-bb-cc-dd"}, TemplateID: "context"})
+	_ = s.Template().Create(entity.Template{ID: "uuid", Template: "uuid {{ .Context.HardwareAddr }} {{ .Context.Firmware }}"})
+	_ = s.Host().Create(entity.Host{ID: "host-uuid", UUID: "4c4c4544-0042-3010-8052-b4c04f4e4e32", TemplateID: "uuid"})
+	_ = s.Close()
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"OK_UUID", "mac-88-99-aa-bb-cc-dd.ipxe?uuid=4C4C4544-0042-3010-8052-B4C04F4E4E32&platform=efi&buildarch=x86_64",
+			"uuid 88-99-aa-bb-cc-dd uefi"},
+		{"OK_UNKNOWN_UUID", "mac-88-99-aa-bb-cc-dd.ipxe?uuid=11111111-2222-3333-4444-555555555555&platform=efi",
+			"tftp 10.0.0.2 10.0.0.1 88-99-aa-bb-cc-dd uefi "},
+		{"OK_PLACEHOLDER_UUID", "mac-88-99-aa-bb-cc-dd.ipxe?uuid=00000000-0000-0000-0000-000000000000",
+			"tftp 10.0.0.2 10.0.0.1 88-99-aa-bb-cc-dd  "},
+		{"OK_EMPTY_UUID", "mac-88-99-aa-bb-cc-dd.ipxe?uuid=&platform=&buildarch=",
+			"tftp 10.0.0.2 10.0.0.1 88-99-aa-bb-cc-dd  "},
+		{"OK_NO_FIRMWARE", "mac-88-99-aa-bb-cc-dd.ipxe", "tftp 10.0.0.2 10.0.0.1 88-99-aa-bb-cc-dd  "},
+		{"OK_UEFI", "mac-88-99-aa-bb-cc-dd.ipxe?platform=efi&buildarch=x86_64",
+			"tftp 10.0.0.2 10.0.0.1 88-99-aa-bb-cc-dd uefi x86_64"},
+		{"OK_BIOS", "mac-88-99-aa-bb-cc-dd.ipxe?platform=pcbios&buildarch=i386",
+			"tftp 10.0.0.2 10.0.0.1 88-99-aa-bb-cc-dd bios i386"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := NewRepositoryIPXEScript(r, template.Context{ServerAddr: "10.0.0.1"}).Lookup(tt.path, net.IPv4(10, 0, 0, 2))
+			if err != nil {
+				t.Fatalf("Lookup() error = %v", err)
+			}
+			got, _ := ioutil.ReadAll(g)
+			if string(got) != tt.want {
+				t.Errorf("Lookup() got = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepositoryIPXEScript_LookupTrap(t *testing.T) {
+	r, _ := repository.NewRepository(map[string]interface{}{"driver": "memory"})
+	s, _ := r.Open(true)
+	_ = s.Template().Create(entity.Template{ID: "install", Template: "install"})
+	_ = s.Host().Create(entity.Host{ID: "host1", HardwareAddr: []string{"88-99-aa-bb-cc-01"},
+		TrapMode: true, TemplateID: "install"})
+	_ = s.Host().Create(entity.Host{ID: "host2", HardwareAddr: []string{"88-99-aa-bb-cc-02"},
+		TrapMode: true, TemplateID: "install"})
+	_ = s.Close()
+	rearm := func() {
+		s, _ := r.Open(true)
+		_ = s.Template().Create(entity.Template{ID: "local-boot", Template: "local"})
+		h, _ := s.Host().Get("host1")
+		h.TrapTriggered = false
+		_ = s.Host().Update(h)
+		_ = s.Close()
+	}
+	tests := []struct {
+		name   string
+		path   string
+		before func()
+		want   string
+	}{
+		{"OK_INSTALL", "mac-88-99-aa-bb-cc-01.ipxe", nil, "install"},
+		{"OK_DEFAULT_LOCAL_BOOT", "mac-88-99-aa-bb-cc-01.ipxe", nil, "#!ipxe\nexit\n"},
+		{"OK_DEFAULT_LOCAL_BOOT_AGAIN", "pxelinux.cfg/01-88-99-aa-bb-cc-01", nil, "#!ipxe\nexit\n"},
+		{"OK_OTHER_HOST_INSTALL", "mac-88-99-aa-bb-cc-02.ipxe", nil, "install"},
+		{"OK_REARMED_INSTALL", "mac-88-99-aa-bb-cc-01.ipxe", rearm, "install"},
+		{"OK_STORED_LOCAL_BOOT", "mac-88-99-aa-bb-cc-01.ipxe", nil, "local"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.before != nil {
+				tt.before()
+			}
+			g, err := NewRepositoryIPXEScript(r, template.Context{}).Lookup(tt.path, nil)
+			if err != nil {
+				t.Fatalf("Lookup() error = %v", err)
+			}
+			got, _ := ioutil.ReadAll(g)
+			if string(got) != tt.want {
+				t.Errorf("Lookup() got = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepositoryIPXEScript_LookupDiscovery(t *testing.T) {
+	r, _ := repository.NewRepository(map[string]interface{}{"driver": "memory"})
+	s, _ := r.Open(true)
+	_ = s.Template().Create(entity.Template{ID: "discovery", Template: "discover {{ .Context.HardwareAddr }}"})
+	_ = s.Template().Create(entity.Template{ID: "install", Template: "install"})
+	_ = s.Host().Create(entity.Host{ID: "host1", HardwareAddr: []string{"88-99-aa-bb-cc-01"}, TemplateID: "install"})
+	_ = s.Host().Create(entity.Host{ID: "host2", HardwareAddr: []string{"88-99-aa-bb-cc-02"}, TemplateID: "install",
+		Pending: true})
+	_ = s.Close()
+	tests := []struct {
+		name      string
+		path      string
+		discovery bool
+		want      string
+		wantErr   bool
+	}{
+		{"OK_KNOWN", "mac-88-99-aa-bb-cc-01.ipxe", true, "install", false},
+		{"OK_PENDING", "mac-88-99-aa-bb-cc-02.ipxe", true, "discover 88-99-aa-bb-cc-02", false},
+		{"OK_UNKNOWN", "mac-88-99-aa-bb-cc-03.ipxe", true, "discover 88-99-aa-bb-cc-03", false},
+		{"KO_UNKNOWN_DISABLED", "mac-88-99-aa-bb-cc-03.ipxe", false, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewRepositoryIPXEScript(r, template.Context{})
+			l.Discovery = tt.discovery
+			g, err := l.Lookup(tt.path, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Lookup() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			got, _ := ioutil.ReadAll(g)
+			if string(got) != tt.want {
+				t.Errorf("Lookup() got = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestRepositoryIPXEScript_MatchIPXEPath(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -66,7 +202,7 @@ func TestRepositoryIPXEScript_MatchIPXEPath(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			s := NewRepositoryIPXEScript(nil)
+			s := NewRepositoryIPXEScript(nil, template.Context{})
 			got, got1 := s.MatchIPXEPath(tt.path)
 			if got != tt.want {
 				t.Errorf("MatchIPXEPath() got = %v, want %v", got, tt.want)
@@ -99,7 +235,7 @@ func TestRepositoryIPXEScript_MatchPXELINUXPathPattern(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			s := NewRepositoryIPXEScript(nil)
+			s := NewRepositoryIPXEScript(nil, template.Context{})
 			got, got1 := s.MatchPXELINUXPathPattern(tt.path)
 			if got != tt.want {
 				t.Errorf("MatchIPXEPath() got = %v, want %v", got, tt.want)
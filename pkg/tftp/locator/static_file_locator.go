@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/pxecore/pxecore/pkg/errors"
 	"io"
+	"net"
 	"os"
 	"strings"
 )
@@ -24,7 +25,7 @@ func NewStaticFile(baseDir string, basePath string) *StaticFile {
 
 // Lookup returns the locator for the provided path.
 // See gitlab.com/pliego/pxecore/pkg/tftp/FileLocator
-func (s StaticFile) Lookup(path string) (io.Reader, error) {
+func (s StaticFile) Lookup(path string, client net.IP) (io.Reader, error) {
 	if s.BasePath == "" || s.BaseDir == "" || !strings.HasPrefix(path, s.BasePath) {
 		return nil, &errors.Error{Code: errors.ENotFound,
 			Msg: "[tftp.locator] file not found."}
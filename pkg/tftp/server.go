@@ -4,9 +4,11 @@ package tftp
 
 import (
 	"github.com/pin/tftp"
+	"github.com/pxecore/pxecore/pkg/entity"
 	"github.com/pxecore/pxecore/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"io"
+	"net"
 	"path/filepath"
 	"strings"
 	"time"
@@ -22,12 +24,27 @@ type ServerConfig struct {
 	FileLocators []FileLocator
 	// LogRequests allows to log all made requests.
 	LogRequests bool
+	// Events records the boot event of the host template files, see EventReader. Optional.
+	Events EventRecorder
 }
 
+// EventRecorder records the boot event of a file request, served with n bytes or failed with err.
+type EventRecorder func(e entity.BootEvent, n int64, err error) error
+
 // FileLocator implements the IPXE static lookup procedure.
 type FileLocator interface {
 	// Lookup finds and returns the IPXE static suitable for the mac address provided.
-	Lookup(path string) (io.Reader, error)
+	// client is the address of the requesting host or nil if unknown.
+	Lookup(path string, client net.IP) (io.Reader, error)
+}
+
+// EventReader is implemented by the located files compiled for a host,
+// their boot event holds the host and the template served.
+// Only the requests of these files are recorded, the locators return it along with the error
+// when the host template can't be compiled so the failure is recorded too.
+type EventReader interface {
+	io.Reader
+	BootEvent() entity.BootEvent
 }
 
 // Server is the representation of the TFTP server for this domain.
@@ -70,22 +87,41 @@ func (s *Server) Shutdown() error {
 // tftpReadHandler handles a read event in the TFTP server.
 func (s Server) tftpReadHandler(path string, rf io.ReaderFrom) error {
 	p := strings.TrimPrefix(filepath.Clean(path), "..")
+	var client net.IP
+	if ot, ok := rf.(tftp.OutgoingTransfer); ok {
+		client = ot.RemoteAddr().IP
+	}
 	for _, v := range s.fileLocators {
-		r, err := v.Lookup(p)
+		r, err := v.Lookup(p, client)
 		if err != nil {
 			if !errors.Is(err, errors.ENotFound) {
 				log.WithError(err).Error("Error locating file.")
 			}
+			s.record(r, 0, err)
 			continue
 		}
-		if _, err := rf.ReadFrom(r); err != nil {
+		n, err := rf.ReadFrom(r)
+		s.record(r, n, err)
+		if err != nil {
 			log.WithError(err).Error("Error sending TFTP response")
 			return nil
 		}
 		if s.config.LogRequests {
-			log.WithFields(log.Fields{"filename": p}).Debug("TFTP Request.")
+			log.WithFields(log.Fields{"filename": p, "client": client}).Debug("TFTP Request.")
 		}
 		return nil
 	}
 	return &errors.Error{Code: errors.ENotFound, Msg: "File not found."}
 }
+
+// record stores the boot event of a host template file if an EventRecorder is configured.
+// Other files are not recorded.
+func (s Server) record(r io.Reader, n int64, err error) {
+	er, ok := r.(EventReader)
+	if !ok || s.config.Events == nil {
+		return
+	}
+	if err := s.config.Events(er.BootEvent(), n, err); err != nil {
+		log.WithError(err).Error("Error recording TFTP boot event.")
+	}
+}
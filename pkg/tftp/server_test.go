@@ -0,0 +1,91 @@
+package tftp
+
+import (
+	"bytes"
+	"github.com/pxecore/pxecore/pkg/entity"
+	"github.com/pxecore/pxecore/pkg/errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+type testLocator func(path string) (io.Reader, error)
+
+func (l testLocator) Lookup(path string, client net.IP) (io.Reader, error) {
+	return l(path)
+}
+
+type testEventReader struct {
+	io.Reader
+	event entity.BootEvent
+}
+
+func (r testEventReader) BootEvent() entity.BootEvent {
+	return r.event
+}
+
+func TestServer_tftpReadHandler(t *testing.T) {
+	firmware := testLocator(func(path string) (io.Reader, error) {
+		switch path {
+		case "undionly.kpxe":
+			return strings.NewReader("firmware"), nil
+		case "broken.kpxe":
+			return nil, &errors.Error{Code: errors.EUnknown, Msg: "broken"}
+		}
+		return nil, &errors.Error{Code: errors.ENotFound}
+	})
+	script := testLocator(func(path string) (io.Reader, error) {
+		e := entity.BootEvent{Transport: entity.BootTransportTFTP, Path: path}
+		switch path {
+		case "mac-88-99-aa-bb-cc-dd.ipxe":
+			e.HostID = "host1"
+			return testEventReader{Reader: strings.NewReader("#!ipxe"), event: e}, nil
+		case "mac-88-99-aa-bb-cc-ee.ipxe":
+			return testEventReader{Reader: strings.NewReader(""), event: e},
+				&errors.Error{Code: errors.ERepositoryKeyNotFound}
+		}
+		return nil, &errors.Error{Code: errors.ENotFound}
+	})
+	type event struct {
+		path   string
+		hostID string
+		n      int64
+		failed bool
+	}
+	var got []event
+	s := Server{fileLocators: []FileLocator{firmware, script}, config: &ServerConfig{
+		Events: func(e entity.BootEvent, n int64, err error) error {
+			got = append(got, event{e.Path, e.HostID, n, err != nil})
+			return nil
+		},
+	}}
+	tests := []struct {
+		name      string
+		path      string
+		wantErr   bool
+		wantEvent *event
+	}{
+		{"OK_FIRMWARE", "undionly.kpxe", false, nil},
+		{"KO_FIRMWARE", "broken.kpxe", true, nil},
+		{"KO_NOT_FOUND", "none", true, nil},
+		{"OK_HOST_SCRIPT", "mac-88-99-aa-bb-cc-dd.ipxe", false,
+			&event{"mac-88-99-aa-bb-cc-dd.ipxe", "host1", 6, false}},
+		{"KO_HOST_SCRIPT", "mac-88-99-aa-bb-cc-ee.ipxe", true,
+			&event{"mac-88-99-aa-bb-cc-ee.ipxe", "", 0, true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = nil
+			if err := s.tftpReadHandler(tt.path, new(bytes.Buffer)); (err != nil) != tt.wantErr {
+				t.Errorf("tftpReadHandler() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantEvent == nil && len(got) != 0 {
+				t.Errorf("tftpReadHandler() recorded %+v, want none", got)
+			}
+			if tt.wantEvent != nil && (len(got) != 1 || got[0] != *tt.wantEvent) {
+				t.Errorf("tftpReadHandler() recorded %+v, want %+v", got, *tt.wantEvent)
+			}
+		})
+	}
+}
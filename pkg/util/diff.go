@@ -0,0 +1,113 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffLine is a line of a diff: ' ' kept, '-' removed or '+' added.
+// from and to are the positions of the line in each text.
+type diffLine struct {
+	op   byte
+	text string
+	from int
+	to   int
+}
+
+// UnifiedDiff returns the line based unified diff between two texts with
+// context unchanged lines around each change. Identical texts return "".
+func UnifiedDiff(from string, to string, fromName string, toName string, context int) string {
+	lines := diffLines(splitLines(from), splitLines(to))
+	hunks := make([][2]int, 0)
+	for k, l := range lines {
+		if l.op == ' ' {
+			continue
+		}
+		start, end := k-context, k+context+1
+		if start < 0 {
+			start = 0
+		}
+		if end > len(lines) {
+			end = len(lines)
+		}
+		if n := len(hunks); n > 0 && start <= hunks[n-1][1] {
+			hunks[n-1][1] = end
+		} else {
+			hunks = append(hunks, [2]int{start, end})
+		}
+	}
+	if len(hunks) == 0 {
+		return ""
+	}
+	b := new(strings.Builder)
+	fmt.Fprintf(b, "--- %v\n+++ %v\n", fromName, toName)
+	for _, h := range hunks {
+		fromCount, toCount := 0, 0
+		for _, l := range lines[h[0]:h[1]] {
+			if l.op != '+' {
+				fromCount++
+			}
+			if l.op != '-' {
+				toCount++
+			}
+		}
+		fromStart, toStart := lines[h[0]].from, lines[h[0]].to
+		if fromCount > 0 {
+			fromStart++
+		}
+		if toCount > 0 {
+			toStart++
+		}
+		fmt.Fprintf(b, "@@ -%v,%v +%v,%v @@\n", fromStart, fromCount, toStart, toCount)
+		for _, l := range lines[h[0]:h[1]] {
+			b.WriteByte(l.op)
+			b.WriteString(l.text)
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
+
+// diffLines aligns both texts on their longest common subsequence of lines.
+func diffLines(a []string, b []string) []diffLine {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	r := make([]diffLine, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			r = append(r, diffLine{' ', a[i], i, j})
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			r = append(r, diffLine{'-', a[i], i, j})
+			i++
+		default:
+			r = append(r, diffLine{'+', b[j], i, j})
+			j++
+		}
+	}
+	return r
+}
+
+// splitLines splits a text in lines ignoring the final line break.
+func splitLines(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
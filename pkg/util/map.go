@@ -36,3 +36,65 @@ func StringFromMap(m map[string]interface{}, k string, d string) (string, error)
 	}
 	return d, nil
 }
+
+// BoolFromMap extract a boolean from a map if the type is incorrect will return an error.
+// If the key is missing will return the default value.
+func BoolFromMap(m map[string]interface{}, k string, d bool) (bool, error) {
+	if e, ok := m[k]; ok {
+		val, ok := e.(bool)
+		if !ok {
+			return val, &errors.Error{
+				Code: errors.EInvalidType,
+				Msg:  fmt.Sprint("[util.BoolFromMap] invalid type for ", k),
+			}
+		}
+		return val, nil
+	}
+	return d, nil
+}
+
+// StringSliceFromMap extract a list of strings from a map if the type is incorrect will return an error.
+// If the key is missing will return the default value.
+func StringSliceFromMap(m map[string]interface{}, k string, d []string) ([]string, error) {
+	e, ok := m[k]
+	if !ok {
+		return d, nil
+	}
+	switch val := e.(type) {
+	case []string:
+		return val, nil
+	case []interface{}:
+		r := make([]string, 0, len(val))
+		for _, i := range val {
+			s, ok := i.(string)
+			if !ok {
+				return nil, &errors.Error{
+					Code: errors.EInvalidType,
+					Msg:  fmt.Sprint("[util.StringSliceFromMap] invalid type for ", k),
+				}
+			}
+			r = append(r, s)
+		}
+		return r, nil
+	}
+	return nil, &errors.Error{
+		Code: errors.EInvalidType,
+		Msg:  fmt.Sprint("[util.StringSliceFromMap] invalid type for ", k),
+	}
+}
+
+// StringMap converts a decoded configuration map into a map with string keys.
+// Returns false if the value is not a map.
+func StringMap(v interface{}) (map[string]interface{}, bool) {
+	switch m := v.(type) {
+	case map[string]interface{}:
+		return m, true
+	case map[interface{}]interface{}:
+		r := make(map[string]interface{}, len(m))
+		for k, val := range m {
+			r[fmt.Sprint(k)] = val
+		}
+		return r, true
+	}
+	return nil, false
+}
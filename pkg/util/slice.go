@@ -22,3 +22,13 @@ func AddUniqueStringToSlice(slice []string, elem string) []string {
 	}
 	return append(slice, elem)
 }
+
+// ContainsString returns true if the slice contains the string.
+func ContainsString(slice []string, elem string) bool {
+	for _, e := range slice {
+		if e == elem {
+			return true
+		}
+	}
+	return false
+}